@@ -0,0 +1,341 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sung01299/mole/internal/config"
+	"github.com/sung01299/mole/internal/ngrok"
+	"github.com/sung01299/mole/internal/storage"
+	"github.com/sung01299/mole/internal/tui"
+	"github.com/sung01299/mole/internal/util"
+)
+
+// commandEnv is what subcommands get from the global flags, the config and
+// the environment
+type commandEnv struct {
+	configPath string
+	dbPath     string
+	opts       tui.Options
+	client     *ngrok.Client
+	baseURL    string
+	cfg        *config.Config
+	themeFlag  string
+}
+
+// command is a subcommand of mole, run in place of the TUI
+type command struct {
+	usage string
+	ngrok bool // Needs the ngrok API, which is checked for before it runs
+	tui   bool // Draws the TUI, so loads the theme and keys before it runs
+	run   func(env *commandEnv, args []string) int
+}
+
+// commands are the subcommands, by name. Only those that capture or replay
+// requests need ngrok running.
+var commands = map[string]command{
+	"import":   {usage: "mole import <file.json> [file.json...]", run: runImport},
+	"export":   {usage: "mole export [--session <id|latest> | --starred] [--format <format>] [--out <file>]", run: runExport},
+	"sessions": {usage: "mole sessions [rm [--force] <id>]", run: runSessions},
+	"stats":    {usage: "mole stats", run: runStats},
+	"replay":   {usage: "mole replay [--target <addr>] [--count <n>] [--interval <duration>] <request-id>", ngrok: true, run: runReplay},
+	"tail":     {usage: "mole tail [--json] [--filter <expr>]", ngrok: true, run: runTail},
+	// watch checks for ngrok itself, to fail with its own exit status
+	"watch":  {usage: "mole watch --for <duration> [--filter <expr>] [--quiet]", run: runWatch},
+	"config": {usage: "mole config init [--force]", run: runConfig},
+	"view":   {usage: "mole view <file.json|file.har>", tui: true, run: runView},
+}
+
+// runCommand runs the subcommand name and returns its exit code
+func runCommand(name string, args []string, env *commandEnv) int {
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\nCommands:\n", name)
+		printCommandUsages(os.Stderr)
+		return 2
+	}
+	if cmd.ngrok && !checkNgrok(env.client, env.baseURL) {
+		return 1
+	}
+	if cmd.tui {
+		if err := loadLook(&env.opts, env.cfg, env.themeFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	return cmd.run(env, args)
+}
+
+// printCommandUsages lists the usage of each command, by name
+func printCommandUsages(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for n := range commands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Fprintln(w, "  "+commands[n].usage)
+	}
+}
+
+// openHistory opens the history database for a subcommand that works on
+// what is already in it. No session is started.
+func openHistory(name string, dbPath string) (*storage.Storage, error) {
+	if dbPath == storage.MemoryPath {
+		return nil, fmt.Errorf("%s needs a database on disk (--db or MOLE_DB_PATH)", name)
+	}
+	return storage.New(dbPath)
+}
+
+// runConfig manages the config file. init writes a commented template of
+// every setting.
+func runConfig(env *commandEnv, args []string) int {
+	if len(args) == 0 || args[0] != "init" {
+		fmt.Fprintln(os.Stderr, "Usage: mole config init [--force]")
+		return 2
+	}
+	fs := flag.NewFlagSet("config init", flag.ContinueOnError)
+	force := fs.Bool("force", false, "replace the config file if there is one")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: mole config init [--force]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	if err := config.WriteTemplate(env.configPath, *force); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Wrote %s\n", env.configPath)
+	return 0
+}
+
+// runSessions lists the sessions in history, newest first, or with rm
+// deletes one
+func runSessions(env *commandEnv, args []string) int {
+	if len(args) > 0 && args[0] == "rm" {
+		return runSessionsRm(env, args[1:])
+	}
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "Usage: mole sessions [rm [--force] <id>]")
+		return 2
+	}
+
+	store, err := openHistory("sessions", env.dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	sessions, err := store.GetSessions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No sessions in history")
+		return 0
+	}
+
+	const timeFormat = "2006-01-02 15:04"
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTARTED\tENDED\tREQUESTS\tTUNNEL\tLABEL")
+	for _, sess := range sessions {
+		count, err := store.GetSessionRequestCount(sess.ID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		// A session without an end is still recording, or its mole didn't
+		// exit cleanly
+		ended := "-"
+		if sess.EndedAt != nil {
+			ended = sess.EndedAt.Local().Format(timeFormat)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			sess.ID, sess.StartedAt.Local().Format(timeFormat), ended, count, sess.TunnelURL, sess.Label)
+	}
+	w.Flush()
+	return 0
+}
+
+// runSessionsRm deletes a session and its requests, starred ones included
+func runSessionsRm(env *commandEnv, args []string) int {
+	fs := flag.NewFlagSet("sessions rm", flag.ContinueOnError)
+	force := fs.Bool("force", false, "delete the session even if it hasn't ended, as when mole is still recording it")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: mole sessions rm [--force] <id>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	id := fs.Arg(0)
+
+	store, err := openHistory("sessions rm", env.dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	sessions, err := store.GetSessions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	idx := slices.IndexFunc(sessions, func(s storage.Session) bool {
+		return s.ID == id
+	})
+	if idx < 0 {
+		fmt.Fprintf(os.Stderr, "Error: no session with ID %q (see mole sessions)\n", id)
+		return 1
+	}
+	if sessions[idx].EndedAt == nil && !*force {
+		fmt.Fprintf(os.Stderr, "Error: session %s hasn't ended, so mole may still be recording it (--force deletes it anyway)\n", id)
+		return 1
+	}
+
+	count, err := store.GetSessionRequestCount(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := store.DeleteSession(id); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Deleted session %s and its %d requests\n", id, count)
+	return 0
+}
+
+// runStats prints what the history database holds and its size on disk
+func runStats(env *commandEnv, args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "Usage: mole stats")
+		return 2
+	}
+
+	store, err := openHistory("stats", env.dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	stats, err := store.GetStats()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Database\t%s\n", store.Path())
+	fmt.Fprintf(w, "Size\t%s\n", util.FormatBytes(stats.SizeBytes))
+	fmt.Fprintf(w, "Sessions\t%d\n", stats.Sessions)
+	fmt.Fprintf(w, "Requests\t%d (%d starred)\n", stats.Requests, stats.Starred)
+	if !stats.Oldest.IsZero() {
+		fmt.Fprintf(w, "Date range\t%s – %s\n",
+			stats.Oldest.Local().Format("2006-01-02 15:04"), stats.Newest.Local().Format("2006-01-02 15:04"))
+	}
+	w.Flush()
+	return 0
+}
+
+// runExport writes a session, or the starred requests of all sessions, to a
+// file
+func runExport(env *commandEnv, args []string) int {
+	names := make([]string, len(storage.ExportFormats))
+	for i, f := range storage.ExportFormats {
+		names[i] = string(f)
+	}
+
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	sessionID := fs.String("session", "latest", `session to export, by ID (see mole sessions) or "latest"`)
+	starred := fs.Bool("starred", false, "export the starred requests of every session instead (json, har or csv)")
+	formatName := fs.String("format", "json", "file format: "+strings.Join(names, ", "))
+	out := fs.String("out", "", "file to write (default: a timestamped name in the current directory)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: mole export [--session <id|latest> | --starred] [--format <format>] [--out <file>]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	sessionSet := false
+	fs.Visit(func(f *flag.Flag) {
+		sessionSet = sessionSet || f.Name == "session"
+	})
+	if *starred && sessionSet {
+		fmt.Fprintln(os.Stderr, "Error: --session and --starred can't be used together")
+		return 2
+	}
+
+	format, err := storage.ParseExportFormat(*formatName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v (use %s)\n", err, strings.Join(names, ", "))
+		return 2
+	}
+	path := *out
+	if path == "" {
+		path = storage.GenerateExportFilenameFor(format)
+	}
+
+	store, err := openHistory("export", env.dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	if *starred {
+		n, err := store.ExportStarred(format, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Exported %d starred requests to %s\n", n, path)
+		return 0
+	}
+
+	id := *sessionID
+	if id == "latest" {
+		sessions, err := store.GetSessions()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(sessions) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: no sessions in history")
+			return 1
+		}
+		id = sessions[0].ID
+	}
+	if err := store.ExportSession(id, format, path); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Exported session %s to %s\n", id, path)
+	return 0
+}
@@ -0,0 +1,123 @@
+// Package capture polls ngrok and records what it captures as history. The
+// TUI and the headless commands share it, so both save requests the same way.
+package capture
+
+import (
+	"github.com/sung01299/mole/internal/ngrok"
+	"github.com/sung01299/mole/internal/storage"
+)
+
+// DefaultPollLimit is how many of its latest requests ngrok is asked for
+// each poll, unless told otherwise
+const DefaultPollLimit = 50
+
+// Poll fetches up to limit of the requests ngrok currently lists, newest
+// first
+func Poll(client *ngrok.Client, limit int) ([]ngrok.Request, error) {
+	return client.GetRequests(limit)
+}
+
+// Recorder saves each request ngrok lists to history once, with sensitive
+// header values masked and bodies capped
+type Recorder struct {
+	store         *storage.Storage
+	writer        *storage.Writer
+	redactHeaders []string
+	maxBodyBytes  int             // 0 = no limit
+	recorded      map[string]bool // Requests already recorded, by ID
+}
+
+// NewRecorder returns a recorder that saves through writer into the current
+// session of store. Without a store nothing is saved, but each request is
+// still only recorded once.
+func NewRecorder(store *storage.Storage, writer *storage.Writer, redactHeaders []string, maxBodyBytes int) *Recorder {
+	return &Recorder{
+		store:         store,
+		writer:        writer,
+		redactHeaders: redactHeaders,
+		maxBodyBytes:  maxBodyBytes,
+		recorded:      make(map[string]bool),
+	}
+}
+
+// Record saves the requests in reqs that weren't recorded before, as one
+// batch, and returns them in the order given. annotate, if not nil, fills in
+// what only the caller knows about a request, such as its note. While there
+// is a store but no session yet, nothing is recorded, so the requests go into
+// the session once it starts.
+func (r *Recorder) Record(reqs []ngrok.Request, annotate func(*storage.HistoryRequest)) []ngrok.Request {
+	if r.writer != nil && r.store.CurrentSessionID() == "" {
+		return nil
+	}
+
+	var fresh []ngrok.Request
+	var batch []storage.HistoryRequest
+	for _, req := range reqs {
+		if r.recorded[req.ID] {
+			continue
+		}
+		r.recorded[req.ID] = true
+		fresh = append(fresh, req)
+		if r.writer == nil {
+			continue
+		}
+
+		histReq := r.HistoryRequest(req)
+		histReq.SessionID = r.store.CurrentSessionID()
+		if annotate != nil {
+			annotate(&histReq)
+		}
+		batch = append(batch, histReq)
+	}
+
+	// Failures come back on the writer's Errors channel
+	if r.writer != nil {
+		r.writer.Save(batch)
+	}
+	return fresh
+}
+
+// Recorded reports whether a request has been recorded
+func (r *Recorder) Recorded(id string) bool {
+	return r.recorded[id]
+}
+
+// HistoryRequest returns a request as it is saved: secrets masked in the
+// headers, and bodies cut to the size limit
+func (r *Recorder) HistoryRequest(req ngrok.Request) storage.HistoryRequest {
+	histReq := ToHistoryRequest(req)
+	histReq.ReqHeaders = storage.RedactHeaders(histReq.ReqHeaders, r.redactHeaders)
+	histReq.ResHeaders = storage.RedactHeaders(histReq.ResHeaders, r.redactHeaders)
+	histReq.ReqBody = storage.TruncateBody(histReq.ReqBody, r.maxBodyBytes)
+	histReq.ResBody = storage.TruncateBody(histReq.ResBody, r.maxBodyBytes)
+	return histReq
+}
+
+// ToHistoryRequest converts a captured request to its storage representation
+func ToHistoryRequest(req ngrok.Request) storage.HistoryRequest {
+	// Each body is decoded once, for it and its size
+	reqBody, resBody := req.Request.DecodeBody(), req.Response.DecodeBody()
+	resSize := req.StoredResponseSize
+	if resSize == 0 {
+		resSize = len(resBody)
+	}
+	reqSize := req.StoredRequestSize
+	if reqSize == 0 {
+		reqSize = len(reqBody)
+	}
+	return storage.HistoryRequest{
+		ID:           req.ID,
+		Method:       req.Request.Method,
+		Path:         req.Request.URI,
+		StatusCode:   req.StatusCode(),
+		DurationMS:   req.Duration / 1_000_000, // nanoseconds to milliseconds
+		Timestamp:    req.Start,
+		ReqHeaders:   req.Request.Headers,
+		ReqBody:      reqBody,
+		ResHeaders:   req.Response.Headers,
+		ResBody:      resBody,
+		RemoteAddr:   req.RemoteAddr,
+		ResponseSize: resSize,
+		ReqBodySize:  reqSize,
+	}
+}
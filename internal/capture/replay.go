@@ -0,0 +1,34 @@
+package capture
+
+import (
+	"time"
+
+	"github.com/sung01299/mole/internal/ngrok"
+)
+
+// ReplayCaptureWait is how long a replay sent through the tunnel is looked
+// for among the polled requests before it is given up on
+const ReplayCaptureWait = 30 * time.Second
+
+// FindReplay picks the capture of a replay out of the requests ngrok lists:
+// the first with the method and URI replayed to start after it was sent.
+// taken reports requests that can't be it, such as the original and the
+// captures of other replays.
+func FindReplay(reqs []ngrok.Request, method, uri string, sent time.Time, taken func(id string) bool) (ngrok.Request, bool) {
+	// The clocks are the same machine's, give or take ngrok's rounding
+	after := sent.Add(-time.Second)
+	var found *ngrok.Request
+	for i := range reqs {
+		req := &reqs[i]
+		if taken(req.ID) || req.Request.Method != method || req.Request.URI != uri || req.Start.Before(after) {
+			continue
+		}
+		if found == nil || req.Start.Before(found.Start) {
+			found = req
+		}
+	}
+	if found == nil {
+		return ngrok.Request{}, false
+	}
+	return *found, true
+}
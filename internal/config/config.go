@@ -0,0 +1,174 @@
+// Package config loads mole's optional config file
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the settings read from the config file. Fields left out of
+// the file are zero, or nil where zero is a setting of its own.
+type Config struct {
+	// APIURL is the URL of ngrok's local API
+	APIURL string `yaml:"api_url"`
+
+	// PollInterval is how often ngrok is polled, and IdlePollInterval how
+	// often while the terminal window is out of focus, as in "500ms"
+	PollInterval     time.Duration `yaml:"poll_interval"`
+	IdlePollInterval time.Duration `yaml:"idle_poll_interval"`
+
+	// PollLimit is how many of its latest requests ngrok is asked for each
+	// poll
+	PollLimit int `yaml:"poll_limit"`
+
+	// DBPath is the history database, or :memory: to keep nothing. A
+	// leading ~/ is the home directory.
+	DBPath string `yaml:"db_path"`
+
+	// Retention is how much history is kept
+	Retention Retention `yaml:"retention"`
+
+	// MaxBodyKB is the longest body stored in history (0 = no limit)
+	MaxBodyKB *int `yaml:"max_body_kb"`
+
+	// HexDumpBytes is the longest part of a binary body shown as hex (0 = no
+	// limit)
+	HexDumpBytes *int `yaml:"hex_dump_bytes"`
+
+	// RedactHeaders replaces the headers whose values are masked in
+	// history. Left out of the file, the defaults apply; an empty list
+	// stores every value.
+	RedactHeaders []string `yaml:"redact_headers"`
+
+	// Theme is the name of a built-in theme (dark, light, high-contrast,
+	// monochrome)
+	Theme string `yaml:"theme"`
+
+	// Colors overrides theme colors by name, as in "muted: '#4B5563'"
+	Colors map[string]string `yaml:"colors"`
+
+	// Keys rebinds actions by name, as in "history: H" or
+	// "filter: [f, ctrl+f]"
+	Keys map[string]KeyList `yaml:"keys"`
+
+	// RestoreFilters restores the search and filters last used on a tunnel
+	// at startup without asking
+	RestoreFilters bool `yaml:"restore_filters"`
+
+	// DiffIgnoreHeaders replaces the headers left out of diffs. Left out of
+	// the file, the defaults apply; an empty list compares every header.
+	DiffIgnoreHeaders []string `yaml:"diff_ignore_headers"`
+}
+
+// Retention is the retention section of the config file. Zero values mean
+// what they do in storage.RetentionPolicy.
+type Retention struct {
+	KeepDays  *int `yaml:"keep_days"`
+	KeepCount *int `yaml:"keep_count"`
+	MaxDBMB   *int `yaml:"max_db_mb"`
+}
+
+// KeyList is the keys bound to an action. The config file may give one key
+// or a list of them.
+type KeyList []string
+
+// UnmarshalYAML accepts a single key as well as a list
+func (k *KeyList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*k = KeyList{value.Value}
+		return nil
+	}
+	var keys []string
+	if err := value.Decode(&keys); err != nil {
+		return err
+	}
+	*k = keys
+	return nil
+}
+
+// DefaultPath returns the default path to the config file
+func DefaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".mole", "config.yaml"), nil
+}
+
+// Load reads the config file at path. A missing file is an empty config.
+// Keys mole doesn't know are skipped and returned as warnings.
+func Load(path string) (*Config, []string, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		// Empty, or nothing but comments
+		return cfg, nil, nil
+	}
+	if err := doc.Decode(cfg); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	warnings := unknownKeys(doc.Content[0], reflect.TypeOf(*cfg), "")
+
+	if rest, ok := strings.CutPrefix(cfg.DBPath, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg.DBPath = filepath.Join(home, rest)
+	}
+	return cfg, warnings, nil
+}
+
+// unknownKeys lists the keys of a mapping that no field of t is tagged
+// with, looking into the sections that are structs themselves
+func unknownKeys(node *yaml.Node, t reflect.Type, prefix string) []string {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	fields := make(map[string]reflect.Type, t.NumField())
+	var valid []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		fields[name] = t.Field(i).Type
+		valid = append(valid, name)
+	}
+	sort.Strings(valid)
+	section := ""
+	if prefix != "" {
+		section = " in " + strings.TrimSuffix(prefix, ".")
+	}
+
+	var warnings []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		ft, ok := fields[key]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("unknown key %q, skipped (valid keys%s: %s)",
+				prefix+key, section, strings.Join(valid, ", ")))
+			continue
+		}
+		if ft.Kind() == reflect.Struct {
+			warnings = append(warnings, unknownKeys(node.Content[i+1], ft, prefix+key+".")...)
+		}
+	}
+	return warnings
+}
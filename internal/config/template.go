@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Template is the config file mole config init writes. Every setting is
+// commented out at its default, so the file changes nothing until edited.
+const Template = `# Mole config file. Uncomment a setting to change it. Flags and MOLE_*
+# environment variables take precedence over what is set here.
+
+# URL of ngrok's local API
+# api_url: http://127.0.0.1:4040
+
+# How often ngrok is polled, and how often while the terminal window is out
+# of focus. Polling can't be faster than every 100ms.
+# poll_interval: 300ms
+# idle_poll_interval: 2s
+
+# How many of its latest requests ngrok is asked for each poll
+# poll_limit: 50
+
+# History database, or :memory: to keep nothing
+# db_path: ~/.mole/history.db
+
+# How much history is kept, applied at startup. 0 means no limit. Starred
+# requests are always kept.
+# retention:
+#   keep_days: 7       # Remove requests older than this many days
+#   keep_count: 1000   # Always keep at least this many recent requests
+#   max_db_mb: 0       # Trim the oldest requests while the database is larger
+
+# Bodies longer than this are truncated before they are stored (0 = no limit)
+# max_body_kb: 256
+
+# Longest part of a binary body shown as a hex dump (0 = no limit)
+# hex_dump_bytes: 4096
+
+# Headers whose values are replaced with [REDACTED] in history. An empty
+# list stores every value.
+# redact_headers: [authorization, cookie, set-cookie, x-api-key]
+
+# dark, light, high-contrast or monochrome, with single colors overridden by
+# name as a hex value, an ANSI color number (0-255) or none
+# theme: dark
+# colors:
+#   muted: "#4B5563"
+
+# Keybindings, by action, with one key or a list of them
+# keys:
+#   history: H
+#   filter: [f, ctrl+g]
+
+# Restore the search and filters last used on a tunnel without asking
+# restore_filters: false
+
+# Headers left out of diffs. A trailing * matches a prefix, and an empty list
+# compares every header.
+# diff_ignore_headers: [date, etag, x-request-id, x-forwarded-for, "ngrok-*"]
+`
+
+// WriteTemplate writes Template to path, creating its directory. An
+// existing file is only replaced if overwrite is set.
+func WriteTemplate(path string, overwrite bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if os.IsExist(err) {
+		return fmt.Errorf("%s already exists (--force replaces it)", path)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(Template); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,121 @@
+// Package debuglog writes the debug log mole keeps with --debug or
+// MOLE_DEBUG=1. Logging is a no-op until Open is called, so packages log
+// unconditionally.
+package debuglog
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// MaxSize is how large the log may grow before it is rotated. The previous
+// log is kept alongside as <path>.1, so at most twice this is on disk.
+const MaxSize = 5 << 20
+
+var (
+	enabled atomic.Bool
+
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+	logger = log.New(os.Stderr, "", 0)
+	size   int64
+)
+
+// DefaultPath returns the default path to the debug log
+func DefaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".mole", "mole.log"), nil
+}
+
+// Open starts logging to path, appending to what is there unless it is
+// already over MaxSize
+func Open(p string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	path = p
+	if err := open(); err != nil {
+		return err
+	}
+	enabled.Store(true)
+	return nil
+}
+
+// open opens the log at path, rotating it first if it is full. mu is held.
+func open() error {
+	if info, err := os.Stat(path); err == nil && info.Size() >= MaxSize {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return err
+		}
+	}
+	f, err := tea.LogToFileWith(path, "mole", logger)
+	if err != nil {
+		return err
+	}
+	logger.SetFlags(log.LstdFlags | log.Lmicroseconds)
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	file, size = f, info.Size()
+	return nil
+}
+
+// Enabled reports whether debug logging is on, for callers that would do
+// extra work to build a message
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Printf logs a line if debug logging is on
+func Printf(format string, args ...any) {
+	if !enabled.Load() {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if file == nil {
+		return
+	}
+	logger.Print(msg)
+	// The prefix and timestamp come to about 40 bytes
+	size += int64(len(msg)) + 40
+	if size >= MaxSize {
+		file.Close()
+		if err := open(); err != nil {
+			// Nowhere left to log to
+			file = nil
+			enabled.Store(false)
+		}
+	}
+}
+
+// Close stops logging and closes the log
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	enabled.Store(false)
+	if file == nil {
+		return nil
+	}
+	err := file.Close()
+	file = nil
+	return err
+}
@@ -1,8 +1,9 @@
 package ngrok
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"strings"
 )
 
 // GetTunnels retrieves all active tunnels
@@ -42,8 +43,18 @@ func (c *Client) GetRequest(id string) (*Request, error) {
 // Replay re-sends a captured request
 // ngrok v3 API: POST /api/requests/http with body {"id": "request_id"}
 func (c *Client) Replay(requestID string) error {
-	body := fmt.Sprintf(`{"id":"%s"}`, requestID)
-	return c.post("/api/requests/http", strings.NewReader(body))
+	return c.ReplayTo(requestID, "")
+}
+
+// ReplayTo re-sends a captured request to target, an address such as
+// localhost:3000, instead of where the tunnel points. An empty target sends
+// it the usual way.
+func (c *Client) ReplayTo(requestID string, target string) error {
+	body, err := json.Marshal(ReplayRequest{ID: requestID, Target: target})
+	if err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	return c.post("/api/requests/http", bytes.NewReader(body))
 }
 
 // DeleteRequests clears all captured requests
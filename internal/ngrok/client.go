@@ -6,6 +6,8 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/sung01299/mole/internal/debuglog"
 )
 
 const (
@@ -35,7 +37,9 @@ func NewClient(baseURL string) *Client {
 // get performs a GET request and decodes the JSON response
 func (c *Client) get(path string, result interface{}) error {
 	url := c.baseURL + path
+	start := time.Now()
 	resp, err := c.httpClient.Get(url)
+	logRequest("GET", url, start, resp, err)
 	if err != nil {
 		return fmt.Errorf("GET %s: %w", path, err)
 	}
@@ -63,7 +67,9 @@ func (c *Client) post(path string, body io.Reader) error {
 	}
 	req.Header.Set("Content-Type", "application/json")
 
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
+	logRequest("POST", url, start, resp, err)
 	if err != nil {
 		return fmt.Errorf("POST %s: %w", path, err)
 	}
@@ -81,10 +87,22 @@ func (c *Client) post(path string, body io.Reader) error {
 // IsAvailable checks if the ngrok API is reachable
 func (c *Client) IsAvailable() bool {
 	url := c.baseURL + "/api"
+	start := time.Now()
 	resp, err := c.httpClient.Get(url)
+	logRequest("GET", url, start, resp, err)
 	if err != nil {
 		return false
 	}
 	defer resp.Body.Close()
 	return resp.StatusCode == http.StatusOK
 }
+
+// logRequest writes a call to the API, and how long it took, to the debug log
+func logRequest(method, url string, start time.Time, resp *http.Response, err error) {
+	elapsed := time.Since(start).Round(time.Microsecond)
+	if err != nil {
+		debuglog.Printf("ngrok: %s %s failed after %s: %v", method, url, elapsed, err)
+		return
+	}
+	debuglog.Printf("ngrok: %s %s %d in %s", method, url, resp.StatusCode, elapsed)
+}
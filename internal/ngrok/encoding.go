@@ -0,0 +1,73 @@
+package ngrok
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// maxDecompressedSize caps how large a decompressed body may grow, so a
+// small compressed body can't expand into gigabytes
+const maxDecompressedSize = 32 << 20 // 32 MB
+
+// decompressBody undoes the Content-Encoding of body. Encodings are listed in
+// the order they were applied, so they are undone last to first. If any of
+// them fails, the body is returned as it came with a marker in front.
+func decompressBody(body string, encoding string) string {
+	encodings := strings.Split(encoding, ",")
+	data := []byte(body)
+	for i := len(encodings) - 1; i >= 0; i-- {
+		decoded, err := decompress(data, strings.ToLower(strings.TrimSpace(encodings[i])))
+		if err != nil {
+			return fmt.Sprintf("(compressed, %d bytes)\n%s", len(body), body)
+		}
+		data = decoded
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// decompress undoes a single content coding
+func decompress(data []byte, encoding string) ([]byte, error) {
+	var r io.Reader
+	switch encoding {
+	case "", "identity":
+		return data, nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	case "deflate":
+		// "deflate" should be zlib-wrapped, but some servers send raw deflate
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			fr := flate.NewReader(bytes.NewReader(data))
+			defer fr.Close()
+			r = fr
+		} else {
+			defer zr.Close()
+			r = zr
+		}
+	case "br":
+		r = brotli.NewReader(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	decoded, err := io.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) > maxDecompressedSize {
+		return nil, fmt.Errorf("decompressed body is larger than %d bytes", maxDecompressedSize)
+	}
+	return decoded, nil
+}
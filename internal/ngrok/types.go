@@ -56,6 +56,11 @@ type Request struct {
 	Request        HTTPData  `json:"request"`
 	Response       HTTPData  `json:"response"`
 	ResponseStatus string    `json:"response_status"` // e.g., "200 OK"
+
+	// StoredResponseSize is the original response size for requests loaded
+	// from history, where the body may have been truncated
+	StoredResponseSize int `json:"-"`
+	StoredRequestSize  int `json:"-"`
 }
 
 // HTTPData represents HTTP request or response data
@@ -69,8 +74,8 @@ type HTTPData struct {
 	Status     string              `json:"status,omitempty"`      // e.g., "200 OK"
 }
 
-// DecodeBody decodes the base64-encoded raw field and extracts the body
-func (h *HTTPData) DecodeBody() string {
+// DecodeRaw decodes the base64-encoded raw field into the full HTTP message
+func (h *HTTPData) DecodeRaw() string {
 	if h.Raw == "" {
 		return ""
 	}
@@ -84,21 +89,47 @@ func (h *HTTPData) DecodeBody() string {
 			return h.Raw // Return as-is if not base64
 		}
 	}
+	return string(decoded)
+}
 
-	raw := string(decoded)
+// DecodeBody decodes the base64-encoded raw field and extracts the body.
+// Bodies sent with a Content-Encoding are decompressed.
+func (h *HTTPData) DecodeBody() string {
+	raw := h.DecodeRaw()
+	if raw == "" || raw == h.Raw {
+		return raw
+	}
 
 	// The raw contains full HTTP message (headers + body)
 	// Find the empty line that separates headers from body
-	headerEnd := strings.Index(raw, "\r\n\r\n")
-	if headerEnd == -1 {
-		headerEnd = strings.Index(raw, "\n\n")
-		if headerEnd == -1 {
-			return raw // No body separator found, return full content
-		}
-		return strings.TrimSpace(raw[headerEnd+2:])
+	var body string
+	if headerEnd := strings.Index(raw, "\r\n\r\n"); headerEnd != -1 {
+		body = raw[headerEnd+4:]
+	} else if headerEnd := strings.Index(raw, "\n\n"); headerEnd != -1 {
+		body = raw[headerEnd+2:]
+	} else {
+		return raw // No body separator found, return full content
 	}
 
-	return strings.TrimSpace(raw[headerEnd+4:])
+	if encoding := h.contentEncoding(); encoding != "" && body != "" {
+		// Compressed bytes must not be trimmed
+		return decompressBody(body, encoding)
+	}
+	return strings.TrimSpace(body)
+}
+
+// contentEncoding returns the Content-Encoding header, ignoring identity
+func (h *HTTPData) contentEncoding() string {
+	for k, v := range h.Headers {
+		if strings.EqualFold(k, "Content-Encoding") && len(v) > 0 {
+			encoding := strings.TrimSpace(strings.Join(v, ","))
+			if strings.EqualFold(encoding, "identity") {
+				return ""
+			}
+			return encoding
+		}
+	}
+	return ""
 }
 
 // RequestsResponse is the response from GET /api/requests/http
@@ -107,7 +138,7 @@ type RequestsResponse struct {
 	URI      string    `json:"uri"`
 }
 
-// ReplayRequest is the request body for POST /api/requests/http/{id}/replay
+// ReplayRequest is the request body for POST /api/requests/http
 type ReplayRequest struct {
 	ID     string `json:"id"`
 	Target string `json:"target,omitempty"` // optional: override target address
@@ -180,6 +211,9 @@ func (r *Request) DurationMs() float64 {
 
 // ResponseSize returns the size of the response body in bytes
 func (r *Request) ResponseSize() int {
+	if r.StoredResponseSize > 0 {
+		return r.StoredResponseSize
+	}
 	body := r.Response.DecodeBody()
 	return len(body)
 }
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// csvColumns are the columns of a CSV export. Bodies and headers are left
+// out so each request stays one row a spreadsheet can take.
+var csvColumns = []string{
+	"timestamp", "id", "session_id", "method", "path", "status_code", "duration_ms",
+	"request_size", "response_size", "remote_addr", "starred", "notes", "replay_of",
+}
+
+// ExportSessionToCSV exports a session as a CSV file, one request per row
+func (s *Storage) ExportSessionToCSV(sessionID string, outputPath string) error {
+	if _, err := s.getSession(sessionID); err != nil {
+		return err
+	}
+
+	requests, err := s.GetSessionRequests(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get requests: %w", err)
+	}
+
+	return WriteCSV(requests, outputPath)
+}
+
+// WriteCSV writes requests as CSV, oldest first, with a header row
+func WriteCSV(requests []HistoryRequest, outputPath string) error {
+	if len(requests) == 0 {
+		return fmt.Errorf("no requests to export")
+	}
+
+	sorted := make([]HistoryRequest, len(requests))
+	copy(sorted, requests)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(csvColumns)
+	for _, req := range sorted {
+		w.Write([]string{
+			req.Timestamp.Format(time.RFC3339Nano),
+			req.ID,
+			req.SessionID,
+			req.Method,
+			req.Path,
+			strconv.Itoa(req.StatusCode),
+			strconv.FormatInt(req.DurationMS, 10),
+			strconv.Itoa(req.ReqBodySize),
+			strconv.Itoa(req.ResponseSize),
+			req.RemoteAddr,
+			strconv.FormatBool(req.Starred),
+			req.Notes,
+			req.ReplayOf,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	return writeExportFile(outputPath, buf.Bytes(), 0644)
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sung01299/mole/internal/util"
+)
+
+// ExportSessionToCurlScript exports a session as an executable shell script
+func (s *Storage) ExportSessionToCurlScript(sessionID string, outputPath string) error {
+	sess, err := s.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	requests, err := s.GetSessionRequests(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get requests: %w", err)
+	}
+
+	return WriteCurlScript(requests, sess.TunnelURL, outputPath)
+}
+
+// WriteCurlScript writes an executable shell script with one curl invocation
+// per request, oldest first. targetURL is the default base URL; it can be
+// overridden when running the script with --target.
+func WriteCurlScript(requests []HistoryRequest, targetURL string, outputPath string) error {
+	if len(requests) == 0 {
+		return fmt.Errorf("no requests to export")
+	}
+
+	// Replay in the order the requests originally arrived
+	sorted := make([]HistoryRequest, len(requests))
+	copy(sorted, requests)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n")
+	sb.WriteString(fmt.Sprintf("# Generated by mole on %s (%d requests)\n", time.Now().Format("2006-01-02 15:04:05"), len(sorted)))
+	sb.WriteString("#\n")
+	sb.WriteString("# Usage: ./script.sh [--target BASE_URL]\n\n")
+	sb.WriteString(fmt.Sprintf("TARGET=%s\n", util.ShellQuote(targetURL)))
+	sb.WriteString("if [ \"$1\" = \"--target\" ] && [ -n \"$2\" ]; then\n")
+	sb.WriteString("\tTARGET=\"$2\"\n")
+	sb.WriteString("fi\n")
+
+	for i, req := range sorted {
+		sb.WriteString(fmt.Sprintf("\n# [%d/%d] %s - original status %d\n",
+			i+1, len(sorted), req.Timestamp.Format("2006-01-02 15:04:05"), req.StatusCode))
+		if req.ReqBodyTruncated() {
+			sb.WriteString(fmt.Sprintf("# Warning: request body was truncated at %s of %s when stored\n",
+				util.FormatBytes(int64(len(req.ReqBody))), util.FormatBytes(int64(req.ReqBodySize))))
+		}
+		if req.Notes != "" {
+			sb.WriteString("# Note: " + strings.ReplaceAll(req.Notes, "\n", " ") + "\n")
+		}
+		target := `"$TARGET"` + util.ShellQuote(req.Path)
+		sb.WriteString(util.CurlCommand(req.Method, req.ReqHeaders, req.ReqBody, target))
+		sb.WriteString("\n")
+	}
+
+	return writeExportFile(outputPath, []byte(sb.String()), 0755)
+}
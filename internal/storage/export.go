@@ -5,92 +5,388 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/sung01299/mole/internal/util"
 )
 
+// ExportVersion is the version of the JSON export format written. Exports
+// from before versions were recorded carry none and read as version 1.
+const ExportVersion = 1
+
 // ExportSession represents a session for JSON export
 type ExportSession struct {
+	Version   int             `json:"version,omitempty"`
 	ID        string          `json:"id"`
 	TunnelURL string          `json:"tunnel_url"`
+	Label     string          `json:"label,omitempty"`
 	StartedAt time.Time       `json:"started_at"`
 	EndedAt   *time.Time      `json:"ended_at,omitempty"`
 	Requests  []ExportRequest `json:"requests"`
+	Templates []Template      `json:"templates,omitempty"` // Saved request templates, shared along with the session
 }
 
 // ExportRequest represents a request for JSON export
 type ExportRequest struct {
-	ID         string              `json:"id"`
-	Method     string              `json:"method"`
-	Path       string              `json:"path"`
-	StatusCode int                 `json:"status_code"`
-	DurationMS int64               `json:"duration_ms"`
-	Timestamp  time.Time           `json:"timestamp"`
-	Request    ExportHTTPData      `json:"request"`
-	Response   ExportHTTPData      `json:"response"`
-	Starred    bool                `json:"starred"`
+	ID           string         `json:"id"`
+	Method       string         `json:"method"`
+	Path         string         `json:"path"`
+	StatusCode   int            `json:"status_code"`
+	DurationMS   int64          `json:"duration_ms"`
+	Timestamp    time.Time      `json:"timestamp"`
+	Request      ExportHTTPData `json:"request"`
+	Response     ExportHTTPData `json:"response"`
+	Starred      bool           `json:"starred"`
+	Notes        string         `json:"notes,omitempty"`
+	RemoteAddr   string         `json:"remote_addr,omitempty"`
+	RequestSize  int            `json:"request_size,omitempty"`
+	ResponseSize int            `json:"response_size,omitempty"`
+	ReplayOf     string         `json:"replay_of,omitempty"` // ID of the request this one replayed
 }
 
 // ExportHTTPData represents HTTP data for export
 type ExportHTTPData struct {
-	Headers map[string][]string `json:"headers"`
-	Body    string              `json:"body"`
+	Headers   map[string][]string `json:"headers"`
+	Body      string              `json:"body"`
+	Truncated bool                `json:"truncated,omitempty"` // Body was cut short when stored
 }
 
-// ExportSessionToJSON exports a session to a JSON file
-func (s *Storage) ExportSessionToJSON(sessionID string, outputPath string) error {
-	// Get session info
+// ExportFormat identifies an export file format
+type ExportFormat string
+
+const (
+	FormatJSON     ExportFormat = "json"
+	FormatPostman  ExportFormat = "postman"
+	FormatCurl     ExportFormat = "curl"
+	FormatMarkdown ExportFormat = "markdown"
+	FormatHAR      ExportFormat = "har"
+	FormatCSV      ExportFormat = "csv"
+)
+
+// ExportFormats lists the supported export formats in display order
+var ExportFormats = []ExportFormat{FormatJSON, FormatPostman, FormatCurl, FormatMarkdown, FormatHAR, FormatCSV}
+
+// ParseExportFormat returns the export format for a name
+func ParseExportFormat(name string) (ExportFormat, error) {
+	for _, f := range ExportFormats {
+		if string(f) == strings.ToLower(name) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format %q", name)
+}
+
+// ExportSession exports a session in the given format
+func (s *Storage) ExportSession(sessionID string, format ExportFormat, outputPath string) error {
+	switch format {
+	case FormatJSON:
+		return s.ExportSessionToJSON(sessionID, outputPath)
+	case FormatPostman:
+		return s.ExportSessionToPostman(sessionID, outputPath)
+	case FormatCurl:
+		return s.ExportSessionToCurlScript(sessionID, outputPath)
+	case FormatMarkdown:
+		return s.ExportSessionToMarkdown(sessionID, outputPath)
+	case FormatHAR:
+		return s.ExportSessionToHAR(sessionID, outputPath)
+	case FormatCSV:
+		return s.ExportSessionToCSV(sessionID, outputPath)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// ExportStarred exports the starred requests of every session and returns
+// how many were written. Only the formats that can mix sessions are
+// supported: json, har and csv.
+func (s *Storage) ExportStarred(format ExportFormat, outputPath string) (int, error) {
+	requests, err := s.GetStarredRequests()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get requests: %w", err)
+	}
+	if len(requests) == 0 {
+		return 0, fmt.Errorf("no starred requests")
+	}
+
+	switch format {
+	case FormatJSON:
+		export := make([]ExportRequest, len(requests))
+		for i, req := range requests {
+			export[i] = ToExportRequest(req)
+		}
+		data, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		return len(requests), writeExportFile(outputPath, data, 0644)
+	case FormatHAR:
+		sessions, err := s.GetSessions()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get sessions: %w", err)
+		}
+		tunnelURLs := make(map[string]string, len(sessions))
+		for _, sess := range sessions {
+			tunnelURLs[sess.ID] = sess.TunnelURL
+		}
+		return len(requests), WriteHAR(requests, tunnelURLs, outputPath)
+	case FormatCSV:
+		return len(requests), WriteCSV(requests, outputPath)
+	}
+	return 0, fmt.Errorf("starred requests can't be exported as %s, only json, har or csv", format)
+}
+
+// getSession loads a single session row
+func (s *Storage) getSession(sessionID string) (Session, error) {
 	var sess Session
 	var endedAt *time.Time
 	err := s.db.QueryRow(
-		"SELECT id, tunnel_url, started_at, ended_at FROM sessions WHERE id = ?",
+		"SELECT id, tunnel_url, label, started_at, ended_at FROM sessions WHERE id = ?",
 		sessionID,
-	).Scan(&sess.ID, &sess.TunnelURL, &sess.StartedAt, &endedAt)
+	).Scan(&sess.ID, &sess.TunnelURL, &sess.Label, &sess.StartedAt, &endedAt)
 	if err != nil {
-		return fmt.Errorf("session not found: %w", err)
+		return sess, fmt.Errorf("session not found: %w", err)
 	}
 	sess.EndedAt = endedAt
+	return sess, nil
+}
+
+// ExportSessionToJSON exports a session to a JSON file
+func (s *Storage) ExportSessionToJSON(sessionID string, outputPath string) error {
+	_, err := s.exportSessionToJSON(sessionID, outputPath)
+	return err
+}
+
+// exportSessionToJSON exports a session to a JSON file and returns the number
+// of requests written
+func (s *Storage) exportSessionToJSON(sessionID string, outputPath string) (int, error) {
+	// Get session info
+	sess, err := s.getSession(sessionID)
+	if err != nil {
+		return 0, err
+	}
 
 	// Get requests
 	requests, err := s.GetSessionRequests(sessionID)
 	if err != nil {
-		return fmt.Errorf("failed to get requests: %w", err)
+		return 0, fmt.Errorf("failed to get requests: %w", err)
 	}
 
 	// Build export structure
 	export := ExportSession{
+		Version:   ExportVersion,
 		ID:        sess.ID,
 		TunnelURL: sess.TunnelURL,
+		Label:     sess.Label,
 		StartedAt: sess.StartedAt,
 		EndedAt:   sess.EndedAt,
 		Requests:  make([]ExportRequest, len(requests)),
 	}
 
 	for i, req := range requests {
-		export.Requests[i] = ExportRequest{
-			ID:         req.ID,
-			Method:     req.Method,
-			Path:       req.Path,
-			StatusCode: req.StatusCode,
-			DurationMS: req.DurationMS,
-			Timestamp:  req.Timestamp,
-			Request: ExportHTTPData{
-				Headers: req.ReqHeaders,
-				Body:    req.ReqBody,
-			},
-			Response: ExportHTTPData{
-				Headers: req.ResHeaders,
-				Body:    req.ResBody,
-			},
-			Starred: req.Starred,
-		}
+		export.Requests[i] = ToExportRequest(req)
+	}
+
+	if export.Templates, err = s.ListTemplates(); err != nil {
+		return 0, fmt.Errorf("failed to get templates: %w", err)
 	}
 
 	// Marshal to JSON
 	data, err := json.MarshalIndent(export, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to marshal JSON: %w", err)
+		return 0, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	return len(requests), writeExportFile(outputPath, data, 0644)
+}
+
+// markdownBodyLimit is the maximum number of body bytes embedded in a Markdown report
+const markdownBodyLimit = 4096
+
+// ExportSessionToMarkdown exports a session as a Markdown report suitable for
+// pasting into an issue
+func (s *Storage) ExportSessionToMarkdown(sessionID string, outputPath string) error {
+	sess, err := s.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	requests, err := s.GetSessionRequests(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get requests: %w", err)
 	}
 
+	// Oldest first reads more naturally in a report
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].Timestamp.Before(requests[j].Timestamp)
+	})
+
+	errorCount := 0
+	for _, req := range requests {
+		if req.StatusCode >= 400 {
+			errorCount++
+		}
+	}
+
+	var sb strings.Builder
+	if sess.Label != "" {
+		sb.WriteString(fmt.Sprintf("# mole session report: %s\n\n", sess.Label))
+	} else {
+		sb.WriteString("# mole session report\n\n")
+	}
+	sb.WriteString(fmt.Sprintf("- **Tunnel:** %s\n", sess.TunnelURL))
+	if len(requests) > 0 {
+		first := requests[0].Timestamp.Format("2006-01-02 15:04:05")
+		last := requests[len(requests)-1].Timestamp.Format("2006-01-02 15:04:05")
+		sb.WriteString(fmt.Sprintf("- **Time range:** %s – %s\n", first, last))
+	} else {
+		sb.WriteString(fmt.Sprintf("- **Started:** %s\n", sess.StartedAt.Format("2006-01-02 15:04:05")))
+	}
+	sb.WriteString(fmt.Sprintf("- **Requests:** %d\n", len(requests)))
+	sb.WriteString(fmt.Sprintf("- **Errors (4xx/5xx):** %d\n", errorCount))
+
+	for i, req := range requests {
+		sb.WriteString(fmt.Sprintf("\n## %d. `%s %s` → %d\n\n", i+1, req.Method, req.Path, req.StatusCode))
+		sb.WriteString(fmt.Sprintf("- **Time:** %s\n", req.Timestamp.Format("2006-01-02 15:04:05")))
+		sb.WriteString(fmt.Sprintf("- **Duration:** %dms\n", req.DurationMS))
+		if req.Starred {
+			sb.WriteString("- **Starred**\n")
+		}
+		if req.Notes != "" {
+			sb.WriteString(fmt.Sprintf("- **Note:** %s\n", req.Notes))
+		}
+
+		if req.ReqBody != "" {
+			sb.WriteString("\n**Request body**\n\n")
+			sb.WriteString(markdownCodeBlock(req.ReqBody))
+			if req.ReqBodyTruncated() {
+				sb.WriteString(truncationNote(len(req.ReqBody), req.ReqBodySize))
+			}
+		}
+		if req.ResBody != "" {
+			sb.WriteString("\n**Response body**\n\n")
+			sb.WriteString(markdownCodeBlock(req.ResBody))
+			if req.ResBodyTruncated() {
+				sb.WriteString(truncationNote(len(req.ResBody), req.ResponseSize))
+			}
+		}
+	}
+
+	return writeExportFile(outputPath, []byte(sb.String()), 0644)
+}
+
+// markdownCodeBlock renders a body as a fenced code block, pretty-printing
+// JSON and truncating bodies past markdownBodyLimit
+func markdownCodeBlock(body string) string {
+	lang := ""
+	if util.IsJSON(body) {
+		lang = "json"
+		body = util.PrettyJSON(body)
+	}
+
+	truncated := false
+	if len(body) > markdownBodyLimit {
+		body = strings.ToValidUTF8(body[:markdownBodyLimit], "")
+		truncated = true
+	}
+
+	// Use a fence longer than any backtick run inside the body
+	fence := "```"
+	for strings.Contains(body, fence) {
+		fence += "`"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fence + lang + "\n")
+	sb.WriteString(body)
+	if !strings.HasSuffix(body, "\n") {
+		sb.WriteString("\n")
+	}
+	sb.WriteString(fence + "\n")
+	if truncated {
+		sb.WriteString(fmt.Sprintf("\n_(truncated to %d bytes)_\n", markdownBodyLimit))
+	}
+	return sb.String()
+}
+
+// truncationNote marks a body that was cut short when it was stored
+func truncationNote(stored, size int) string {
+	return fmt.Sprintf("\n_(body truncated at %s of %s when stored)_\n",
+		util.FormatBytes(int64(stored)), util.FormatBytes(int64(size)))
+}
+
+// ToExportRequest converts a stored request to its export representation
+func ToExportRequest(req HistoryRequest) ExportRequest {
+	return ExportRequest{
+		ID:         req.ID,
+		Method:     req.Method,
+		Path:       req.Path,
+		StatusCode: req.StatusCode,
+		DurationMS: req.DurationMS,
+		Timestamp:  req.Timestamp,
+		Request: ExportHTTPData{
+			Headers:   req.ReqHeaders,
+			Body:      req.ReqBody,
+			Truncated: req.ReqBodyTruncated(),
+		},
+		Response: ExportHTTPData{
+			Headers:   req.ResHeaders,
+			Body:      req.ResBody,
+			Truncated: req.ResBodyTruncated(),
+		},
+		Starred:      req.Starred,
+		Notes:        req.Notes,
+		RemoteAddr:   req.RemoteAddr,
+		RequestSize:  req.ReqBodySize,
+		ResponseSize: req.ResponseSize,
+		ReplayOf:     req.ReplayOf,
+	}
+}
+
+// FromExportRequest converts an exported request back into a stored one
+func FromExportRequest(req ExportRequest, sessionID string) HistoryRequest {
+	// Exports from before the sizes existed carry full bodies
+	requestSize := req.RequestSize
+	if requestSize == 0 {
+		requestSize = len(req.Request.Body)
+	}
+	responseSize := req.ResponseSize
+	if responseSize == 0 {
+		responseSize = len(req.Response.Body)
+	}
+
+	return HistoryRequest{
+		ID:           req.ID,
+		SessionID:    sessionID,
+		Method:       req.Method,
+		Path:         req.Path,
+		StatusCode:   req.StatusCode,
+		DurationMS:   req.DurationMS,
+		Timestamp:    req.Timestamp,
+		ReqHeaders:   req.Request.Headers,
+		ReqBody:      req.Request.Body,
+		ResHeaders:   req.Response.Headers,
+		ResBody:      req.Response.Body,
+		Starred:      req.Starred,
+		Notes:        req.Notes,
+		RemoteAddr:   req.RemoteAddr,
+		ResponseSize: responseSize,
+		ReqBodySize:  requestSize,
+		ReplayOf:     req.ReplayOf,
+	}
+}
+
+// HistoryRequests converts the exported requests back into stored ones
+func (e *ExportSession) HistoryRequests() []HistoryRequest {
+	reqs := make([]HistoryRequest, len(e.Requests))
+	for i, req := range e.Requests {
+		reqs[i] = FromExportRequest(req, e.ID)
+	}
+	return reqs
+}
+
+// writeExportFile writes export data, creating the parent directory if needed
+func writeExportFile(outputPath string, data []byte, perm os.FileMode) error {
 	// Ensure directory exists
 	if dir := filepath.Dir(outputPath); dir != "." {
 		if err := os.MkdirAll(dir, 0755); err != nil {
@@ -99,81 +395,80 @@ func (s *Storage) ExportSessionToJSON(sessionID string, outputPath string) error
 	}
 
 	// Write file
-	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+	if err := os.WriteFile(outputPath, data, perm); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
 	return nil
 }
 
-// ExportCurrentSession exports the current session to a JSON file
-func (s *Storage) ExportCurrentSession(outputPath string) error {
-	if s.sessionID == "" {
-		return fmt.Errorf("no active session")
+// ExportCurrentSession exports the current session to a JSON file and returns
+// the number of requests written
+func (s *Storage) ExportCurrentSession(outputPath string) (int, error) {
+	sessionID := s.CurrentSessionID()
+	if sessionID == "" {
+		return 0, fmt.Errorf("no active session")
 	}
-	return s.ExportSessionToJSON(s.sessionID, outputPath)
+	return s.exportSessionToJSON(sessionID, outputPath)
 }
 
 // GenerateExportFilename generates a filename for export
 func GenerateExportFilename() string {
-	return fmt.Sprintf("mole_export_%s.json", time.Now().Format("2006-01-02_15-04-05"))
+	return GenerateExportFilenameFor(FormatJSON)
+}
+
+// GenerateExportFilenameFor generates a filename for export in the given format
+func GenerateExportFilenameFor(format ExportFormat) string {
+	stamp := time.Now().Format("2006-01-02_15-04-05")
+	switch format {
+	case FormatPostman:
+		return fmt.Sprintf("mole_postman_%s.json", stamp)
+	case FormatCurl:
+		return fmt.Sprintf("mole_replay_%s.sh", stamp)
+	case FormatMarkdown:
+		return fmt.Sprintf("mole_report_%s.md", stamp)
+	case FormatHAR:
+		return fmt.Sprintf("mole_export_%s.har", stamp)
+	case FormatCSV:
+		return fmt.Sprintf("mole_export_%s.csv", stamp)
+	}
+	return fmt.Sprintf("mole_export_%s.json", stamp)
 }
 
-// ExportRequests exports specific requests to a JSON file
-func (s *Storage) ExportRequests(requestIDs []string, outputPath string) error {
+// ExportRequests exports specific requests to a JSON file and returns the
+// number of requests written. IDs that aren't in history are skipped.
+func (s *Storage) ExportRequests(requestIDs []string, outputPath string) (int, error) {
 	if len(requestIDs) == 0 {
-		return fmt.Errorf("no requests to export")
+		return 0, fmt.Errorf("no requests to export")
 	}
 
 	var requests []ExportRequest
 
 	for _, id := range requestIDs {
-		var req HistoryRequest
-		var reqHeadersJSON, resHeadersJSON string
-
-		err := s.db.QueryRow(`
-			SELECT id, session_id, method, path, status_code, duration_ms, timestamp, 
-			       req_headers, req_body, res_headers, res_body, starred
-			FROM requests WHERE id = ?
-		`, id).Scan(
-			&req.ID, &req.SessionID, &req.Method, &req.Path, &req.StatusCode,
-			&req.DurationMS, &req.Timestamp, &reqHeadersJSON, &req.ReqBody,
-			&resHeadersJSON, &req.ResBody, &req.Starred,
-		)
+		rows, err := s.db.Query("SELECT "+requestColumns+" FROM requests WHERE id = ?", id)
 		if err != nil {
-			continue // Skip not found
+			return 0, err
+		}
+		found, err := s.scanRequests(rows)
+		rows.Close()
+		if err != nil {
+			return 0, err
 		}
 
-		json.Unmarshal([]byte(reqHeadersJSON), &req.ReqHeaders)
-		json.Unmarshal([]byte(resHeadersJSON), &req.ResHeaders)
-
-		requests = append(requests, ExportRequest{
-			ID:         req.ID,
-			Method:     req.Method,
-			Path:       req.Path,
-			StatusCode: req.StatusCode,
-			DurationMS: req.DurationMS,
-			Timestamp:  req.Timestamp,
-			Request: ExportHTTPData{
-				Headers: req.ReqHeaders,
-				Body:    req.ReqBody,
-			},
-			Response: ExportHTTPData{
-				Headers: req.ResHeaders,
-				Body:    req.ResBody,
-			},
-			Starred: req.Starred,
-		})
+		// Skip not found
+		for _, req := range found {
+			requests = append(requests, ToExportRequest(req))
+		}
 	}
 
-	data, err := json.MarshalIndent(requests, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal JSON: %w", err)
+	if len(requests) == 0 {
+		return 0, fmt.Errorf("none of the requests are in history")
 	}
 
-	if err := os.WriteFile(outputPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	data, err := json.MarshalIndent(requests, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal JSON: %w", err)
 	}
 
-	return nil
+	return len(requests), writeExportFile(outputPath, data, 0644)
 }
@@ -0,0 +1,303 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// harVersion is the version of the HAR format written
+const harVersion = "1.2"
+
+// harTruncatedComment marks a body cut short when it was stored
+const harTruncatedComment = "truncated when stored"
+
+// HAR is an HTTP Archive, the format browser developer tools and most HTTP
+// debugging tools import
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog holds the archived requests
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator names the program that wrote the archive
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is one request and its response. ID is mole's own, kept so an
+// archive can be imported without duplicating requests.
+type HAREntry struct {
+	ID              string      `json:"_id,omitempty"`
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"` // Milliseconds
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+// HARRequest is the request of an entry
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	Cookies     []HARNameValue `json:"cookies"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+}
+
+// HARResponse is the response of an entry
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []HARNameValue `json:"headers"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARNameValue is a header, query parameter or cookie
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is a request body
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARContent is a response body
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"` // "base64" for binary bodies written by other tools
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARTimings splits an entry's time into phases. Only the total is known,
+// so it is all counted as waiting.
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// ExportSessionToHAR exports a session as an HTTP Archive
+func (s *Storage) ExportSessionToHAR(sessionID string, outputPath string) error {
+	sess, err := s.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	requests, err := s.GetSessionRequests(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get requests: %w", err)
+	}
+
+	return WriteHAR(requests, map[string]string{sess.ID: sess.TunnelURL}, outputPath)
+}
+
+// WriteHAR writes requests as an HTTP Archive, oldest first. tunnelURLs maps
+// each session to its tunnel URL, which request paths are resolved against.
+func WriteHAR(requests []HistoryRequest, tunnelURLs map[string]string, outputPath string) error {
+	if len(requests) == 0 {
+		return fmt.Errorf("no requests to export")
+	}
+
+	sorted := make([]HistoryRequest, len(requests))
+	copy(sorted, requests)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	har := HAR{Log: HARLog{
+		Version: harVersion,
+		Creator: HARCreator{Name: "mole"},
+		Entries: make([]HAREntry, len(sorted)),
+	}}
+	for i, req := range sorted {
+		har.Log.Entries[i] = buildHAREntry(req, tunnelURLs[req.SessionID])
+	}
+
+	data, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal HAR: %w", err)
+	}
+	return writeExportFile(outputPath, data, 0644)
+}
+
+// buildHAREntry converts a stored request into an archive entry
+func buildHAREntry(req HistoryRequest, tunnelURL string) HAREntry {
+	entry := HAREntry{
+		ID:              req.ID,
+		StartedDateTime: req.Timestamp,
+		Time:            float64(req.DurationMS),
+		Timings:         HARTimings{Wait: float64(req.DurationMS)},
+		Comment:         req.Notes,
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         strings.TrimSuffix(tunnelURL, "/") + req.Path,
+			HTTPVersion: "HTTP/1.1",
+			Headers:     harHeaders(req.ReqHeaders),
+			QueryString: []HARNameValue{},
+			Cookies:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    req.ReqBodySize,
+		},
+		Response: HARResponse{
+			Status:      req.StatusCode,
+			StatusText:  http.StatusText(req.StatusCode),
+			HTTPVersion: "HTTP/1.1",
+			Headers:     harHeaders(req.ResHeaders),
+			Cookies:     []HARNameValue{},
+			Content: HARContent{
+				Size:     req.ResponseSize,
+				MimeType: headerValue(req.ResHeaders, "Content-Type"),
+				Text:     req.ResBody,
+			},
+			RedirectURL: headerValue(req.ResHeaders, "Location"),
+			HeadersSize: -1,
+			BodySize:    req.ResponseSize,
+		},
+	}
+
+	if u, err := url.Parse(req.Path); err == nil {
+		for _, key := range sortedKeys(u.Query()) {
+			for _, v := range u.Query()[key] {
+				entry.Request.QueryString = append(entry.Request.QueryString, HARNameValue{Name: key, Value: v})
+			}
+		}
+	}
+	if req.ReqBody != "" {
+		entry.Request.PostData = &HARPostData{
+			MimeType: headerValue(req.ReqHeaders, "Content-Type"),
+			Text:     req.ReqBody,
+		}
+		if req.ReqBodyTruncated() {
+			entry.Request.PostData.Comment = harTruncatedComment
+		}
+	}
+	if req.ResBodyTruncated() {
+		entry.Response.Content.Comment = harTruncatedComment
+	}
+	return entry
+}
+
+// readHAR reads an HTTP Archive, from mole or another tool, as a session.
+// Entries without a mole ID are numbered in the order they appear.
+func readHAR(data []byte, path string) (*ExportSession, error) {
+	var har HAR
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("failed to parse %s as HAR: %w", path, err)
+	}
+	if v := har.Log.Version; v != "" && !strings.HasPrefix(v, "1.") {
+		return nil, fmt.Errorf("%s is HAR version %s; only 1.x archives can be read", path, v)
+	}
+	if len(har.Log.Entries) == 0 {
+		return nil, fmt.Errorf("%s has no entries", path)
+	}
+
+	export := &ExportSession{ID: importSessionID(path)}
+	for i, e := range har.Log.Entries {
+		req := ExportRequest{
+			ID:           e.ID,
+			Method:       e.Request.Method,
+			Path:         e.Request.URL,
+			StatusCode:   e.Response.Status,
+			DurationMS:   int64(e.Time),
+			Timestamp:    e.StartedDateTime,
+			Notes:        e.Comment,
+			Request:      ExportHTTPData{Headers: fromHARHeaders(e.Request.Headers)},
+			Response:     ExportHTTPData{Headers: fromHARHeaders(e.Response.Headers), Body: e.Response.Content.Text},
+			RequestSize:  max(e.Request.BodySize, 0),
+			ResponseSize: max(e.Response.Content.Size, 0),
+		}
+		if req.ID == "" {
+			req.ID = fmt.Sprintf("%s_%d", export.ID, i+1)
+		}
+		// Paths are kept relative to the tunnel, as when captured
+		if u, err := url.Parse(e.Request.URL); err == nil && u.Host != "" {
+			req.Path = u.RequestURI()
+			if export.TunnelURL == "" {
+				export.TunnelURL = u.Scheme + "://" + u.Host
+			}
+		}
+		if e.Request.PostData != nil {
+			req.Request.Body = e.Request.PostData.Text
+		}
+		if e.Response.Content.Encoding == "base64" {
+			if body, err := base64.StdEncoding.DecodeString(req.Response.Body); err == nil {
+				req.Response.Body = string(body)
+			}
+		}
+
+		if export.StartedAt.IsZero() || req.Timestamp.Before(export.StartedAt) {
+			export.StartedAt = req.Timestamp
+		}
+		export.Requests = append(export.Requests, req)
+	}
+	return export, nil
+}
+
+// fromHARHeaders groups archived headers by name
+func fromHARHeaders(list []HARNameValue) map[string][]string {
+	headers := make(map[string][]string, len(list))
+	for _, h := range list {
+		headers[h.Name] = append(headers[h.Name], h.Value)
+	}
+	return headers
+}
+
+// harHeaders lists headers by name, one entry per value
+func harHeaders(headers map[string][]string) []HARNameValue {
+	list := []HARNameValue{}
+	for _, name := range sortedKeys(headers) {
+		for _, v := range headers[name] {
+			list = append(list, HARNameValue{Name: name, Value: v})
+		}
+	}
+	return list
+}
+
+// sortedKeys returns the names in a header or query map in order
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// headerValue returns the first value of a header, ignoring case
+func headerValue(headers map[string][]string, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) && len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
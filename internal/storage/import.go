@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ImportResult summarizes an import
+type ImportResult struct {
+	SessionID string
+	Imported  int
+	Skipped   int
+	Templates int // Templates added; ones with a name already in use are kept as they are
+}
+
+// ReadExportFile reads a file written by ExportSessionToJSON, ExportRequests
+// or WriteHAR. A bare request array (from ExportRequests) or an HTTP Archive
+// is wrapped in a synthetic session.
+func ReadExportFile(path string) (*ExportSession, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+
+	if trimmed[0] == '[' {
+		var requests []ExportRequest
+		if err := json.Unmarshal(trimmed, &requests); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		export := &ExportSession{
+			ID:       importSessionID(path),
+			Requests: requests,
+		}
+		for _, req := range requests {
+			if export.StartedAt.IsZero() || req.Timestamp.Before(export.StartedAt) {
+				export.StartedAt = req.Timestamp
+			}
+		}
+		return export, nil
+	}
+
+	var probe struct {
+		Log json.RawMessage `json:"log"`
+	}
+	if err := json.Unmarshal(trimmed, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if probe.Log != nil {
+		return readHAR(trimmed, path)
+	}
+
+	var export ExportSession
+	if err := json.Unmarshal(trimmed, &export); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if export.ID == "" {
+		return nil, fmt.Errorf("%s is not a mole export (missing session id)", path)
+	}
+	if export.Version > ExportVersion {
+		return nil, fmt.Errorf("%s is a version %d export from a newer mole; this one reads up to version %d", path, export.Version, ExportVersion)
+	}
+	return &export, nil
+}
+
+// importSessionID names the session made up for a file that doesn't carry
+// one, after the file
+func importSessionID(path string) string {
+	return "import_" + strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// ImportSessionJSON imports a file produced by ExportSessionToJSON, or any
+// file ReadExportFile reads, into the history database. The original session ID and timestamps are preserved and
+// requests that already exist are skipped.
+func (s *Storage) ImportSessionJSON(path string) (*ImportResult, error) {
+	export, err := ReadExportFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	startedAt := export.StartedAt
+	if startedAt.IsZero() {
+		startedAt = time.Now()
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(
+		"INSERT OR IGNORE INTO sessions (id, tunnel_url, label, started_at, ended_at) VALUES (?, ?, ?, ?, ?)",
+		export.ID, export.TunnelURL, export.Label, startedAt, export.EndedAt,
+	); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	// Re-importing a session picks up a label given to it since
+	if export.Label != "" {
+		if _, err := tx.Exec("UPDATE sessions SET label = ? WHERE id = ? AND label = ''", export.Label, export.ID); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to label session: %w", err)
+		}
+	}
+
+	result := &ImportResult{SessionID: export.ID}
+	for _, req := range export.HistoryRequests() {
+		reqHeaders, _ := json.Marshal(req.ReqHeaders)
+		resHeaders, _ := json.Marshal(req.ResHeaders)
+
+		res, err := tx.Exec(`
+			INSERT OR IGNORE INTO requests
+			(id, session_id, method, path, status_code, duration_ms, timestamp, req_headers, req_body, res_headers, res_body, starred, notes, remote_addr, response_size, req_body_size, replay_of)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`,
+			req.ID, req.SessionID, req.Method, req.Path, req.StatusCode, req.DurationMS,
+			req.Timestamp, string(reqHeaders), req.ReqBody, string(resHeaders), req.ResBody, req.Starred, req.Notes,
+			req.RemoteAddr, req.ResponseSize, req.ReqBodySize, req.ReplayOf,
+		)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to import request %s: %w", req.ID, err)
+		}
+
+		if n, _ := res.RowsAffected(); n > 0 {
+			if err := s.indexRequest(tx, req.ID); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			result.Imported++
+		} else {
+			result.Skipped++
+		}
+	}
+
+	for _, t := range export.Templates {
+		headers, _ := json.Marshal(t.Headers)
+		res, err := tx.Exec(
+			"INSERT OR IGNORE INTO templates (name, method, path, headers, body, assertions, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			t.Name, t.Method, t.Path, string(headers), t.Body, encodeAssertions(t.Assertions), t.UpdatedAt,
+		)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to import template %q: %w", t.Name, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			result.Templates++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration is one step in the evolution of the schema. Migrations run in
+// order, each in its own transaction, and must never change once released:
+// schema changes ship as a new migration appended to the list.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations lists every schema change, oldest first
+var migrations = []migration{
+	{1, "initial schema", func(tx *sql.Tx) error {
+		// IF NOT EXISTS so databases created before versioning adopt it
+		_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS sessions (
+			id TEXT PRIMARY KEY,
+			tunnel_url TEXT,
+			started_at DATETIME,
+			ended_at DATETIME
+		);
+
+		CREATE TABLE IF NOT EXISTS requests (
+			id TEXT PRIMARY KEY,
+			session_id TEXT,
+			method TEXT,
+			path TEXT,
+			status_code INTEGER,
+			duration_ms INTEGER,
+			timestamp DATETIME,
+			req_headers TEXT,
+			req_body TEXT,
+			res_headers TEXT,
+			res_body TEXT,
+			starred BOOLEAN DEFAULT FALSE,
+			FOREIGN KEY (session_id) REFERENCES sessions(id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_requests_session ON requests(session_id);
+		CREATE INDEX IF NOT EXISTS idx_requests_timestamp ON requests(timestamp);
+		CREATE INDEX IF NOT EXISTS idx_requests_starred ON requests(starred);
+		`)
+		return err
+	}},
+	{2, "request notes", func(tx *sql.Tx) error {
+		return addColumn(tx, "requests", "notes", "TEXT DEFAULT ''")
+	}},
+	{3, "session labels", func(tx *sql.Tx) error {
+		return addColumn(tx, "sessions", "label", "TEXT DEFAULT ''")
+	}},
+	{4, "remote address and response size", func(tx *sql.Tx) error {
+		if err := addColumn(tx, "requests", "remote_addr", "TEXT DEFAULT ''"); err != nil {
+			return err
+		}
+		if err := addColumn(tx, "requests", "response_size", "INTEGER DEFAULT 0"); err != nil {
+			return err
+		}
+		// Bodies stored so far were never truncated, so their length is the size
+		_, err := tx.Exec("UPDATE requests SET response_size = length(CAST(res_body AS BLOB))")
+		return err
+	}},
+	{5, "request body size", func(tx *sql.Tx) error {
+		if err := addColumn(tx, "requests", "req_body_size", "INTEGER DEFAULT 0"); err != nil {
+			return err
+		}
+		_, err := tx.Exec("UPDATE requests SET req_body_size = length(CAST(req_body AS BLOB))")
+		return err
+	}},
+	{6, "settings", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS settings (
+			key TEXT PRIMARY KEY,
+			value TEXT,
+			updated_at DATETIME
+		)
+		`)
+		return err
+	}},
+	{7, "request templates", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS templates (
+			name TEXT PRIMARY KEY,
+			method TEXT,
+			path TEXT,
+			headers TEXT,
+			body TEXT,
+			updated_at DATETIME
+		)
+		`)
+		return err
+	}},
+	{8, "template assertions", func(tx *sql.Tx) error {
+		return addColumn(tx, "templates", "assertions", "TEXT DEFAULT ''")
+	}},
+	{9, "replay lineage", func(tx *sql.Tx) error {
+		return addColumn(tx, "requests", "replay_of", "TEXT DEFAULT ''")
+	}},
+}
+
+// migrate applies any migrations the database hasn't seen yet
+func (s *Storage) migrate() error {
+	if _, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			applied_at DATETIME
+		)
+	`); err != nil {
+		return err
+	}
+
+	var current int
+	if err := s.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&current); err != nil {
+		return err
+	}
+
+	// A newer mole may have migrated further; its changes are additive, so
+	// an older build keeps working with the columns it knows about
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := s.applyMigration(m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+// applyMigration runs a migration and records it in one transaction
+func (s *Storage) applyMigration(m migration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Another mole starting at the same time may have got here first
+	var applied int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM schema_version WHERE version = ?", m.version).Scan(&applied); err != nil {
+		return err
+	}
+	if applied > 0 {
+		return nil
+	}
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO schema_version (version, applied_at) VALUES (?, ?)",
+		m.version, time.Now(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// addColumn adds a column to a table if it doesn't already exist. Columns
+// added before versioning existed may already be there.
+func addColumn(tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   bool
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	rows.Close()
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// v1Schema is the schema before versioning, as migration 1 adopts it
+const v1Schema = `
+	CREATE TABLE sessions (
+		id TEXT PRIMARY KEY,
+		tunnel_url TEXT,
+		started_at DATETIME,
+		ended_at DATETIME
+	);
+	CREATE TABLE requests (
+		id TEXT PRIMARY KEY,
+		session_id TEXT,
+		method TEXT,
+		path TEXT,
+		status_code INTEGER,
+		duration_ms INTEGER,
+		timestamp DATETIME,
+		req_headers TEXT,
+		req_body TEXT,
+		res_headers TEXT,
+		res_body TEXT,
+		starred BOOLEAN DEFAULT FALSE
+	);
+	INSERT INTO sessions (id, tunnel_url, started_at) VALUES ('s1', 'https://example.ngrok.app', '2024-01-01 12:00:00');
+	INSERT INTO requests (id, session_id, method, path, status_code, duration_ms, timestamp, req_headers, req_body, res_headers, res_body, starred)
+	VALUES ('r1', 's1', 'POST', '/hooks', 200, 12, '2024-01-01 12:00:01', '{}', '{"a":1}', '{}', '{"ok":true}', TRUE);
+`
+
+// writeFixture creates a database at path with the given SQL
+func writeFixture(t *testing.T, path, schema string) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+}
+
+func TestMigrateToHead(t *testing.T) {
+	head := migrations[len(migrations)-1].version
+	tests := []struct {
+		name   string
+		schema string
+		notes  string // Note the request should keep
+	}{
+		{"unversioned", v1Schema, ""},
+		{"version 1", v1Schema + `
+			CREATE TABLE schema_version (version INTEGER PRIMARY KEY, applied_at DATETIME);
+			INSERT INTO schema_version (version, applied_at) VALUES (1, '2024-01-01 12:00:00');
+		`, ""},
+		// Columns were added in place before there were migrations
+		{"unversioned with notes", v1Schema + `
+			ALTER TABLE requests ADD COLUMN notes TEXT DEFAULT '';
+			UPDATE requests SET notes = 'kept';
+		`, "kept"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "history.db")
+			writeFixture(t, path, tt.schema)
+
+			s := newTestStorage(t, path)
+			var version, applied int
+			if err := s.db.QueryRow("SELECT MAX(version), COUNT(*) FROM schema_version").Scan(&version, &applied); err != nil {
+				t.Fatal(err)
+			}
+			if version != head || applied != len(migrations) {
+				t.Errorf("schema at version %d with %d migrations applied, want %d and %d", version, applied, head, len(migrations))
+			}
+
+			reqs, err := s.GetSessionRequests("s1")
+			if err != nil {
+				t.Fatalf("reading migrated requests: %v", err)
+			}
+			if len(reqs) != 1 {
+				t.Fatalf("got %d requests, want 1", len(reqs))
+			}
+			got := reqs[0]
+			if !got.Starred || got.ResBody != `{"ok":true}` {
+				t.Errorf("request changed by migrating: %+v", got)
+			}
+			// Sizes are filled in from the bodies stored before they were kept
+			if got.ResponseSize != len(got.ResBody) || got.ReqBodySize != len(got.ReqBody) {
+				t.Errorf("sizes = %d, %d, want %d, %d", got.ResponseSize, got.ReqBodySize, len(got.ResBody), len(got.ReqBody))
+			}
+			if got.Notes != tt.notes {
+				t.Errorf("Notes = %q, want %q", got.Notes, tt.notes)
+			}
+		})
+	}
+}
+
+// Opening a database already at head changes nothing
+func TestMigrateAgain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	newTestStorage(t, path).Close()
+
+	s := newTestStorage(t, path)
+	var applied int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM schema_version").Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(migrations) {
+		t.Errorf("%d migrations recorded, want %d", applied, len(migrations))
+	}
+}
+
+func TestMigrationsOrdered(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("migration %d (%s) has version %d, want %d", i, m.name, m.version, i+1)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// postmanSchema is the schema URL for Postman Collection v2.1
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// PostmanCollection is the top-level Postman Collection v2.1 document
+type PostmanCollection struct {
+	Info PostmanInfo   `json:"info"`
+	Item []PostmanItem `json:"item"`
+}
+
+// PostmanInfo describes the collection
+type PostmanInfo struct {
+	PostmanID string `json:"_postman_id"`
+	Name      string `json:"name"`
+	Schema    string `json:"schema"`
+}
+
+// PostmanItem is either a folder (Item set) or a request (Request set)
+type PostmanItem struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Item        []PostmanItem   `json:"item,omitempty"`
+	Request     *PostmanRequest `json:"request,omitempty"`
+}
+
+// PostmanRequest is a single request inside a collection
+type PostmanRequest struct {
+	Method string          `json:"method"`
+	Header []PostmanHeader `json:"header"`
+	Body   *PostmanBody    `json:"body,omitempty"`
+	URL    PostmanURL      `json:"url"`
+}
+
+// PostmanHeader is a header key/value pair
+type PostmanHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// PostmanBody holds a raw request body
+type PostmanBody struct {
+	Mode string `json:"mode"`
+	Raw  string `json:"raw"`
+}
+
+// PostmanURL is the parsed form of a request URL
+type PostmanURL struct {
+	Raw      string         `json:"raw"`
+	Protocol string         `json:"protocol,omitempty"`
+	Host     []string       `json:"host,omitempty"`
+	Path     []string       `json:"path,omitempty"`
+	Query    []PostmanQuery `json:"query,omitempty"`
+}
+
+// PostmanQuery is a query string parameter
+type PostmanQuery struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ExportSessionToPostman exports a session to a Postman Collection v2.1 file
+func (s *Storage) ExportSessionToPostman(sessionID string, outputPath string) error {
+	return s.ExportSessionsToPostman([]string{sessionID}, outputPath)
+}
+
+// ExportSessionsToPostman exports sessions to a Postman collection with one
+// folder per session. Starred requests are also collected in a separate folder.
+func (s *Storage) ExportSessionsToPostman(sessionIDs []string, outputPath string) error {
+	if len(sessionIDs) == 0 {
+		return fmt.Errorf("no sessions to export")
+	}
+
+	collection := PostmanCollection{
+		Info: PostmanInfo{
+			PostmanID: fmt.Sprintf("mole-%d", time.Now().UnixNano()),
+			Name:      fmt.Sprintf("mole export %s", time.Now().Format("2006-01-02 15:04")),
+			Schema:    postmanSchema,
+		},
+	}
+
+	var starred []PostmanItem
+	for _, id := range sessionIDs {
+		sess, err := s.getSession(id)
+		if err != nil {
+			return err
+		}
+
+		requests, err := s.GetSessionRequests(id)
+		if err != nil {
+			return fmt.Errorf("failed to get requests: %w", err)
+		}
+
+		name := fmt.Sprintf("%s %s", sess.StartedAt.Format("2006-01-02 15:04:05"), sess.TunnelURL)
+		if sess.Label != "" {
+			name = sess.Label + " (" + sess.StartedAt.Format("2006-01-02 15:04:05") + ")"
+		}
+		folder := PostmanItem{
+			Name: name,
+			Item: []PostmanItem{},
+		}
+		for _, req := range requests {
+			item := buildPostmanItem(req, sess.TunnelURL)
+			folder.Item = append(folder.Item, item)
+			if req.Starred {
+				starred = append(starred, item)
+			}
+		}
+		collection.Item = append(collection.Item, folder)
+	}
+
+	if len(starred) > 0 {
+		collection.Item = append([]PostmanItem{{Name: "Starred", Item: starred}}, collection.Item...)
+	}
+
+	data, err := json.MarshalIndent(collection, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	return writeExportFile(outputPath, data, 0644)
+}
+
+// buildPostmanItem converts a stored request to a Postman request item
+func buildPostmanItem(req HistoryRequest, tunnelURL string) PostmanItem {
+	pr := &PostmanRequest{
+		Method: req.Method,
+		Header: []PostmanHeader{},
+		URL:    buildPostmanURL(tunnelURL + req.Path),
+	}
+
+	// Sort header keys so exports are stable
+	keys := make([]string, 0, len(req.ReqHeaders))
+	for k := range req.ReqHeaders {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		// Postman computes these itself
+		lowerK := strings.ToLower(k)
+		if lowerK == "host" || lowerK == "content-length" {
+			continue
+		}
+		for _, v := range req.ReqHeaders[k] {
+			pr.Header = append(pr.Header, PostmanHeader{Key: k, Value: v})
+		}
+	}
+
+	if req.ReqBody != "" {
+		pr.Body = &PostmanBody{Mode: "raw", Raw: req.ReqBody}
+	}
+
+	return PostmanItem{
+		Name:        req.Method + " " + req.Path,
+		Description: req.Notes,
+		Request:     pr,
+	}
+}
+
+// buildPostmanURL splits a URL into the parts Postman expects
+func buildPostmanURL(raw string) PostmanURL {
+	pu := PostmanURL{Raw: raw}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return pu
+	}
+
+	pu.Protocol = u.Scheme
+	if u.Host != "" {
+		pu.Host = strings.Split(u.Host, ".")
+	}
+	if p := strings.Trim(u.Path, "/"); p != "" {
+		pu.Path = strings.Split(p, "/")
+	}
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(pair, "=")
+		pu.Query = append(pu.Query, PostmanQuery{Key: k, Value: v})
+	}
+
+	return pu
+}
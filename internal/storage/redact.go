@@ -0,0 +1,42 @@
+package storage
+
+import "strings"
+
+// RedactedValue replaces the values of redacted headers
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactedHeaders lists headers whose values are not persisted by default
+var DefaultRedactedHeaders = []string{"authorization", "cookie", "set-cookie", "x-api-key"}
+
+// RedactHeaders returns a copy of headers with the values of the named
+// headers (case-insensitive) replaced by RedactedValue. The input is not
+// modified.
+func RedactHeaders(headers map[string][]string, names []string) map[string][]string {
+	if headers == nil || len(names) == 0 {
+		return headers
+	}
+
+	redacted := make(map[string][]string, len(headers))
+	for key, values := range headers {
+		if containsFold(names, key) {
+			masked := make([]string, len(values))
+			for i := range masked {
+				masked[i] = RedactedValue
+			}
+			redacted[key] = masked
+		} else {
+			redacted[key] = values
+		}
+	}
+	return redacted
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), s) {
+			return true
+		}
+	}
+	return false
+}
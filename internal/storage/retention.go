@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"time"
+)
+
+// RetentionPolicy controls which requests Cleanup removes. Starred requests
+// are never removed.
+type RetentionPolicy struct {
+	KeepDays  int // Remove requests older than this many days (0 = never)
+	KeepCount int // Always keep at least this many recent requests (0 = keep all)
+	MaxSizeMB int // Trim oldest requests while the database exceeds this size (0 = no limit)
+}
+
+// DefaultRetention keeps 7 days or the last 1000 requests, whichever is more
+var DefaultRetention = RetentionPolicy{KeepDays: 7, KeepCount: 1000}
+
+// Cleanup removes old requests according to policy and returns how many
+// requests were removed
+func (s *Storage) Cleanup(policy RetentionPolicy) (_ int64, err error) {
+	var removed int64
+	start := time.Now()
+	defer logQuery("cleanup", start, &err)
+
+	if policy.KeepDays > 0 && policy.KeepCount > 0 {
+		cutoff := time.Now().AddDate(0, 0, -policy.KeepDays)
+
+		// Delete old non-starred requests, keeping at least KeepCount
+		res, err := s.db.Exec(`
+			DELETE FROM requests 
+			WHERE starred = FALSE 
+			AND timestamp < ?
+			AND id NOT IN (
+				SELECT id FROM requests 
+				WHERE starred = FALSE 
+				ORDER BY timestamp DESC 
+				LIMIT ?
+			)
+		`, cutoff, policy.KeepCount)
+		if err != nil {
+			return removed, err
+		}
+		n, _ := res.RowsAffected()
+		removed += n
+	}
+
+	if policy.MaxSizeMB > 0 {
+		n, err := s.trimToSize(int64(policy.MaxSizeMB) * 1024 * 1024)
+		removed += n
+		if err != nil {
+			return removed, err
+		}
+	}
+
+	if err := s.pruneIndex(s.db); err != nil {
+		return removed, err
+	}
+
+	// Delete empty sessions. Cleanup runs in the background at startup, so
+	// the live session, or one started while this ran, may not have any
+	// requests yet.
+	_, err = s.db.Exec(`
+		DELETE FROM sessions 
+		WHERE id NOT IN (SELECT DISTINCT session_id FROM requests)
+		AND id != ?
+		AND started_at < ?
+	`, s.CurrentSessionID(), start)
+	if err != nil {
+		return removed, err
+	}
+
+	// Give the freed pages back to the filesystem
+	if removed > 0 && policy.MaxSizeMB > 0 {
+		_, err = s.Compact()
+	}
+
+	return removed, err
+}
+
+// trimToSize removes the oldest non-starred requests until the pages in use
+// fit within maxBytes
+func (s *Storage) trimToSize(maxBytes int64) (int64, error) {
+	var removed int64
+	for {
+		size, err := s.usedBytes()
+		if err != nil || size <= maxBytes {
+			return removed, err
+		}
+
+		// Remove a share of the requests proportional to the excess; more
+		// passes follow if bodies are unevenly sized
+		var count int64
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM requests WHERE starred = FALSE").Scan(&count); err != nil {
+			return removed, err
+		}
+		batch := count*(size-maxBytes)/size + 1
+
+		res, err := s.db.Exec(`
+			DELETE FROM requests 
+			WHERE id IN (
+				SELECT id FROM requests 
+				WHERE starred = FALSE 
+				ORDER BY timestamp ASC 
+				LIMIT ?
+			)
+		`, batch)
+		if err != nil {
+			return removed, err
+		}
+		n, _ := res.RowsAffected()
+		if n == 0 {
+			// Only starred requests left
+			return removed, nil
+		}
+		removed += n
+
+		// Index rows count towards the size too, and deleting them only
+		// frees space once the segments are merged
+		if err := s.pruneIndex(s.db); err != nil {
+			return removed, err
+		}
+		if err := s.optimizeIndex(); err != nil {
+			return removed, err
+		}
+	}
+}
+
+// usedBytes returns the size of the database excluding free pages
+func (s *Storage) usedBytes() (int64, error) {
+	var pageCount, freePages, pageSize int64
+	if err := s.db.QueryRow("PRAGMA page_count").Scan(&pageCount); err != nil {
+		return 0, err
+	}
+	if err := s.db.QueryRow("PRAGMA freelist_count").Scan(&freePages); err != nil {
+		return 0, err
+	}
+	if err := s.db.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, err
+	}
+	return (pageCount - freePages) * pageSize, nil
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// Cleanup runs in the background at startup, when the live session may
+// have no requests yet. It keeps that session while removing other empty
+// ones.
+func TestCleanupKeepsLiveSession(t *testing.T) {
+	s := newTestStorage(t, MemoryPath)
+	if _, err := s.db.Exec("INSERT INTO sessions (id, tunnel_url, started_at) VALUES (?, ?, ?)",
+		"old", "https://old.ngrok.app", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	live, err := s.StartSession("https://example.ngrok.app", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Cleanup(DefaultRetention); err != nil {
+		t.Fatalf("Cleanup: %v", err)
+	}
+	sessions, err := s.GetSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != live {
+		t.Fatalf("sessions after cleanup = %+v, want only the live session %s", sessions, live)
+	}
+
+	if err := s.SaveRequests(testRequests("r", 3)); err != nil {
+		t.Fatal(err)
+	}
+	reqs, err := s.GetSessionRequests(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 3 {
+		t.Errorf("live session has %d requests, want 3", len(reqs))
+	}
+}
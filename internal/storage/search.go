@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SearchLimit is the maximum number of results returned by SearchRequests
+const SearchLimit = 100
+
+// SearchResult is a request matched by SearchRequests
+type SearchResult struct {
+	HistoryRequest
+	MatchedField string // "method", "path", "req_body", "res_body" or "notes"
+	Snippet      string // Excerpt of the matched field
+}
+
+// searchFields lists the indexed columns in FTS column order
+var searchFields = []string{"method", "path", "req_body", "res_body", "notes"}
+
+// Markers wrapped around matches in FTS snippets
+const (
+	snippetStart = "\x02"
+	snippetEnd   = "\x03"
+)
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// initFTS sets up the full-text index when SQLite was built with FTS5
+// (go build -tags sqlite_fts5). Without it, search falls back to LIKE.
+//
+// The index is maintained from Go rather than with triggers so that a
+// database indexed by an FTS5 build stays writable by a build without it.
+func (s *Storage) initFTS() error {
+	_, err := s.db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS requests_fts USING fts5(method, path, req_body, res_body, notes)`)
+	if err == nil {
+		// CREATE is a no-op for an existing table even without the module, so
+		// probe the table itself
+		_, err = s.db.Exec("SELECT 1 FROM requests_fts LIMIT 1")
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			s.fts = false
+			return nil
+		}
+		return err
+	}
+	s.fts = true
+
+	// Backfill databases created before the index existed, or written to by
+	// a build without FTS5 (rowids in the index must match the table)
+	var total, indexed int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM requests").Scan(&total); err != nil {
+		return err
+	}
+	if err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM requests_fts WHERE rowid IN (SELECT rowid FROM requests)
+	`).Scan(&indexed); err != nil {
+		return err
+	}
+	var stale int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM requests_fts").Scan(&stale); err != nil {
+		return err
+	}
+	if indexed == total && stale == total {
+		return nil
+	}
+
+	_, err = s.db.Exec(`
+		DELETE FROM requests_fts;
+		INSERT INTO requests_fts (rowid, method, path, req_body, res_body, notes)
+		SELECT rowid, method, path, req_body, res_body, notes FROM requests;
+	`)
+	return err
+}
+
+// Index maintenance statements, keyed by request ID
+const (
+	unindexRequestSQL = `DELETE FROM requests_fts WHERE rowid IN (SELECT rowid FROM requests WHERE id = ?)`
+	indexRequestSQL   = `
+		INSERT INTO requests_fts (rowid, method, path, req_body, res_body, notes)
+		SELECT rowid, method, path, req_body, res_body, notes FROM requests WHERE id = ?
+	`
+)
+
+// unindexRequest removes a request from the search index. It must run
+// before the row is replaced or deleted.
+func (s *Storage) unindexRequest(e execer, requestID string) error {
+	if !s.fts {
+		return nil
+	}
+	_, err := e.Exec(unindexRequestSQL, requestID)
+	return err
+}
+
+// indexRequest adds a request to the search index
+func (s *Storage) indexRequest(e execer, requestID string) error {
+	if !s.fts {
+		return nil
+	}
+	_, err := e.Exec(indexRequestSQL, requestID)
+	return err
+}
+
+// pruneIndex removes index entries for requests that no longer exist
+func (s *Storage) pruneIndex(e execer) error {
+	if !s.fts {
+		return nil
+	}
+	_, err := e.Exec(`DELETE FROM requests_fts WHERE rowid NOT IN (SELECT rowid FROM requests)`)
+	return err
+}
+
+// optimizeIndex merges the index segments so space held by deleted entries
+// is released
+func (s *Storage) optimizeIndex() error {
+	if !s.fts {
+		return nil
+	}
+	_, err := s.db.Exec(`INSERT INTO requests_fts(requests_fts) VALUES('optimize')`)
+	return err
+}
+
+// SearchRequests searches requests by path, method, bodies and notes, best
+// matches first
+func (s *Storage) SearchRequests(query string) (_ []SearchResult, err error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+	defer logQuery(fmt.Sprintf("searching for %q", query), time.Now(), &err)
+	if s.fts {
+		return s.searchFTS(query)
+	}
+	return s.searchLike(query)
+}
+
+// searchFTS searches using the FTS5 index, ranked by bm25
+func (s *Storage) searchFTS(query string) ([]SearchResult, error) {
+	var snippets []string
+	for i := range searchFields {
+		snippets = append(snippets, fmt.Sprintf(
+			"snippet(requests_fts, %d, char(2), char(3), '…', 12) AS s%d", i, i))
+	}
+
+	rows, err := s.db.Query(`
+		SELECT `+requestColumns+`, s0, s1, s2, s3, s4
+		FROM requests
+		JOIN (
+			SELECT rowid AS fts_rowid, rank, `+strings.Join(snippets, ", ")+`
+			FROM requests_fts
+			WHERE requests_fts MATCH ?
+		) m ON requests.rowid = m.fts_rowid
+		ORDER BY m.rank
+		LIMIT ?
+	`, ftsQuery(query), SearchLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var res SearchResult
+		fieldSnippets := make([]sql.NullString, len(searchFields))
+		extra := make([]interface{}, len(fieldSnippets))
+		for i := range fieldSnippets {
+			extra[i] = &fieldSnippets[i]
+		}
+
+		req, err := scanRequest(rows, extra...)
+		if err != nil {
+			return nil, err
+		}
+		res.HistoryRequest = req
+
+		// The first column whose snippet contains a match marker is the one that matched
+		for i, sn := range fieldSnippets {
+			if strings.Contains(sn.String, snippetStart) {
+				res.MatchedField = searchFields[i]
+				res.Snippet = strings.NewReplacer(snippetStart, "", snippetEnd, "").Replace(sn.String)
+				break
+			}
+		}
+
+		results = append(results, res)
+	}
+
+	return results, rows.Err()
+}
+
+// ftsQuery turns user input into an FTS5 query: every term must match as a
+// prefix, with FTS syntax characters treated literally
+func ftsQuery(query string) string {
+	var terms []string
+	for _, term := range strings.Fields(query) {
+		terms = append(terms, `"`+strings.ReplaceAll(term, `"`, `""`)+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// searchLike searches with LIKE scans when FTS5 isn't available
+func (s *Storage) searchLike(query string) ([]SearchResult, error) {
+	searchTerm := "%" + query + "%"
+	rows, err := s.db.Query(`
+		SELECT `+requestColumns+`
+		FROM requests 
+		WHERE path LIKE ? OR method LIKE ? OR req_body LIKE ? OR res_body LIKE ? OR notes LIKE ?
+		ORDER BY timestamp DESC
+		LIMIT ?
+	`, searchTerm, searchTerm, searchTerm, searchTerm, searchTerm, SearchLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests, err := s.scanRequests(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, len(requests))
+	for i, req := range requests {
+		results[i].HistoryRequest = req
+		fields := []string{req.Method, req.Path, req.ReqBody, req.ResBody, req.Notes}
+		for j, text := range fields {
+			if snippet, ok := likeSnippet(text, query); ok {
+				results[i].MatchedField = searchFields[j]
+				results[i].Snippet = snippet
+				break
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// likeSnippet returns an excerpt of text around the first case-insensitive
+// occurrence of query
+func likeSnippet(text, query string) (string, bool) {
+	idx := strings.Index(strings.ToLower(text), strings.ToLower(query))
+	if idx == -1 {
+		return "", false
+	}
+
+	const context = 40
+	start := max(0, idx-context)
+	end := min(len(text), idx+len(query)+context)
+
+	snippet := strings.ToValidUTF8(text[start:end], "")
+	snippet = strings.Join(strings.Fields(snippet), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+	return snippet, true
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// GetSetting returns the value saved under key, or "" if there is none
+func (s *Storage) GetSetting(key string) (string, error) {
+	var value string
+	err := s.db.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+// SetSetting saves value under key, replacing what was there. An empty
+// value removes the setting.
+func (s *Storage) SetSetting(key string, value string) error {
+	if value == "" {
+		_, err := s.db.Exec("DELETE FROM settings WHERE key = ?", key)
+		return err
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`, key, value, time.Now())
+	return err
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"os"
+	"time"
+)
+
+// Stats summarizes the contents of the history database
+type Stats struct {
+	Sessions  int
+	Requests  int
+	Starred   int
+	SizeBytes int64     // Size on disk, including the write-ahead log
+	Oldest    time.Time // Timestamp of the oldest request, zero if none
+	Newest    time.Time // Timestamp of the newest request, zero if none
+}
+
+// GetStats returns storage statistics
+func (s *Storage) GetStats() (Stats, error) {
+	var stats Stats
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM sessions").Scan(&stats.Sessions); err != nil {
+		return stats, err
+	}
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM requests").Scan(&stats.Requests); err != nil {
+		return stats, err
+	}
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM requests WHERE starred = TRUE").Scan(&stats.Starred); err != nil {
+		return stats, err
+	}
+
+	if stats.Requests > 0 {
+		// MIN/MAX would lose the column type, so the driver wouldn't parse the time
+		if err := s.db.QueryRow("SELECT timestamp FROM requests ORDER BY timestamp ASC LIMIT 1").Scan(&stats.Oldest); err != nil {
+			return stats, err
+		}
+		if err := s.db.QueryRow("SELECT timestamp FROM requests ORDER BY timestamp DESC LIMIT 1").Scan(&stats.Newest); err != nil {
+			return stats, err
+		}
+	}
+
+	size, err := s.Size()
+	if err != nil {
+		return stats, err
+	}
+	stats.SizeBytes = size
+
+	return stats, nil
+}
+
+// Size returns the size of the database on disk. For an in-memory database
+// it is the memory used by its pages.
+func (s *Storage) Size() (int64, error) {
+	if s.InMemory() {
+		var pageCount, pageSize int64
+		if err := s.db.QueryRow("PRAGMA page_count").Scan(&pageCount); err != nil {
+			return 0, err
+		}
+		if err := s.db.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+			return 0, err
+		}
+		return pageCount * pageSize, nil
+	}
+
+	var total int64
+	for _, path := range []string{s.path, s.path + "-wal"} {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
+
+// Compact rebuilds the database to release space left by deleted requests and
+// returns the number of bytes reclaimed
+func (s *Storage) Compact() (_ int64, err error) {
+	defer logQuery("compacting", time.Now(), &err)
+	before, err := s.Size()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.optimizeIndex(); err != nil {
+		return 0, err
+	}
+	if _, err := s.db.Exec("VACUUM"); err != nil {
+		return 0, err
+	}
+	if !s.InMemory() {
+		// VACUUM goes through the WAL; fold it back so the file shrinks now
+		if _, err := s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+			return 0, err
+		}
+	}
+
+	after, err := s.Size()
+	if err != nil {
+		return 0, err
+	}
+	return max(0, before-after), nil
+}
@@ -1,65 +1,156 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/sung01299/mole/internal/debuglog"
 )
 
 // Storage handles persistent storage of request history
 type Storage struct {
 	db        *sql.DB
+	path      string
+	mu        sync.Mutex // Guards sessionID, which background cleanup reads
 	sessionID string
+	fts       bool // Whether the FTS5 search index is available
+}
+
+// MemoryPath is the database path that keeps history in memory only
+const MemoryPath = ":memory:"
+
+// Connection settings. WAL lets readers and a writer (possibly in another
+// mole process) work at the same time; writers wait up to busyTimeoutMS for
+// each other instead of failing with "database is locked".
+const (
+	busyTimeoutMS = 5000
+	maxOpenConns  = 4
+)
+
+// endSessionTimeout bounds how long quitting waits to record the session end
+const endSessionTimeout = time.Second
+
+// slowQuery is how long a query may take before the debug log notes it
+const slowQuery = 100 * time.Millisecond
+
+// logQuery writes a failed or slow query to the debug log. It is deferred
+// with the query's start and a pointer to its error.
+func logQuery(name string, start time.Time, err *error) {
+	elapsed := time.Since(start)
+	switch {
+	case *err != nil:
+		debuglog.Printf("storage: %s failed after %s: %v", name, elapsed.Round(time.Microsecond), *err)
+	case elapsed >= slowQuery:
+		debuglog.Printf("storage: %s was slow: %s", name, elapsed.Round(time.Millisecond))
+	}
 }
 
 // Session represents a mole session (one ngrok connection)
 type Session struct {
 	ID        string
 	TunnelURL string
+	Label     string // User-given name, empty if unnamed
 	StartedAt time.Time
 	EndedAt   *time.Time
 }
 
+// requestColumns is the column list scanned by scanRequests
+const requestColumns = `id, session_id, method, path, status_code, duration_ms, timestamp,
+	req_headers, req_body, res_headers, res_body, starred, notes, remote_addr, response_size, req_body_size, replay_of`
+
+// summaryColumns is requestColumns with empty bodies, for listing requests
+// whose bodies are loaded later with GetRequestBodies
+const summaryColumns = `id, session_id, method, path, status_code, duration_ms, timestamp,
+	req_headers, '', res_headers, '', starred, notes, remote_addr, response_size, req_body_size, replay_of`
+
 // HistoryRequest represents a stored request
 type HistoryRequest struct {
-	ID          string
-	SessionID   string
-	Method      string
-	Path        string
-	StatusCode  int
-	DurationMS  int64
-	Timestamp   time.Time
-	ReqHeaders  map[string][]string
-	ReqBody     string
-	ResHeaders  map[string][]string
-	ResBody     string
-	Starred     bool
-}
-
-// New creates a new Storage instance
-func New() (*Storage, error) {
-	dbPath, err := getDBPath()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get db path: %w", err)
+	ID           string
+	SessionID    string
+	Method       string
+	Path         string
+	StatusCode   int
+	DurationMS   int64
+	Timestamp    time.Time
+	ReqHeaders   map[string][]string
+	ReqBody      string
+	ResHeaders   map[string][]string
+	ResBody      string
+	Starred      bool
+	Notes        string
+	RemoteAddr   string
+	ResponseSize int    // Size of the response body as captured, in bytes
+	ReqBodySize  int    // Size of the request body as captured, in bytes
+	ReplayOf     string // ID of the request this one replayed, if any
+}
+
+// DefaultMaxBodyBytes is the default limit on each stored body
+const DefaultMaxBodyBytes = 256 * 1024
+
+// ReqBodyTruncated reports whether the stored request body is incomplete
+func (r HistoryRequest) ReqBodyTruncated() bool {
+	return r.ReqBodySize > len(r.ReqBody)
+}
+
+// ResBodyTruncated reports whether the stored response body is incomplete
+func (r HistoryRequest) ResBodyTruncated() bool {
+	return r.ResponseSize > len(r.ResBody)
+}
+
+// TruncateBody cuts body to at most maxBytes without splitting a UTF-8
+// sequence. maxBytes of 0 means no limit.
+func TruncateBody(body string, maxBytes int) string {
+	if maxBytes <= 0 || len(body) <= maxBytes {
+		return body
 	}
+	return strings.ToValidUTF8(body[:maxBytes], "")
+}
 
-	// Ensure directory exists
-	dir := filepath.Dir(dbPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create db directory: %w", err)
+// New creates a new Storage instance backed by the database at dbPath.
+// MemoryPath keeps everything in memory, so nothing outlives the process.
+func New(dbPath string) (*Storage, error) {
+	if dbPath == "" {
+		return nil, fmt.Errorf("no database path")
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	if dbPath != MemoryPath {
+		// Ensure directory exists
+		dir := filepath.Dir(dbPath)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create db directory: %w", err)
+		}
+	}
+
+	// Transactions take the write lock up front so the busy timeout applies
+	// to them rather than failing when a read lock can't be upgraded
+	dsn := fmt.Sprintf("%s?_busy_timeout=%d&_txlock=immediate", dbPath, busyTimeoutMS)
+	if dbPath != MemoryPath {
+		dsn += "&_journal_mode=WAL"
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	s := &Storage{db: db}
+	if dbPath == MemoryPath {
+		// Every connection to :memory: gets its own database
+		db.SetMaxOpenConns(1)
+	} else {
+		db.SetMaxOpenConns(maxOpenConns)
+	}
+
+	s := &Storage{db: db, path: dbPath}
 
 	if err := s.initSchema(); err != nil {
 		db.Close()
@@ -69,8 +160,8 @@ func New() (*Storage, error) {
 	return s, nil
 }
 
-// getDBPath returns the path to the SQLite database
-func getDBPath() (string, error) {
+// DefaultDBPath returns the default path to the SQLite database
+func DefaultDBPath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
@@ -78,90 +169,196 @@ func getDBPath() (string, error) {
 	return filepath.Join(homeDir, ".mole", "history.db"), nil
 }
 
-// initSchema creates the database tables if they don't exist
+// initSchema brings the database schema up to date
 func (s *Storage) initSchema() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS sessions (
-		id TEXT PRIMARY KEY,
-		tunnel_url TEXT,
-		started_at DATETIME,
-		ended_at DATETIME
-	);
-
-	CREATE TABLE IF NOT EXISTS requests (
-		id TEXT PRIMARY KEY,
-		session_id TEXT,
-		method TEXT,
-		path TEXT,
-		status_code INTEGER,
-		duration_ms INTEGER,
-		timestamp DATETIME,
-		req_headers TEXT,
-		req_body TEXT,
-		res_headers TEXT,
-		res_body TEXT,
-		starred BOOLEAN DEFAULT FALSE,
-		FOREIGN KEY (session_id) REFERENCES sessions(id)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_requests_session ON requests(session_id);
-	CREATE INDEX IF NOT EXISTS idx_requests_timestamp ON requests(timestamp);
-	CREATE INDEX IF NOT EXISTS idx_requests_starred ON requests(starred);
-	`
-
-	_, err := s.db.Exec(schema)
-	return err
+	if err := s.migrate(); err != nil {
+		return err
+	}
+	return s.initFTS()
 }
 
-// StartSession creates a new session and returns its ID
-func (s *Storage) StartSession(tunnelURL string) (string, error) {
+// StartSession creates a new session and returns its ID. label may be empty.
+func (s *Storage) StartSession(tunnelURL string, label string) (string, error) {
 	id := fmt.Sprintf("session_%d", time.Now().UnixNano())
-	s.sessionID = id
 
+	// Held until the session is current, so cleanup running meanwhile sees
+	// it as live rather than as an empty session to remove
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	_, err := s.db.Exec(
-		"INSERT INTO sessions (id, tunnel_url, started_at) VALUES (?, ?, ?)",
-		id, tunnelURL, time.Now(),
+		"INSERT INTO sessions (id, tunnel_url, label, started_at) VALUES (?, ?, ?, ?)",
+		id, tunnelURL, label, time.Now(),
 	)
 	if err != nil {
 		return "", err
 	}
+	s.sessionID = id
 
 	return id, nil
 }
 
-// EndSession marks the current session as ended
+// SetSessionLabel names a session (empty clears the name)
+func (s *Storage) SetSessionLabel(sessionID string, label string) error {
+	res, err := s.db.Exec("UPDATE sessions SET label = ? WHERE id = ?", label, sessionID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("session %s not found", sessionID)
+	}
+	return nil
+}
+
+// EndSession marks the current session as ended. It is best-effort: if the
+// database stays locked it gives up after endSessionTimeout.
 func (s *Storage) EndSession() error {
-	if s.sessionID == "" {
+	sessionID := s.CurrentSessionID()
+	if sessionID == "" {
 		return nil
 	}
 
-	_, err := s.db.Exec(
+	ctx, cancel := context.WithTimeout(context.Background(), endSessionTimeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx,
 		"UPDATE sessions SET ended_at = ? WHERE id = ?",
-		time.Now(), s.sessionID,
+		time.Now(), sessionID,
 	)
 	return err
 }
 
 // SaveRequest saves a request to the database
 func (s *Storage) SaveRequest(req HistoryRequest) error {
-	if s.sessionID == "" {
-		return fmt.Errorf("no active session")
-	}
+	return s.SaveRequests([]HistoryRequest{req})
+}
 
-	reqHeaders, _ := json.Marshal(req.ReqHeaders)
-	resHeaders, _ := json.Marshal(req.ResHeaders)
+// SaveRequests saves requests in a single transaction. Requests without a
+// SessionID belong to the current session.
+func (s *Storage) SaveRequests(reqs []HistoryRequest) (err error) {
+	if len(reqs) == 0 {
+		return nil
+	}
+	defer logQuery(fmt.Sprintf("saving %d requests", len(reqs)), time.Now(), &err)
+	for _, req := range reqs {
+		if req.SessionID == "" && s.CurrentSessionID() == "" {
+			return fmt.Errorf("no active session")
+		}
+	}
 
-	_, err := s.db.Exec(`
-		INSERT OR REPLACE INTO requests 
-		(id, session_id, method, path, status_code, duration_ms, timestamp, req_headers, req_body, res_headers, res_body, starred)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`,
-		req.ID, s.sessionID, req.Method, req.Path, req.StatusCode, req.DurationMS,
-		req.Timestamp, string(reqHeaders), req.ReqBody, string(resHeaders), req.ResBody, req.Starred,
-	)
+	err = s.saveBatch(reqs)
+	if isBusy(err) {
+		debuglog.Printf("storage: database busy, saving %d requests again", len(reqs))
+		// Another process held the lock past the busy timeout; try once more
+		err = s.saveBatch(reqs)
+	}
 	return err
 }
 
+// insertRequestSQL saves a request, replacing an earlier copy. ngrok keeps
+// requests across mole restarts, so a request starred in an earlier run is
+// saved again; it stays starred, and a replay stays linked to its original.
+const insertRequestSQL = `
+	INSERT INTO requests 
+	(id, session_id, method, path, status_code, duration_ms, timestamp, req_headers, req_body, res_headers, res_body, starred, notes, remote_addr, response_size, req_body_size, replay_of)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(id) DO UPDATE SET
+		session_id = excluded.session_id, method = excluded.method, path = excluded.path,
+		status_code = excluded.status_code, duration_ms = excluded.duration_ms, timestamp = excluded.timestamp,
+		req_headers = excluded.req_headers, req_body = excluded.req_body,
+		res_headers = excluded.res_headers, res_body = excluded.res_body,
+		starred = requests.starred OR excluded.starred, notes = excluded.notes,
+		remote_addr = excluded.remote_addr, response_size = excluded.response_size, req_body_size = excluded.req_body_size,
+		replay_of = CASE WHEN excluded.replay_of = '' THEN requests.replay_of ELSE excluded.replay_of END
+`
+
+// saveBatch stores requests and their index entries in one transaction,
+// preparing each statement once for the whole batch
+func (s *Storage) saveBatch(reqs []HistoryRequest) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	insert, err := tx.Prepare(insertRequestSQL)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	var unindex, index *sql.Stmt
+	if s.fts {
+		if unindex, err = tx.Prepare(unindexRequestSQL); err != nil {
+			return err
+		}
+		defer unindex.Close()
+		if index, err = tx.Prepare(indexRequestSQL); err != nil {
+			return err
+		}
+		defer index.Close()
+	}
+
+	for _, req := range reqs {
+		sessionID := req.SessionID
+		if sessionID == "" {
+			sessionID = s.CurrentSessionID()
+		}
+
+		reqHeaders, _ := json.Marshal(req.ReqHeaders)
+		resHeaders, _ := json.Marshal(req.ResHeaders)
+
+		// The index entry must go before the row it points at is replaced
+		if unindex != nil {
+			if _, err := unindex.Exec(req.ID); err != nil {
+				return err
+			}
+		}
+
+		_, err := insert.Exec(
+			req.ID, sessionID, req.Method, req.Path, req.StatusCode, req.DurationMS,
+			req.Timestamp, string(reqHeaders), req.ReqBody, string(resHeaders), req.ResBody, req.Starred, req.Notes,
+			req.RemoteAddr, req.ResponseSize, req.ReqBodySize, req.ReplayOf,
+		)
+		if err != nil {
+			return err
+		}
+
+		if index != nil {
+			if _, err := index.Exec(req.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// isBusy reports whether err means the database was locked by another
+// connection
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
+
+// SetNote sets the note attached to a request (empty clears it)
+func (s *Storage) SetNote(requestID string, note string) error {
+	res, err := s.db.Exec("UPDATE requests SET notes = ? WHERE id = ?", note, requestID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("request %s not found", requestID)
+	}
+
+	if err := s.unindexRequest(s.db, requestID); err != nil {
+		return err
+	}
+	return s.indexRequest(s.db, requestID)
+}
+
 // ToggleStar toggles the starred status of a request
 func (s *Storage) ToggleStar(requestID string) (bool, error) {
 	// Get current starred status
@@ -181,6 +378,27 @@ func (s *Storage) ToggleStar(requestID string) (bool, error) {
 	return newStarred, nil
 }
 
+// StarRequests stars the given requests and returns how many were found
+func (s *Storage) StarRequests(requestIDs []string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	var starred int64
+	for _, id := range requestIDs {
+		res, err := tx.Exec("UPDATE requests SET starred = 1 WHERE id = ?", id)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		starred += n
+	}
+
+	return starred, tx.Commit()
+}
+
 // IsStarred checks if a request is starred
 func (s *Storage) IsStarred(requestID string) bool {
 	var starred bool
@@ -194,7 +412,7 @@ func (s *Storage) IsStarred(requestID string) bool {
 // GetSessions returns all sessions, ordered by start time descending
 func (s *Storage) GetSessions() ([]Session, error) {
 	rows, err := s.db.Query(`
-		SELECT id, tunnel_url, started_at, ended_at 
+		SELECT id, tunnel_url, label, started_at, ended_at 
 		FROM sessions 
 		ORDER BY started_at DESC
 	`)
@@ -207,7 +425,7 @@ func (s *Storage) GetSessions() ([]Session, error) {
 	for rows.Next() {
 		var sess Session
 		var endedAt sql.NullTime
-		if err := rows.Scan(&sess.ID, &sess.TunnelURL, &sess.StartedAt, &endedAt); err != nil {
+		if err := rows.Scan(&sess.ID, &sess.TunnelURL, &sess.Label, &sess.StartedAt, &endedAt); err != nil {
 			return nil, err
 		}
 		if endedAt.Valid {
@@ -220,10 +438,10 @@ func (s *Storage) GetSessions() ([]Session, error) {
 }
 
 // GetSessionRequests returns all requests for a session
-func (s *Storage) GetSessionRequests(sessionID string) ([]HistoryRequest, error) {
+func (s *Storage) GetSessionRequests(sessionID string) (_ []HistoryRequest, err error) {
+	defer logQuery("loading session "+sessionID, time.Now(), &err)
 	rows, err := s.db.Query(`
-		SELECT id, session_id, method, path, status_code, duration_ms, timestamp, 
-		       req_headers, req_body, res_headers, res_body, starred
+		SELECT `+requestColumns+`
 		FROM requests 
 		WHERE session_id = ?
 		ORDER BY timestamp DESC
@@ -236,15 +454,16 @@ func (s *Storage) GetSessionRequests(sessionID string) ([]HistoryRequest, error)
 	return s.scanRequests(rows)
 }
 
-// GetStarredRequests returns all starred requests
-func (s *Storage) GetStarredRequests() ([]HistoryRequest, error) {
+// GetSessionSummaries returns all requests for a session like
+// GetSessionRequests, but without their bodies
+func (s *Storage) GetSessionSummaries(sessionID string) (_ []HistoryRequest, err error) {
+	defer logQuery("listing session "+sessionID, time.Now(), &err)
 	rows, err := s.db.Query(`
-		SELECT id, session_id, method, path, status_code, duration_ms, timestamp, 
-		       req_headers, req_body, res_headers, res_body, starred
+		SELECT `+summaryColumns+`
 		FROM requests 
-		WHERE starred = TRUE
+		WHERE session_id = ?
 		ORDER BY timestamp DESC
-	`)
+	`, sessionID)
 	if err != nil {
 		return nil, err
 	}
@@ -253,17 +472,58 @@ func (s *Storage) GetStarredRequests() ([]HistoryRequest, error) {
 	return s.scanRequests(rows)
 }
 
-// SearchRequests searches requests by path or method
-func (s *Storage) SearchRequests(query string) ([]HistoryRequest, error) {
-	searchTerm := "%" + query + "%"
+// GetRequestBodies returns the stored request and response bodies of a
+// request
+func (s *Storage) GetRequestBodies(id string) (reqBody, resBody string, err error) {
+	defer logQuery("loading bodies of "+id, time.Now(), &err)
+	err = s.db.QueryRow("SELECT req_body, res_body FROM requests WHERE id = ?", id).Scan(&reqBody, &resBody)
+	return reqBody, resBody, err
+}
+
+// Bodies is the stored request and response bodies of a request
+type Bodies struct {
+	Request  string
+	Response string
+}
+
+// GetSessionBodies returns the stored bodies of every request in a session,
+// by request ID, for searching a session listed without them
+func (s *Storage) GetSessionBodies(sessionID string) (_ map[string]Bodies, err error) {
+	defer logQuery("loading bodies of session "+sessionID, time.Now(), &err)
+	rows, err := s.db.Query("SELECT id, req_body, res_body FROM requests WHERE session_id = ?", sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bodies := make(map[string]Bodies)
+	for rows.Next() {
+		var id string
+		var b Bodies
+		if err := rows.Scan(&id, &b.Request, &b.Response); err != nil {
+			return nil, err
+		}
+		bodies[id] = b
+	}
+	return bodies, rows.Err()
+}
+
+// GetSessionRequestCount returns how many requests a session has, without
+// loading them
+func (s *Storage) GetSessionRequestCount(sessionID string) (int, error) {
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM requests WHERE session_id = ?", sessionID).Scan(&n)
+	return n, err
+}
+
+// GetStarredRequests returns all starred requests
+func (s *Storage) GetStarredRequests() ([]HistoryRequest, error) {
 	rows, err := s.db.Query(`
-		SELECT id, session_id, method, path, status_code, duration_ms, timestamp, 
-		       req_headers, req_body, res_headers, res_body, starred
+		SELECT ` + requestColumns + `
 		FROM requests 
-		WHERE path LIKE ? OR method LIKE ? OR req_body LIKE ? OR res_body LIKE ?
+		WHERE starred = TRUE
 		ORDER BY timestamp DESC
-		LIMIT 100
-	`, searchTerm, searchTerm, searchTerm, searchTerm)
+	`)
 	if err != nil {
 		return nil, err
 	}
@@ -275,8 +535,7 @@ func (s *Storage) SearchRequests(query string) ([]HistoryRequest, error) {
 // GetRecentRequests returns recent requests across all sessions
 func (s *Storage) GetRecentRequests(limit int) ([]HistoryRequest, error) {
 	rows, err := s.db.Query(`
-		SELECT id, session_id, method, path, status_code, duration_ms, timestamp, 
-		       req_headers, req_body, res_headers, res_body, starred
+		SELECT `+requestColumns+`
 		FROM requests 
 		ORDER BY timestamp DESC
 		LIMIT ?
@@ -292,28 +551,43 @@ func (s *Storage) GetRecentRequests(limit int) ([]HistoryRequest, error) {
 func (s *Storage) scanRequests(rows *sql.Rows) ([]HistoryRequest, error) {
 	var requests []HistoryRequest
 	for rows.Next() {
-		var req HistoryRequest
-		var reqHeadersJSON, resHeadersJSON string
-
-		if err := rows.Scan(
-			&req.ID, &req.SessionID, &req.Method, &req.Path, &req.StatusCode,
-			&req.DurationMS, &req.Timestamp, &reqHeadersJSON, &req.ReqBody,
-			&resHeadersJSON, &req.ResBody, &req.Starred,
-		); err != nil {
+		req, err := scanRequest(rows)
+		if err != nil {
 			return nil, err
 		}
-
-		json.Unmarshal([]byte(reqHeadersJSON), &req.ReqHeaders)
-		json.Unmarshal([]byte(resHeadersJSON), &req.ResHeaders)
-
 		requests = append(requests, req)
 	}
 
 	return requests, nil
 }
 
+// scanRequest scans one row selected with requestColumns, plus any extra
+// trailing columns into extra
+func scanRequest(rows *sql.Rows, extra ...interface{}) (HistoryRequest, error) {
+	var req HistoryRequest
+	var reqHeadersJSON, resHeadersJSON string
+
+	dest := []interface{}{
+		&req.ID, &req.SessionID, &req.Method, &req.Path, &req.StatusCode,
+		&req.DurationMS, &req.Timestamp, &reqHeadersJSON, &req.ReqBody,
+		&resHeadersJSON, &req.ResBody, &req.Starred, &req.Notes,
+		&req.RemoteAddr, &req.ResponseSize, &req.ReqBodySize, &req.ReplayOf,
+	}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return req, err
+	}
+
+	json.Unmarshal([]byte(reqHeadersJSON), &req.ReqHeaders)
+	json.Unmarshal([]byte(resHeadersJSON), &req.ResHeaders)
+
+	return req, nil
+}
+
 // DeleteRequest deletes a request by ID
 func (s *Storage) DeleteRequest(requestID string) error {
+	if err := s.unindexRequest(s.db, requestID); err != nil {
+		return err
+	}
 	_, err := s.db.Exec("DELETE FROM requests WHERE id = ?", requestID)
 	return err
 }
@@ -335,62 +609,33 @@ func (s *Storage) DeleteSession(sessionID string) error {
 		return err
 	}
 
-	return tx.Commit()
-}
-
-// Cleanup removes old requests (keeps starred and recent)
-func (s *Storage) Cleanup(keepDays int, keepCount int) error {
-	cutoff := time.Now().AddDate(0, 0, -keepDays)
-
-	// Delete old non-starred requests, keeping at least keepCount
-	_, err := s.db.Exec(`
-		DELETE FROM requests 
-		WHERE starred = FALSE 
-		AND timestamp < ?
-		AND id NOT IN (
-			SELECT id FROM requests 
-			WHERE starred = FALSE 
-			ORDER BY timestamp DESC 
-			LIMIT ?
-		)
-	`, cutoff, keepCount)
-
-	if err != nil {
+	if err := s.pruneIndex(tx); err != nil {
+		tx.Rollback()
 		return err
 	}
 
-	// Delete empty sessions
-	_, err = s.db.Exec(`
-		DELETE FROM sessions 
-		WHERE id NOT IN (SELECT DISTINCT session_id FROM requests)
-	`)
-
-	return err
-}
-
-// GetStats returns storage statistics
-func (s *Storage) GetStats() (sessionCount, requestCount, starredCount int, err error) {
-	err = s.db.QueryRow("SELECT COUNT(*) FROM sessions").Scan(&sessionCount)
-	if err != nil {
-		return
-	}
-	err = s.db.QueryRow("SELECT COUNT(*) FROM requests").Scan(&requestCount)
-	if err != nil {
-		return
-	}
-	err = s.db.QueryRow("SELECT COUNT(*) FROM requests WHERE starred = TRUE").Scan(&starredCount)
-	return
+	return tx.Commit()
 }
 
 // Close closes the database connection
 func (s *Storage) Close() error {
-	if s.sessionID != "" {
-		s.EndSession()
-	}
+	s.EndSession()
 	return s.db.Close()
 }
 
+// Path returns the database path
+func (s *Storage) Path() string {
+	return s.path
+}
+
+// InMemory reports whether history is kept in memory only
+func (s *Storage) InMemory() bool {
+	return s.path == MemoryPath
+}
+
 // CurrentSessionID returns the current session ID
 func (s *Storage) CurrentSessionID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.sessionID
 }
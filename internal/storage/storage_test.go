@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// newTestStorage opens a history database in a temporary directory
+func newTestStorage(t testing.TB, path string) *Storage {
+	t.Helper()
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("New(%q): %v", path, err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// testRequests returns n requests with distinct IDs for a session
+func testRequests(prefix string, n int) []HistoryRequest {
+	reqs := make([]HistoryRequest, n)
+	for i := range reqs {
+		reqs[i] = HistoryRequest{
+			ID:         fmt.Sprintf("%s_%d", prefix, i),
+			Method:     "POST",
+			Path:       fmt.Sprintf("/hooks/%d", i),
+			StatusCode: 200,
+			DurationMS: 12,
+			Timestamp:  time.Now(),
+			ReqHeaders: map[string][]string{"Content-Type": {"application/json"}},
+			ReqBody:    fmt.Sprintf(`{"event":"payment.%d"}`, i),
+			ResHeaders: map[string][]string{"Content-Type": {"application/json"}},
+			ResBody:    `{"ok":true}`,
+		}
+	}
+	return reqs
+}
+
+func TestNewConnectionSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		journalMode string
+	}{
+		{"file", filepath.Join(t.TempDir(), "history.db"), "wal"},
+		{"memory", MemoryPath, "memory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t, tt.path)
+
+			var mode string
+			if err := s.db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+				t.Fatal(err)
+			}
+			if mode != tt.journalMode {
+				t.Errorf("journal_mode = %q, want %q", mode, tt.journalMode)
+			}
+			var timeout int
+			if err := s.db.QueryRow("PRAGMA busy_timeout").Scan(&timeout); err != nil {
+				t.Fatal(err)
+			}
+			if timeout != busyTimeoutMS {
+				t.Errorf("busy_timeout = %d, want %d", timeout, busyTimeoutMS)
+			}
+		})
+	}
+}
+
+func TestNewNoPath(t *testing.T) {
+	if _, err := New(""); err == nil {
+		t.Error("New(\"\") succeeded, want an error")
+	}
+}
+
+// Two mole processes, as when mole export runs while the TUI is up, share a
+// database file and write to it at the same time
+func TestConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	const writers, batches, perBatch = 2, 20, 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*batches)
+	for w := 0; w < writers; w++ {
+		s := newTestStorage(t, path)
+		if _, err := s.StartSession("https://example.ngrok.app", ""); err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(w int, s *Storage) {
+			defer wg.Done()
+			for b := 0; b < batches; b++ {
+				errs <- s.SaveRequests(testRequests(fmt.Sprintf("w%d_b%d", w, b), perBatch))
+			}
+		}(w, s)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("SaveRequests: %v", err)
+		}
+	}
+
+	s := newTestStorage(t, path)
+	stats, err := s.GetStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := writers * batches * perBatch; stats.Requests != want {
+		t.Errorf("stored %d requests, want %d", stats.Requests, want)
+	}
+}
+
+func TestIsBusy(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"busy", sqlite3.Error{Code: sqlite3.ErrBusy}, true},
+		{"locked", sqlite3.Error{Code: sqlite3.ErrLocked}, true},
+		{"wrapped busy", fmt.Errorf("saving: %w", sqlite3.Error{Code: sqlite3.ErrBusy}), true},
+		{"constraint", sqlite3.Error{Code: sqlite3.ErrConstraint}, false},
+		{"other", errors.New("database is locked"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isBusy(tt.err); got != tt.want {
+				t.Errorf("isBusy(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// A session that couldn't be stored doesn't become current, so requests
+// aren't saved against it
+func TestStartSessionFails(t *testing.T) {
+	s := newTestStorage(t, MemoryPath)
+	first, err := s.StartSession("https://example.ngrok.app", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.Exec(`CREATE TRIGGER refuse BEFORE INSERT ON sessions
+		BEGIN SELECT RAISE(ABORT, 'refused'); END`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.StartSession("https://other.ngrok.app", ""); err == nil {
+		t.Fatal("StartSession succeeded, want the insert's error")
+	}
+	if got := s.CurrentSessionID(); got != first {
+		t.Errorf("CurrentSessionID() = %q, want %q from before", got, first)
+	}
+}
+
+func TestSaveRequestsWithoutSession(t *testing.T) {
+	s := newTestStorage(t, MemoryPath)
+	if err := s.SaveRequests(nil); err != nil {
+		t.Errorf("SaveRequests(nil) = %v, want nil", err)
+	}
+	if err := s.SaveRequests(testRequests("r", 1)); err == nil {
+		t.Error("SaveRequests with no session succeeded, want an error")
+	}
+}
+
+// A request saved again, as ngrok lists it again after mole restarts,
+// replaces the earlier copy but keeps what was added to it since
+func TestSaveRequestsAgain(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  func(*HistoryRequest)
+		second func(*HistoryRequest)
+		check  func(t *testing.T, got HistoryRequest)
+	}{
+		{
+			name:   "replaces the body",
+			second: func(r *HistoryRequest) { r.ResBody = `{"ok":false}` },
+			check: func(t *testing.T, got HistoryRequest) {
+				if got.ResBody != `{"ok":false}` {
+					t.Errorf("ResBody = %q, want the second copy's", got.ResBody)
+				}
+			},
+		},
+		{
+			name:  "stays starred",
+			first: func(r *HistoryRequest) { r.Starred = true },
+			check: func(t *testing.T, got HistoryRequest) {
+				if !got.Starred {
+					t.Error("request saved again is no longer starred")
+				}
+			},
+		},
+		{
+			name:  "stays linked to its original",
+			first: func(r *HistoryRequest) { r.ReplayOf = "orig" },
+			check: func(t *testing.T, got HistoryRequest) {
+				if got.ReplayOf != "orig" {
+					t.Errorf("ReplayOf = %q, want %q", got.ReplayOf, "orig")
+				}
+			},
+		},
+		{
+			name:   "takes a new link",
+			first:  func(r *HistoryRequest) { r.ReplayOf = "orig" },
+			second: func(r *HistoryRequest) { r.ReplayOf = "other" },
+			check: func(t *testing.T, got HistoryRequest) {
+				if got.ReplayOf != "other" {
+					t.Errorf("ReplayOf = %q, want %q", got.ReplayOf, "other")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t, MemoryPath)
+			sessionID, err := s.StartSession("https://example.ngrok.app", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, change := range []func(*HistoryRequest){tt.first, tt.second} {
+				req := testRequests("r", 1)[0]
+				if change != nil {
+					change(&req)
+				}
+				if err := s.SaveRequests([]HistoryRequest{req}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := s.GetSessionRequests(sessionID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("session has %d requests, want 1", len(got))
+			}
+			tt.check(t, got[0])
+		})
+	}
+}
+
+// BenchmarkSaveRequests saves 1000 requests in one batch, against saving
+// them one at a time as mole used to
+func BenchmarkSaveRequests(b *testing.B) {
+	reqs := testRequests("r", 1000)
+	save := map[string]func(s *Storage) error{
+		"batch": func(s *Storage) error { return s.SaveRequests(reqs) },
+		"per-row": func(s *Storage) error {
+			for _, req := range reqs {
+				if err := s.SaveRequest(req); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	for _, name := range []string{"batch", "per-row"} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				s := newTestStorage(b, filepath.Join(b.TempDir(), "history.db"))
+				if _, err := s.StartSession("https://example.ngrok.app", ""); err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+				if err := save[name](s); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
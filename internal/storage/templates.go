@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Template is a request saved from replay with edit to send again later
+type Template struct {
+	Name       string              `json:"name"`
+	Method     string              `json:"method"`
+	Path       string              `json:"path"`
+	Headers    map[string][]string `json:"headers"`
+	Body       string              `json:"body"`
+	Assertions []string            `json:"assertions,omitempty"` // Checks on the response, as typed
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+// SaveTemplate saves t under its name, replacing a template of that name
+func (s *Storage) SaveTemplate(t Template) error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("template name is empty")
+	}
+	headers, _ := json.Marshal(t.Headers)
+	_, err := s.db.Exec(`
+		INSERT INTO templates (name, method, path, headers, body, assertions, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET method = excluded.method, path = excluded.path,
+			headers = excluded.headers, body = excluded.body, assertions = excluded.assertions,
+			updated_at = excluded.updated_at
+	`, t.Name, t.Method, t.Path, string(headers), t.Body, encodeAssertions(t.Assertions), time.Now())
+	return err
+}
+
+// ListTemplates returns the saved templates by name
+func (s *Storage) ListTemplates() ([]Template, error) {
+	rows, err := s.db.Query("SELECT name, method, path, headers, body, assertions, updated_at FROM templates ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []Template
+	for rows.Next() {
+		var t Template
+		var headers, assertions string
+		if err := rows.Scan(&t.Name, &t.Method, &t.Path, &headers, &t.Body, &assertions, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(headers), &t.Headers)
+		if assertions != "" {
+			json.Unmarshal([]byte(assertions), &t.Assertions)
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// DeleteTemplate removes the template saved under name
+func (s *Storage) DeleteTemplate(name string) error {
+	_, err := s.db.Exec("DELETE FROM templates WHERE name = ?", name)
+	return err
+}
+
+// encodeAssertions stores a template's assertions as a JSON list, or as ""
+// when there are none
+func encodeAssertions(assertions []string) string {
+	if len(assertions) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(assertions)
+	return string(data)
+}
@@ -0,0 +1,120 @@
+package storage
+
+import "github.com/sung01299/mole/internal/debuglog"
+
+// Writer queue sizes
+const (
+	writerQueueSize = 64  // Batches buffered before Save blocks
+	writerBatchSize = 500 // Requests after which queued batches stop being merged
+)
+
+// Writer saves requests on a background goroutine so callers never wait on
+// disk I/O. Requests that queue up while a write is in progress are saved
+// together in a single transaction.
+type Writer struct {
+	store *Storage
+	queue chan writeOp
+	errs  chan error
+	done  chan struct{}
+}
+
+// writeOp is a batch of requests to save, or a flush marker when flushed is set
+type writeOp struct {
+	reqs    []HistoryRequest
+	flushed chan struct{}
+}
+
+// NewWriter starts a background writer for s. Close must be called to flush
+// queued requests before s is closed.
+func NewWriter(s *Storage) *Writer {
+	w := &Writer{
+		store: s,
+		queue: make(chan writeOp, writerQueueSize),
+		errs:  make(chan error, 8),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Save queues requests to be stored together. Requests without a SessionID
+// are assigned the current session now, not when they are written.
+func (w *Writer) Save(reqs []HistoryRequest) {
+	if len(reqs) == 0 {
+		return
+	}
+	batch := make([]HistoryRequest, len(reqs))
+	for i, req := range reqs {
+		if req.SessionID == "" {
+			req.SessionID = w.store.CurrentSessionID()
+		}
+		batch[i] = req
+	}
+	w.queue <- writeOp{reqs: batch}
+}
+
+// Flush returns a channel that is closed once everything queued before the
+// call has been written. Like Save, it must not be called after Close.
+func (w *Writer) Flush() <-chan struct{} {
+	flushed := make(chan struct{})
+	w.queue <- writeOp{flushed: flushed}
+	return flushed
+}
+
+// Errors returns a channel of write failures. It is closed once the writer
+// has stopped.
+func (w *Writer) Errors() <-chan error {
+	return w.errs
+}
+
+// Close stops accepting requests and waits until everything queued has been
+// written
+func (w *Writer) Close() {
+	close(w.queue)
+	<-w.done
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	defer close(w.errs)
+
+	for op := range w.queue {
+		batch := op.reqs
+		var flushed []chan struct{}
+		if op.flushed != nil {
+			flushed = append(flushed, op.flushed)
+		}
+
+		// Pick up whatever else is already waiting
+	drain:
+		for len(batch) < writerBatchSize {
+			select {
+			case next, ok := <-w.queue:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, next.reqs...)
+				if next.flushed != nil {
+					flushed = append(flushed, next.flushed)
+				}
+			default:
+				break drain
+			}
+		}
+
+		if len(batch) > 0 {
+			if err := w.store.SaveRequests(batch); err != nil {
+				select {
+				case w.errs <- err:
+				default:
+					// Nobody is keeping up with errors; drop rather than stall writes
+					debuglog.Printf("storage: save error dropped, none were being read: %v", err)
+				}
+			}
+		}
+
+		for _, ch := range flushed {
+			close(ch)
+		}
+	}
+}
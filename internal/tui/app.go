@@ -2,10 +2,11 @@ package tui
 
 import (
 	"fmt"
-	"io"
 	"net/http"
 	"os/exec"
+	"regexp"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -17,6 +18,8 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/sung01299/mole/internal/capture"
+	"github.com/sung01299/mole/internal/debuglog"
 	"github.com/sung01299/mole/internal/ngrok"
 	"github.com/sung01299/mole/internal/storage"
 	"github.com/sung01299/mole/internal/tui/messages"
@@ -27,25 +30,56 @@ import (
 type FocusState int
 
 const (
-	FocusList        FocusState = iota
-	FocusDetailPanel            // Detail panel in split view (scrollable)
-	FocusSearch                 // Search input mode
-	FocusFilter                 // Filter mode
-	FocusReplayEdit             // Replay with edit mode
-	FocusDiff                   // Diff view mode
-	FocusHistory                // History view mode
+	FocusList          FocusState = iota
+	FocusDetailPanel              // Detail panel in split view (scrollable)
+	FocusSearch                   // Search input mode
+	FocusFilter                   // Filter mode
+	FocusReplayEdit               // Replay with edit mode
+	FocusDiff                     // Diff view mode
+	FocusHistory                  // History view mode
+	FocusNote                     // Note input mode
+	FocusStats                    // Storage info view
+	FocusExport                   // Export path input mode
+	FocusCopyMenu                 // Copy-as format menu
+	FocusQuery                    // JSON body query input mode
+	FocusFilterExpr               // Typed filter expression input mode
+	FocusRestore                  // Asking whether to restore the last filters
+	FocusReplayResult             // Response to an edited replay
+	FocusRepeat                   // Asking how often to repeat a replay
+	FocusReplayAll                // Confirming a replay of every listed request
+	FocusReplaySummary            // How each request of a replayed list went
+	FocusTemplates                // Picking a saved request template
 )
 
+// DetailTab is a tab of the detail panel
+type DetailTab int
+
+const (
+	TabOverview DetailTab = iota // Status, timing and sizes
+	TabRequest                   // Request headers and body
+	TabResponse                  // Response headers and body
+	TabRaw                       // Raw HTTP messages
+)
+
+var detailTabNames = []string{"Overview", "Request", "Response", "Raw"}
+
 // ReplayEditStep represents the current step in replay edit
 type ReplayEditStep int
 
 const (
-	ReplayEditStepMain ReplayEditStep = iota // Main menu (Method, Path, Headers, Body, Send)
+	ReplayEditStepMain ReplayEditStep = iota // Main menu (Method, Path, Query Params, Headers, Body, Send)
 	ReplayEditStepMethod
 	ReplayEditStepPath
+	ReplayEditStepParams
+	ReplayEditStepParamEdit // Editing a single query parameter
 	ReplayEditStepHeaders
 	ReplayEditStepHeaderEdit // Editing a single header
 	ReplayEditStepBody
+	ReplayEditStepAssertions
+	ReplayEditStepAssertionEdit // Editing a single assertion
+	ReplayEditStepTemplateName  // Naming the request to save it as a template
+	ReplayEditStepTargetURL     // Typing the URL to send to
+	ReplayEditStepPreview       // Confirming the request before it is sent
 )
 
 // FilterStep represents the current step in filter creation
@@ -56,7 +90,9 @@ const (
 	FilterStepOperator
 	FilterStepUnit
 	FilterStepValue
-	FilterStepLogical // Ask for && or || after adding a filter
+	FilterStepLogical    // Ask for && or || after adding a filter
+	FilterStepHeaderName // Type the name of a header not in filterFields
+	FilterStepManage     // List the active filters to edit or remove them
 )
 
 // FilterFieldType defines the type of filter field
@@ -65,15 +101,21 @@ type FilterFieldType int
 const (
 	FilterTypeString FilterFieldType = iota
 	FilterTypeNumericWithUnit
+	FilterTypeNumeric // A plain number, or a lo..hi range for "in"
+	FilterTypeTime    // after/before a time, or within the last N units
+	FilterTypeBool    // true or false
 )
 
 // Filter represents an active filter
 type Filter struct {
-	Field           string
-	Operator        string
-	Unit            string // For numeric fields with units (ms, s, kb, etc.)
-	Value           string
-	LogicalOperator string // "&&" or "||" to chain with next filter
+	Field           string `json:"field"`
+	Operator        string `json:"operator"`
+	Unit            string `json:"unit,omitempty"` // For numeric fields with units (ms, s, kb, etc.)
+	Value           string `json:"value"`
+	LogicalOperator string `json:"join,omitempty"`  // "&&" or "||" to chain with next filter
+	Not             bool   `json:"not,omitempty"`   // Match requests the condition doesn't hold for
+	Open            string `json:"open,omitempty"`  // Groups opened before this filter, outermost first: "(" or "!(" for a negated one
+	Close           int    `json:"close,omitempty"` // Groups closed after this filter
 }
 
 // HeaderEntry represents a header key-value pair for editing
@@ -91,16 +133,35 @@ type FilterField struct {
 	Key       string
 	Type      FilterFieldType
 	Operators []string
-	Units     []string // For numeric fields
+	Units     []string // For numeric fields, and "last" on time fields
+}
+
+// takesUnit reports whether a filter on the field with operator op picks a
+// unit before its value
+func (f FilterField) takesUnit(op string) bool {
+	switch f.Type {
+	case FilterTypeNumericWithUnit:
+		return len(f.Units) > 0
+	case FilterTypeTime:
+		return op == "last"
+	}
+	return false
 }
 
 var filterFields = []FilterField{
 	// Basic fields
 	{Name: "Duration", Key: "duration", Type: FilterTypeNumericWithUnit, Operators: []string{">", "<", ">=", "<="}, Units: []string{"ms", "s", "m", "h", "d"}},
 	{Name: "Path", Key: "path", Type: FilterTypeString, Operators: []string{"==", "!=", "match", "!match"}},
+	{Name: "RequestBody", Key: "req_body", Type: FilterTypeString, Operators: []string{"match", "!match"}},
+	{Name: "ResponseBody", Key: "res_body", Type: FilterTypeString, Operators: []string{"match", "!match"}},
+	{Name: "RemoteAddr", Key: "remote_addr", Type: FilterTypeString, Operators: []string{"==", "!=", "match", "!match", "in"}},
 	{Name: "ResponseSize", Key: "response_size", Type: FilterTypeNumericWithUnit, Operators: []string{">", "<", ">=", "<="}, Units: []string{"b", "kb", "mb"}},
-	{Name: "StatusCode", Key: "status", Type: FilterTypeString, Operators: []string{"==", "!=", "match", "!match"}},
+	{Name: "Timestamp", Key: "timestamp", Type: FilterTypeTime, Operators: []string{"last", "after", "before"}, Units: []string{"m", "h"}},
+	{Name: "Starred", Key: "starred", Type: FilterTypeBool, Operators: []string{"=="}},
+	{Name: "StatusCode", Key: "status", Type: FilterTypeNumeric, Operators: []string{"==", "!=", ">", "<", ">=", "<=", "in"}},
 	// Headers
+	{Name: "Headers (custom)…", Key: customRequestHeader, Type: FilterTypeString, Operators: []string{"==", "!=", "match", "!match"}},
+	{Name: "Response headers (custom)…", Key: customResponseHeader, Type: FilterTypeString, Operators: []string{"==", "!=", "match", "!match"}},
 	{Name: "Headers.Accept", Key: "header.accept", Type: FilterTypeString, Operators: []string{"==", "!=", "match", "!match"}},
 	{Name: "Headers.Accept-Charset", Key: "header.accept-charset", Type: FilterTypeString, Operators: []string{"==", "!=", "match", "!match"}},
 	{Name: "Headers.Accept-Datetime", Key: "header.accept-datetime", Type: FilterTypeString, Operators: []string{"==", "!=", "match", "!match"}},
@@ -143,18 +204,38 @@ var filterFields = []FilterField{
 	{Name: "Headers.Server", Key: "header.server", Type: FilterTypeString, Operators: []string{"==", "!=", "match", "!match"}},
 }
 
-// Polling intervals
+// Custom header fields ask for a header name, which is added to their
+// prefix to make the filter's field, as in "req.x-webhook-event"
+const (
+	customRequestHeader  = "req.*"
+	customResponseHeader = "res.*"
+)
+
+// Polling intervals, used unless Options sets others. The idle interval
+// applies while the terminal window is out of focus.
 const (
-	ActivePollingInterval = 300 * time.Millisecond
-	IdlePollingInterval   = 2 * time.Second
+	DefaultPollInterval     = 300 * time.Millisecond
+	DefaultIdlePollInterval = 2 * time.Second
+
+	// MinPollInterval is the shortest interval ngrok is polled at
+	MinPollInterval = 100 * time.Millisecond
 )
 
+// detailHorizontalStep is how many columns h/l scroll unwrapped detail lines
+const detailHorizontalStep = 8
+
 // App is the main Bubbletea model
 type App struct {
 	// Window dimensions
 	width  int
 	height int
 
+	// Colors and styles everything is rendered with
+	theme *Theme
+
+	// mole's version, shown in the header
+	version string
+
 	// Focus state
 	focus     FocusState
 	prevFocus FocusState // To restore after search/filter
@@ -166,49 +247,209 @@ type App struct {
 	selected       int
 	lastError      error
 	lastSelectedID string // Track selected request ID for viewport updates
+	detailShownID  string // Request whose details are in the viewport
+	detailContent  string // Content last set on the detail viewport
+	following      bool   // Keep the newest request selected as requests arrive
+	listOffset     int    // Index of the first request shown in the list
+	showColumns    bool   // Show duration and response size columns in the list
+	showTraffic    bool   // Show the stats bar under the header
+	traffic        trafficStats
+
+	// Decoded bodies by request ID, so body search and filters don't decode
+	// every body again on every poll
+	bodies map[string]decodedBodies
+
+	// Rendered list rows by request ID, so an unchanged list isn't styled
+	// again on every frame
+	lineCache map[string]cachedLine
+
+	// Bodies of a history session, loaded as its requests are shown
+	lazy lazyBodies
+
+	// Detail panel tabs; the active tab stays as the selection moves
+	detailTab  DetailTab
+	tabOffsets map[DetailTab]int // Scroll position of each tab for the shown request
+
+	// Bodies are shown as hex when binary and as text otherwise; toggling
+	// swaps that for the shown request
+	hexToggled   bool
+	hexDumpBytes int // Longest part of a body shown as hex (0 = no limit)
+
+	// Show long detail lines as they are and scroll sideways, instead of
+	// wrapping them to the panel width
+	unwrapped bool
+
+	// Search matches in the detail panel, in the order they were highlighted.
+	// The current one is drawn in its own color and stepped through with n/N.
+	matchIndex   int
+	matchLines   []int  // Line of each match in detailContent
+	matchQuery   string // Query matchIndex belongs to
+	matchSeq     int    // Matches highlighted so far in the current render
+	countMatches bool   // Number highlighted matches while rendering the detail panel
+
+	// Layout: the list's share of the screen, in percent, for the side-by-side
+	// and stacked layouts; and one panel zoomed to fill the screen
+	sideBySideSplit int
+	stackedSplit    int
+	zoomed          bool
+	zoomDetail      bool // The detail panel is zoomed, rather than the list
+
+	// Collapsing runs of identical requests into one row
+	collapseRepeats bool
+	groups          map[string]requestGroup // Runs in the list, by the ID of their first row
+	expandedGroups  map[string]bool         // Runs shown in full, by requestGroup.key
+
+	// Requests picked with v/V for bulk actions, by ID so polling doesn't
+	// shift which rows are marked
+	marked     map[string]bool
+	markAnchor string // Request last toggled with v, where a V range starts
+
+	// Requests hidden with D, by ID since every poll returns them again. They
+	// are only hidden from the list, never deleted from ngrok or storage.
+	dismissed map[string]bool
+
+	// Footer columns of the filter badges from the last render, by filter index
+	filterBadgeSpans [][2]int
 
 	// Status messages
 	statusMessage     string
 	statusMessageTime time.Time
+	statusMessageTTL  time.Duration
+	statusIsError     bool // Render the status message as an error
 
 	// Search (full-text with highlighting)
 	searchQuery  string
 	searchCursor int
+	search       searchTerms // Compiled searchQuery
+	searchErr    string      // Why searchQuery doesn't compile, shown in the prompt
+	highlightSeq int         // Latest highlighting scheduled while typing; earlier ones are dropped
 
 	// Filter (field-based conditions)
 	filterStep     FilterStep
+	editingFilter  int // Active filter the wizard is editing, or -1 when adding one
 	filterInput    string
 	filterCursor   int
-	filterSelected int           // Selected item in field/operator list
-	activeFilters  []Filter      // Currently active filters
-	pendingFilter  Filter        // Filter being created
-	filteredFields []FilterField // Filtered field list based on input
+	filterSelected int                       // Selected item in field/operator list
+	activeFilters  []Filter                  // Currently active filters
+	pendingFilter  Filter                    // Filter being created
+	filteredFields []FilterField             // Filtered field list based on input
+	filterErr      string                    // Why the entered value was rejected
+	filterRegexps  map[string]*regexp.Regexp // Compiled /pattern/ filter values
+	filterCounts   []int                     // Requests each active filter keeps on its own, for its badge
+	preview        *filterPreview            // What the value being typed would keep
+	previewSeq     int                       // Latest preview scheduled; earlier ones are dropped
 
 	// Replay Edit
-	replayEditStep     ReplayEditStep
-	replayEditSelected int
-	replayEditMethod   string
-	replayEditPath     string
-	replayEditHeaders  []HeaderEntry // Editable headers
-	replayEditBody     string
-	replayEditCursor   int    // Cursor position for text input
-	replayEditInput    string // Current input text
-	replayHeaderIdx    int    // Which header is being edited
-	replayHeaderField  string // "key" or "value" being edited
+	replayEditStep       ReplayEditStep
+	replayEditSelected   int
+	replayEditMethod     string
+	replayEditPath       string
+	replayEditHeaders    []HeaderEntry // Editable headers
+	replayEditBody       string
+	replayEditTarget     int                 // Where to send it, one of the replayTarget values
+	replayEditLocal      string              // Local address of the request's tunnel
+	replayEditURL        string              // Typed target, kept for the next edit
+	replayEditHost       string              // Host the request arrived with
+	replayEditWarned     bool                // Sending a body that isn't valid JSON was warned about
+	replayEditCursor     int                 // Cursor position for text input
+	replayEditInput      string              // Current input text
+	replayHeaderIdx      int                 // Which header is being edited
+	replayHeaderField    string              // "key" or "value" being edited
+	replayEditParams     []HeaderEntry       // Query parameters, while their editor is open
+	replayParamIdx       int                 // Which query parameter is being edited
+	replayParamField     string              // "key" or "value" being edited
+	replayEditTemplate   string              // Template the request was loaded from or saved as
+	replayEditID         string              // Request being edited, empty for a template
+	replayEditAssertions []string            // Checks on the response, as typed
+	replayAssertIdx      int                 // Which assertion is being edited
+	assertions           map[string][]string // Assertions written for each request, by ID
+	replayOf             map[string]string   // Request each replay replayed, by the replay's ID
+	pendingReplays       []pendingReplay     // Replays sent through ngrok, not yet listed
+	replayDiffPending    string              // Request whose replay is diffed once it is listed
+	replayEditErr        string              // Why the typed target or assertion was refused
+	replayPreviewURL     string              // URL the previewed request goes to
+	replayResult         *replayResult       // Response to the last edited replay
+	resultViewport       viewport.Model      // Viewport for the replay result
+	templates            []storage.Template
+	templateSelected     int
 
 	// Diff view
-	diffRequestA   *ngrok.Request // First request for diff (nil if not selected)
-	diffRequestB   *ngrok.Request // Second request for diff
+	diffIDA        string         // Request marked [A] for a diff ("" if none)
+	diffIDB        string         // Request compared with it, once the diff is open
+	diffRequestA   *ngrok.Request // diffIDA as of the last refresh
+	diffRequestB   *ngrok.Request // diffIDB as of the last refresh
+	diffMarkHidden bool           // [A] is still listed but filtered out
 	diffViewport   viewport.Model // Viewport for diff content
-	diffScrollSync bool           // Whether to sync scroll between panels
+	diffUnified    bool           // Diff one side under the other even on a wide terminal
+
+	diffIgnoreHeaders []string  // Headers left out of diffs
+	diffShowIgnored   bool      // Show ignored headers anyway, dimmed
+	diffCursor        int       // Which differing header is selected, -1 for none
+	diffChange        int       // Which change was jumped to, -1 for none
+	diffRows          []diffRow // Rows last rendered
+	diffRowLines      []int     // Line each of diffRows starts on
+
+	// Notes attached to requests, keyed by request ID
+	notes         map[string]string
+	starred       map[string]bool // Whether requests are starred, looked up in history once
+	noteInput     string
+	noteCursor    int
+	noteRequestID string // Request the note being edited belongs to
+
+	// Typed filter expression, shown with the active filters when opened
+	exprInput  string
+	exprCursor int
+	exprErr    *exprError // Why the expression didn't parse, and where
+
+	// JSON body query; the last query is offered again next time
+	queryInput  string
+	queryCursor int
+	queryErr    string // Why the query failed, shown in the prompt
+	queryResult string // Result shown above the details of queryReqID
+	queryReqID  string
+	lastQuery   string
+
+	// Repeated replays
+	repeat        *replayRun // Replay being repeated
+	pendingRepeat *replayRun // Replay the repeat prompt is for
+	repeatSeq     int        // Latest run started; replies to earlier ones are dropped
+	replaySummary *replayRun // Finished replay of a list, while its results are shown
+	repeatInput   string
+	repeatCursor  int
+	repeatErr     string
+	lastRepeat    string // Count and interval of the last run, offered again
+
+	// Export path prompt
+	exportInput    string
+	exportCursor   int
+	exportFiltered bool                 // Export only the filtered requests instead of the whole session
+	exportMarked   bool                 // Export only the requests marked with v/V
+	exportFormat   storage.ExportFormat // Format of a whole session export
+	exportName     string               // Generated file name, replaced as the format changes until edited
 
 	// History view
 	historySessions     []storage.Session
-	historySelectedSess int // Selected session index
+	historyCounts       map[string]int // Requests in each of historySessions, by ID
+	historySelectedSess int            // Selected session index
+	historyRenaming     bool           // Whether the selected session's label is being edited
+	historyRenameInput  string
+	historyRenameCursor int
+
+	// History search (across all sessions)
+	historySearching     bool // Whether the query input is open in the history view
+	historySearchInput   string
+	historySearchCursor  int
+	historySearchQuery   string // Query whose results are shown, empty if none
+	historySearchMatches map[string]storage.SearchResult
+
+	// Storage info view
+	stats      storage.Stats
+	compacting bool // Whether a VACUUM is running
 
 	// Components
 	detailViewport viewport.Model // For detail panel scrolling
 	spinner        spinner.Model
+	spinning       bool // Whether the spinner is ticking; it stops while nothing shows it
 	keys           KeyMap
 
 	// API client
@@ -216,9 +457,22 @@ type App struct {
 
 	// Storage for persistent history
 	storage          *storage.Storage
-	savedReqIDs      map[string]bool // Track which requests have been saved
-	viewingHistory   bool            // Whether we're viewing historical session
-	viewingSessionID string          // ID of historical session being viewed
+	writer           *storage.Writer // Saves requests off the UI goroutine
+	retention        storage.RetentionPolicy
+	sessionName      string            // Label for the session started on connect
+	restoreView      bool              // Restore the last search and filters without asking
+	pendingView      *savedView        // Last search and filters, while asking whether to restore them
+	recorder         *capture.Recorder // Saves each polled request to history once
+	viewingHistory   bool              // Whether we're viewing historical session
+	viewingSessionID string            // ID of historical session being viewed
+	offline          bool              // Browsing history without ngrok, which is never polled
+	requestsPolled   bool              // The list is as ngrok last listed it, rather than from history
+	viewingFile      string            // Name of the exported file shown offline, if any
+
+	// Polling
+	pollInterval     time.Duration
+	idlePollInterval time.Duration // While the window is out of focus
+	pollLimit        int           // Requests asked of ngrok each poll
 
 	// State
 	loading     bool
@@ -226,43 +480,138 @@ type App struct {
 	ready       bool
 }
 
-// NewApp creates a new App instance
-func NewApp(client *ngrok.Client) *App {
-	s := spinner.New()
-	s.Spinner = spinner.Dot
-	s.Style = SpinnerStyle
+// Options configures an App
+type Options struct {
+	Retention        storage.RetentionPolicy  // Applied to history on startup
+	SessionName      string                   // Label for the live session
+	RedactHeaders    []string                 // Header values masked in history (live view is unaffected)
+	MaxBodyBytes     int                      // Longest body kept in history (0 = no limit)
+	HexDumpBytes     int                      // Longest part of a binary body shown as hex (0 = no limit)
+	Theme            *Theme                   // Colors and styles (nil = the default theme)
+	Keys             *KeyMap                  // Keybindings (nil = the default keys)
+	RestoreFilters   bool                     // Restore the tunnel's last search and filters without asking
+	DiffIgnore       []string                 // Headers left out of diffs
+	PollInterval     time.Duration            // How often ngrok is polled (0 = the default)
+	IdlePollInterval time.Duration            // The same while the window is out of focus (0 = the default)
+	PollLimit        int                      // Requests asked of ngrok each poll (0 = the default)
+	Offline          bool                     // Only browse history: ngrok isn't polled and nothing is recorded
+	ViewName         string                   // Offline, names the file ViewRequests were read from
+	ViewRequests     []storage.HistoryRequest // Offline, shown in place of history
+	Version          string                   // Shown in the header (empty leaves it out)
+}
 
-	// Initialize storage (non-fatal if it fails)
-	store, err := storage.New()
-	if err != nil {
-		// Log error but continue without storage
-		store = nil
+// DefaultOptions returns the options used when nothing is configured
+func DefaultOptions() Options {
+	return Options{
+		Retention:        storage.DefaultRetention,
+		RedactHeaders:    storage.DefaultRedactedHeaders,
+		MaxBodyBytes:     storage.DefaultMaxBodyBytes,
+		HexDumpBytes:     util.DefaultHexDumpBytes,
+		DiffIgnore:       DefaultDiffIgnoreHeaders,
+		PollInterval:     DefaultPollInterval,
+		IdlePollInterval: DefaultIdlePollInterval,
+		PollLimit:        capture.DefaultPollLimit,
+	}
+}
+
+// NewApp creates a new App instance. store may be nil, in which case
+// history is not recorded.
+func NewApp(client *ngrok.Client, store *storage.Storage, opts Options) *App {
+	theme := opts.Theme
+	if theme == nil {
+		theme, _ = NewTheme(DefaultThemeName, nil)
+	}
+	// Bodies are highlighted outside the app, so they follow the theme
+	// through util
+	util.SyntaxStyle = theme.SyntaxStyle
+	keys := opts.Keys
+	if keys == nil {
+		km := DefaultKeyMap()
+		keys = &km
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.IdlePollInterval <= 0 {
+		opts.IdlePollInterval = DefaultIdlePollInterval
+	}
+	if opts.PollLimit <= 0 {
+		opts.PollLimit = capture.DefaultPollLimit
 	}
 
-	return &App{
-		client:      client,
-		storage:     store,
-		savedReqIDs: make(map[string]bool),
-		keys:        DefaultKeyMap(),
-		spinner:     s,
-		loading:     true,
-		windowFocus: true,
-		focus:       FocusList,
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = theme.SpinnerStyle
+
+	var writer *storage.Writer
+	if store != nil {
+		writer = storage.NewWriter(store)
+	}
+
+	a := &App{
+		theme:             theme,
+		client:            client,
+		storage:           store,
+		writer:            writer,
+		retention:         opts.Retention,
+		sessionName:       opts.SessionName,
+		restoreView:       opts.RestoreFilters,
+		diffIgnoreHeaders: append([]string(nil), opts.DiffIgnore...),
+		hexDumpBytes:      opts.HexDumpBytes,
+		pollInterval:      opts.PollInterval,
+		idlePollInterval:  opts.IdlePollInterval,
+		pollLimit:         opts.PollLimit,
+		recorder:          capture.NewRecorder(store, writer, opts.RedactHeaders, opts.MaxBodyBytes),
+		notes:             make(map[string]string),
+		assertions:        make(map[string][]string),
+		replayOf:          make(map[string]string),
+		starred:           make(map[string]bool),
+		marked:            make(map[string]bool),
+		dismissed:         make(map[string]bool),
+		expandedGroups:    make(map[string]bool),
+		tabOffsets:        make(map[DetailTab]int),
+		editingFilter:     -1,
+		sideBySideSplit:   defaultSideBySideSplit,
+		stackedSplit:      defaultStackedSplit,
+		keys:              *keys,
+		spinner:           s,
+		spinning:          true, // Started by Init
+		loading:           true,
+		windowFocus:       true,
+		focus:             FocusList,
+		offline:           opts.Offline,
+		version:           opts.Version,
+	}
+	if a.offline {
+		// Start in the session list, or the file's requests, as there is
+		// nothing live to show
+		a.loading = false
+		if opts.ViewName != "" {
+			a.viewingFile = opts.ViewName
+			a.showHistoryRequests(opts.ViewRequests)
+		} else {
+			a.focus = FocusHistory
+			a.prevFocus = FocusList
+			a.initHistoryView()
+		}
 	}
+	return a
 }
 
 // Init implements tea.Model
 func (a *App) Init() tea.Cmd {
-	// Run cleanup on startup (keep 7 days or 1000 requests)
-	if a.storage != nil {
-		a.storage.Cleanup(7, 1000)
+	if a.offline {
+		// History is left as it is while browsing it
+		return tea.Batch(a.spinner.Tick, clockCmd())
 	}
-
 	return tea.Batch(
+		a.cleanupHistory(),
+		a.waitForSaveError(),
 		a.spinner.Tick,
 		a.fetchTunnels(),
 		a.fetchRequests(),
-		tickCmd(ActivePollingInterval),
+		tickCmd(a.pollInterval),
+		clockCmd(),
 	)
 }
 
@@ -284,15 +633,25 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case tea.MouseButtonWheelUp:
 				if a.focus == FocusDiff {
 					a.diffViewport.LineUp(3)
+				} else if a.focus == FocusReplayResult || a.focus == FocusReplaySummary {
+					a.resultViewport.LineUp(3)
 				} else {
 					a.detailViewport.LineUp(3)
 				}
 			case tea.MouseButtonWheelDown:
 				if a.focus == FocusDiff {
 					a.diffViewport.LineDown(3)
+				} else if a.focus == FocusReplayResult || a.focus == FocusReplaySummary {
+					a.resultViewport.LineDown(3)
 				} else {
 					a.detailViewport.LineDown(3)
 				}
+			case tea.MouseButtonLeft:
+				// Clicks only apply to the main list/detail view, not to modes
+				// that take over input
+				if a.focus == FocusList || a.focus == FocusDetailPanel {
+					a.handleClick(msg.X, msg.Y)
+				}
 			}
 		}
 
@@ -301,16 +660,34 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.height = msg.Height
 		a.updateViewportSize()
 		a.ready = true
-		// Force update of detail viewport on resize
-		a.lastSelectedID = ""
-		a.updateDetailViewport()
 
-	case messages.TickMsg:
-		interval := ActivePollingInterval
-		if !a.windowFocus {
-			interval = IdlePollingInterval
+	case messages.FilterPreviewMsg:
+		if msg.Seq == a.previewSeq {
+			a.updatePreview()
 		}
-		cmds = append(cmds, a.fetchRequests(), tickCmd(interval))
+
+	case messages.SearchHighlightMsg:
+		if msg.Seq == a.highlightSeq {
+			// Force re-render of detail panel for live highlighting
+			a.lastSelectedID = ""
+			a.updateDetailViewport()
+		}
+
+	case messages.ClockMsg:
+		// Nothing to update: receiving the message re-renders the list, which
+		// recomputes the relative times even when polling is slow
+		cmds = append(cmds, clockCmd())
+
+	case tea.FocusMsg:
+		a.windowFocus = true
+		// Catch up now rather than when the idle tick comes round
+		cmds = append(cmds, a.fetchRequests())
+
+	case tea.BlurMsg:
+		a.windowFocus = false
+
+	case messages.TickMsg:
+		cmds = append(cmds, a.fetchRequests(), tickCmd(a.tickInterval()))
 
 	case messages.TunnelsMsg:
 		a.loading = false
@@ -323,7 +700,10 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Start storage session if we have tunnels and storage is available
 			if a.storage != nil && len(a.tunnels) > 0 && a.storage.CurrentSessionID() == "" {
 				tunnelURL := a.tunnels[0].PublicURL
-				a.storage.StartSession(tunnelURL)
+				if _, err := a.storage.StartSession(tunnelURL, a.sessionName); err != nil {
+					debuglog.Printf("tui: starting a session for %s: %v", tunnelURL, err)
+				}
+				a.loadSavedView(tunnelURL)
 			}
 		}
 
@@ -331,11 +711,26 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.loading = false
 		if msg.Err != nil {
 			a.lastError = msg.Err
+		} else if !a.viewingHistory && a.requestsPolled && sameRequests(a.requests, msg.Requests) {
+			// Nothing new since the last poll, so nothing to save, filter or
+			// render again. Replays sent since may still be waiting to be
+			// matched with a capture already listed, and filters on age
+			// change with time.
+			a.findReplayedRequest()
+			a.linkReplays()
+			if a.filtersOnAge() {
+				a.applyFilters()
+			}
+			a.lastError = nil
 		} else if !a.viewingHistory {
 			// Only update if not viewing historical session
 			// Preserve selection if possible
 			oldLen := len(a.requests)
 			a.requests = msg.Requests
+			a.requestsPolled = true
+			a.pruneBodies()
+			a.findReplayedRequest()
+			a.linkReplays()
 
 			// Auto-save new requests to storage
 			a.saveNewRequests()
@@ -345,8 +740,14 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if a.selected >= len(a.filteredReqs) {
 				a.selected = max(0, len(a.filteredReqs)-1)
 			}
-			// Update detail view if we have new data
-			if len(a.requests) != oldLen {
+			// Update detail view if we have new data. Following shows the
+			// newest, which is new even when ngrok's list is full and its
+			// length stays the same.
+			if a.following {
+				a.selected = 0
+				a.listOffset = 0
+				a.updateDetailViewport()
+			} else if len(a.requests) != oldLen {
 				a.updateDetailViewport()
 			}
 			a.lastError = nil
@@ -355,8 +756,56 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case messages.CopyMsg:
 		if msg.Success {
 			a.lastError = nil
-			a.statusMessage = "Copied!"
-			a.statusMessageTime = time.Now()
+			a.setStatus(fmt.Sprintf("Copied! (%s)", msg.Method), 2*time.Second)
+		}
+
+	case messages.OpenMsg:
+		if msg.Err != nil {
+			a.setErrorStatus(msg.Err, 5*time.Second)
+		} else {
+			a.setStatus("Opened "+msg.URL, 2*time.Second)
+		}
+
+	case messages.StarMsg:
+		if msg.Err != nil {
+			a.setErrorStatus(fmt.Errorf("failed to star requests: %w", msg.Err), 5*time.Second)
+		} else {
+			a.setStatus(fmt.Sprintf("Starred %d requests", msg.Count), 3*time.Second)
+			for _, id := range msg.IDs {
+				a.starred[id] = true
+			}
+			a.applyFilters()
+		}
+
+	case messages.ExportMsg:
+		if msg.Err != nil {
+			// Shown as a status so the next successful poll doesn't clear it
+			a.setErrorStatus(fmt.Errorf("failed to export: %w", msg.Err), 5*time.Second)
+		} else {
+			a.lastError = nil
+			a.setStatus(fmt.Sprintf("Exported %d requests to %s", msg.Count, msg.Path), 3*time.Second)
+		}
+
+	case messages.SaveErrorMsg:
+		debuglog.Printf("tui: saving requests: %v", msg.Err)
+		a.lastError = fmt.Errorf("failed to save request: %w", msg.Err)
+		cmds = append(cmds, a.waitForSaveError())
+
+	case messages.CompactMsg:
+		a.compacting = false
+		if msg.Err != nil {
+			a.lastError = fmt.Errorf("failed to compact database: %w", msg.Err)
+		} else {
+			a.lastError = nil
+			a.setStatus("Compacted database, reclaimed "+util.FormatBytes(msg.Reclaimed), 3*time.Second)
+		}
+		a.loadStats()
+
+	case messages.CleanupMsg:
+		if msg.Err != nil {
+			a.lastError = fmt.Errorf("history cleanup: %w", msg.Err)
+		} else if msg.Removed > 0 {
+			a.setStatus(fmt.Sprintf("Removed %d old requests from history", msg.Removed), 5*time.Second)
 		}
 
 	case messages.ErrorMsg:
@@ -370,10 +819,26 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, a.fetchRequests())
 		}
 
+	case messages.EditedReplayMsg:
+		a.showReplayResult(msg)
+		cmds = append(cmds, a.fetchRequests())
+
+	case messages.RepeatReplayMsg:
+		cmds = append(cmds, a.updateRepeat(msg))
+
 	case spinner.TickMsg:
-		var cmd tea.Cmd
-		a.spinner, cmd = a.spinner.Update(msg)
-		cmds = append(cmds, cmd)
+		// Each tick re-renders the screen, so the spinner stops once
+		// nothing waits on it
+		if !a.ready || a.loading || a.compacting {
+			var cmd tea.Cmd
+			a.spinner, cmd = a.spinner.Update(msg)
+			cmds = append(cmds, cmd)
+		} else {
+			a.spinning = false
+		}
+
+	default:
+		debuglog.Printf("tui: dropped %T", msg)
 	}
 
 	// Update detail viewport when focused
@@ -408,11 +873,71 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) tea.Cmd {
 		return a.handleDiffInput(msg)
 	}
 
+	// Handle replay result input
+	if a.focus == FocusReplayResult {
+		return a.handleReplayResultInput(msg)
+	}
+
 	// Handle history view input
 	if a.focus == FocusHistory {
 		return a.handleHistoryInput(msg)
 	}
 
+	// Handle note input
+	if a.focus == FocusNote {
+		return a.handleNoteInput(msg)
+	}
+
+	// Handle storage info view input
+	if a.focus == FocusStats {
+		return a.handleStatsInput(msg)
+	}
+
+	// Handle export path input
+	if a.focus == FocusExport {
+		return a.handleExportInput(msg)
+	}
+
+	// Handle copy-as menu
+	if a.focus == FocusCopyMenu {
+		return a.handleCopyMenuInput(msg)
+	}
+
+	// Handle JSON body query input
+	if a.focus == FocusQuery {
+		return a.handleQueryInput(msg)
+	}
+
+	// Handle filter expression input
+	if a.focus == FocusFilterExpr {
+		return a.handleFilterExprInput(msg)
+	}
+
+	// Handle the restore prompt
+	if a.focus == FocusRestore {
+		return a.handleRestoreInput(msg)
+	}
+
+	// Handle the repeat prompt
+	if a.focus == FocusRepeat {
+		return a.handleRepeatInput(msg)
+	}
+
+	// Handle the replay all confirmation
+	if a.focus == FocusReplayAll {
+		return a.handleReplayAllInput(msg)
+	}
+
+	// Handle the replay summary
+	if a.focus == FocusReplaySummary {
+		return a.handleReplaySummaryInput(msg)
+	}
+
+	// Handle the template browser
+	if a.focus == FocusTemplates {
+		return a.handleTemplatesInput(msg)
+	}
+
 	switch {
 	case key.Matches(msg, a.keys.Quit):
 		return tea.Quit
@@ -426,11 +951,18 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) tea.Cmd {
 	case key.Matches(msg, a.keys.Filter):
 		a.prevFocus = a.focus
 		a.focus = FocusFilter
-		a.filterStep = FilterStepField
-		a.filterInput = ""
-		a.filterCursor = 0
-		a.filterSelected = 0
-		a.filteredFields = filterFields
+		a.startFilterWizard()
+		if len(a.activeFilters) > 0 {
+			a.openFilterManager()
+		}
+		return nil
+
+	case key.Matches(msg, a.keys.FilterExpr):
+		a.prevFocus = a.focus
+		a.focus = FocusFilterExpr
+		a.exprInput = formatFilterExpr(a.activeFilters)
+		a.exprCursor = len(a.exprInput)
+		a.exprErr = nil
 		return nil
 
 	case key.Matches(msg, a.keys.Clear):
@@ -442,8 +974,207 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) tea.Cmd {
 			return a.copyAsCurl(a.filteredReqs[a.selected])
 		}
 
+	case key.Matches(msg, a.keys.CopyURL):
+		if len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
+			return copyToClipboard(a.publicURL(a.filteredReqs[a.selected]))
+		}
+
+	case key.Matches(msg, a.keys.CopyAs):
+		if len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
+			a.prevFocus = a.focus
+			a.focus = FocusCopyMenu
+		}
+
+	case key.Matches(msg, a.keys.BodyQuery):
+		if len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
+			a.queryInput = a.lastQuery
+			if a.queryInput == "" {
+				a.queryInput = "."
+			}
+			a.queryCursor = len(a.queryInput)
+			a.queryErr = ""
+			a.prevFocus = a.focus
+			a.focus = FocusQuery
+		}
+
+	case key.Matches(msg, a.keys.HexView):
+		if len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
+			a.hexToggled = !a.hexToggled
+			a.lastSelectedID = ""
+			a.updateDetailViewport()
+		}
+
+	case key.Matches(msg, a.keys.Wrap):
+		a.unwrapped = !a.unwrapped
+		if a.unwrapped {
+			a.detailViewport.SetHorizontalStep(detailHorizontalStep)
+		} else {
+			a.detailViewport.SetHorizontalStep(0)
+		}
+		a.detailViewport.SetXOffset(0)
+		a.lastSelectedID = ""
+		a.updateDetailViewport()
+
+	case a.focus == FocusDetailPanel && a.searchQuery != "" && key.Matches(msg, a.keys.NextMatch, a.keys.PrevMatch):
+		// n/N step through matches instead of opening a note
+		if len(a.matchLines) > 0 {
+			step := 1
+			if key.Matches(msg, a.keys.PrevMatch) {
+				step = len(a.matchLines) - 1
+			}
+			a.matchIndex = (a.matchIndex + step) % len(a.matchLines)
+			a.lastSelectedID = ""
+			a.updateDetailViewport()
+		}
+
+	case a.focus == FocusDetailPanel && a.unwrapped && key.Matches(msg, a.keys.Left, a.keys.Right):
+		// The detail viewport scrolls sideways itself once Update passes the
+		// key on; this only keeps h from opening history
+
+	case key.Matches(msg, a.keys.Open):
+		if len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
+			return a.openInBrowser(a.filteredReqs[a.selected])
+		}
+
+	case key.Matches(msg, a.keys.ExportScript):
+		return a.exportCurlScript()
+
+	case key.Matches(msg, a.keys.Export):
+		if a.storage == nil {
+			a.lastError = fmt.Errorf("history is unavailable, nothing to export")
+			return nil
+		}
+		a.exportFiltered = a.isFiltered()
+		a.exportMarked = len(a.markedIDs()) > 0
+		a.exportFormat = storage.FormatJSON
+		a.exportName = ""
+		a.exportInput = ""
+		a.updateExportName()
+		a.prevFocus = a.focus
+		a.focus = FocusExport
+
+	case key.Matches(msg, a.keys.Note):
+		if len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
+			a.noteRequestID = a.filteredReqs[a.selected].ID
+			a.noteInput = a.notes[a.noteRequestID]
+			a.noteCursor = len(a.noteInput)
+			a.prevFocus = a.focus
+			a.focus = FocusNote
+		}
+
+	case key.Matches(msg, a.keys.Mark):
+		if len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
+			id := a.filteredReqs[a.selected].ID
+			if a.marked[id] {
+				delete(a.marked, id)
+			} else {
+				a.marked[id] = true
+			}
+			a.markAnchor = id
+		}
+
+	case key.Matches(msg, a.keys.MarkRange):
+		if len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
+			a.markRange()
+		}
+
+	case key.Matches(msg, a.keys.Star):
+		ids := a.markedIDs()
+		if len(ids) == 0 && len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
+			ids = []string{a.filteredReqs[a.selected].ID}
+		}
+		if len(ids) > 0 {
+			return a.starRequests(ids)
+		}
+
+	case key.Matches(msg, a.keys.Dismiss):
+		// Hide the marked requests, or the selected one if none are marked
+		ids := a.markedIDs()
+		if len(ids) == 0 && len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
+			ids = []string{a.filteredReqs[a.selected].ID}
+		}
+		for _, id := range ids {
+			a.dismissed[id] = true
+		}
+		if len(ids) > 0 {
+			a.clearMarks()
+			a.applyFilters()
+		}
+
+	case key.Matches(msg, a.keys.UnhideAll):
+		if n := a.hiddenCount(); n > 0 {
+			a.dismissed = make(map[string]bool)
+			a.applyFilters()
+			a.setStatus(fmt.Sprintf("Unhid %d requests", n), 2*time.Second)
+		}
+
+	case key.Matches(msg, a.keys.Columns):
+		a.showColumns = !a.showColumns
+
+	case key.Matches(msg, a.keys.Traffic):
+		a.showTraffic = !a.showTraffic
+		a.updateViewportSize()
+
+	case a.focus == FocusDetailPanel && key.Matches(msg, a.keys.DetailTab):
+		a.setDetailTab(DetailTab(msg.String()[0] - '1'))
+
+	case key.Matches(msg, a.keys.PrevTab):
+		a.setDetailTab((a.detailTab + DetailTab(len(detailTabNames)) - 1) % DetailTab(len(detailTabNames)))
+
+	case key.Matches(msg, a.keys.NextTab):
+		a.setDetailTab((a.detailTab + 1) % DetailTab(len(detailTabNames)))
+
+	case key.Matches(msg, a.keys.QuickFilter):
+		a.toggleStatusClassFilter(msg.String() + "xx")
+
+	case key.Matches(msg, a.keys.Collapse):
+		a.collapseRepeats = !a.collapseRepeats
+		a.applyFilters()
+
+	case key.Matches(msg, a.keys.ShrinkList, a.keys.GrowList):
+		step := splitStep
+		if key.Matches(msg, a.keys.ShrinkList) {
+			step = -splitStep
+		}
+		if a.width >= 120 {
+			a.sideBySideSplit = clampSplit(a.sideBySideSplit + step)
+		} else {
+			a.stackedSplit = clampSplit(a.stackedSplit + step)
+		}
+		a.updateViewportSize()
+
+	case key.Matches(msg, a.keys.Zoom):
+		a.zoomed = !a.zoomed
+		a.zoomDetail = a.focus == FocusDetailPanel
+		a.updateViewportSize()
+
+	case key.Matches(msg, a.keys.Enter):
+		// Expand or collapse a run of repeated requests
+		if a.focus == FocusList && len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
+			if g, ok := a.groups[a.filteredReqs[a.selected].ID]; ok {
+				if g.expanded {
+					delete(a.expandedGroups, g.key)
+				} else {
+					a.expandedGroups[g.key] = true
+				}
+				a.applyFilters()
+			}
+		}
+
+	case key.Matches(msg, a.keys.Follow):
+		if a.viewingHistory {
+			return nil
+		}
+		a.following = !a.following
+		if a.following {
+			a.selected = 0
+			a.updateDetailViewport()
+		}
+
 	case key.Matches(msg, a.keys.Down):
 		if a.focus == FocusList {
+			// Moving by hand pauses follow mode until F is pressed again
+			a.following = false
 			if len(a.filteredReqs) > 0 {
 				a.selected = min(a.selected+1, len(a.filteredReqs)-1)
 				a.updateDetailViewport()
@@ -454,6 +1185,7 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) tea.Cmd {
 
 	case key.Matches(msg, a.keys.Up):
 		if a.focus == FocusList {
+			a.following = false
 			if len(a.filteredReqs) > 0 {
 				a.selected = max(a.selected-1, 0)
 				a.updateDetailViewport()
@@ -472,20 +1204,55 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) tea.Cmd {
 
 	case key.Matches(msg, a.keys.Bottom):
 		if a.focus == FocusList && len(a.filteredReqs) > 0 {
+			a.following = false
 			a.selected = len(a.filteredReqs) - 1
 			a.updateDetailViewport()
 		} else if a.focus == FocusDetailPanel {
 			a.detailViewport.GotoBottom()
 		}
 
+	case key.Matches(msg, a.keys.PageDown):
+		if a.focus == FocusList {
+			a.moveSelection(a.listVisibleLines())
+		} else if a.focus == FocusDetailPanel {
+			a.detailViewport.PageDown()
+		}
+
+	case key.Matches(msg, a.keys.PageUp):
+		if a.focus == FocusList {
+			a.moveSelection(-a.listVisibleLines())
+		} else if a.focus == FocusDetailPanel {
+			a.detailViewport.PageUp()
+		}
+
+	case key.Matches(msg, a.keys.ScrollDown):
+		if a.focus == FocusList {
+			a.moveSelection(max(a.listVisibleLines()/2, 1))
+		} else if a.focus == FocusDetailPanel {
+			a.detailViewport.HalfPageDown()
+		}
+
+	case key.Matches(msg, a.keys.ScrollUp):
+		if a.focus == FocusList {
+			a.moveSelection(-max(a.listVisibleLines()/2, 1))
+		} else if a.focus == FocusDetailPanel {
+			a.detailViewport.HalfPageUp()
+		}
+
 	case key.Matches(msg, a.keys.Escape):
-		if a.diffRequestA != nil {
+		if a.repeat != nil {
+			a.stopRepeat()
+		} else if a.diffIDA != "" {
 			// Cancel diff selection
-			a.diffRequestA = nil
+			a.clearDiffMarks()
+		} else if len(a.markedIDs()) > 0 {
+			a.clearMarks()
 		} else if a.searchQuery != "" || len(a.activeFilters) > 0 {
 			a.clearAll()
 		} else if a.focus == FocusDetailPanel {
 			a.focus = FocusList
+		} else if a.historySearchQuery != "" || (a.offline && a.viewingFile == "") {
+			a.returnToHistoryList()
 		}
 
 	case key.Matches(msg, a.keys.Toggle):
@@ -494,12 +1261,51 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) tea.Cmd {
 		} else {
 			a.focus = FocusList
 		}
+		if a.zoomed {
+			// The zoom follows focus to the other panel
+			a.zoomDetail = a.focus == FocusDetailPanel
+			a.updateViewportSize()
+		}
 
 	case key.Matches(msg, a.keys.Replay):
+		if a.viewingHistory {
+			a.setStatus(historyReplayHint, 3*time.Second)
+		} else if len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
+			req := a.filteredReqs[a.selected]
+			a.expectReplay(req.ID, req.Request.Method, req.Request.URI, 0)
+			return a.replayRequest(req.ID)
+		}
+
+	case key.Matches(msg, a.keys.ReplayRepeat):
+		if a.viewingHistory {
+			a.setStatus(historyReplayHint, 3*time.Second)
+		} else if a.repeat != nil {
+			a.setStatus("A replay is already repeating (esc stops it)", 2*time.Second)
+		} else if len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
+			req := a.filteredReqs[a.selected]
+			a.promptRepeat("replay of "+req.Request.Method+" "+req.Request.URI, a.plainReplayStep(req.ID))
+			a.pendingRepeat.expect = func(int) pendingReplay {
+				return pendingReplay{parentID: req.ID, method: req.Request.Method, uri: req.Request.URI}
+			}
+		}
+
+	case key.Matches(msg, a.keys.ReplayLocal):
 		if len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
-			return a.replayRequest(a.filteredReqs[a.selected].ID)
+			return a.replayLocally(a.filteredReqs[a.selected])
 		}
 
+	case key.Matches(msg, a.keys.ReplayAll):
+		a.promptReplayAll()
+
+	case key.Matches(msg, a.keys.Templates):
+		a.openTemplates()
+
+	case key.Matches(msg, a.keys.ReplayLink):
+		a.jumpToReplayLink()
+
+	case key.Matches(msg, a.keys.ReplayDiff):
+		a.diffReplayLink()
+
 	case key.Matches(msg, a.keys.ReplayEdit):
 		if len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
 			a.initReplayEdit(a.filteredReqs[a.selected])
@@ -510,30 +1316,37 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) tea.Cmd {
 	case key.Matches(msg, a.keys.Diff):
 		if len(a.filteredReqs) > 0 && a.selected < len(a.filteredReqs) {
 			req := a.filteredReqs[a.selected]
-			if a.diffRequestA == nil {
+			if a.diffIDA == "" {
 				// First request - mark it
+				a.diffIDA = req.ID
 				a.diffRequestA = &req
-			} else if a.diffRequestA.ID == req.ID {
+			} else if a.diffIDA == req.ID {
 				// Same request - unmark
-				a.diffRequestA = nil
+				a.clearDiffMarks()
 			} else {
 				// Second request - show diff
-				a.diffRequestB = &req
-				a.initDiffView()
-				a.prevFocus = a.focus
-				a.focus = FocusDiff
+				a.showDiff(*a.diffRequestA, req)
 			}
 		}
 
 	case key.Matches(msg, a.keys.History):
-		// If viewing history, go back to live
-		if a.viewingHistory {
+		// If viewing history, go back to live, or offline to the sessions
+		if a.viewingFile != "" {
+			a.setStatus("Only "+a.viewingFile+" is open, without history", 2*time.Second)
+		} else if a.offline {
+			a.returnToHistoryList()
+		} else if a.viewingHistory {
 			a.exitHistoryView()
 		} else {
 			a.prevFocus = a.focus
 			a.focus = FocusHistory
 			a.initHistoryView()
 		}
+
+	case key.Matches(msg, a.keys.Stats):
+		a.prevFocus = a.focus
+		a.focus = FocusStats
+		a.loadStats()
 	}
 
 	return nil
@@ -543,57 +1356,336 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) tea.Cmd {
 func (a *App) handleSearchInput(msg tea.KeyMsg) tea.Cmd {
 	switch msg.Type {
 	case tea.KeyEnter:
+		if a.searchErr != "" {
+			// Keep the prompt open so the pattern can be fixed
+			return nil
+		}
 		a.focus = a.prevFocus
+		a.highlightSeq++ // Highlighted below
 		a.performSearch()
 		return nil
 
 	case tea.KeyEscape:
 		a.focus = a.prevFocus
+		a.highlightSeq++
 		// Clear search completely
 		a.searchQuery = ""
 		a.searchCursor = 0
+		a.compileSearchQuery()
 		// Force re-render of detail panel to remove highlighting
 		a.lastSelectedID = ""
 		// Reset to show all requests (respecting active filters)
 		a.applyFilters()
 		return nil
+	}
 
-	case tea.KeyBackspace:
-		if len(a.searchQuery) > 0 && a.searchCursor > 0 {
-			a.searchQuery = a.searchQuery[:a.searchCursor-1] + a.searchQuery[a.searchCursor:]
-			a.searchCursor--
-			// Force re-render of detail panel for live highlighting
-			a.lastSelectedID = ""
-			a.updateDetailViewport()
-		}
-		return nil
+	// Spaces separate scoped terms, so they are typed like any other key
+	query := a.searchQuery
+	a.searchQuery, a.searchCursor, _ = editLine(a.searchQuery, a.searchCursor, msg)
+	if a.searchQuery != query {
+		a.compileSearchQuery()
+		return a.scheduleHighlight()
+	}
+	return nil
+}
 
-	case tea.KeyLeft:
-		if a.searchCursor > 0 {
-			a.searchCursor--
-		}
-		return nil
+// searchHighlightDelay is how long typing a search must pause before the
+// detail panel highlights it, so typing stays snappy with large bodies
+const searchHighlightDelay = 150 * time.Millisecond
+
+// scheduleHighlight highlights the search being typed in the detail panel
+// once typing pauses. Only the last highlighting scheduled is done.
+func (a *App) scheduleHighlight() tea.Cmd {
+	a.highlightSeq++
+	seq := a.highlightSeq
+	return tea.Tick(searchHighlightDelay, func(time.Time) tea.Msg {
+		return messages.SearchHighlightMsg{Seq: seq}
+	})
+}
 
-	case tea.KeyRight:
-		if a.searchCursor < len(a.searchQuery) {
-			a.searchCursor++
+// handleExportInput handles keyboard input in the export path prompt
+func (a *App) handleExportInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEnter:
+		path := strings.TrimSpace(a.exportInput)
+		if path == "" {
+			return nil
 		}
+		a.focus = a.prevFocus
+		return a.writeExport(path)
+
+	case tea.KeyEscape:
+		a.focus = a.prevFocus
 		return nil
 
-	case tea.KeyRunes:
-		char := string(msg.Runes)
-		a.searchQuery = a.searchQuery[:a.searchCursor] + char + a.searchQuery[a.searchCursor:]
-		a.searchCursor += len(char)
-		// Force re-render of detail panel for live highlighting
+	case tea.KeyTab:
+		// Search results span sessions, so there is no whole session to export
+		if a.isFiltered() && a.historySearchQuery == "" && !a.exportMarked {
+			a.exportFiltered = !a.exportFiltered
+			a.updateExportName()
+		}
+		return nil
+
+	case tea.KeyUp, tea.KeyDown:
+		// Only a whole session can be written in every format
+		if a.exportsSubset() {
+			return nil
+		}
+		step := 1
+		if msg.Type == tea.KeyUp {
+			step = len(storage.ExportFormats) - 1
+		}
+		i := slices.Index(storage.ExportFormats, a.exportFormat)
+		a.exportFormat = storage.ExportFormats[(i+step)%len(storage.ExportFormats)]
+		a.updateExportName()
+		return nil
+	}
+
+	a.exportInput, a.exportCursor, _ = editLine(a.exportInput, a.exportCursor, msg)
+	return nil
+}
+
+// handleCopyMenuInput copies the selected request in the chosen format
+func (a *App) handleCopyMenuInput(msg tea.KeyMsg) tea.Cmd {
+	a.focus = a.prevFocus
+	if len(a.filteredReqs) == 0 || a.selected >= len(a.filteredReqs) {
+		return nil
+	}
+	req := a.filteredReqs[a.selected]
+	reqBody, _ := a.decodedBodies(req)
+
+	switch msg.String() {
+	case "c":
+		return a.copyAsCurl(req)
+	case "f":
+		return copyToClipboard(util.FetchSnippet(req.Request.Method, req.Request.Headers,
+			reqBody, a.publicURL(req)))
+	case "g":
+		return copyToClipboard(util.GoSnippet(req.Request.Method, req.Request.Headers,
+			reqBody, a.publicURL(req), req.StatusCode()))
+	case "u":
+		return copyToClipboard(a.publicURL(req))
+	}
+	// Anything else, including esc, closes the menu
+	return nil
+}
+
+// handleFilterExprInput edits the filter expression. Enter replaces the
+// active filters with the parsed ones, or leaves the prompt open with the
+// mistake marked.
+func (a *App) handleFilterExprInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEnter:
+		filters, err := a.parseFilterExpr(a.exprInput)
+		if err != nil {
+			// Every parse error is an *exprError
+			a.exprErr = err.(*exprError)
+			a.exprCursor = min(a.exprErr.pos, len(a.exprInput))
+			return nil
+		}
+		a.exprErr = nil
+		a.activeFilters = filters
+		a.focus = a.prevFocus
+		a.applyFilters()
+		return nil
+
+	case tea.KeyEscape:
+		a.exprErr = nil
+		a.focus = a.prevFocus
+		return nil
+	}
+
+	var changed bool
+	a.exprInput, a.exprCursor, changed = editLine(a.exprInput, a.exprCursor, msg)
+	if changed {
+		a.exprErr = nil
+	}
+	return nil
+}
+
+// handleQueryInput runs a jq-style query against the selected request's body.
+// The prompt stays open so the query can be refined.
+func (a *App) handleQueryInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEnter:
+		if len(a.filteredReqs) == 0 || a.selected >= len(a.filteredReqs) {
+			a.focus = a.prevFocus
+			return nil
+		}
+		req := a.filteredReqs[a.selected]
+		result, err := util.QueryJSON(a.queryBody(req), a.queryInput)
+		if err != nil {
+			a.queryErr = err.Error()
+			return nil
+		}
+		a.queryErr = ""
+		a.lastQuery = strings.TrimSpace(a.queryInput)
+		a.queryResult = result
+		a.queryReqID = req.ID
 		a.lastSelectedID = ""
 		a.updateDetailViewport()
+		a.detailViewport.GotoTop()
+		return nil
+
+	case tea.KeyCtrlY:
+		if a.queryResult != "" {
+			return copyToClipboard(a.queryResult)
+		}
+		return nil
+
+	case tea.KeyEscape:
+		a.focus = a.prevFocus
+		a.clearQueryResult()
+		return nil
+	}
+
+	a.queryInput, a.queryCursor, _ = editLine(a.queryInput, a.queryCursor, msg)
+	return nil
+}
+
+// queryBody returns the body a query runs against: the request body on the
+// Request tab, otherwise the response body, or the request body when the
+// response has none (as with most webhooks)
+func (a *App) queryBody(req ngrok.Request) string {
+	reqBody, resBody := a.decodedBodies(req)
+	if a.detailTab == TabRequest || resBody == "" {
+		return reqBody
+	}
+	return resBody
+}
+
+// clearQueryResult removes a query result from the detail panel
+func (a *App) clearQueryResult() {
+	if a.queryResult == "" {
+		return
+	}
+	a.queryResult = ""
+	a.queryReqID = ""
+	a.lastSelectedID = ""
+	a.updateDetailViewport()
+}
+
+// isFiltered reports whether the list shows a subset of the loaded requests
+func (a *App) isFiltered() bool {
+	return len(a.activeFilters) > 0 || a.searchQuery != "" || a.historySearchQuery != ""
+}
+
+// exportsSubset reports whether the export prompt is for the marked or
+// filtered requests rather than a whole session. Those are written as JSON.
+func (a *App) exportsSubset() bool {
+	return a.exportMarked || a.exportFiltered
+}
+
+// exportFormatShown returns the format the export prompt will write in
+func (a *App) exportFormatShown() storage.ExportFormat {
+	if a.exportsSubset() {
+		return storage.FormatJSON
+	}
+	return a.exportFormat
+}
+
+// updateExportName names the export file for its format, unless another
+// name has been typed
+func (a *App) updateExportName() {
+	if a.exportInput != a.exportName {
+		return
+	}
+	a.exportName = storage.GenerateExportFilenameFor(a.exportFormatShown())
+	a.exportInput, a.exportCursor = a.exportName, len(a.exportName)
+}
+
+// writeExport writes the current session, in the chosen format, or only the
+// marked or filtered requests, as JSON, to a file at path
+func (a *App) writeExport(path string) tea.Cmd {
+	store := a.storage
+
+	// Requests still queued for the live session must reach the database first
+	var flushed <-chan struct{}
+	if a.writer != nil && !a.viewingHistory {
+		flushed = a.writer.Flush()
+	}
+
+	var export func() (int, error)
+	switch {
+	case a.exportMarked:
+		ids := a.markedIDs()
+		export = func() (int, error) { return store.ExportRequests(ids, path) }
+	case a.exportFiltered:
+		ids := make([]string, len(a.filteredReqs))
+		for i, req := range a.filteredReqs {
+			ids[i] = req.ID
+		}
+		export = func() (int, error) { return store.ExportRequests(ids, path) }
+	case a.viewingHistory:
+		sessionID, count, format := a.viewingSessionID, len(a.requests), a.exportFormat
+		export = func() (int, error) { return count, store.ExportSession(sessionID, format, path) }
+	default:
+		format := a.exportFormat
+		export = func() (int, error) {
+			sessionID := store.CurrentSessionID()
+			if sessionID == "" {
+				return 0, fmt.Errorf("no active session")
+			}
+			if err := store.ExportSession(sessionID, format, path); err != nil {
+				return 0, err
+			}
+			return store.GetSessionRequestCount(sessionID)
+		}
+	}
+
+	return func() tea.Msg {
+		if flushed != nil {
+			<-flushed
+		}
+		count, err := export()
+		return messages.ExportMsg{Path: path, Count: count, Err: err}
+	}
+}
+
+// handleNoteInput handles keyboard input while editing a request note
+func (a *App) handleNoteInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEnter:
+		a.focus = a.prevFocus
+		a.saveNote(a.noteRequestID, strings.TrimSpace(a.noteInput))
+		return nil
+
+	case tea.KeyEscape:
+		a.focus = a.prevFocus
 		return nil
 	}
+
+	a.noteInput, a.noteCursor, _ = editLine(a.noteInput, a.noteCursor, msg)
 	return nil
 }
 
+// saveNote stores a note in memory and, when available, in storage
+func (a *App) saveNote(requestID, note string) {
+	if note == "" {
+		delete(a.notes, requestID)
+	} else {
+		a.notes[requestID] = note
+	}
+
+	if a.storage != nil {
+		if err := a.storage.SetNote(requestID, note); err != nil {
+			a.lastError = fmt.Errorf("failed to save note: %w", err)
+		}
+	}
+
+	// Force re-render of detail panel to show the note
+	a.lastSelectedID = ""
+	a.updateDetailViewport()
+}
+
 // handleFilterInput handles keyboard input in filter mode
 func (a *App) handleFilterInput(msg tea.KeyMsg) tea.Cmd {
+	cmd := a.handleFilterStep(msg)
+	return tea.Batch(cmd, a.schedulePreview())
+}
+
+// handleFilterStep handles a key in the current step of the filter wizard
+func (a *App) handleFilterStep(msg tea.KeyMsg) tea.Cmd {
 	switch a.filterStep {
 	case FilterStepField:
 		return a.handleFilterFieldInput(msg)
@@ -605,23 +1697,145 @@ func (a *App) handleFilterInput(msg tea.KeyMsg) tea.Cmd {
 		return a.handleFilterValueInput(msg)
 	case FilterStepLogical:
 		return a.handleFilterLogicalInput(msg)
+	case FilterStepHeaderName:
+		return a.handleFilterHeaderNameInput(msg)
+	case FilterStepManage:
+		return a.handleFilterManageInput(msg)
 	}
 	return nil
 }
 
-func (a *App) handleFilterFieldInput(msg tea.KeyMsg) tea.Cmd {
+// startFilterWizard starts the filter wizard at its first step, to add a
+// filter
+func (a *App) startFilterWizard() {
+	a.filterStep = FilterStepField
+	a.editingFilter = -1
+	a.pendingFilter = Filter{}
+	a.filterInput = ""
+	a.filterCursor = 0
+	a.filterSelected = 0
+	a.filteredFields = filterFields
+}
+
+// openFilterManager lists the active filters, with nothing left half-added
+func (a *App) openFilterManager() {
+	a.filterStep = FilterStepManage
+	a.editingFilter = -1
+	a.filterSelected = 0
+	if n := len(a.activeFilters); n > 0 {
+		// A join left by an abandoned && or || has nothing after it
+		a.activeFilters[n-1].LogicalOperator = ""
+	}
+}
+
+// handleFilterManageInput edits the list of active filters. The row after
+// the last filter adds a new one.
+func (a *App) handleFilterManageInput(msg tea.KeyMsg) tea.Cmd {
+	n := len(a.activeFilters)
 	switch msg.Type {
 	case tea.KeyEscape:
 		a.focus = a.prevFocus
+		return nil
+
+	case tea.KeyUp:
+		if a.filterSelected > 0 {
+			a.filterSelected--
+		}
+		return nil
+
+	case tea.KeyDown:
+		if a.filterSelected < n {
+			a.filterSelected++
+		}
+		return nil
+
+	case tea.KeyEnter:
+		if a.filterSelected >= n {
+			a.startFilterWizard()
+			return nil
+		}
+		// Edit the value first, the usual fix; esc steps back to the
+		// operator and field
+		i := a.filterSelected
+		a.editingFilter = i
+		a.pendingFilter = a.activeFilters[i]
+		a.filterStep = FilterStepValue
+		a.filterInput = a.pendingFilter.Value
+		a.filterCursor = len(a.filterInput)
+		a.filterErr = ""
+		return nil
+
+	case tea.KeyBackspace, tea.KeyDelete:
+		if a.filterSelected < n {
+			a.removeFilter(a.filterSelected)
+			a.filterSelected = min(a.filterSelected, len(a.activeFilters))
+		}
+		return nil
+
+	case tea.KeySpace:
+		// Switch the join between this filter and the next
+		if i := a.filterSelected; i < n-1 {
+			if a.activeFilters[i].LogicalOperator == "||" {
+				a.activeFilters[i].LogicalOperator = "&&"
+			} else {
+				a.activeFilters[i].LogicalOperator = "||"
+			}
+			a.applyFilters()
+		}
+		return nil
+	}
+
+	i := a.filterSelected
+	if i >= n {
+		return nil
+	}
+	f := &a.activeFilters[i]
+	switch msg.String() {
+	case "!":
+		f.Not = !f.Not
+	case "(":
+		// Open a group here, running to the end of the chain until it is
+		// ended; on a filter that already opens one, remove the outermost
+		if opens := groupOpens(f.Open); len(opens) > 0 {
+			f.Open = joinGroupOpens(opens[1:])
+		} else {
+			f.Open = "("
+		}
+	case ")":
+		// End the innermost group still open here
+		if groupDepth(a.activeFilters, i) == 0 {
+			return nil
+		}
+		f.Close++
+	default:
+		return nil
+	}
+	balanceGroups(a.activeFilters)
+	a.applyFilters()
+	return nil
+}
+
+func (a *App) handleFilterFieldInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEscape:
 		a.filterInput = ""
+		if len(a.activeFilters) > 0 {
+			a.openFilterManager()
+			return nil
+		}
+		a.focus = a.prevFocus
 		return nil
 
 	case tea.KeyEnter:
 		if len(a.filteredFields) > 0 && a.filterSelected < len(a.filteredFields) {
 			a.pendingFilter.Field = a.filteredFields[a.filterSelected].Key
 			a.filterStep = FilterStepOperator
+			if key := a.pendingFilter.Field; key == customRequestHeader || key == customResponseHeader {
+				a.filterStep = FilterStepHeaderName
+			}
 			a.filterSelected = 0
 			a.filterInput = ""
+			a.filterCursor = 0
 		}
 		return nil
 
@@ -653,6 +1867,34 @@ func (a *App) handleFilterFieldInput(msg tea.KeyMsg) tea.Cmd {
 	return nil
 }
 
+// handleFilterHeaderNameInput reads the header name for a custom header
+// filter
+func (a *App) handleFilterHeaderNameInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEscape:
+		a.filterStep = FilterStepField
+		a.filterInput = ""
+		a.filterSelected = 0
+		a.updateFilteredFields()
+		return nil
+
+	case tea.KeyEnter:
+		name := strings.ToLower(strings.TrimSpace(a.filterInput))
+		if name == "" || strings.ContainsAny(name, " :") {
+			return nil
+		}
+		a.pendingFilter.Field = strings.TrimSuffix(a.pendingFilter.Field, "*") + name
+		a.filterStep = FilterStepOperator
+		a.filterSelected = 0
+		a.filterInput = ""
+		a.filterCursor = 0
+		return nil
+	}
+
+	a.filterInput, a.filterCursor, _ = editLine(a.filterInput, a.filterCursor, msg)
+	return nil
+}
+
 func (a *App) handleFilterOperatorInput(msg tea.KeyMsg) tea.Cmd {
 	field := a.getFieldByKey(a.pendingFilter.Field)
 	if field == nil {
@@ -670,8 +1912,11 @@ func (a *App) handleFilterOperatorInput(msg tea.KeyMsg) tea.Cmd {
 		if a.filterSelected < len(field.Operators) {
 			a.pendingFilter.Operator = field.Operators[a.filterSelected]
 			a.filterSelected = 0
+			if !field.takesUnit(a.pendingFilter.Operator) {
+				a.pendingFilter.Unit = ""
+			}
 			// If field has units, go to unit step; otherwise go to value step
-			if field.Type == FilterTypeNumericWithUnit && len(field.Units) > 0 {
+			if field.takesUnit(a.pendingFilter.Operator) {
 				a.filterStep = FilterStepUnit
 			} else {
 				a.filterStep = FilterStepValue
@@ -734,11 +1979,15 @@ func (a *App) handleFilterUnitInput(msg tea.KeyMsg) tea.Cmd {
 
 func (a *App) handleFilterValueInput(msg tea.KeyMsg) tea.Cmd {
 	field := a.getFieldByKey(a.pendingFilter.Field)
+	if msg.Type != tea.KeyEnter {
+		// The error is about the value as it was when enter was pressed
+		a.filterErr = ""
+	}
 
 	switch msg.Type {
 	case tea.KeyEscape:
 		// Go back to previous step
-		if field != nil && field.Type == FilterTypeNumericWithUnit {
+		if field != nil && field.takesUnit(a.pendingFilter.Operator) {
 			a.filterStep = FilterStepUnit
 		} else {
 			a.filterStep = FilterStepOperator
@@ -748,7 +1997,22 @@ func (a *App) handleFilterValueInput(msg tea.KeyMsg) tea.Cmd {
 
 	case tea.KeyEnter:
 		if a.filterInput != "" {
+			if err := validateFilterValue(field, a.pendingFilter.Operator, a.filterInput); err != nil {
+				a.filterErr = err.Error()
+				return nil
+			}
+			a.filterErr = ""
 			a.pendingFilter.Value = a.filterInput
+			a.pendingFilter = normalizeStatusFilter(a.pendingFilter)
+			if a.editingFilter >= 0 && a.editingFilter < len(a.activeFilters) {
+				// An edited filter keeps its place and its join to the next
+				a.activeFilters[a.editingFilter] = a.pendingFilter
+				selected := a.editingFilter
+				a.applyFilters()
+				a.openFilterManager()
+				a.filterSelected = selected
+				return nil
+			}
 			a.filterStep = FilterStepLogical
 			a.filterSelected = 0
 		}
@@ -849,8 +2113,19 @@ func (a *App) updateFilteredFields() {
 }
 
 func (a *App) getFieldByKey(key string) *FilterField {
+	// A custom header filter takes its operators from its entry, and is
+	// named after the header
+	name := ""
+	for _, custom := range []string{customRequestHeader, customResponseHeader} {
+		if prefix := strings.TrimSuffix(custom, "*"); strings.HasPrefix(key, prefix) && key != custom {
+			name, key = key, custom
+		}
+	}
 	for _, f := range filterFields {
 		if f.Key == key {
+			if name != "" {
+				f.Name = name
+			}
 			return &f
 		}
 	}
@@ -863,23 +2138,24 @@ func (a *App) initReplayEdit(req ngrok.Request) {
 	a.replayEditSelected = 0
 	a.replayEditMethod = req.Request.Method
 	a.replayEditPath = req.Request.URI
-	a.replayEditBody = req.Request.DecodeBody()
+	a.replayEditBody = a.replayBody(req)
 	a.replayEditCursor = 0
 	a.replayEditInput = ""
 
 	// Copy headers
-	a.replayEditHeaders = nil
-	for k, vals := range req.Request.Headers {
-		// Skip some internal headers
-		lowerK := strings.ToLower(k)
-		if lowerK == "host" || lowerK == "content-length" ||
-			strings.HasPrefix(lowerK, "x-forwarded") {
-			continue
-		}
-		for _, v := range vals {
-			a.replayEditHeaders = append(a.replayEditHeaders, HeaderEntry{Key: k, Value: v})
-		}
-	}
+	a.replayEditHeaders = replayableHeaders(req.Request.Headers)
+
+	a.replayEditTarget = replayTargetTunnel
+	if len(a.tunnels) == 0 {
+		// Only history is left to replay from
+		a.replayEditTarget = replayTargetCustom
+	}
+	a.replayEditLocal = a.localURL(req)
+	a.replayEditWarned = false
+	a.replayEditHost = a.originalHost(req)
+	a.replayEditTemplate = ""
+	a.replayEditID = req.ID
+	a.replayEditAssertions = append([]string(nil), a.assertions[req.ID]...)
 }
 
 // handleReplayEditInput handles keyboard input in replay edit mode
@@ -891,19 +2167,34 @@ func (a *App) handleReplayEditInput(msg tea.KeyMsg) tea.Cmd {
 		return a.handleReplayEditMethod(msg)
 	case ReplayEditStepPath:
 		return a.handleReplayEditPath(msg)
+	case ReplayEditStepParams:
+		return a.handleReplayEditParams(msg)
+	case ReplayEditStepParamEdit:
+		return a.handleReplayEditParamEdit(msg)
 	case ReplayEditStepHeaders:
 		return a.handleReplayEditHeaders(msg)
 	case ReplayEditStepHeaderEdit:
 		return a.handleReplayEditHeaderEdit(msg)
 	case ReplayEditStepBody:
 		return a.handleReplayEditBody(msg)
+	case ReplayEditStepAssertions:
+		return a.handleReplayEditAssertions(msg)
+	case ReplayEditStepAssertionEdit:
+		return a.handleReplayEditAssertionEdit(msg)
+	case ReplayEditStepTemplateName:
+		return a.handleReplayEditTemplateName(msg)
+	case ReplayEditStepTargetURL:
+		return a.handleReplayEditTargetURL(msg)
+	case ReplayEditStepPreview:
+		return a.handleReplayEditPreview(msg)
 	}
 	return nil
 }
 
 func (a *App) handleReplayEditMain(msg tea.KeyMsg) tea.Cmd {
-	// Main menu: Method, Path, Headers, Body, Send, Cancel
-	menuItems := 6
+	// Main menu: Method, Path, Query Params, Headers, Body, Assertions,
+	// Target, Send, Send Repeatedly, Save as Template, Cancel
+	menuItems := 11
 
 	switch msg.Type {
 	case tea.KeyEscape:
@@ -919,16 +2210,39 @@ func (a *App) handleReplayEditMain(msg tea.KeyMsg) tea.Cmd {
 			a.replayEditStep = ReplayEditStepPath
 			a.replayEditInput = a.replayEditPath
 			a.replayEditCursor = len(a.replayEditInput)
-		case 2: // Headers
+		case 2: // Query Params
+			a.replayEditStep = ReplayEditStepParams
+			a.replayEditParams = parseQueryParams(a.replayEditPath)
+			a.replayEditSelected = 0
+		case 3: // Headers
 			a.replayEditStep = ReplayEditStepHeaders
 			a.replayEditSelected = 0
-		case 3: // Body
+		case 4: // Body
 			a.replayEditStep = ReplayEditStepBody
 			a.replayEditInput = a.replayEditBody
 			a.replayEditCursor = len(a.replayEditInput)
-		case 4: // Send
-			return a.sendEditedRequest()
-		case 5: // Cancel
+		case 5: // Assertions
+			a.replayEditStep = ReplayEditStepAssertions
+			a.replayEditSelected = 0
+		case 6: // Target
+			a.cycleReplayTarget()
+		case 7: // Send, once the preview is confirmed
+			if !a.confirmJSONBody() {
+				return nil
+			}
+			a.previewEditedRequest()
+		case 8: // Send Repeatedly
+			if a.repeat != nil {
+				a.setStatus("A replay is already repeating (esc stops it)", 2*time.Second)
+				return nil
+			}
+			if !a.confirmJSONBody() {
+				return nil
+			}
+			return a.sendEditedRequest(true)
+		case 9: // Save as Template
+			a.promptTemplateName()
+		case 10: // Cancel
 			a.focus = a.prevFocus
 		}
 		return nil
@@ -1026,7 +2340,7 @@ func (a *App) handleReplayEditHeaders(msg tea.KeyMsg) tea.Cmd {
 	switch msg.Type {
 	case tea.KeyEscape:
 		a.replayEditStep = ReplayEditStepMain
-		a.replayEditSelected = 2
+		a.replayEditSelected = 3
 		return nil
 
 	case tea.KeyEnter:
@@ -1048,7 +2362,7 @@ func (a *App) handleReplayEditHeaders(msg tea.KeyMsg) tea.Cmd {
 		} else {
 			// Done
 			a.replayEditStep = ReplayEditStepMain
-			a.replayEditSelected = 2
+			a.replayEditSelected = 3
 		}
 		return nil
 
@@ -1127,7 +2441,7 @@ func (a *App) handleReplayEditBody(msg tea.KeyMsg) tea.Cmd {
 	switch msg.Type {
 	case tea.KeyEscape:
 		a.replayEditStep = ReplayEditStepMain
-		a.replayEditSelected = 3
+		a.replayEditSelected = 4
 		return nil
 
 	case tea.KeyEnter:
@@ -1139,8 +2453,9 @@ func (a *App) handleReplayEditBody(msg tea.KeyMsg) tea.Cmd {
 	case tea.KeyTab:
 		// Tab to confirm body editing
 		a.replayEditBody = a.replayEditInput
+		a.replayEditWarned = false
 		a.replayEditStep = ReplayEditStepMain
-		a.replayEditSelected = 3
+		a.replayEditSelected = 4
 		return nil
 
 	case tea.KeyBackspace:
@@ -1162,6 +2477,10 @@ func (a *App) handleReplayEditBody(msg tea.KeyMsg) tea.Cmd {
 		}
 		return nil
 
+	case tea.KeyCtrlF:
+		a.formatReplayBody()
+		return nil
+
 	case tea.KeyUp:
 		// Move cursor up one line
 		a.replayEditCursor = a.moveCursorVertical(a.replayEditInput, a.replayEditCursor, -1)
@@ -1181,60 +2500,81 @@ func (a *App) handleReplayEditBody(msg tea.KeyMsg) tea.Cmd {
 	return nil
 }
 
-// sendEditedRequest sends the edited request
-func (a *App) sendEditedRequest() tea.Cmd {
-	// Get base URL from tunnels
+// sendEditedRequest sends the edited request, or asks how often to send it
+// when repeat is set
+func (a *App) sendEditedRequest(repeat bool) tea.Cmd {
+	baseURL, ok := a.replayEditBaseURL()
+	if !ok {
+		return nil
+	}
+
+	method := a.replayEditMethod
+	body := a.replayEditBody
+	headers := a.replayEditHeaderMap()
+	url := baseURL + a.replayEditPath
+
+	// Exit edit mode
+	a.focus = a.prevFocus
+
+	send := withAssertions(sendReplay(method, url, a.replayEditPath, body, headers), a.replayEditAssertions)
+	viaTunnel := a.replayEditTarget == replayTargetTunnel
+	if !viaTunnel {
+		send = sentLocally(send, baseURL)
+	}
+	parentID, path := a.replayEditID, a.replayEditPath
+	if repeat {
+		a.promptRepeat("edited "+method+" "+path, editedReplayStep(send))
+		if viaTunnel {
+			a.pendingRepeat.expect = func(int) pendingReplay {
+				return pendingReplay{parentID: parentID, method: method, uri: path}
+			}
+		}
+		return nil
+	}
+	if viaTunnel {
+		a.expectReplay(parentID, method, path, 0)
+	}
+	return send
+}
+
+// replayEditBaseURL returns where the edited request goes: the tunnel, its
+// local address, or the typed target. When there is nowhere to send it, it
+// asks for a URL or leaves edit mode.
+func (a *App) replayEditBaseURL() (string, bool) {
 	baseURL := ""
 	if len(a.tunnels) > 0 {
 		baseURL = a.tunnels[0].PublicURL
 	}
+	switch a.replayEditTarget {
+	case replayTargetLocal, replayTargetLocalKeepHost:
+		baseURL = a.replayEditLocal
+	case replayTargetCustom:
+		if a.replayEditURL == "" {
+			a.promptTargetURL()
+			return "", false
+		}
+		baseURL = a.replayEditURL
+	}
 	if baseURL == "" {
 		a.lastError = fmt.Errorf("no tunnel available")
 		a.focus = a.prevFocus
-		return nil
+		return "", false
 	}
+	return baseURL, true
+}
 
-	method := a.replayEditMethod
-	url := baseURL + a.replayEditPath
-	body := a.replayEditBody
+// replayEditHeaderMap returns the headers the edited request is sent with
+func (a *App) replayEditHeaderMap() map[string]string {
 	headers := make(map[string]string)
 	for _, h := range a.replayEditHeaders {
 		if h.Key != "" {
 			headers[h.Key] = h.Value
 		}
 	}
-
-	// Exit edit mode
-	a.focus = a.prevFocus
-
-	return func() tea.Msg {
-		// Create HTTP request
-		var reqBody io.Reader
-		if body != "" {
-			reqBody = strings.NewReader(body)
-		}
-
-		req, err := http.NewRequest(method, url, reqBody)
-		if err != nil {
-			return messages.ErrorMsg{Err: fmt.Errorf("failed to create request: %w", err)}
-		}
-
-		// Set headers
-		for k, v := range headers {
-			req.Header.Set(k, v)
-		}
-
-		// Send request
-		client := &http.Client{Timeout: 30 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			return messages.ErrorMsg{Err: fmt.Errorf("request failed: %w", err)}
-		}
-		defer resp.Body.Close()
-
-		// Success - refresh requests to see the new one
-		return messages.ReplayMsg{RequestID: "edited", Err: nil}
+	if a.replayEditTarget == replayTargetLocalKeepHost {
+		headers["Host"] = a.replayEditHost
 	}
+	return headers
 }
 
 // indexOf finds the index of a string in a slice
@@ -1308,6 +2648,8 @@ func (a *App) initHistoryView() {
 	}
 
 	a.historySelectedSess = 0
+	a.historyRenaming = false
+	a.historySearching = false
 
 	// Load sessions (exclude current session)
 	sessions, err := a.storage.GetSessions()
@@ -1320,13 +2662,39 @@ func (a *App) initHistoryView() {
 			}
 		}
 	}
-}
+
+	// Counted once here rather than on every frame; a session's requests are
+	// only loaded when it is opened
+	a.historyCounts = make(map[string]int, len(a.historySessions))
+	for _, s := range a.historySessions {
+		if n, err := a.storage.GetSessionRequestCount(s.ID); err == nil {
+			a.historyCounts[s.ID] = n
+		}
+	}
+}
 
 // handleHistoryInput handles keyboard input in history view
 func (a *App) handleHistoryInput(msg tea.KeyMsg) tea.Cmd {
+	if a.historyRenaming {
+		a.handleHistoryRenameInput(msg)
+		return nil
+	}
+	if a.historySearching {
+		a.handleHistorySearchInput(msg)
+		return nil
+	}
+
+	if a.offline && !a.viewingHistory && key.Matches(msg, a.keys.Quit) {
+		// The session list is the main view offline
+		return tea.Quit
+	}
+
 	switch msg.Type {
 	case tea.KeyEscape:
-		a.focus = a.prevFocus
+		// Offline there is no live list to go back to
+		if !a.offline || a.viewingHistory {
+			a.focus = a.prevFocus
+		}
 		return nil
 
 	case tea.KeyEnter:
@@ -1360,57 +2728,197 @@ func (a *App) handleHistoryInput(msg tea.KeyMsg) tea.Cmd {
 			if a.historySelectedSess > 0 {
 				a.historySelectedSess--
 			}
+		case "/":
+			a.historySearching = true
+			a.historySearchCursor = len(a.historySearchInput)
+		case "r":
+			if len(a.historySessions) > 0 {
+				a.historyRenaming = true
+				a.historyRenameInput = a.historySessions[a.historySelectedSess].Label
+				a.historyRenameCursor = len(a.historyRenameInput)
+			}
 		}
 		return nil
 	}
 	return nil
 }
 
+// handleHistoryRenameInput handles keyboard input while renaming a session
+func (a *App) handleHistoryRenameInput(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEscape:
+		a.historyRenaming = false
+
+	case tea.KeyEnter:
+		a.historyRenaming = false
+		sess := &a.historySessions[a.historySelectedSess]
+		label := strings.TrimSpace(a.historyRenameInput)
+		if err := a.storage.SetSessionLabel(sess.ID, label); err != nil {
+			a.lastError = fmt.Errorf("failed to rename session: %w", err)
+			return
+		}
+		sess.Label = label
+
+	default:
+		a.historyRenameInput, a.historyRenameCursor, _ = editLine(a.historyRenameInput, a.historyRenameCursor, msg)
+	}
+}
+
+// handleHistorySearchInput handles keyboard input while typing a history query
+func (a *App) handleHistorySearchInput(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEscape:
+		a.historySearching = false
+
+	case tea.KeyEnter:
+		a.historySearching = false
+		a.runHistorySearch(a.historySearchInput)
+
+	default:
+		a.historySearchInput, a.historySearchCursor, _ = editLine(a.historySearchInput, a.historySearchCursor, msg)
+	}
+}
+
+// sessionLabel returns the label of a loaded history session, if any
+func (a *App) sessionLabel(sessionID string) string {
+	for _, sess := range a.historySessions {
+		if sess.ID == sessionID {
+			return sess.Label
+		}
+	}
+	return ""
+}
+
 // loadHistoricalSession loads a historical session into the main view
 func (a *App) loadHistoricalSession(sessionID string) {
 	if a.storage == nil {
 		return
 	}
 
-	histReqs, err := a.storage.GetSessionRequests(sessionID)
+	// Bodies are loaded as requests are shown, as a long session's would
+	// take a while to read and most are never looked at
+	histReqs, err := a.storage.GetSessionSummaries(sessionID)
 	if err != nil {
 		return
 	}
 
+	a.historySearchQuery = ""
+	a.historySearchMatches = nil
+	a.viewingSessionID = sessionID
+	a.unloadedBodies(histReqs)
+	a.showHistoryRequests(histReqs)
+}
+
+// showHistoryRequests replaces the main list with stored requests
+func (a *App) showHistoryRequests(histReqs []storage.HistoryRequest) {
 	// Convert storage.HistoryRequest to ngrok.Request for display
+	a.clearMarks()
 	a.requests = nil
+	a.requestsPolled = false
+	// History may have stored less of a body than the live request had
+	a.bodies = nil
+	a.lineCache = nil
 	for _, hr := range histReqs {
-		req := ngrok.Request{
-			ID:       hr.ID,
-			Start:    hr.Timestamp,
-			Duration: hr.DurationMS * 1_000_000, // ms to ns
-			Request: ngrok.HTTPData{
-				Method:  hr.Method,
-				URI:     hr.Path,
-				Headers: hr.ReqHeaders,
-			},
-			Response: ngrok.HTTPData{
-				StatusCode: hr.StatusCode,
-				Headers:    hr.ResHeaders,
-			},
-		}
-		// Store body data for later retrieval
-		req.Request.Raw = hr.ReqBody
-		req.Response.Raw = hr.ResBody
-		a.requests = append(a.requests, req)
+		a.requests = append(a.requests, fromHistoryRequest(hr))
+		if hr.Notes != "" {
+			a.notes[hr.ID] = hr.Notes
+		}
+		a.starred[hr.ID] = hr.Starred
+		if hr.ReplayOf != "" {
+			a.replayOf[hr.ID] = hr.ReplayOf
+		}
 	}
 
 	a.viewingHistory = true
-	a.viewingSessionID = sessionID
 	a.selected = 0
 	a.applyFilters()
+	a.lastSelectedID = ""
 	a.updateDetailViewport()
 }
 
+// runHistorySearch searches all stored sessions and shows the matches in
+// the main list
+func (a *App) runHistorySearch(query string) {
+	if a.storage == nil || strings.TrimSpace(query) == "" {
+		return
+	}
+
+	results, err := a.storage.SearchRequests(query)
+	if err != nil {
+		a.lastError = fmt.Errorf("history search failed: %w", err)
+		return
+	}
+
+	histReqs := make([]storage.HistoryRequest, len(results))
+	a.historySearchMatches = make(map[string]storage.SearchResult, len(results))
+	for i, res := range results {
+		histReqs[i] = res.HistoryRequest
+		a.historySearchMatches[res.ID] = res
+	}
+
+	a.historySearchQuery = query
+	a.viewingSessionID = ""
+	a.unloadedBodies(nil)
+	a.showHistoryRequests(histReqs)
+	a.focus = FocusList
+}
+
+// returnToHistoryList leaves history search results for the session list
+func (a *App) returnToHistoryList() {
+	a.clearMarks()
+	a.historySearchQuery = ""
+	a.historySearchMatches = nil
+	a.viewingHistory = false
+	a.focus = FocusHistory
+	a.initHistoryView()
+}
+
+// loadStats refreshes the storage info view
+func (a *App) loadStats() {
+	if a.storage == nil {
+		return
+	}
+	stats, err := a.storage.GetStats()
+	if err != nil {
+		a.lastError = fmt.Errorf("failed to read storage stats: %w", err)
+		return
+	}
+	a.stats = stats
+}
+
+// handleStatsInput handles keyboard input in the storage info view
+func (a *App) handleStatsInput(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case msg.Type == tea.KeyEscape, key.Matches(msg, a.keys.Stats):
+		a.focus = a.prevFocus
+	case msg.String() == "c":
+		if a.storage != nil && !a.compacting {
+			a.compacting = true
+			return tea.Batch(a.compactStorage(), a.startSpinner())
+		}
+	}
+	return nil
+}
+
+// compactStorage runs VACUUM in the background
+func (a *App) compactStorage() tea.Cmd {
+	store := a.storage
+	return func() tea.Msg {
+		reclaimed, err := store.Compact()
+		return messages.CompactMsg{Reclaimed: reclaimed, Err: err}
+	}
+}
+
 // exitHistoryView returns to live view
 func (a *App) exitHistoryView() {
+	a.clearMarks()
 	a.viewingHistory = false
 	a.viewingSessionID = ""
+	a.historySearchQuery = ""
+	a.historySearchMatches = nil
+	a.bodies = nil
+	a.lineCache = nil
+	a.unloadedBodies(nil)
 	// Requests will be refreshed on next poll
 }
 
@@ -1418,47 +2926,62 @@ func (a *App) exitHistoryView() {
 func (a *App) initDiffView() {
 	a.diffViewport = viewport.New(0, 0)
 	a.diffViewport.Style = lipgloss.NewStyle()
+	a.diffCursor = -1
+	a.diffChange = -1
+}
+
+// showDiff opens the diff view on two requests
+func (a *App) showDiff(reqA, reqB ngrok.Request) {
+	reqA, reqB = a.withBodies(reqA), a.withBodies(reqB)
+	a.diffIDA, a.diffIDB = reqA.ID, reqB.ID
+	a.diffRequestA = &reqA
+	a.diffRequestB = &reqB
+	a.initDiffView()
+	a.prevFocus = a.focus
+	a.focus = FocusDiff
 }
 
 // handleDiffInput handles keyboard input in diff view
 func (a *App) handleDiffInput(msg tea.KeyMsg) tea.Cmd {
-	switch msg.Type {
-	case tea.KeyEscape:
-		a.diffRequestA = nil
-		a.diffRequestB = nil
+	switch msg.String() {
+	case "esc":
+		a.clearDiffMarks()
 		a.focus = a.prevFocus
-		return nil
-
-	case tea.KeyUp, tea.KeyRunes:
-		if msg.Type == tea.KeyRunes && string(msg.Runes) == "k" {
-			a.diffViewport.LineUp(1)
-		} else if msg.Type == tea.KeyUp {
-			a.diffViewport.LineUp(1)
-		}
-		return nil
-
-	case tea.KeyDown:
+	case "up", "k":
+		a.diffViewport.LineUp(1)
+	case "down", "j":
 		a.diffViewport.LineDown(1)
-		return nil
-	}
-
-	if msg.Type == tea.KeyRunes {
-		switch string(msg.Runes) {
-		case "j":
-			a.diffViewport.LineDown(1)
-		case "g":
-			a.diffViewport.GotoTop()
-		case "G":
-			a.diffViewport.GotoBottom()
+	case "g":
+		a.diffViewport.GotoTop()
+	case "G":
+		a.diffViewport.GotoBottom()
+	case "n":
+		a.jumpDiffChange(1)
+	case "N":
+		a.jumpDiffChange(-1)
+	case "tab":
+		a.moveDiffCursor(1)
+	case "shift+tab":
+		a.moveDiffCursor(-1)
+	case "x":
+		a.ignoreDiffHeader()
+	case "i":
+		a.diffShowIgnored = !a.diffShowIgnored
+	case "s":
+		if a.width < splitDiffMinWidth {
+			a.setStatus(fmt.Sprintf("Side by side needs a terminal %d columns wide", splitDiffMinWidth), 2*time.Second)
+		} else {
+			a.diffUnified = !a.diffUnified
 		}
 	}
 	return nil
 }
 
-// generateDiff generates a diff between two requests
-func (a *App) generateDiff() string {
+// generateDiff generates a diff between two requests, as rows to be laid
+// out unified or side by side
+func (a *App) generateDiff() []diffRow {
 	if a.diffRequestA == nil || a.diffRequestB == nil {
-		return "No requests selected for diff"
+		return []diffRow{{kind: diffRowText, left: "No requests selected for diff"}}
 	}
 
 	reqA := a.diffRequestA
@@ -1466,10 +2989,10 @@ func (a *App) generateDiff() string {
 
 	var sb strings.Builder
 
-	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(ColorPrimary)
-	addedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#10B981"))   // green
-	removedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#EF4444")) // red
-	unchangedStyle := lipgloss.NewStyle().Foreground(ColorMuted)
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(a.theme.ColorPrimary)
+	addedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorSecondary) // green
+	removedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorError)   // red
+	unchangedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorMuted)
 	labelStyle := lipgloss.NewStyle().Bold(true)
 
 	sb.WriteString(titleStyle.Render("Request Diff"))
@@ -1525,45 +3048,66 @@ func (a *App) generateDiff() string {
 	} else {
 		sb.WriteString(unchangedStyle.Render(durA))
 	}
-	sb.WriteString("\n\n")
+	sb.WriteString("\n")
+
+	rows := textRows(sb.String())
+	label := func(text string) {
+		rows = append(rows, diffRow{kind: diffRowText, left: labelStyle.Render(text)})
+	}
+	blank := func() {
+		rows = append(rows, diffRow{kind: diffRowText})
+	}
 
 	// Request Headers diff
-	sb.WriteString(labelStyle.Render("Request Headers:"))
-	sb.WriteString("\n")
-	sb.WriteString(a.diffHeaders(reqA.Request.Headers, reqB.Request.Headers, addedStyle, removedStyle, unchangedStyle))
-	sb.WriteString("\n")
+	blank()
+	label("Request Headers:")
+	rows = append(rows, a.diffHeaders(reqA.Request.Headers, reqB.Request.Headers)...)
 
 	// Request Body diff
-	bodyA := reqA.Request.DecodeBody()
-	bodyB := reqB.Request.DecodeBody()
+	bodyA := diffBody(reqA.Request)
+	bodyB := diffBody(reqB.Request)
 	if bodyA != "" || bodyB != "" {
-		sb.WriteString(labelStyle.Render("Request Body:"))
-		sb.WriteString("\n")
-		sb.WriteString(a.diffText(bodyA, bodyB, addedStyle, removedStyle, unchangedStyle))
-		sb.WriteString("\n")
+		blank()
+		label("Request Body:")
+		rows = append(rows, diffText(bodyA, bodyB)...)
 	}
 
 	// Response Headers diff
-	sb.WriteString(labelStyle.Render("Response Headers:"))
-	sb.WriteString("\n")
-	sb.WriteString(a.diffHeaders(reqA.Response.Headers, reqB.Response.Headers, addedStyle, removedStyle, unchangedStyle))
-	sb.WriteString("\n")
+	blank()
+	label("Response Headers:")
+	rows = append(rows, a.diffHeaders(reqA.Response.Headers, reqB.Response.Headers)...)
 
 	// Response Body diff
-	respBodyA := reqA.Response.DecodeBody()
-	respBodyB := reqB.Response.DecodeBody()
+	respBodyA := diffBody(reqA.Response)
+	respBodyB := diffBody(reqB.Response)
 	if respBodyA != "" || respBodyB != "" {
-		sb.WriteString(labelStyle.Render("Response Body:"))
-		sb.WriteString("\n")
-		sb.WriteString(a.diffText(respBodyA, respBodyB, addedStyle, removedStyle, unchangedStyle))
+		blank()
+		label("Response Body:")
+		rows = append(rows, diffText(respBodyA, respBodyB)...)
 	}
 
-	return sb.String()
+	a.selectDiffHeader(rows)
+	return rows
 }
 
-// diffHeaders generates a diff for headers
-func (a *App) diffHeaders(headersA, headersB map[string][]string, addedStyle, removedStyle, unchangedStyle lipgloss.Style) string {
-	var sb strings.Builder
+// diffBody returns a body as it is compared in the diff view. Form bodies are
+// decoded to one field per line so they diff field by field.
+func diffBody(data ngrok.HTTPData) string {
+	body := data.DecodeBody()
+	if ct := data.Headers["Content-Type"]; len(ct) > 0 {
+		if decoded, ok := util.DecodeFormBody(body, ct[0]); ok {
+			return decoded
+		}
+	}
+	return body
+}
+
+// diffHeaders generates a diff for headers, one row per header. Headers on
+// the ignore list are left out, with a note saying how many, unless they
+// are being shown.
+func (a *App) diffHeaders(headersA, headersB map[string][]string) []diffRow {
+	var rows []diffRow
+	ignored := 0
 
 	// Collect all keys
 	allKeys := make(map[string]bool)
@@ -1585,92 +3129,101 @@ func (a *App) diffHeaders(headersA, headersB map[string][]string, addedStyle, re
 		valsA := headersA[k]
 		valsB := headersB[k]
 
-		valA := strings.Join(valsA, ", ")
-		valB := strings.Join(valsB, ", ")
+		lineA := k + ": " + strings.Join(valsA, ", ")
+		lineB := k + ": " + strings.Join(valsB, ", ")
 
+		row := diffRow{kind: diffRowEqual, left: lineA, right: lineB, header: k, ignored: a.diffIgnored(k)}
 		if len(valsA) == 0 {
 			// Added in B
-			sb.WriteString(addedStyle.Render(fmt.Sprintf("  + %s: %s", k, valB)))
-			sb.WriteString("\n")
+			row.kind, row.left = diffRowAdded, ""
 		} else if len(valsB) == 0 {
 			// Removed in B
-			sb.WriteString(removedStyle.Render(fmt.Sprintf("  - %s: %s", k, valA)))
-			sb.WriteString("\n")
-		} else if valA != valB {
+			row.kind, row.right = diffRowRemoved, ""
+		} else if lineA != lineB {
 			// Changed
-			sb.WriteString(removedStyle.Render(fmt.Sprintf("  - %s: %s", k, valA)))
-			sb.WriteString("\n")
-			sb.WriteString(addedStyle.Render(fmt.Sprintf("  + %s: %s", k, valB)))
-			sb.WriteString("\n")
-		} else {
-			// Unchanged
-			sb.WriteString(unchangedStyle.Render(fmt.Sprintf("    %s: %s", k, valA)))
-			sb.WriteString("\n")
+			row.kind = diffRowChanged
+		}
+
+		if row.ignored && !a.diffShowIgnored {
+			ignored++
+			continue
 		}
+		rows = append(rows, row)
 	}
 
-	return sb.String()
+	if ignored > 0 {
+		rows = append(rows, diffRow{kind: diffRowSkipped, left: fmt.Sprintf("… %d ignored (i shows them)", ignored)})
+	}
+	return rows
 }
 
-// diffText generates a simple line-by-line diff for text content
-func (a *App) diffText(textA, textB string, addedStyle, removedStyle, unchangedStyle lipgloss.Style) string {
+// A body diff shows at most maxDiffLines lines. A longer one keeps
+// diffContext unchanged lines either side of each change.
+const (
+	maxDiffLines = 50
+	diffContext  = 3
+)
+
+// diffText generates a line diff for text content. Lines removed and added
+// together are paired up, so a changed line sits beside what replaced it.
+func diffText(textA, textB string) []diffRow {
 	if textA == textB {
 		// Show truncated if same
 		if len(textA) > 200 {
-			return unchangedStyle.Render("  (identical, " + fmt.Sprintf("%d bytes", len(textA)) + ")\n")
+			return []diffRow{{kind: diffRowSkipped, left: fmt.Sprintf("(identical, %d bytes)", len(textA))}}
 		}
-		lines := strings.Split(textA, "\n")
-		var sb strings.Builder
-		for _, line := range lines {
-			sb.WriteString(unchangedStyle.Render("    " + line))
-			sb.WriteString("\n")
+		var rows []diffRow
+		for _, line := range strings.Split(textA, "\n") {
+			rows = append(rows, diffRow{kind: diffRowEqual, left: line, right: line})
 		}
-		return sb.String()
+		return rows
 	}
 
-	linesA := strings.Split(textA, "\n")
-	linesB := strings.Split(textB, "\n")
-
-	var sb strings.Builder
-
-	// Simple line-by-line comparison (not a full diff algorithm)
-	maxLines := len(linesA)
-	if len(linesB) > maxLines {
-		maxLines = len(linesB)
-	}
+	diff := pairDiffLines(util.DiffLines(strings.Split(textA, "\n"), strings.Split(textB, "\n")))
 
-	// Limit output for very long diffs
-	if maxLines > 50 {
-		sb.WriteString(fmt.Sprintf("  (showing first 50 of %d lines)\n", maxLines))
-		maxLines = 50
+	// A long diff keeps only the lines around changes, so a small change
+	// deep in a large body still shows
+	keep := make([]bool, len(diff))
+	for i, row := range diff {
+		if len(diff) <= maxDiffLines {
+			keep[i] = true
+		} else if row.kind != diffRowEqual {
+			for j := max(0, i-diffContext); j <= min(len(diff)-1, i+diffContext); j++ {
+				keep[j] = true
+			}
+		}
 	}
 
-	for i := 0; i < maxLines; i++ {
-		lineA := ""
-		lineB := ""
-		if i < len(linesA) {
-			lineA = linesA[i]
+	var rows []diffRow
+	for i := 0; i < len(diff); i++ {
+		if keep[i] {
+			rows = append(rows, diff[i])
+			continue
 		}
-		if i < len(linesB) {
-			lineB = linesB[i]
+		skipped := 0
+		for ; i < len(diff) && !keep[i]; i++ {
+			skipped++
 		}
+		i--
+		rows = append(rows, diffRow{kind: diffRowSkipped, left: fmt.Sprintf("… %d unchanged lines", skipped)})
+	}
 
-		if lineA == lineB {
-			sb.WriteString(unchangedStyle.Render("    " + lineA))
-			sb.WriteString("\n")
-		} else {
-			if lineA != "" {
-				sb.WriteString(removedStyle.Render("  - " + lineA))
-				sb.WriteString("\n")
-			}
-			if lineB != "" {
-				sb.WriteString(addedStyle.Render("  + " + lineB))
-				sb.WriteString("\n")
-			}
-		}
+	if len(rows) > maxDiffLines {
+		note := diffRow{kind: diffRowText, left: fmt.Sprintf("  (showing first %d of %d lines)", maxDiffLines, len(rows))}
+		rows = append([]diffRow{note}, rows[:maxDiffLines]...)
 	}
+	return rows
+}
 
-	return sb.String()
+// compileSearchQuery compiles searchQuery after it changes. A regex that
+// doesn't compile leaves the search inactive and its error in the prompt.
+func (a *App) compileSearchQuery() {
+	search, err := compileSearch(a.searchQuery)
+	a.search = search
+	a.searchErr = ""
+	if err != nil {
+		a.searchErr = err.Error()
+	}
 }
 
 // performSearch applies search and resets selection
@@ -1689,14 +3242,27 @@ func (a *App) applyFilters() {
 		selectedID = a.filteredReqs[a.selected].ID
 	}
 
-	// Start with all requests
+	a.loadSessionBodies()
+	defer a.dropSessionBodies()
+
+	// Start with all requests that haven't been hidden
 	baseReqs := a.requests
+	if len(a.dismissed) > 0 {
+		var visible []ngrok.Request
+		for _, req := range baseReqs {
+			if !a.dismissed[req.ID] {
+				visible = append(visible, req)
+			}
+		}
+		baseReqs = visible
+	}
 
-	// Apply active filters first
+	// Apply active filters first, counting what each keeps for its badge
+	a.filterCounts = make([]int, len(a.activeFilters))
 	if len(a.activeFilters) > 0 {
 		var filtered []ngrok.Request
 		for _, req := range baseReqs {
-			if a.matchesAllFilters(req) {
+			if a.matchesAllFilters(req, a.activeFilters, a.filterCounts) {
 				filtered = append(filtered, req)
 			}
 		}
@@ -1704,11 +3270,10 @@ func (a *App) applyFilters() {
 	}
 
 	// Then apply search query if present
-	if a.searchQuery != "" {
-		query := strings.ToLower(a.searchQuery)
+	if !a.search.empty() {
 		var filtered []ngrok.Request
 		for _, req := range baseReqs {
-			if a.matchesSearch(req, query) {
+			if a.matchesSearch(req) {
 				filtered = append(filtered, req)
 			}
 		}
@@ -1716,16 +3281,34 @@ func (a *App) applyFilters() {
 	} else {
 		a.filteredReqs = baseReqs
 	}
+	a.traffic = computeTrafficStats(a.filteredReqs)
+
+	// Group repeats last so runs are formed from what is actually listed
+	var collapsedInto map[string]int
+	a.groups = nil
+	if a.collapseRepeats {
+		a.filteredReqs, collapsedInto = a.groupRepeats(a.filteredReqs)
+	}
+	a.resolveDiffMarks()
 
-	// Try to restore selection by ID
+	// Try to restore selection by ID. The list scrolls along with it so the
+	// selected row stays put on screen when requests are added above it.
 	if selectedID != "" {
 		for i, req := range a.filteredReqs {
 			if req.ID == selectedID {
+				a.listOffset = max(0, a.listOffset+i-a.selected)
 				a.selected = i
 				a.updateDetailViewport()
 				return
 			}
 		}
+		// The selected request may now be folded into a newer repeat
+		if row, ok := collapsedInto[selectedID]; ok {
+			a.listOffset = max(0, a.listOffset+row-a.selected)
+			a.selected = row
+			a.updateDetailViewport()
+			return
+		}
 	}
 
 	// If not found, clamp selection
@@ -1735,97 +3318,236 @@ func (a *App) applyFilters() {
 	a.updateDetailViewport()
 }
 
-// matchesSearch checks if a request matches the search query
-func (a *App) matchesSearch(req ngrok.Request, query string) bool {
-	// Search in method
-	if strings.Contains(strings.ToLower(req.Request.Method), query) {
-		return true
+// matchesSearch checks if a request matches every term of the search query
+func (a *App) matchesSearch(req ngrok.Request) bool {
+	for _, term := range a.search {
+		if !a.matchesSearchTerm(req, term) {
+			return false
+		}
 	}
-	// Search in path
-	if strings.Contains(strings.ToLower(req.Request.URI), query) {
+	return true
+}
+
+// matchesSearchTerm checks if a request matches one search term, looking
+// only at the field it is scoped to
+func (a *App) matchesSearchTerm(req ngrok.Request, term searchTerm) bool {
+	p := term.pattern
+	scope := term.scope
+
+	// Search in method and note, which no scope covers
+	if scope == scopeAny && (p.matches(req.Request.Method) || p.matches(a.notes[req.ID])) {
 		return true
 	}
 	// Search in status
-	if strings.Contains(fmt.Sprintf("%d", req.StatusCode()), query) {
+	if (scope == scopeAny || scope == scopeStatus) && p.matches(fmt.Sprintf("%d", req.StatusCode())) {
 		return true
 	}
-	// Search in headers
-	for k, vals := range req.Request.Headers {
-		for _, v := range vals {
-			if strings.Contains(strings.ToLower(k+": "+v), query) {
-				return true
-			}
-		}
+	if p.re == nil {
+		// Plain text is looked for in the lowercased text, kept between
+		// searches, of the path, headers and bodies
+		text := a.searchText(req)
+		return (scope == scopeAny || scope == scopePath) && strings.Contains(text.path, p.text) ||
+			(scope == scopeAny || scope == scopeHeader) && strings.Contains(text.headers, p.text) ||
+			(scope == scopeAny || scope == scopeBody) &&
+				(strings.Contains(text.reqBody, p.text) || strings.Contains(text.resBody, p.text))
+	}
+	// Search in path
+	if (scope == scopeAny || scope == scopePath) && p.matches(req.Request.URI) {
+		return true
 	}
-	for k, vals := range req.Response.Headers {
-		for _, v := range vals {
-			if strings.Contains(strings.ToLower(k+": "+v), query) {
-				return true
+	// Search in headers
+	if scope == scopeAny || scope == scopeHeader {
+		for _, headers := range []map[string][]string{req.Request.Headers, req.Response.Headers} {
+			for k, vals := range headers {
+				for _, v := range vals {
+					if p.matches(k + ": " + v) {
+						return true
+					}
+				}
 			}
 		}
 	}
 	// Search in body
-	reqBody := req.Request.DecodeBody()
-	if strings.Contains(strings.ToLower(reqBody), query) {
-		return true
-	}
-	respBody := req.Response.DecodeBody()
-	if strings.Contains(strings.ToLower(respBody), query) {
-		return true
+	if scope == scopeAny || scope == scopeBody {
+		reqBody, resBody := a.decodedBodies(req)
+		if p.matches(reqBody) || p.matches(resBody) {
+			return true
+		}
 	}
 	return false
 }
 
-// matchesAllFilters checks if a request matches all active filters with AND/OR logic
-func (a *App) matchesAllFilters(req ngrok.Request) bool {
-	if len(a.activeFilters) == 0 {
+// matchesAllFilters checks if a request matches a filter chain with AND/OR
+// logic. If counts is given, counts[i] goes up when filters[i] holds on its
+// own.
+func (a *App) matchesAllFilters(req ngrok.Request, filters []Filter, counts []int) bool {
+	if len(filters) == 0 {
 		return true
 	}
 
-	// Process filters with AND/OR logic
-	result := a.matchesFilter(req, a.activeFilters[0])
-
-	for i := 1; i < len(a.activeFilters); i++ {
-		prevFilter := a.activeFilters[i-1]
-		currentMatch := a.matchesFilter(req, a.activeFilters[i])
-
-		if prevFilter.LogicalOperator == "||" {
-			result = result || currentMatch
-		} else {
-			// Default to AND
-			result = result && currentMatch
+	return evalFilterChain(filters, func(i int) bool {
+		match := a.matchesFilter(req, filters[i])
+		if counts != nil && match != filters[i].Not {
+			counts[i]++
 		}
-	}
-
-	return result
+		return match
+	})
 }
 
 // matchesFilter checks if a request matches a single filter
 func (a *App) matchesFilter(req ngrok.Request, f Filter) bool {
 	switch f.Field {
 	case "status":
-		return a.compareStringOp(fmt.Sprintf("%d", req.StatusCode()), f.Operator, f.Value)
+		code := float64(req.StatusCode())
+		if f.Operator == "in" {
+			lo, hi, err := parseRange(f.Value)
+			return err == nil && code >= lo && code <= hi
+		}
+		target, err := strconv.ParseFloat(f.Value, 64)
+		return err == nil && a.compareFloat(code, f.Operator, target)
 	case "path":
 		return a.compareStringOp(req.Request.URI, f.Operator, f.Value)
+	case "req_body":
+		reqBody, _ := a.decodedBodies(req)
+		return a.compareStringOp(reqBody, f.Operator, f.Value)
+	case "res_body":
+		_, resBody := a.decodedBodies(req)
+		return a.compareStringOp(resBody, f.Operator, f.Value)
+	case "remote_addr":
+		if f.Operator == "in" {
+			return addrInPrefix(req.RemoteAddr, f.Value)
+		}
+		return a.compareStringOp(req.RemoteAddr, f.Operator, f.Value)
 	case "duration":
 		return a.compareDuration(req.DurationMs(), f.Operator, f.Unit, f.Value)
+	case "starred":
+		return (a.isStarred(req.ID) == (f.Value == "true")) == (f.Operator == "==")
+	case "timestamp":
+		return a.compareTime(req.Start, f)
 	case "response_size":
-		return a.compareSize(req.ResponseSize(), f.Operator, f.Unit, f.Value)
+		return a.compareSize(a.responseSize(req), f.Operator, f.Unit, f.Value)
 	default:
-		// Handle headers
-		if strings.HasPrefix(f.Field, "header.") {
-			headerName := strings.TrimPrefix(f.Field, "header.")
-			headerValue := a.getHeaderValue(req, headerName)
-			return a.compareStringOp(headerValue, f.Operator, f.Value)
+		// Handle headers; req. and res. fields are custom header names
+		if headerName, ok := strings.CutPrefix(f.Field, "header."); ok {
+			return a.compareStringOp(a.getHeaderValue(req.Request.Headers, headerName), f.Operator, f.Value)
+		}
+		if headerName, ok := strings.CutPrefix(f.Field, "req."); ok {
+			return a.compareStringOp(a.getHeaderValue(req.Request.Headers, headerName), f.Operator, f.Value)
+		}
+		if headerName, ok := strings.CutPrefix(f.Field, "res."); ok {
+			return a.compareStringOp(a.getHeaderValue(req.Response.Headers, headerName), f.Operator, f.Value)
+		}
+	}
+	return true
+}
+
+// decodedBodies holds a request's bodies as DecodeBody returns them
+type decodedBodies struct {
+	request  string
+	response string
+	search   *searchText // Set once the request is first searched
+}
+
+// decodedBodies returns the decoded request and response bodies of req,
+// decoding them only the first time they're asked for. A request still
+// waiting for its response is decoded each time until it has one.
+func (a *App) decodedBodies(req ngrok.Request) (string, string) {
+	if b, ok := a.bodies[req.ID]; ok {
+		return b.request, b.response
+	}
+	req = a.withBodies(req)
+	b := decodedBodies{request: req.Request.DecodeBody(), response: req.Response.DecodeBody()}
+	// Bodies loaded from history are left to lazyBodies, which holds few
+	if req.Response.StatusCode != 0 && !a.lazy.unloaded[req.ID] {
+		if a.bodies == nil {
+			a.bodies = make(map[string]decodedBodies)
+		}
+		a.bodies[req.ID] = b
+	}
+	return b.request, b.response
+}
+
+// searchText returns the lowercased text of req that plain search terms are
+// looked for in, kept with its decoded bodies once worked out
+func (a *App) searchText(req ngrok.Request) *searchText {
+	reqBody, resBody := a.decodedBodies(req)
+	b, ok := a.bodies[req.ID]
+	if ok && b.search != nil {
+		return b.search
+	}
+	text := newSearchText(req, reqBody, resBody)
+	if ok {
+		b.search = text
+		a.bodies[req.ID] = b
+	}
+	return text
+}
+
+// responseSize is req.ResponseSize, with the body decoded through the cache
+// rather than on every call
+func (a *App) responseSize(req ngrok.Request) int {
+	// History stores the size, so a response listed without its body is
+	// known to be empty without loading it
+	if req.StoredResponseSize > 0 || a.lazy.unloaded[req.ID] {
+		return req.StoredResponseSize
+	}
+	_, resBody := a.decodedBodies(req)
+	return len(resBody)
+}
+
+// sameRequests reports whether a poll listed the same requests as the last,
+// in the same order and in the same state
+func sameRequests(old, listed []ngrok.Request) bool {
+	if len(old) != len(listed) {
+		return false
+	}
+	for i := range listed {
+		if old[i].ID != listed[i].ID || old[i].Duration != listed[i].Duration ||
+			old[i].Response.StatusCode != listed[i].Response.StatusCode {
+			return false
 		}
 	}
 	return true
 }
 
-// getHeaderValue gets a header value from request (case-insensitive)
-func (a *App) getHeaderValue(req ngrok.Request, headerName string) string {
+// filtersOnAge reports whether an active filter keeps requests by how long
+// ago they came in, so what it keeps changes as time passes
+func (a *App) filtersOnAge() bool {
+	for _, f := range a.activeFilters {
+		if f.Field == "timestamp" && f.Operator == "last" {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneBodies drops the decoded bodies, and rendered rows, of requests no
+// longer in the list
+func (a *App) pruneBodies() {
+	if len(a.bodies) <= len(a.requests) && len(a.lineCache) <= len(a.requests) {
+		return
+	}
+	current := make(map[string]bool, len(a.requests))
+	for _, req := range a.requests {
+		current[req.ID] = true
+	}
+	for id := range a.bodies {
+		if !current[id] {
+			delete(a.bodies, id)
+		}
+	}
+	for id := range a.lineCache {
+		if !current[id] {
+			delete(a.lineCache, id)
+		}
+	}
+}
+
+// getHeaderValue gets a header value from request or response headers
+// (case-insensitive)
+func (a *App) getHeaderValue(headers map[string][]string, headerName string) string {
 	headerName = strings.ToLower(headerName)
-	for k, vals := range req.Request.Headers {
+	for k, vals := range headers {
 		if strings.ToLower(k) == headerName && len(vals) > 0 {
 			return vals[0]
 		}
@@ -1833,21 +3555,49 @@ func (a *App) getHeaderValue(req ngrok.Request, headerName string) string {
 	return ""
 }
 
-// compareStringOp compares strings with operators ==, !=, match, !match
+// compareStringOp compares strings with operators ==, !=, match, !match.
+// A match value written as /pattern/ is a case-insensitive regex.
 func (a *App) compareStringOp(val string, op string, target string) bool {
 	switch op {
 	case "==":
 		return val == target
 	case "!=":
 		return val != target
-	case "match":
-		return strings.Contains(strings.ToLower(val), strings.ToLower(target))
-	case "!match":
-		return !strings.Contains(strings.ToLower(val), strings.ToLower(target))
+	case "match", "!match":
+		var found bool
+		if re := a.filterRegex(target); re != nil {
+			found = re.MatchString(val)
+		} else {
+			found = strings.Contains(strings.ToLower(val), strings.ToLower(target))
+		}
+		return found == (op == "match")
 	}
 	return false
 }
 
+// filterRegex returns the compiled regex of a /pattern/ filter value, or nil
+// if the value is a plain substring. Patterns are compiled once and cached.
+func (a *App) filterRegex(target string) *regexp.Regexp {
+	expr, ok := slashRegex(target)
+	if !ok {
+		return nil
+	}
+	if re, ok := a.filterRegexps[expr]; ok {
+		return re
+	}
+	// Values are checked when entered, so this only fails for filters
+	// that were never valid; they match nothing
+	re, err := compileRegex(expr)
+	if err != nil {
+		re = regexp.MustCompile(`$^`)
+	}
+	if a.filterRegexps == nil {
+		a.filterRegexps = make(map[string]*regexp.Regexp)
+	}
+	a.filterRegexps[expr] = re
+	return re
+}
+
 // compareDuration compares duration with unit conversion
 func (a *App) compareDuration(valMs float64, op string, unit string, target string) bool {
 	t, err := strconv.ParseFloat(target, 64)
@@ -1875,33 +3625,142 @@ func (a *App) compareDuration(valMs float64, op string, unit string, target stri
 	return a.compareFloat(valMs, op, targetMs)
 }
 
-// compareSize compares size with unit conversion
-func (a *App) compareSize(valBytes int, op string, unit string, target string) bool {
-	t, err := strconv.ParseFloat(target, 64)
-	if err != nil {
-		return false
+// validateFilterValue checks a value for a filter on field with operator op
+// before it is applied, so a mistake is reported rather than quietly matching
+// nothing: regexes, CIDR ranges and times must parse
+func validateFilterValue(field *FilterField, op, value string) error {
+	if expr, ok := slashRegex(value); ok && strings.HasSuffix(op, "match") {
+		if _, err := compileRegex(expr); err != nil {
+			return err
+		}
 	}
-
-	// Convert target to bytes based on unit
-	var targetBytes float64
-	switch unit {
-	case "b":
-		targetBytes = t
-	case "kb":
-		targetBytes = t * 1024
-	case "mb":
-		targetBytes = t * 1024 * 1024
-	default:
-		targetBytes = t
+	if op == "in" && (field == nil || field.Type == FilterTypeString) {
+		if _, err := parsePrefix(value); err != nil {
+			return err
+		}
 	}
-
-	return a.compareFloat(float64(valBytes), op, targetBytes)
+	if field != nil && field.Type == FilterTypeNumeric {
+		return validateNumericValue(op, value)
+	}
+	if field != nil && field.Type == FilterTypeTime {
+		return validateTimeValue(op, value)
+	}
+	if field != nil && field.Type == FilterTypeBool && value != "true" && value != "false" {
+		return fmt.Errorf("expected true or false")
+	}
+	return nil
 }
 
-// compareFloat compares two float values
-func (a *App) compareFloat(val float64, op string, target float64) bool {
-	switch op {
-	case ">":
+// validateNumericValue checks the value of a numeric filter: a number, or
+// for "in" a lo..hi range. A status class such as "5xx" stands for its range.
+func validateNumericValue(op, value string) error {
+	if _, ok := statusClass(value); ok && (op == "==" || op == "!=" || op == "in") {
+		return nil
+	}
+	if op == "in" {
+		_, _, err := parseRange(value)
+		return err
+	}
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return fmt.Errorf("expected a number")
+	}
+	return nil
+}
+
+// parseRange parses a range of numbers such as 400..499, which includes
+// both ends
+func parseRange(value string) (float64, float64, error) {
+	loText, hiText, ok := strings.Cut(value, "..")
+	lo, loErr := strconv.ParseFloat(loText, 64)
+	hi, hiErr := strconv.ParseFloat(hiText, 64)
+	if !ok || loErr != nil || hiErr != nil {
+		return 0, 0, fmt.Errorf("expected a range like 400..499")
+	}
+	if lo > hi {
+		return 0, 0, fmt.Errorf("range %s is backwards", value)
+	}
+	return lo, hi, nil
+}
+
+// compareTime compares when a request started with a timestamp filter.
+// "last" keeps requests from the last N minutes or hours. "after" and
+// "before" take RFC3339 times, or HH:MM, which is on the request's own day
+// so it works for old sessions too.
+func (a *App) compareTime(start time.Time, f Filter) bool {
+	if f.Operator == "last" {
+		return a.compareDuration(float64(time.Since(start).Milliseconds()), "<=", f.Unit, f.Value)
+	}
+	target, err := filterTime(f.Value, start)
+	if err != nil {
+		return false
+	}
+	switch f.Operator {
+	case "after":
+		return !start.Before(target)
+	case "before":
+		return start.Before(target)
+	}
+	return false
+}
+
+// timeOfDayLayouts are the HH:MM forms a timestamp filter accepts
+var timeOfDayLayouts = []string{"15:04", "15:04:05"}
+
+// filterTime parses a timestamp filter value. A time of day is placed on
+// the same local day as ref.
+func filterTime(value string, ref time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range timeOfDayLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			ref = ref.Local()
+			return time.Date(ref.Year(), ref.Month(), ref.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use HH:MM or RFC3339", value)
+}
+
+// validateTimeValue checks a timestamp filter value before it is applied
+func validateTimeValue(op, value string) error {
+	if op == "last" {
+		if n, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil || n < 0 {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		return nil
+	}
+	_, err := filterTime(value, time.Now())
+	return err
+}
+
+// compareSize compares size with unit conversion
+func (a *App) compareSize(valBytes int, op string, unit string, target string) bool {
+	t, err := strconv.ParseFloat(target, 64)
+	if err != nil {
+		return false
+	}
+
+	// Convert target to bytes based on unit
+	var targetBytes float64
+	switch unit {
+	case "b":
+		targetBytes = t
+	case "kb":
+		targetBytes = t * 1024
+	case "mb":
+		targetBytes = t * 1024 * 1024
+	default:
+		targetBytes = t
+	}
+
+	return a.compareFloat(float64(valBytes), op, targetBytes)
+}
+
+// compareFloat compares two float values
+func (a *App) compareFloat(val float64, op string, target float64) bool {
+	switch op {
+	case ">":
 		return val > target
 	case "<":
 		return val < target
@@ -1909,6 +3768,10 @@ func (a *App) compareFloat(val float64, op string, target float64) bool {
 		return val >= target
 	case "<=":
 		return val <= target
+	case "==":
+		return val == target
+	case "!=":
+		return val != target
 	}
 	return false
 }
@@ -1917,9 +3780,12 @@ func (a *App) compareFloat(val float64, op string, target float64) bool {
 func (a *App) clearAll() {
 	a.searchQuery = ""
 	a.searchCursor = 0
+	a.compileSearchQuery()
 	a.activeFilters = nil
-	a.filteredReqs = a.requests
+	// Hidden requests stay hidden and repeats stay grouped
+	a.applyFilters()
 	a.selected = 0
+	a.listOffset = 0
 	// Force re-render to remove highlighting
 	a.lastSelectedID = ""
 	a.updateDetailViewport()
@@ -1927,74 +3793,92 @@ func (a *App) clearAll() {
 
 // copyAsCurl copies the request as a cURL command to clipboard
 func (a *App) copyAsCurl(req ngrok.Request) tea.Cmd {
-	// Get the base URL from tunnels
-	baseURL := ""
-	if len(a.tunnels) > 0 {
-		baseURL = a.tunnels[0].PublicURL
+	return copyToClipboard(buildCurlCommand(a.withBodies(req), a.tunnelURL(req)))
+}
+
+// tunnelURL returns the public URL of the tunnel the request arrived on,
+// falling back to the first tunnel when it is unknown or has since closed
+func (a *App) tunnelURL(req ngrok.Request) string {
+	if t := a.tunnelFor(req); t != nil {
+		return t.PublicURL
 	}
+	return ""
+}
 
-	return func() tea.Msg {
-		curl := buildCurlCommand(req, baseURL)
+// publicURL returns the full public URL the request was sent to
+func (a *App) publicURL(req ngrok.Request) string {
+	return a.tunnelURL(req) + req.Request.URI
+}
 
-		// Try to copy to clipboard using system command
-		var cmd *exec.Cmd
+// openInBrowser opens the request's public URL in the system browser. Only
+// GET requests are opened, since the browser will always send a GET.
+func (a *App) openInBrowser(req ngrok.Request) tea.Cmd {
+	if req.Request.Method != http.MethodGet {
+		a.setStatus(fmt.Sprintf("Only GET requests can be opened in the browser, not %s", req.Request.Method), 3*time.Second)
+		return nil
+	}
+	if len(a.tunnels) == 0 {
+		a.setErrorStatus(fmt.Errorf("no tunnel to open the request on"), 3*time.Second)
+		return nil
+	}
+	url := a.publicURL(req)
+
+	return func() tea.Msg {
+		var name string
+		var args []string
 		switch runtime.GOOS {
 		case "darwin":
-			cmd = exec.Command("pbcopy")
+			name, args = "open", []string{url}
 		case "linux":
-			cmd = exec.Command("xclip", "-selection", "clipboard")
+			name, args = "xdg-open", []string{url}
+		case "windows":
+			// start is a cmd builtin; the empty argument is the window title
+			// and & would otherwise end the command
+			name, args = "cmd", []string{"/c", "start", "", strings.ReplaceAll(url, "&", "^&")}
 		default:
-			return messages.ErrorMsg{Err: fmt.Errorf("clipboard not supported on %s", runtime.GOOS)}
+			return messages.OpenMsg{Err: fmt.Errorf("opening a browser is not supported on %s", runtime.GOOS)}
 		}
 
-		cmd.Stdin = strings.NewReader(curl)
-		if err := cmd.Run(); err != nil {
-			return messages.ErrorMsg{Err: fmt.Errorf("failed to copy: %w", err)}
+		if _, err := exec.LookPath(name); err != nil {
+			return messages.OpenMsg{Err: fmt.Errorf("no browser opener found (%s)", name)}
 		}
-
-		return messages.CopyMsg{Success: true}
+		if err := exec.Command(name, args...).Run(); err != nil {
+			return messages.OpenMsg{Err: fmt.Errorf("failed to open browser: %w", err)}
+		}
+		return messages.OpenMsg{URL: url}
 	}
 }
 
 // buildCurlCommand builds a cURL command string from a request
 func buildCurlCommand(req ngrok.Request, baseURL string) string {
-	var parts []string
-	parts = append(parts, "curl")
-
-	// Method
-	if req.Request.Method != "GET" {
-		parts = append(parts, "-X", req.Request.Method)
-	}
-
-	// Headers (skip internal/automatic headers)
-	for key, values := range req.Request.Headers {
-		lowerKey := strings.ToLower(key)
-		// Skip headers that curl handles automatically or are ngrok-specific
-		if lowerKey == "host" ||
-			lowerKey == "content-length" ||
-			lowerKey == "accept-encoding" ||
-			lowerKey == "user-agent" ||
-			strings.HasPrefix(lowerKey, "x-forwarded") {
-			continue
-		}
-		for _, v := range values {
-			parts = append(parts, "-H", fmt.Sprintf("'%s: %s'", key, v))
-		}
+	fullURL := baseURL + req.Request.URI
+	return util.CurlCommand(req.Request.Method, req.Request.Headers, req.Request.DecodeBody(), util.ShellQuote(fullURL))
+}
+
+// exportCurlScript writes the currently filtered requests as a runnable shell script
+func (a *App) exportCurlScript() tea.Cmd {
+	if len(a.filteredReqs) == 0 {
+		return nil
 	}
 
-	// Body
-	body := req.Request.DecodeBody()
-	if body != "" {
-		// Escape single quotes in body
-		body = strings.ReplaceAll(body, "'", "'\\''")
-		parts = append(parts, "-d", fmt.Sprintf("'%s'", body))
+	baseURL := ""
+	if len(a.tunnels) > 0 {
+		baseURL = a.tunnels[0].PublicURL
 	}
 
-	// Full URL
-	fullURL := baseURL + req.Request.URI
-	parts = append(parts, fmt.Sprintf("'%s'", fullURL))
+	a.loadAllBodies()
+	defer a.dropSessionBodies()
+	histReqs := make([]storage.HistoryRequest, len(a.filteredReqs))
+	for i, req := range a.filteredReqs {
+		histReqs[i] = capture.ToHistoryRequest(a.withBodies(req))
+		histReqs[i].Notes = a.notes[req.ID]
+	}
 
-	return strings.Join(parts, " ")
+	return func() tea.Msg {
+		path := storage.GenerateExportFilenameFor(storage.FormatCurl)
+		err := storage.WriteCurlScript(histReqs, baseURL, path)
+		return messages.ExportMsg{Path: path, Count: len(histReqs), Err: err}
+	}
 }
 
 // View implements tea.Model
@@ -2003,6 +3887,13 @@ func (a *App) View() string {
 		return fmt.Sprintf("\n  %s Loading...", a.spinner.View())
 	}
 
+	// Below the minimum size the layout can't fit its borders
+	if a.width < minTermWidth || a.height < minTermHeight {
+		msg := fmt.Sprintf("Terminal too small (needs ≥ %d×%d)\nnow %d×%d", minTermWidth, minTermHeight, a.width, a.height)
+		return lipgloss.Place(a.width, a.height, lipgloss.Center, lipgloss.Center,
+			lipgloss.NewStyle().Foreground(a.theme.ColorWarning).Align(lipgloss.Center).Render(msg))
+	}
+
 	// Build layout
 	header := a.renderHeader()
 	content := a.renderContent()
@@ -2017,50 +3908,116 @@ func (a *App) renderHeader() string {
 
 	if a.viewingHistory {
 		// Show history mode indicator
-		tunnelInfo = lipgloss.NewStyle().
-			Background(lipgloss.Color("#7C3AED")).
-			Foreground(lipgloss.Color("#FFFFFF")).
+		back, searchBack := "press 'h' to return to live", "esc for sessions, 'h' for live"
+		if a.offline {
+			back, searchBack = "esc for sessions", "esc for sessions"
+		}
+		banner := fmt.Sprintf(" 📜 Viewing History - %s ", back)
+		if a.viewingFile != "" {
+			banner = fmt.Sprintf(" 📄 %s ", a.viewingFile)
+		} else if a.historySearchQuery != "" {
+			count := fmt.Sprintf("%d matches", len(a.requests))
+			if len(a.requests) == 1 {
+				count = "1 match"
+			} else if len(a.requests) >= storage.SearchLimit {
+				count = fmt.Sprintf("first %d matches", storage.SearchLimit)
+			}
+			banner = fmt.Sprintf(" 🔎 History search %q: %s - %s ", a.historySearchQuery, count, searchBack)
+		} else if label := a.sessionLabel(a.viewingSessionID); label != "" {
+			banner = fmt.Sprintf(" 📜 Viewing History: %s - %s ", label, back)
+		}
+		tunnelInfo = a.theme.Badge(a.theme.ColorPrimary, a.theme.ColorOnPrimary).
 			Padding(0, 1).
-			Render(" 📜 Viewing History - press 'h' to return to live ")
+			Render(banner)
+	} else if a.offline {
+		tunnelInfo = " Browsing history, pick a session "
 	} else if len(a.tunnels) > 0 {
 		t := a.tunnels[0]
 		tunnelInfo = fmt.Sprintf(" %s → %s ",
-			TunnelURLStyle.Render(t.PublicURL),
-			TunnelLocalStyle.Render(t.Config.Addr),
+			a.theme.TunnelURLStyle.Render(t.PublicURL),
+			a.theme.TunnelLocalStyle.Render(t.Config.Addr),
 		)
 	} else if a.lastError != nil {
-		tunnelInfo = ErrorStyle.Render(" ⚠ ngrok not running ")
+		tunnelInfo = a.theme.ErrorStyle.Render(" ⚠ ngrok not running ")
 	} else {
 		tunnelInfo = " No active tunnels "
 	}
 
-	title := HeaderStyle.Render(" 🕳 MOLE ")
+	title := a.theme.HeaderStyle.Render(" 🕳 MOLE ")
+	if a.version != "" {
+		title += lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Render(" " + a.version)
+	}
+	if a.offline {
+		// ngrok isn't polled, so nothing new will show up
+		title += a.theme.Badge(a.theme.ColorWarning, a.theme.ColorOnAccent).Bold(true).
+			Render(" OFFLINE ")
+	}
 	var info string
 	if a.viewingHistory {
 		info = tunnelInfo
 	} else {
 		info = lipgloss.NewStyle().
-			Background(lipgloss.Color("#1F2937")).
+			Background(a.theme.ColorHighlight).
 			Padding(0, 1).
 			Render(tunnelInfo)
 	}
 
 	headerContent := lipgloss.JoinHorizontal(lipgloss.Center, title, info)
 
-	return lipgloss.NewStyle().
+	// Subtle hint that nothing will be kept after exit
+	if a.storage == nil || a.storage.InMemory() {
+		headerContent = lipgloss.JoinHorizontal(lipgloss.Center, headerContent,
+			lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Render("  ○ history off"))
+	}
+
+	// Latency sparkline in whatever room is left, right-aligned
+	if !a.viewingHistory && !a.offline && a.width >= sparklineMinTerm {
+		label := lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Render("last 5m ")
+		room := a.width - lipgloss.Width(headerContent) - lipgloss.Width(label) - 3 // Gap and right margin
+		if width := min(room, sparklineMaxWidth); width >= sparklineMinWidth {
+			spark := label + a.renderSparkline(a.requests, time.Now(), width)
+			gap := a.width - lipgloss.Width(headerContent) - lipgloss.Width(spark) - 1
+			headerContent += strings.Repeat(" ", gap) + spark
+		}
+	}
+
+	header := lipgloss.NewStyle().
 		Width(a.width).
-		Render(headerContent)
+		Render(clipLine(headerContent, a.width))
+	if a.showTraffic {
+		header += "\n" + lipgloss.NewStyle().Width(a.width).MaxWidth(a.width).Render(a.renderTrafficBar())
+	}
+	return header
+}
+
+// contentHeight returns the height of the panels between the header and the
+// footer
+func (a *App) contentHeight() int {
+	height := a.height - 4 // header + footer
+	if a.showTraffic {
+		height-- // Stats bar
+	}
+	return height
 }
 
 // renderContent renders the main content area
 func (a *App) renderContent() string {
-	contentHeight := a.height - 4 // header + footer
+	contentHeight := a.contentHeight()
 
 	// History view takes full screen
 	if a.focus == FocusHistory {
 		return a.renderHistoryView(a.width, contentHeight)
 	}
 
+	// So does the storage info view
+	if a.focus == FocusStats {
+		return a.renderStatsView(a.width, contentHeight)
+	}
+
+	if a.zoomed {
+		return a.renderZoomed(contentHeight)
+	}
+
 	// Responsive layout
 	if a.width >= 120 {
 		return a.renderSideBySide(contentHeight)
@@ -2068,13 +4025,69 @@ func (a *App) renderContent() string {
 	return a.renderStacked(contentHeight)
 }
 
+// The smallest terminal the layout fits in. Anything smaller shows a message
+// instead.
+const (
+	minTermWidth  = 60
+	minTermHeight = 15
+)
+
+// minStackedDetailHeight is the shortest the stacked layout lets the detail
+// panel get, borders included, before it shows one panel at a time
+const minStackedDetailHeight = 6
+
+const (
+	// By default the list gets 30% of the width side by side, leaving 70%
+	// for the detail panel to view request/response, and 40% of the height
+	// when stacked
+	defaultSideBySideSplit = 30
+	defaultStackedSplit    = 40
+
+	// splitStep is how far < and > move the divider, in percent
+	splitStep = 5
+)
+
+// clampSplit keeps the list's share of the screen where both panels stay
+// usable
+func clampSplit(percent int) int {
+	return max(15, min(percent, 85))
+}
+
+// sideBySideListWidth returns the width given to the list in the side-by-side
+// layout
+func (a *App) sideBySideListWidth() int {
+	return max(a.width*a.sideBySideSplit/100, 36)
+}
+
+// stackedListHeight returns the height given to the list in the stacked
+// layout. When the detail panel wouldn't fit below it, the list gets the
+// whole height.
+func (a *App) stackedListHeight(height int) int {
+	list := max(height*a.stackedSplit/100, 8)
+	if height-list < minStackedDetailHeight {
+		return height
+	}
+	return list
+}
+
+// renderZoomed renders the zoomed panel alone, filling the content area
+func (a *App) renderZoomed(height int) string {
+	// Content dimensions (subtract border=2 + padding=2 = 4)
+	contentWidth := a.width - 4
+	contentHeight := height - 2
+
+	var content string
+	if a.zoomDetail {
+		content = a.renderDetailPanel(contentWidth, contentHeight)
+	} else {
+		content = a.renderRequestList(contentWidth, contentHeight)
+	}
+	return a.theme.ActiveBorderStyle.Width(contentWidth).Height(contentHeight).Render(content)
+}
+
 // renderSideBySide renders list and detail side by side
 func (a *App) renderSideBySide(height int) string {
-	// Give 30% to list, 70% to detail for more space to view request/response
-	listWidth := a.width * 30 / 100
-	if listWidth < 36 {
-		listWidth = 36
-	}
+	listWidth := a.sideBySideListWidth()
 	detailWidth := a.width - listWidth
 
 	// Content dimensions (subtract border=2 + padding=2 = 4)
@@ -2086,12 +4099,12 @@ func (a *App) renderSideBySide(height int) string {
 	detail := a.renderDetailPanel(detailContentWidth, contentHeight)
 
 	// Highlight focused panel
-	listBorder := BorderStyle
-	detailBorder := BorderStyle
-	if a.focus == FocusList || a.focus == FocusFilter || a.focus == FocusReplayEdit {
-		listBorder = ActiveBorderStyle
-	} else if a.focus == FocusDetailPanel || a.focus == FocusDiff {
-		detailBorder = ActiveBorderStyle
+	listBorder := a.theme.BorderStyle
+	detailBorder := a.theme.BorderStyle
+	if a.focus == FocusList || a.focus == FocusFilter || a.focus == FocusReplayEdit || a.focus == FocusTemplates {
+		listBorder = a.theme.ActiveBorderStyle
+	} else if a.detailFocused() {
+		detailBorder = a.theme.ActiveBorderStyle
 	}
 
 	listBox := listBorder.Width(listContentWidth).Height(contentHeight).Render(list)
@@ -2102,12 +4115,12 @@ func (a *App) renderSideBySide(height int) string {
 
 // renderStacked renders list above detail
 func (a *App) renderStacked(height int) string {
-	// Give 40% to list, 60% to detail
-	listHeight := height * 40 / 100
-	if listHeight < 8 {
-		listHeight = 8
-	}
+	listHeight := a.stackedListHeight(height)
 	detailHeight := height - listHeight
+	if detailHeight == 0 {
+		// Too short for both: show the panel in use, as if zoomed
+		return a.renderStackedAlone(height)
+	}
 
 	// Content dimensions (subtract border=2 + padding=2 = 4)
 	contentWidth := a.width - 4
@@ -2118,12 +4131,12 @@ func (a *App) renderStacked(height int) string {
 	detail := a.renderDetailPanel(contentWidth, detailContentHeight)
 
 	// Highlight focused panel
-	listBorder := BorderStyle
-	detailBorder := BorderStyle
-	if a.focus == FocusList || a.focus == FocusFilter || a.focus == FocusReplayEdit {
-		listBorder = ActiveBorderStyle
-	} else if a.focus == FocusDetailPanel || a.focus == FocusDiff {
-		detailBorder = ActiveBorderStyle
+	listBorder := a.theme.BorderStyle
+	detailBorder := a.theme.BorderStyle
+	if a.focus == FocusList || a.focus == FocusFilter || a.focus == FocusReplayEdit || a.focus == FocusTemplates {
+		listBorder = a.theme.ActiveBorderStyle
+	} else if a.detailFocused() {
+		detailBorder = a.theme.ActiveBorderStyle
 	}
 
 	listBox := listBorder.Width(contentWidth).Height(listContentHeight).Render(list)
@@ -2132,6 +4145,27 @@ func (a *App) renderStacked(height int) string {
 	return lipgloss.JoinVertical(lipgloss.Left, listBox, detailBox)
 }
 
+// renderStackedAlone renders the list, or the detail panel when it has focus,
+// filling the content area of a terminal too short for both
+func (a *App) renderStackedAlone(height int) string {
+	contentWidth := a.width - 4
+	contentHeight := height - 2
+
+	if a.detailFocused() {
+		detail := a.renderDetailPanel(contentWidth, contentHeight)
+		return a.theme.ActiveBorderStyle.Width(contentWidth).Height(contentHeight).Render(detail)
+	}
+	list := a.renderRequestList(contentWidth, contentHeight)
+	return a.theme.ActiveBorderStyle.Width(contentWidth).Height(contentHeight).Render(list)
+}
+
+// detailFocused reports whether the detail panel, or a view shown in its
+// place, has focus
+func (a *App) detailFocused() bool {
+	return a.focus == FocusDetailPanel || a.focus == FocusDiff || a.focus == FocusReplayResult ||
+		a.focus == FocusReplaySummary
+}
+
 // renderRequestList renders the list of requests
 func (a *App) renderRequestList(width, height int) string {
 	// If in filter mode, show filter UI at top
@@ -2144,6 +4178,11 @@ func (a *App) renderRequestList(width, height int) string {
 		return a.renderReplayEditInPanel(width, height)
 	}
 
+	// Template browser
+	if a.focus == FocusTemplates {
+		return a.renderTemplates(width, height)
+	}
+
 	if len(a.requests) == 0 {
 		msg := "Waiting for requests..."
 		if a.loading {
@@ -2160,21 +4199,24 @@ func (a *App) renderRequestList(width, height int) string {
 	var lines []string
 
 	// Title with filter/search count
-	title := ListTitleStyle.Render("Requests")
+	title := a.theme.ListTitleStyle.Render("Requests")
 	if len(a.activeFilters) > 0 || a.searchQuery != "" {
-		filterInfo := lipgloss.NewStyle().Foreground(ColorMuted).
+		filterInfo := lipgloss.NewStyle().Foreground(a.theme.ColorMuted).
 			Render(fmt.Sprintf(" (%d/%d)", len(a.filteredReqs), len(a.requests)))
 		title = title + filterInfo
 	}
-	lines = append(lines, title)
+	if n := a.hiddenCount(); n > 0 {
+		title += lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Render(fmt.Sprintf(" (%d hidden)", n))
+	}
 
-	visibleLines := height - 2
+	visibleLines := visibleListLines(height)
+	startIdx := a.listScrollOffset(visibleLines)
 
-	// Calculate scroll offset
-	startIdx := 0
-	if a.selected >= visibleLines {
-		startIdx = a.selected - visibleLines + 1
+	// Newer requests scrolled out of view above (g jumps to them)
+	if startIdx > 0 {
+		title += lipgloss.NewStyle().Foreground(a.theme.ColorWarning).Render(fmt.Sprintf(" ↑%d newer", startIdx))
 	}
+	lines = append(lines, title)
 
 	endIdx := min(startIdx+visibleLines, len(a.filteredReqs))
 
@@ -2187,22 +4229,332 @@ func (a *App) renderRequestList(width, height int) string {
 	return strings.Join(lines, "\n")
 }
 
+// requestGroup is a run of consecutive requests with the same method, path
+// and status
+type requestGroup struct {
+	count    int
+	key      string // ID of the oldest request, which stays put as repeats arrive
+	expanded bool
+}
+
+// groupRepeats collapses runs of identical requests into their newest
+// request, unless the run has been expanded. It records the runs in a.groups
+// and returns the rows along with the row each folded request went into.
+func (a *App) groupRepeats(reqs []ngrok.Request) ([]ngrok.Request, map[string]int) {
+	a.groups = make(map[string]requestGroup)
+	collapsedInto := make(map[string]int)
+
+	var rows []ngrok.Request
+	for i := 0; i < len(reqs); {
+		j := i + 1
+		for j < len(reqs) && sameRequest(reqs[i], reqs[j]) {
+			j++
+		}
+		if j-i == 1 {
+			rows = append(rows, reqs[i])
+			i = j
+			continue
+		}
+
+		g := requestGroup{count: j - i, key: reqs[j-1].ID}
+		g.expanded = a.expandedGroups[g.key]
+		a.groups[reqs[i].ID] = g
+		if g.expanded {
+			rows = append(rows, reqs[i:j]...)
+		} else {
+			rows = append(rows, reqs[i])
+			for _, req := range reqs[i+1 : j] {
+				collapsedInto[req.ID] = len(rows) - 1
+			}
+		}
+		i = j
+	}
+	return rows, collapsedInto
+}
+
+// sameRequest reports whether two requests count as repeats of each other
+func sameRequest(a, b ngrok.Request) bool {
+	return a.Request.Method == b.Request.Method &&
+		a.Request.URI == b.Request.URI &&
+		a.StatusCode() == b.StatusCode()
+}
+
+// hiddenCount returns how many loaded requests are hidden with D
+func (a *App) hiddenCount() int {
+	if len(a.dismissed) == 0 {
+		return 0
+	}
+	n := 0
+	for _, req := range a.requests {
+		if a.dismissed[req.ID] {
+			n++
+		}
+	}
+	return n
+}
+
+// markRange marks every request between the last one toggled with v and the
+// current one
+func (a *App) markRange() {
+	anchor := a.selected
+	for i, req := range a.filteredReqs {
+		if req.ID == a.markAnchor {
+			anchor = i
+			break
+		}
+	}
+	for i := min(anchor, a.selected); i <= max(anchor, a.selected); i++ {
+		a.marked[a.filteredReqs[i].ID] = true
+	}
+	a.markAnchor = a.filteredReqs[a.selected].ID
+}
+
+// markedIDs returns the marked requests that are still loaded, in list order
+func (a *App) markedIDs() []string {
+	if len(a.marked) == 0 {
+		return nil
+	}
+	var ids []string
+	for _, req := range a.requests {
+		if a.marked[req.ID] {
+			ids = append(ids, req.ID)
+		}
+	}
+	return ids
+}
+
+// clearMarks drops all marked requests
+func (a *App) clearMarks() {
+	a.marked = make(map[string]bool)
+	a.markAnchor = ""
+}
+
+// isStarred reports whether a request is starred in history. Requests
+// loaded from history and those starred here are known; a live request is
+// looked up once it has been saved, and remembered.
+func (a *App) isStarred(id string) bool {
+	if starred, ok := a.starred[id]; ok {
+		return starred
+	}
+	if a.storage == nil || !a.recorder.Recorded(id) {
+		return false
+	}
+	starred := a.storage.IsStarred(id)
+	a.starred[id] = starred
+	return starred
+}
+
+// starRequests stars requests in history so retention never removes them
+func (a *App) starRequests(ids []string) tea.Cmd {
+	if a.storage == nil {
+		a.lastError = fmt.Errorf("history is unavailable, cannot star requests")
+		return nil
+	}
+	store := a.storage
+
+	// Live requests may still be queued for writing
+	var flushed <-chan struct{}
+	if a.writer != nil && !a.viewingHistory {
+		flushed = a.writer.Flush()
+	}
+
+	return func() tea.Msg {
+		if flushed != nil {
+			<-flushed
+		}
+		count, err := store.StarRequests(ids)
+		return messages.StarMsg{IDs: ids, Count: count, Err: err}
+	}
+}
+
+// moveSelection moves the list selection by delta rows, clamped to the list.
+// Like j/k, it pauses follow mode.
+func (a *App) moveSelection(delta int) {
+	if len(a.filteredReqs) == 0 {
+		return
+	}
+	a.following = false
+	a.selected = max(0, min(a.selected+delta, len(a.filteredReqs)-1))
+	a.updateDetailViewport()
+}
+
+// listContentHeight returns the height inside the request list panel's border
+// for the current layout
+func (a *App) listContentHeight() int {
+	height := a.contentHeight()
+	if a.width >= 120 || a.zoomed {
+		return height - 2
+	}
+	return a.stackedListHeight(height) - 2
+}
+
+// listVisibleLines returns how many requests fit in the list panel
+func (a *App) listVisibleLines() int {
+	return visibleListLines(a.listContentHeight())
+}
+
+// visibleListLines returns how many requests fit in a list panel of the
+// given content height, below the title
+func visibleListLines(height int) int {
+	return height - 2
+}
+
+// listScrollOffset returns the index of the first request shown when the list
+// has room for visibleLines rows. The list only scrolls as far as needed to
+// keep the selection in view.
+func (a *App) listScrollOffset(visibleLines int) int {
+	if a.selected < a.listOffset {
+		a.listOffset = a.selected
+	} else if a.selected >= a.listOffset+visibleLines {
+		a.listOffset = a.selected - visibleLines + 1
+	}
+	// Don't leave empty rows at the bottom when the list shrinks
+	a.listOffset = max(0, min(a.listOffset, len(a.filteredReqs)-visibleLines))
+	return a.listOffset
+}
+
+// handleClick selects the clicked request or focuses the clicked panel. A
+// click on a filter badge in the footer removes that filter.
+func (a *App) handleClick(x, y int) {
+	top := lipgloss.Height(a.renderHeader())
+	height := a.contentHeight()
+	if y >= top+height {
+		if y == top+height {
+			a.handleFooterClick(x)
+		}
+		return
+	}
+	if y < top {
+		return
+	}
+
+	var inList bool
+	switch {
+	case a.zoomed:
+		inList = !a.zoomDetail
+	case a.width >= 120:
+		// The box is the content width plus padding and border, see renderSideBySide
+		inList = x < a.sideBySideListWidth()-2
+	default:
+		inList = y < top+a.stackedListHeight(height)
+	}
+
+	if !inList {
+		a.focus = FocusDetailPanel
+		return
+	}
+	a.focus = FocusList
+
+	// Rows start below the top border and the list title
+	row := y - top - 2
+	visibleLines := a.listVisibleLines()
+	if row < 0 || row >= visibleLines {
+		return
+	}
+	idx := a.listScrollOffset(visibleLines) + row
+	if idx < len(a.filteredReqs) {
+		a.following = false
+		a.selected = idx
+		a.updateDetailViewport()
+	}
+}
+
+// handleFooterClick removes the filter whose footer badge is at column x
+func (a *App) handleFooterClick(x int) {
+	for i, span := range a.filterBadgeSpans {
+		if x < span[0] || x >= span[1] || i >= len(a.activeFilters) {
+			continue
+		}
+		a.removeFilter(i)
+		return
+	}
+}
+
+// removeFilter removes the i-th active filter. The operator joining it to the
+// next filter goes with it; a trailing operator left on the new last filter
+// is dropped.
+func (a *App) removeFilter(i int) {
+	a.activeFilters = removeFromChain(a.activeFilters, i)
+	if n := len(a.activeFilters); n > 0 {
+		a.activeFilters[n-1].LogicalOperator = ""
+	}
+	a.applyFilters()
+}
+
+// toggleStatusClassFilter shows only responses of a status class such as
+// "5xx", or clears that filter if it is already active. Only one class
+// filter is active at a time; it is ANDed with any other filters. The filter
+// is the one "status == 5xx" makes: "status in 500..599".
+func (a *App) toggleStatusClassFilter(class string) {
+	quick := normalizeStatusFilter(Filter{Field: "status", Operator: "==", Value: class})
+	for i, f := range a.activeFilters {
+		if f.Field != "status" || f.Operator != "in" || f.Not || !isStatusClassRange(f.Value) {
+			continue
+		}
+		a.removeFilter(i)
+		if f.Value == quick.Value {
+			return
+		}
+		break
+	}
+
+	if n := len(a.activeFilters); n > 0 {
+		a.activeFilters[n-1].LogicalOperator = "&&"
+	}
+	a.activeFilters = append(a.activeFilters, quick)
+	a.applyFilters()
+}
+
+// normalizeStatusFilter writes a status class filter such as "status == 5xx"
+// as the range the class covers, "status in 500..599", so the quick filters,
+// the wizard and expressions all make the same filter
+func normalizeStatusFilter(f Filter) Filter {
+	class, ok := statusClass(f.Value)
+	if f.Field != "status" || !ok {
+		return f
+	}
+	switch f.Operator {
+	case "!=":
+		f.Not = !f.Not
+		fallthrough
+	case "==", "in":
+		f.Operator = "in"
+		f.Value = fmt.Sprintf("%d..%d", class*100, class*100+99)
+	}
+	return f
+}
+
+// isStatusClassRange reports whether a range is a whole status class, as
+// the quick filters make
+func isStatusClassRange(value string) bool {
+	lo, hi, err := parseRange(value)
+	return err == nil && lo == hi-99 && int(lo)%100 == 0 && lo >= 100 && lo <= 500
+}
+
+// statusClass returns the leading digit of a status class such as "4xx"
+func statusClass(value string) (int, bool) {
+	if len(value) != 3 || !strings.EqualFold(value[1:], "xx") || value[0] < '1' || value[0] > '5' {
+		return 0, false
+	}
+	return int(value[0] - '0'), true
+}
+
 // renderFilterInPanel renders the filter UI inside the request list panel
 func (a *App) renderFilterInPanel(width, height int) string {
 	var lines []string
 
-	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(ColorPrimary)
-	mutedStyle := lipgloss.NewStyle().Foreground(ColorMuted)
-	selectedStyle := lipgloss.NewStyle().Foreground(ColorPrimary).Bold(true)
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(a.theme.ColorPrimary)
+	mutedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorMuted)
+	selectedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorPrimary).Bold(true)
 
 	// Show current filter chain being built
-	if len(a.activeFilters) > 0 {
+	if len(a.activeFilters) > 0 && a.filterStep != FilterStepManage {
 		var filterChain string
 		for i, f := range a.activeFilters {
 			if i > 0 {
 				filterChain += " "
 			}
-			filterChain += a.formatFilterBadge(f)
+			filterChain += a.formatGroupedFilter(f)
 			if f.LogicalOperator != "" {
 				filterChain += " " + f.LogicalOperator
 			}
@@ -2212,6 +4564,36 @@ func (a *App) renderFilterInPanel(width, height int) string {
 	}
 
 	switch a.filterStep {
+	case FilterStepManage:
+		lines = append(lines, titleStyle.Render("Active Filters"))
+		lines = append(lines, "")
+		for i, f := range a.activeFilters {
+			row := a.formatGroupedFilter(f)
+			if i < len(a.activeFilters)-1 {
+				join := f.LogicalOperator
+				if join == "" {
+					join = "&&"
+				}
+				row += "  " + join
+			}
+			if i == a.filterSelected {
+				lines = append(lines, selectedStyle.Render("▶ "+row))
+			} else {
+				lines = append(lines, "  "+row)
+			}
+		}
+		if a.filterSelected == len(a.activeFilters) {
+			lines = append(lines, selectedStyle.Render("▶ + Add filter"))
+		} else {
+			lines = append(lines, mutedStyle.Render("  + Add filter"))
+		}
+		lines = append(lines, "")
+		lines = append(lines, mutedStyle.Render("Enter: edit  Backspace: remove"))
+		lines = append(lines, mutedStyle.Render("Space: toggle &&/||  !: toggle not"))
+		lines = append(lines, mutedStyle.Render("(: open group  ): close group"))
+		lines = append(lines, mutedStyle.Render("Esc: close"))
+		return strings.Join(lines, "\n")
+
 	case FilterStepField:
 		lines = append(lines, titleStyle.Render("Select Field"))
 		lines = append(lines, "")
@@ -2245,6 +4627,16 @@ func (a *App) renderFilterInPanel(width, height int) string {
 			lines = append(lines, mutedStyle.Render("  No matching fields"))
 		}
 
+	case FilterStepHeaderName:
+		where := "request"
+		if a.pendingFilter.Field == customResponseHeader {
+			where = "response"
+		}
+		lines = append(lines, titleStyle.Render("Header Name"))
+		lines = append(lines, mutedStyle.Render("Any "+where+" header, as in X-Webhook-Event"))
+		lines = append(lines, "")
+		lines = append(lines, "> "+renderInputCursor(a.filterInput, a.filterCursor))
+
 	case FilterStepOperator:
 		field := a.getFieldByKey(a.pendingFilter.Field)
 		if field != nil {
@@ -2288,6 +4680,22 @@ func (a *App) renderFilterInPanel(width, height int) string {
 			lines = append(lines, mutedStyle.Render(filterDesc))
 			lines = append(lines, "")
 			lines = append(lines, "> "+a.filterInput+"█")
+			if p := a.preview; p != nil && p.value == a.filterInput && a.filterErr == "" {
+				lines = append(lines, mutedStyle.Render(fmt.Sprintf("→ %d of %d match", p.count, p.total)))
+			}
+			if a.filterErr != "" {
+				lines = append(lines, a.theme.ErrorStyle.Render(a.filterErr))
+			} else if strings.HasSuffix(a.pendingFilter.Operator, "match") {
+				lines = append(lines, mutedStyle.Render("/pattern/ for a regex"))
+			} else if a.pendingFilter.Operator == "in" {
+				lines = append(lines, mutedStyle.Render("a CIDR range like 52.0.0.0/8, or one address"))
+			} else if field.Type == FilterTypeTime && a.pendingFilter.Operator != "last" {
+				lines = append(lines, mutedStyle.Render("HH:MM (time of day), or RFC3339 like 2026-01-02T14:00:00Z"))
+			} else if field.Type == FilterTypeNumeric && a.pendingFilter.Operator == "in" {
+				lines = append(lines, mutedStyle.Render("a range like 400..499, or a class like 4xx"))
+			} else if field.Type == FilterTypeBool {
+				lines = append(lines, mutedStyle.Render("true or false"))
+			}
 		}
 
 	case FilterStepLogical:
@@ -2318,6 +4726,9 @@ func (a *App) renderFilterInPanel(width, height int) string {
 // formatFilterBadge formats a filter as a display string
 func (a *App) formatFilterBadge(f Filter) string {
 	result := f.Field + " " + f.Operator
+	if f.Not {
+		result = "not " + result
+	}
 	if f.Unit != "" {
 		result += " " + f.Value + f.Unit
 	} else {
@@ -2326,14 +4737,27 @@ func (a *App) formatFilterBadge(f Filter) string {
 	return result
 }
 
+// formatGroupedFilter formats a filter with the groups it opens and closes,
+// as in "( path match /api"
+func (a *App) formatGroupedFilter(f Filter) string {
+	result := a.formatFilterBadge(f)
+	if f.Open != "" {
+		result = groupOpenText(f.Open) + " " + result
+	}
+	if f.Close > 0 {
+		result += " " + strings.Repeat(")", f.Close)
+	}
+	return result
+}
+
 // renderReplayEditInPanel renders the replay edit UI inside the request list panel
 func (a *App) renderReplayEditInPanel(width, height int) string {
 	var lines []string
 
-	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(ColorPrimary)
-	mutedStyle := lipgloss.NewStyle().Foreground(ColorMuted)
-	selectedStyle := lipgloss.NewStyle().Foreground(ColorPrimary).Bold(true)
-	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#10B981"))
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(a.theme.ColorPrimary)
+	mutedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorMuted)
+	selectedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorPrimary).Bold(true)
+	valueStyle := lipgloss.NewStyle().Foreground(a.theme.ColorSecondary)
 
 	switch a.replayEditStep {
 	case ReplayEditStepMain:
@@ -2346,9 +4770,14 @@ func (a *App) renderReplayEditInPanel(width, height int) string {
 		}{
 			{"Method", a.replayEditMethod},
 			{"Path", a.replayEditPath},
+			{"Query Params", fmt.Sprintf("(%d)", len(parseQueryParams(a.replayEditPath)))},
 			{"Headers", fmt.Sprintf("(%d)", len(a.replayEditHeaders))},
-			{"Body", fmt.Sprintf("(%d bytes)", len(a.replayEditBody))},
+			{"Body", a.replayBodyLabel()},
+			{"Assertions", fmt.Sprintf("(%d)", len(a.replayEditAssertions))},
+			{"Target", a.replayTargetLabel()},
 			{"► Send Request", ""},
+			{"↻ Send Repeatedly", ""},
+			{"+ Save as Template", a.replayEditTemplate},
 			{"✕ Cancel", ""},
 		}
 
@@ -2389,6 +4818,38 @@ func (a *App) renderReplayEditInPanel(width, height int) string {
 		}
 		lines = append(lines, "> "+input)
 
+	case ReplayEditStepParams, ReplayEditStepParamEdit:
+		return a.renderReplayEditParams(width, height)
+
+	case ReplayEditStepAssertions, ReplayEditStepAssertionEdit:
+		return a.renderReplayEditAssertions(width, height)
+
+	case ReplayEditStepPreview:
+		lines = append(lines, titleStyle.Render("Send this request?"))
+		lines = append(lines, "")
+		lines = append(lines, mutedStyle.Render("The request as it will be sent is"))
+		lines = append(lines, mutedStyle.Render("shown on the right."))
+		lines = append(lines, "")
+		lines = append(lines, "Enter: send")
+		lines = append(lines, "c: copy as cURL without sending")
+		lines = append(lines, "Esc: back to editing")
+		return strings.Join(lines, "\n")
+
+	case ReplayEditStepTargetURL:
+		lines = append(lines, titleStyle.Render("Send To"))
+		lines = append(lines, mutedStyle.Render("A URL such as http://localhost:8080"))
+		lines = append(lines, "")
+		lines = append(lines, "> "+renderInputCursor(a.replayEditInput, a.replayEditCursor))
+		if a.replayEditErr != "" {
+			lines = append(lines, "", a.theme.ErrorStyle.Render(a.replayEditErr))
+		}
+
+	case ReplayEditStepTemplateName:
+		lines = append(lines, titleStyle.Render("Save as Template"))
+		lines = append(lines, mutedStyle.Render("A template of the same name is replaced"))
+		lines = append(lines, "")
+		lines = append(lines, "> "+renderInputCursor(a.replayEditInput, a.replayEditCursor))
+
 	case ReplayEditStepHeaders:
 		lines = append(lines, titleStyle.Render("Edit Headers"))
 		lines = append(lines, mutedStyle.Render("Enter: edit  Backspace: delete"))
@@ -2488,7 +4949,10 @@ func (a *App) renderReplayEditInPanel(width, height int) string {
 		}
 
 		lines = append(lines, "")
-		lines = append(lines, mutedStyle.Render("Tab: save  Esc: cancel"))
+		if status := a.renderJSONStatus(a.replayEditInput); status != "" {
+			lines = append(lines, status)
+		}
+		lines = append(lines, mutedStyle.Render("Tab: save  Ctrl+f: format  Esc: cancel"))
 		return strings.Join(lines, "\n")
 	}
 
@@ -2500,23 +4964,111 @@ func (a *App) renderReplayEditInPanel(width, height int) string {
 
 // renderRequestLine renders a single request line (compact mode)
 func (a *App) renderRequestLine(req ngrok.Request, width int, selected bool) string {
+	age := lipgloss.NewStyle().
+		Foreground(a.theme.ColorMuted).
+		Width(6).
+		Align(lipgloss.Right).
+		Render(formatRelativeTime(req.Start))
+
+	group, grouped := a.groups[req.ID]
+	key := requestLineKey{
+		status:   req.StatusCode(),
+		duration: req.Duration,
+		width:    width,
+		wide:     a.width >= 120,
+		columns:  a.showColumns,
+		selected: selected,
+		marked:   a.marked[req.ID],
+		replay:   a.isReplayedRequest(req.ID) || a.replayOf[req.ID] != "",
+		diffA:    a.diffIDA,
+		diffB:    a.diffIDB,
+		group:    group,
+		grouped:  grouped,
+		search:   a.searchQuery,
+	}
+	if c, ok := a.lineCache[req.ID]; ok && c.key == key {
+		return c.line + age
+	}
+	line := a.renderRequestRow(req, width, selected)
+	if a.lineCache == nil {
+		a.lineCache = make(map[string]cachedLine)
+	}
+	a.lineCache[req.ID] = cachedLine{key: key, line: line}
+	return line + age
+}
+
+// requestLineKey is everything a row of the list depends on besides its
+// age, which is rendered each time
+type requestLineKey struct {
+	status       int
+	duration     int64
+	width        int
+	wide         bool
+	columns      bool
+	selected     bool
+	marked       bool
+	replay       bool
+	diffA, diffB string
+	group        requestGroup
+	grouped      bool
+	search       string
+}
+
+// cachedLine is a rendered row of the list and what it was rendered from
+type cachedLine struct {
+	key  requestLineKey
+	line string
+}
+
+// renderRequestRow renders a row of the list up to its age
+func (a *App) renderRequestRow(req ngrok.Request, width int, selected bool) string {
 	statusCode := req.StatusCode()
-	timeAgo := formatRelativeTime(req.Start)
 
 	// Check if this is a diff-selected request
-	isDiffA := a.diffRequestA != nil && a.diffRequestA.ID == req.ID
-	isDiffB := a.diffRequestB != nil && a.diffRequestB.ID == req.ID
+	isDiffA := a.diffIDA != "" && a.diffIDA == req.ID
+	isDiffB := a.diffIDB != "" && a.diffIDB == req.ID
 
 	// Compact format: "▶ METHOD  STATUS PATH         TIME"
 	// Widths:          2  8       4     var          6
 	// METHOD is 8 chars to fit "OPTIONS" (7) + space
 	// Extra 4 chars for [A]/[B] marker when diff is active
 	extraWidth := 0
-	if a.diffRequestA != nil || a.diffRequestB != nil {
+	if a.diffIDA != "" || a.diffIDB != "" {
 		extraWidth = 4
 	}
 	fixedWidth := 2 + 8 + 4 + 6 + extraWidth
-	pathWidth := width - fixedWidth
+
+	// Optional columns are dropped, remote address first, then size, rather
+	// than squeeze the path. The stacked layout gives them up sooner since its
+	// rows are more useful with a readable path.
+	durWidth, sizeWidth, remoteWidth := 0, 0, 0
+	if a.showColumns {
+		minPathWidth := 12
+		if a.width < 120 {
+			minPathWidth = 24
+		}
+		if width-fixedWidth-durationColumnWidth >= minPathWidth {
+			durWidth = durationColumnWidth
+		}
+		if width-fixedWidth-durWidth-sizeColumnWidth >= minPathWidth {
+			sizeWidth = sizeColumnWidth
+		}
+		if width-fixedWidth-durWidth-sizeWidth-remoteColumnWidth >= minPathWidth {
+			remoteWidth = remoteColumnWidth
+		}
+	}
+
+	// Runs of repeats show how many requests the row stands for
+	var multiplier string
+	if g, ok := a.groups[req.ID]; ok {
+		marker := fmt.Sprintf("×%d ", g.count)
+		if g.expanded {
+			marker = "▾" + marker
+		}
+		multiplier = lipgloss.NewStyle().Foreground(a.theme.ColorWarning).Bold(true).Render(marker)
+	}
+
+	pathWidth := width - fixedWidth - durWidth - sizeWidth - remoteWidth - lipgloss.Width(multiplier)
 	if pathWidth < 8 {
 		pathWidth = 8
 	}
@@ -2525,19 +5077,26 @@ func (a *App) renderRequestLine(req ngrok.Request, width int, selected bool) str
 
 	// Build the line with proper formatting
 	var indicator string
-	if selected {
-		indicator = lipgloss.NewStyle().Foreground(ColorPrimary).Bold(true).Render("▶ ")
-	} else {
+	switch {
+	case selected && a.marked[req.ID]:
+		indicator = lipgloss.NewStyle().Foreground(a.theme.ColorSecondary).Bold(true).Render("▶ ")
+	case selected:
+		indicator = lipgloss.NewStyle().Foreground(a.theme.ColorPrimary).Bold(true).Render("▶ ")
+	case a.marked[req.ID]:
+		indicator = lipgloss.NewStyle().Foreground(a.theme.ColorSecondary).Bold(true).Render("● ")
+	case a.isReplayedRequest(req.ID), a.replayOf[req.ID] != "":
+		indicator = lipgloss.NewStyle().Foreground(a.theme.ColorInfo).Bold(true).Render("↻ ")
+	default:
 		indicator = "  "
 	}
 
 	// Add diff marker
 	var diffMarker string
-	if a.diffRequestA != nil || a.diffRequestB != nil {
+	if a.diffIDA != "" || a.diffIDB != "" {
 		if isDiffA {
-			diffMarker = lipgloss.NewStyle().Foreground(lipgloss.Color("#FBBF24")).Bold(true).Render("[A] ")
+			diffMarker = lipgloss.NewStyle().Foreground(a.theme.ColorWarning).Bold(true).Render("[A] ")
 		} else if isDiffB {
-			diffMarker = lipgloss.NewStyle().Foreground(lipgloss.Color("#60A5FA")).Bold(true).Render("[B] ")
+			diffMarker = lipgloss.NewStyle().Foreground(a.theme.ColorInfo).Bold(true).Render("[B] ")
 		} else {
 			diffMarker = "    "
 		}
@@ -2548,71 +5107,103 @@ func (a *App) renderRequestLine(req ngrok.Request, width int, selected bool) str
 	statusStr := fmt.Sprintf("%d", statusCode)
 
 	if a.searchQuery != "" {
-		methodStr = a.highlightText(methodStr)
-		statusStr = a.highlightText(statusStr)
-		pathStr = a.highlightText(pathStr)
+		methodStr = a.highlightText(methodStr, scopeAny)
+		statusStr = a.highlightText(statusStr, scopeStatus)
+		pathStr = a.highlightText(pathStr, scopePath)
 	}
 
 	method := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(MethodColor(req.Request.Method)).
+		Foreground(a.theme.MethodColor(req.Request.Method)).
 		Width(8).
 		Render(methodStr)
 
 	status := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(StatusCodeColor(statusCode)).
+		Foreground(a.theme.StatusCodeColor(statusCode)).
 		Width(4).
 		Render(statusStr)
 
 	path := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#D1D5DB")).
+		Foreground(a.theme.ColorSubtle).
 		Width(pathWidth).
 		Render(pathStr)
 
-	time := lipgloss.NewStyle().
-		Foreground(ColorMuted).
-		Width(6).
-		Align(lipgloss.Right).
-		Render(timeAgo)
+	var columns string
+	if remoteWidth > 0 {
+		columns += lipgloss.NewStyle().
+			Foreground(a.theme.ColorMuted).
+			Width(remoteWidth).
+			Align(lipgloss.Right).
+			Render(util.TruncateString(req.RemoteAddr, remoteWidth-1))
+	}
+	if durWidth > 0 {
+		columns += lipgloss.NewStyle().
+			Foreground(a.theme.DurationColor(req.DurationMs())).
+			Width(durWidth).
+			Align(lipgloss.Right).
+			Render(formatListDuration(req.DurationMs()))
+	}
+	if sizeWidth > 0 {
+		columns += lipgloss.NewStyle().
+			Foreground(a.theme.ColorMuted).
+			Width(sizeWidth).
+			Align(lipgloss.Right).
+			Render(util.FormatBytes(int64(a.responseSize(req))))
+	}
+
+	return fmt.Sprintf("%s%s%s%s%s%s%s", indicator, diffMarker, method, status, multiplier, path, columns)
+}
 
-	return fmt.Sprintf("%s%s%s%s%s%s", indicator, diffMarker, method, status, path, time)
+// Widths of the optional request list columns, including a leading gap
+const (
+	durationColumnWidth = 7  // " 1234ms"
+	sizeColumnWidth     = 9  // " 123.4 KB"
+	remoteColumnWidth   = 16 // " 203.0.113.255", longer addresses are cut short
+)
+
+// formatListDuration formats a duration in milliseconds to fit the list column
+func formatListDuration(ms float64) string {
+	switch {
+	case ms < 1000:
+		return fmt.Sprintf("%dms", int(ms))
+	case ms < 10_000:
+		return fmt.Sprintf("%.1fs", ms/1000)
+	default:
+		return fmt.Sprintf("%ds", int(ms/1000))
+	}
 }
 
-// highlightText highlights search query matches in text with yellow background
-func (a *App) highlightText(text string) string {
-	if a.searchQuery == "" {
+// highlightText highlights search query matches in text with yellow
+// background, and the current detail panel match in orange. Scoped terms
+// only highlight text of their own scope.
+func (a *App) highlightText(text string, scope searchScope) string {
+	spans := a.search.find(text, scope)
+	if len(spans) == 0 {
 		return text
 	}
 
-	query := strings.ToLower(a.searchQuery)
-	lowerText := strings.ToLower(text)
-
-	// Find all match positions
 	var result strings.Builder
 	lastEnd := 0
 
-	highlightStyle := lipgloss.NewStyle().
-		Background(lipgloss.Color("#FBBF24")).
-		Foreground(lipgloss.Color("#000000"))
-
-	for {
-		idx := strings.Index(lowerText[lastEnd:], query)
-		if idx == -1 {
-			result.WriteString(text[lastEnd:])
-			break
-		}
-
-		matchStart := lastEnd + idx
-		matchEnd := matchStart + len(a.searchQuery)
+	for _, span := range spans {
+		matchStart, matchEnd := span[0], span[1]
 
 		// Add text before match
 		result.WriteString(text[lastEnd:matchStart])
 		// Add highlighted match (preserve original case)
-		result.WriteString(highlightStyle.Render(text[matchStart:matchEnd]))
+		style := a.theme.SearchMatchStyle
+		if a.countMatches {
+			if a.matchSeq == a.matchIndex {
+				style = a.theme.CurrentMatchStyle
+			}
+			a.matchSeq++
+		}
+		result.WriteString(style.Render(text[matchStart:matchEnd]))
 
 		lastEnd = matchEnd
 	}
+	result.WriteString(text[lastEnd:])
 
 	return result.String()
 }
@@ -2639,6 +5230,18 @@ func formatRelativeTime(t time.Time) string {
 	}
 }
 
+// formatSessionDuration formats how long a session ran (e.g., "45m", "2h10m")
+func formatSessionDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
+
 // httpStatusText returns the standard HTTP status text for a status code
 func httpStatusText(code int) string {
 	statusTexts := map[int]string{
@@ -2690,14 +5293,23 @@ func (a *App) renderDetailPanel(width, height int) string {
 	if a.focus == FocusDiff {
 		return a.renderDiffView(width, height)
 	}
+	if a.focus == FocusReplayResult {
+		return a.renderReplayResult(width, height)
+	}
+	if a.focus == FocusReplaySummary {
+		return a.renderReplaySummary(width, height)
+	}
+	if a.focus == FocusReplayEdit && a.replayEditStep == ReplayEditStepPreview {
+		return a.renderReplayPreview(width, height)
+	}
 
 	if len(a.filteredReqs) == 0 || a.selected >= len(a.filteredReqs) {
 		return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center,
 			"Select a request to view details")
 	}
 
-	// Use viewport for scrollable content
-	return a.detailViewport.View()
+	// Tab bar above the scrollable content
+	return a.renderDetailTabs() + "\n\n" + a.detailViewport.View()
 }
 
 // renderDiffView renders the diff comparison view
@@ -2707,18 +5319,26 @@ func (a *App) renderDiffView(width, height int) string {
 			"No diff to display")
 	}
 
+	// Fit the text area, which is the content width less the padding, so
+	// the columns aren't wrapped a second time
+	width -= 2
+
 	// Update viewport size if needed
 	if a.diffViewport.Width != width || a.diffViewport.Height != height {
 		a.diffViewport.Width = width
 		a.diffViewport.Height = height
 	}
 
-	// Generate diff content
-	content := a.generateDiff()
-
-	// Wrap content to fit width
-	wrappedContent := lipgloss.NewStyle().Width(width).Render(content)
-	a.diffViewport.SetContent(wrappedContent)
+	// Generate diff content. Side by side, both columns are one viewport's
+	// content, so they scroll together.
+	var content string
+	a.diffRows = a.generateDiff()
+	if a.splitDiff() {
+		content, a.diffRowLines = a.renderSplitDiff(a.diffRows, width)
+	} else {
+		content, a.diffRowLines = a.renderUnifiedDiff(a.diffRows, width)
+	}
+	a.diffViewport.SetContent(content)
 
 	return a.diffViewport.View()
 }
@@ -2730,9 +5350,9 @@ func (a *App) renderHistoryView(width, height int) string {
 			"Storage not available")
 	}
 
-	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(ColorPrimary)
-	selectedStyle := lipgloss.NewStyle().Foreground(ColorPrimary).Bold(true)
-	mutedStyle := lipgloss.NewStyle().Foreground(ColorMuted)
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(a.theme.ColorPrimary)
+	selectedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorPrimary).Bold(true)
+	mutedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorMuted)
 
 	var lines []string
 
@@ -2753,11 +5373,22 @@ func (a *App) renderHistoryView(width, height int) string {
 			sess := a.historySessions[i]
 			dateStr := sess.StartedAt.Format("Jan 02, 15:04")
 
-			// Count requests in session
-			reqs, _ := a.storage.GetSessionRequests(sess.ID)
-			reqCount := len(reqs)
+			reqCount := "? requests"
+			if n, ok := a.historyCounts[sess.ID]; ok {
+				reqCount = fmt.Sprintf("%d requests", n)
+			}
 
-			line := fmt.Sprintf("%s (%d requests)", dateStr, reqCount)
+			line := fmt.Sprintf("%s (%s)", dateStr, reqCount)
+			if sess.EndedAt != nil {
+				line = fmt.Sprintf("%s, %s (%s)", dateStr, formatSessionDuration(sess.EndedAt.Sub(sess.StartedAt)), reqCount)
+			}
+			if sess.Label != "" {
+				line = sess.Label + " · " + line
+			}
+			if i == a.historySelectedSess && a.historyRenaming {
+				lines = append(lines, selectedStyle.Render("▶ name: ")+renderInputCursor(a.historyRenameInput, a.historyRenameCursor))
+				continue
+			}
 			if sess.TunnelURL != "" {
 				// Truncate URL if too long
 				url := sess.TunnelURL
@@ -2768,111 +5399,430 @@ func (a *App) renderHistoryView(width, height int) string {
 				line += " - " + url
 			}
 
-			if i == a.historySelectedSess {
-				lines = append(lines, selectedStyle.Render("▶ "+line))
-			} else {
-				lines = append(lines, "  "+line)
-			}
+			if i == a.historySelectedSess {
+				lines = append(lines, selectedStyle.Render("▶ "+line))
+			} else {
+				lines = append(lines, "  "+line)
+			}
+		}
+	}
+
+	lines = append(lines, "")
+	if a.historySearching {
+		lines = append(lines, selectedStyle.Render("Search all sessions: ")+renderInputCursor(a.historySearchInput, a.historySearchCursor))
+		lines = append(lines, mutedStyle.Render("Enter: search  Esc: cancel"))
+	} else if a.historyRenaming {
+		lines = append(lines, mutedStyle.Render("Enter: save name  Esc: cancel"))
+	} else {
+		back := "Esc: back"
+		if a.offline && !a.viewingHistory {
+			back = "q: quit"
+		}
+		lines = append(lines, mutedStyle.Render("j/k: nav  Enter: load session  /: search all  r: rename  "+back))
+	}
+
+	return a.renderFullView(lines, width, height)
+}
+
+// renderFullView renders the lines of a view that takes the whole content
+// area in a border, cutting what doesn't fit rather than wrapping it
+func (a *App) renderFullView(lines []string, width, height int) string {
+	// Border and padding take 4 columns and the border 2 rows
+	lines = lines[:min(len(lines), max(height-2, 0))]
+	for i, line := range lines {
+		lines[i] = clipLine(line, width-4)
+	}
+	return a.theme.BorderStyle.Width(width - 2).Height(height - 2).Render(strings.Join(lines, "\n"))
+}
+
+// renderStatsView renders storage statistics and retention settings
+func (a *App) renderStatsView(width, height int) string {
+	if a.storage == nil {
+		return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center,
+			"Storage not available")
+	}
+
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(a.theme.ColorPrimary)
+	labelStyle := lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Width(16)
+	mutedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorMuted)
+
+	row := func(label, value string) string {
+		return labelStyle.Render(label) + value
+	}
+	limit := func(n int, unit string) string {
+		if n == 0 {
+			return "never"
 		}
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+
+	st := a.stats
+	var lines []string
+
+	lines = append(lines, titleStyle.Render("Storage"))
+	lines = append(lines, "")
+	location := a.storage.Path()
+	if a.storage.InMemory() {
+		location = "in memory (not saved)"
+	}
+	lines = append(lines, row("Database", location))
+	lines = append(lines, row("Size", util.FormatBytes(st.SizeBytes)))
+	lines = append(lines, row("Sessions", fmt.Sprintf("%d", st.Sessions)))
+	lines = append(lines, row("Requests", fmt.Sprintf("%d (%d starred)", st.Requests, st.Starred)))
+	if !st.Oldest.IsZero() {
+		lines = append(lines, row("Date range", fmt.Sprintf("%s – %s",
+			st.Oldest.Local().Format("Jan 02, 2006 15:04"), st.Newest.Local().Format("Jan 02, 2006 15:04"))))
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, titleStyle.Render("Retention"))
+	lines = append(lines, "")
+	lines = append(lines, row("Remove after", limit(a.retention.KeepDays, "days")))
+	if a.retention.KeepCount == 0 {
+		lines = append(lines, row("Always keep", "all requests"))
+	} else {
+		lines = append(lines, row("Always keep", fmt.Sprintf("last %d requests", a.retention.KeepCount)))
+	}
+	if a.retention.MaxSizeMB == 0 {
+		lines = append(lines, row("Max size", "no limit"))
+	} else {
+		lines = append(lines, row("Max size", fmt.Sprintf("%d MB", a.retention.MaxSizeMB)))
 	}
+	lines = append(lines, row("Starred", "always kept"))
 
 	lines = append(lines, "")
-	lines = append(lines, mutedStyle.Render("j/k: nav  Enter: load session  Esc: back"))
+	if a.compacting {
+		lines = append(lines, fmt.Sprintf("%s Compacting database...", a.spinner.View()))
+	} else {
+		lines = append(lines, mutedStyle.Render("c: compact database  Esc: back"))
+	}
 
-	content := strings.Join(lines, "\n")
+	return a.renderFullView(lines, width, height)
+}
 
-	return BorderStyle.Width(width - 2).Height(height - 2).Render(content)
+// truncatedNote explains that a stored body is incomplete
+func (a *App) truncatedNote(stored, size int) string {
+	return lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Italic(true).Render(
+		fmt.Sprintf("  (body truncated at %s of %s)", util.FormatBytes(int64(stored)), util.FormatBytes(int64(size))))
 }
 
-// renderRequestDetail renders request details
+// renderRequestDetail renders the active detail tab for a request
 func (a *App) renderRequestDetail(req ngrok.Request, width, height int, full bool) string {
-	var sb strings.Builder
+	var query string
+	if a.queryResult != "" && a.queryReqID == req.ID {
+		query = a.theme.DetailLabelStyle.Render("Query "+a.lastQuery+":") + "\n" +
+			indentLines(util.FormatBody(a.queryResult, "application/json"), "  ") + "\n\n"
+	}
+
+	switch a.detailTab {
+	case TabRequest:
+		return query + a.renderRequestTab(req)
+	case TabResponse:
+		return query + a.renderResponseTab(req)
+	case TabRaw:
+		return query + a.renderRawTab(req)
+	}
+	return query + a.renderOverviewTab(req)
+}
 
+// renderDetailTitle renders the method badge and endpoint heading every tab
+func (a *App) renderDetailTitle(req ngrok.Request) string {
 	// Title with colored method (badge style)
-	method := lipgloss.NewStyle().
+	method := a.theme.Badge(a.theme.MethodColor(req.Request.Method), a.theme.ColorOnAccent).
 		Bold(true).
-		Foreground(lipgloss.Color("#000000")).
-		Background(MethodColor(req.Request.Method)).
 		Padding(0, 1).
 		Render(req.Request.Method)
 
 	// Highlight endpoint if search is active
 	endpointText := req.Request.URI
 	if a.searchQuery != "" {
-		endpointText = a.highlightText(endpointText)
+		endpointText = a.highlightText(endpointText, scopePath)
 	}
 	endpoint := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("#FFFFFF")).
+		Foreground(a.theme.ColorText).
 		Render(endpointText)
-	sb.WriteString(fmt.Sprintf("%s %s\n\n", method, endpoint))
+	return fmt.Sprintf("%s %s\n\n", method, endpoint)
+}
 
-	// Status with full text
+// renderStatusLine renders the response status with its full text
+func (a *App) renderStatusLine(req ngrok.Request) string {
 	statusCode := req.StatusCode()
 	statusText := fmt.Sprintf("%d %s", statusCode, httpStatusText(statusCode))
 	if a.searchQuery != "" {
-		statusText = a.highlightText(statusText)
+		statusText = a.highlightText(statusText, scopeStatus)
+	}
+	status := a.theme.StatusStyle.Foreground(a.theme.StatusCodeColor(statusCode)).Render(statusText)
+	return fmt.Sprintf("Status:   %s\n", status)
+}
+
+// renderOverviewTab renders status, timing and sizes
+func (a *App) renderOverviewTab(req ngrok.Request) string {
+	var sb strings.Builder
+
+	// Where a history search matched
+	if match, ok := a.historySearchMatches[req.ID]; ok && a.historySearchQuery != "" && match.MatchedField != "" {
+		sb.WriteString(lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Render(
+			fmt.Sprintf("matched in %s: %s", match.MatchedField, match.Snippet)) + "\n\n")
+	}
+
+	// Note attached to the request
+	if note := a.notes[req.ID]; note != "" {
+		if a.searchQuery != "" {
+			note = a.highlightText(note, scopeAny)
+		}
+		sb.WriteString(a.theme.NoteStyle.Render("✎ "+note) + "\n\n")
 	}
-	status := StatusStyle.Foreground(StatusCodeColor(statusCode)).Render(statusText)
-	sb.WriteString(fmt.Sprintf("Status:   %s\n", status))
 
-	// Duration
+	sb.WriteString(a.renderReplayLineage(req))
+	sb.WriteString(a.renderDetailTitle(req))
+	sb.WriteString(a.renderStatusLine(req))
 	sb.WriteString(fmt.Sprintf("Duration: %.2fms\n", req.DurationMs()))
+	sb.WriteString(fmt.Sprintf("Time:     %s\n", req.Start.Format("2006-01-02 15:04:05")))
+	if req.RemoteAddr != "" {
+		sb.WriteString(fmt.Sprintf("Remote:   %s\n", req.RemoteAddr))
+	}
+
+	// Sizes of the original bodies, even if history stored less
+	reqSize := req.StoredRequestSize
+	if reqSize == 0 {
+		reqBody, _ := a.decodedBodies(req)
+		reqSize = len(reqBody)
+	}
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("Request:  %s, %d headers\n", util.FormatBytes(int64(reqSize)), len(req.Request.Headers)))
+	sb.WriteString(fmt.Sprintf("Response: %s, %d headers\n", util.FormatBytes(int64(a.responseSize(req))), len(req.Response.Headers)))
+
+	return sb.String()
+}
+
+// renderRequestTab renders the request headers and body
+func (a *App) renderRequestTab(req ngrok.Request) string {
+	var sb strings.Builder
+	sb.WriteString(a.renderDetailTitle(req))
 
-	// Timestamp
-	timestamp := req.Start.Format("2006-01-02 15:04:05")
-	sb.WriteString(fmt.Sprintf("Time:     %s\n", timestamp))
+	// Query parameters, decoded, in the order they were sent
+	if params := util.QueryParams(req.Request.URI); len(params) > 0 {
+		sb.WriteString(a.theme.DetailLabelStyle.Render("Query Parameters:"))
+		sb.WriteString("\n")
+		for _, param := range params {
+			if a.searchQuery != "" {
+				param = a.highlightText(param, scopePath)
+			}
+			sb.WriteString("  " + param + "\n")
+		}
+		sb.WriteString("\n")
+	}
 
 	// Request headers (sorted to prevent flickering)
-	sb.WriteString("\n")
-	sb.WriteString(DetailLabelStyle.Render("Request Headers:"))
+	sb.WriteString(a.theme.DetailLabelStyle.Render("Request Headers:"))
 	sb.WriteString("\n")
 	sb.WriteString(a.renderHeaders(req.Request.Headers))
+	sb.WriteString(a.renderCookies(util.FormatCookies(req.Request.Headers)))
 
 	// Request body (if available) - decode from base64
-	reqBody := req.Request.DecodeBody()
+	reqBody, _ := a.decodedBodies(req)
 	if reqBody != "" {
 		sb.WriteString("\n")
-		sb.WriteString(DetailLabelStyle.Render("Request Body:"))
+		sb.WriteString(a.theme.DetailLabelStyle.Render("Request Body:"))
 		sb.WriteString("\n")
-		reqContentType := ""
-		if ct, ok := req.Request.Headers["Content-Type"]; ok && len(ct) > 0 {
-			reqContentType = ct[0]
+		if graphQL, ok := util.FormatGraphQL(reqBody, req.Request.URI); ok && !a.hexToggled {
+			sb.WriteString(a.renderFormattedBody(graphQL))
+		} else {
+			sb.WriteString(a.renderBody(reqBody, req.Request.Headers))
 		}
-		formattedReqBody := util.FormatBody(reqBody, reqContentType)
-		if a.searchQuery != "" {
-			formattedReqBody = a.highlightText(formattedReqBody)
+		if req.StoredRequestSize > len(reqBody) {
+			sb.WriteString("\n" + a.truncatedNote(len(reqBody), req.StoredRequestSize))
 		}
-		sb.WriteString(indentLines(formattedReqBody, "  "))
-		sb.WriteString("\n") // Extra blank line after request body
 	}
 
+	return sb.String()
+}
+
+// renderResponseTab renders the response status, headers and body
+func (a *App) renderResponseTab(req ngrok.Request) string {
+	var sb strings.Builder
+	sb.WriteString(a.renderDetailTitle(req))
+	sb.WriteString(a.renderStatusLine(req))
+
 	// Response headers (sorted to prevent flickering)
 	sb.WriteString("\n")
-	sb.WriteString(DetailLabelStyle.Render("Response Headers:"))
+	sb.WriteString(a.theme.DetailLabelStyle.Render("Response Headers:"))
 	sb.WriteString("\n")
 	sb.WriteString(a.renderHeaders(req.Response.Headers))
+	sb.WriteString(a.renderCookies(util.FormatSetCookies(req.Response.Headers)))
 
 	// Response body (if available) - decode from base64
-	respBody := req.Response.DecodeBody()
+	_, respBody := a.decodedBodies(req)
 	if respBody != "" {
 		sb.WriteString("\n")
-		sb.WriteString(DetailLabelStyle.Render("Response Body:"))
+		sb.WriteString(a.theme.DetailLabelStyle.Render("Response Body:"))
 		sb.WriteString("\n")
-		respContentType := ""
-		if ct, ok := req.Response.Headers["Content-Type"]; ok && len(ct) > 0 {
-			respContentType = ct[0]
+		sb.WriteString(a.renderBody(respBody, req.Response.Headers))
+		if req.StoredResponseSize > len(respBody) {
+			sb.WriteString("\n" + a.truncatedNote(len(respBody), req.StoredResponseSize))
 		}
-		formattedRespBody := util.FormatBody(respBody, respContentType)
+	}
+
+	return sb.String()
+}
+
+// renderCookies renders a Cookies section from parsed cookie lines, or
+// nothing when there are none
+func (a *App) renderCookies(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n")
+	sb.WriteString(a.theme.DetailLabelStyle.Render("Cookies:"))
+	sb.WriteString("\n")
+	for _, line := range lines {
 		if a.searchQuery != "" {
-			formattedRespBody = a.highlightText(formattedRespBody)
+			line = a.highlightText(line, scopeHeader)
+		}
+		sb.WriteString("  " + line + "\n")
+	}
+	return sb.String()
+}
+
+// renderBody formats a body for its content type, indented under its label
+func (a *App) renderBody(body string, headers map[string][]string) string {
+	contentType := ""
+	if ct, ok := headers["Content-Type"]; ok && len(ct) > 0 {
+		contentType = ct[0]
+	}
+	// Neither view of a binary body goes through search highlighting, which
+	// can't make sense of it
+	binary := util.IsBinary(body)
+	if binary != a.hexToggled {
+		return indentLines(util.HexDump(body, a.hexDumpBytes), "  ")
+	}
+	if binary {
+		return indentLines(util.PrintableText(body), "  ")
+	}
+	return a.renderFormattedBody(util.FormatBody(body, contentType))
+}
+
+// renderFormattedBody highlights search matches in a formatted body and
+// indents it under its label
+func (a *App) renderFormattedBody(formatted string) string {
+	if a.searchQuery != "" {
+		formatted = a.highlightText(formatted, scopeBody)
+	}
+	return indentLines(formatted, "  ")
+}
+
+// renderRawTab renders the request and response as raw HTTP messages
+func (a *App) renderRawTab(req ngrok.Request) string {
+	var sb strings.Builder
+	sb.WriteString(a.renderDetailTitle(req))
+
+	reqRaw, resRaw := req.Request.DecodeRaw(), req.Response.DecodeRaw()
+	if a.viewingHistory {
+		// History only keeps the parsed parts, so put the messages back together
+		sb.WriteString(lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Italic(true).
+			Render("(rebuilt from history)") + "\n\n")
+		reqBody, resBody := a.decodedBodies(req)
+		reqRaw = rawHTTPMessage(fmt.Sprintf("%s %s HTTP/1.1", req.Request.Method, req.Request.URI),
+			req.Request.Headers, reqBody)
+		resRaw = rawHTTPMessage(fmt.Sprintf("HTTP/1.1 %d %s", req.StatusCode(), httpStatusText(req.StatusCode())),
+			req.Response.Headers, resBody)
+	}
+
+	for _, part := range []struct{ label, raw string }{
+		{"Request:", reqRaw},
+		{"Response:", resRaw},
+	} {
+		sb.WriteString(a.theme.DetailLabelStyle.Render(part.label))
+		sb.WriteString("\n")
+		raw := strings.ReplaceAll(part.raw, "\r\n", "\n")
+		if util.IsBinary(raw) {
+			raw = util.PrintableText(raw)
+		} else if a.searchQuery != "" {
+			raw = a.highlightText(raw, scopeAny)
 		}
-		sb.WriteString(indentLines(formattedRespBody, "  "))
+		sb.WriteString(raw)
+		sb.WriteString("\n\n")
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// rawHTTPMessage assembles an HTTP message from its start line, headers and body
+func rawHTTPMessage(startLine string, headers map[string][]string, body string) string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 
+	var sb strings.Builder
+	sb.WriteString(startLine + "\n")
+	for _, k := range keys {
+		for _, v := range headers[k] {
+			sb.WriteString(k + ": " + v + "\n")
+		}
+	}
+	sb.WriteString("\n" + body)
 	return sb.String()
 }
 
+// helpKey renders the key a binding is shown by in the footer help, which
+// follows any keys rebound in the config file
+func (a *App) helpKey(b key.Binding) string {
+	return a.theme.HelpKeyStyle.Render(b.Help().Key)
+}
+
+// helpKeys renders a pair of bindings as one hint, as in "j/k"
+func (a *App) helpKeys(first, second key.Binding) string {
+	return a.theme.HelpKeyStyle.Render(shortKey(first) + "/" + shortKey(second))
+}
+
+// replayHelp is the replay hint of the detail panel. Offline nothing can be
+// replayed through ngrok, so it offers replaying with edit instead.
+func (a *App) replayHelp() string {
+	if a.offline {
+		return a.helpKey(a.keys.ReplayEdit) + " replay with edit"
+	}
+	return a.helpKey(a.keys.Replay) + " replay"
+}
+
+// renderDetailTabs renders the tab bar above the detail panel
+func (a *App) renderDetailTabs() string {
+	var tabs []string
+	for i, name := range detailTabNames {
+		label := fmt.Sprintf(" %d %s ", i+1, name)
+		if DetailTab(i) == a.detailTab {
+			tabs = append(tabs, a.theme.Badge(a.theme.ColorPrimary, a.theme.ColorOnPrimary).Bold(true).Render(label))
+		} else {
+			tabs = append(tabs, lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Render(label))
+		}
+	}
+	bar := strings.Join(tabs, " ")
+	if a.unwrapped {
+		bar += lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Render("  no wrap ←/→")
+	}
+	return bar
+}
+
+// setDetailTab switches the detail panel tab, remembering where the old one
+// was scrolled to
+func (a *App) setDetailTab(tab DetailTab) {
+	if tab == a.detailTab {
+		return
+	}
+	a.tabOffsets[a.detailTab] = a.detailViewport.YOffset
+	a.detailTab = tab
+	a.matchIndex = 0 // Each tab has its own matches
+	a.lastSelectedID = ""
+	a.updateDetailViewport()
+	// A tab not visited yet keeps the search match updateDetailViewport found
+	if offset, ok := a.tabOffsets[tab]; ok || a.searchQuery == "" {
+		a.detailViewport.SetYOffset(offset)
+	}
+}
+
 // indentLines adds a prefix to each line of text
 func indentLines(text, prefix string) string {
 	lines := strings.Split(text, "\n")
@@ -2903,7 +5853,7 @@ func (a *App) renderHeaders(headers map[string][]string) string {
 		for _, v := range values {
 			headerLine := fmt.Sprintf("%s: %s", key, v)
 			if a.searchQuery != "" {
-				headerLine = a.highlightText(headerLine)
+				headerLine = a.highlightText(headerLine, scopeHeader)
 			}
 			sb.WriteString(fmt.Sprintf("  %s\n", headerLine))
 		}
@@ -2911,11 +5861,22 @@ func (a *App) renderHeaders(headers map[string][]string) string {
 	return sb.String()
 }
 
+// renderFooterBar renders a line of the footer, cut rather than wrapped to
+// the width so the footer never takes more than its one line
+func (a *App) renderFooterBar(content string) string {
+	return a.theme.HelpStyle.Width(a.width).Padding(0, 1).Render(clipLine(content, a.width-2))
+}
+
+// clipLine cuts a rendered line to width cells
+func clipLine(s string, width int) string {
+	return lipgloss.NewStyle().Inline(true).MaxWidth(max(width, 0)).Render(s)
+}
+
 // renderFooter renders the help footer
 func (a *App) renderFooter() string {
 	// Search mode: show search input
 	if a.focus == FocusSearch {
-		prompt := lipgloss.NewStyle().Foreground(ColorPrimary).Bold(true).Render("/")
+		prompt := lipgloss.NewStyle().Foreground(a.theme.ColorPrimary).Bold(true).Render("/")
 
 		// Build input with cursor
 		input := a.searchQuery
@@ -2926,53 +5887,184 @@ func (a *App) renderFooter() string {
 		}
 
 		searchLine := fmt.Sprintf("%s %s", prompt, input)
-		hint := lipgloss.NewStyle().Foreground(ColorMuted).
-			Render("  (enter: search, esc: cancel)")
+		hint := lipgloss.NewStyle().Foreground(a.theme.ColorMuted).
+			Render("  (enter: search, r/ regex, path:/body:/header:/status: scope, esc: cancel)")
+		if a.searchErr != "" {
+			hint = "  " + a.theme.ErrorStyle.Render(a.searchErr)
+		}
 
-		return HelpStyle.Width(a.width).Padding(0, 1).Render(searchLine + hint)
+		return a.renderFooterBar(searchLine + hint)
+	}
+
+	// Note mode: show note input
+	if a.focus == FocusNote {
+		prompt := lipgloss.NewStyle().Foreground(a.theme.ColorPrimary).Bold(true).Render("note:")
+		hint := lipgloss.NewStyle().Foreground(a.theme.ColorMuted).
+			Render("  (enter: save, empty to clear, esc: cancel)")
+		return a.renderFooterBar(prompt + " " + renderInputCursor(a.noteInput, a.noteCursor) + hint)
+	}
+
+	// Filter expression mode: show the expression, with the token a mistake
+	// is at marked
+	if a.focus == FocusFilterExpr {
+		prompt := lipgloss.NewStyle().Foreground(a.theme.ColorPrimary).Bold(true).Render("filter:")
+		input := renderInputCursor(a.exprInput, a.exprCursor)
+		hint := lipgloss.NewStyle().Foreground(a.theme.ColorMuted).
+			Render(`  (enter: apply, as in status >= 500 && path match "/api", esc: cancel)`)
+		if e := a.exprErr; e != nil {
+			input = a.renderExprError(e)
+			hint = "  " + a.theme.ErrorStyle.Render(e.Error())
+		}
+		return a.renderFooterBar(prompt + " " + input + hint)
+	}
+
+	// Query mode: show query input, and why the last attempt failed
+	if a.focus == FocusQuery {
+		prompt := lipgloss.NewStyle().Foreground(a.theme.ColorPrimary).Bold(true).Render("query:")
+		hintText := "  (enter: run, ctrl+y: copy result, esc: close)"
+		hint := lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Render(hintText)
+		if a.queryErr != "" {
+			hint = "  " + a.theme.ErrorStyle.Render(a.queryErr)
+		}
+		return a.renderFooterBar(prompt + " " + renderInputCursor(a.queryInput, a.queryCursor) + hint)
+	}
+
+	// Restore prompt: show what would be restored
+	if a.focus == FocusRestore && a.pendingView != nil {
+		prompt := lipgloss.NewStyle().Foreground(a.theme.ColorPrimary).Bold(true).Render("restore last filters?")
+		options := fmt.Sprintf("%s yes  %s no",
+			a.theme.HelpKeyStyle.Render("y"),
+			a.theme.HelpKeyStyle.Render("n"))
+		hint := lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Render("  " + a.pendingView.summary())
+		return a.renderFooterBar(prompt + " " + options + hint)
+	}
+
+	// Repeat prompt: show count and interval input
+	if a.focus == FocusRepeat {
+		return a.renderRepeatPrompt()
+	}
+
+	// Replay all: show the count and target input
+	if a.focus == FocusReplayAll {
+		return a.renderReplayAllPrompt()
+	}
+
+	// Copy-as menu: show the formats
+	if a.focus == FocusCopyMenu {
+		prompt := lipgloss.NewStyle().Foreground(a.theme.ColorPrimary).Bold(true).Render("copy as:")
+		options := fmt.Sprintf("%s curl  %s fetch()  %s go  %s url",
+			a.theme.HelpKeyStyle.Render("c"),
+			a.theme.HelpKeyStyle.Render("f"),
+			a.theme.HelpKeyStyle.Render("g"),
+			a.theme.HelpKeyStyle.Render("u"))
+		hint := lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Render("  (esc: cancel)")
+		return a.renderFooterBar(prompt + " " + options + hint)
+	}
+
+	// Export mode: show path input
+	if a.focus == FocusExport {
+		scope := "whole session"
+		if a.exportMarked {
+			scope = fmt.Sprintf("selected (%d)", len(a.markedIDs()))
+		} else if a.exportFiltered {
+			scope = fmt.Sprintf("filtered only (%d)", len(a.filteredReqs))
+		}
+		prompt := lipgloss.NewStyle().Foreground(a.theme.ColorPrimary).Bold(true).
+			Render(fmt.Sprintf("export %s as %s:", scope, a.exportFormatShown()))
+		hints := []string{"enter: export"}
+		if a.isFiltered() && a.historySearchQuery == "" && !a.exportMarked {
+			hints = append(hints, "tab: filtered/whole session")
+		}
+		if !a.exportsSubset() {
+			hints = append(hints, "↑/↓: format")
+		}
+		hintText := "  (" + strings.Join(append(hints, "esc: cancel"), ", ") + ")"
+		hint := lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Render(hintText)
+		return a.renderFooterBar(prompt + " " + renderInputCursor(a.exportInput, a.exportCursor) + hint)
 	}
 
 	// Build status line with active filters and search
 	var statusParts []string
+	statusWidth := func() int {
+		return lipgloss.Width(strings.Join(statusParts, " ")) + 1 // Separator before the next part
+	}
 
-	// Show active filters
+	// Show active filters, with the groups they open and close around them
+	a.filterBadgeSpans = a.filterBadgeSpans[:0]
+	opStyle := lipgloss.NewStyle().Foreground(a.theme.ColorWarning).Bold(true)
 	for i, f := range a.activeFilters {
-		// Format filter value with unit if present
-		value := f.Value
-		if f.Unit != "" {
-			value += f.Unit
-		}
-		badge := lipgloss.NewStyle().
-			Background(ColorPrimary).
-			Foreground(lipgloss.Color("#FFFFFF")).
+		if f.Open != "" {
+			statusParts = append(statusParts, opStyle.Render(groupOpenText(f.Open)))
+		}
+		text := a.formatFilterBadge(f)
+		if i < len(a.filterCounts) {
+			text += fmt.Sprintf(" · %d", a.filterCounts[i])
+		}
+		badge := a.theme.Badge(a.theme.ColorPrimary, a.theme.ColorOnPrimary).
 			Padding(0, 1).
-			Render(fmt.Sprintf("%s %s %s", f.Field, f.Operator, value))
+			Render(text)
+		start := 0
+		if len(statusParts) > 0 {
+			start = statusWidth()
+		}
+		a.filterBadgeSpans = append(a.filterBadgeSpans, [2]int{start, start + lipgloss.Width(badge)})
 		statusParts = append(statusParts, badge)
+		if f.Close > 0 {
+			statusParts = append(statusParts, opStyle.Render(strings.Repeat(")", f.Close)))
+		}
 
 		// Show logical operator if not the last filter
 		if f.LogicalOperator != "" && i < len(a.activeFilters)-1 {
-			opStyle := lipgloss.NewStyle().Foreground(ColorWarning).Bold(true)
 			statusParts = append(statusParts, opStyle.Render(f.LogicalOperator))
 		}
 	}
 
-	// Show search query
+	// Show search query, and where the current match is in the detail panel
 	if a.searchQuery != "" {
-		searchBadge := lipgloss.NewStyle().
-			Background(ColorWarning).
-			Foreground(lipgloss.Color("#000000")).
+		searchBadge := a.theme.Badge(a.theme.ColorWarning, a.theme.ColorOnAccent).
 			Padding(0, 1).
 			Render("/" + a.searchQuery)
 		statusParts = append(statusParts, searchBadge)
+		if n := len(a.matchLines); n > 0 {
+			statusParts = append(statusParts, a.theme.HelpStyle.Render(fmt.Sprintf("match %d/%d", a.matchIndex+1, n)))
+		}
+	}
+
+	// Show how many requests are marked for bulk actions
+	if n := len(a.markedIDs()); n > 0 {
+		markBadge := a.theme.Badge(a.theme.ColorSecondary, a.theme.ColorOnAccent).
+			Padding(0, 1).
+			Render(fmt.Sprintf("%d selected", n))
+		statusParts = append(statusParts, markBadge)
+	}
+
+	// Show follow mode indicator
+	if a.following && !a.viewingHistory {
+		followBadge := a.theme.Badge(a.theme.ColorSecondary, a.theme.ColorOnAccent).
+			Padding(0, 1).
+			Render("FOLLOW")
+		statusParts = append(statusParts, followBadge)
+	}
+
+	// Show how far a repeated replay has got
+	if a.repeat != nil {
+		statusParts = append(statusParts, a.renderRepeatBadge())
+	}
+
+	// Show whether the replay result passed its assertions
+	if a.focus == FocusReplayResult && len(a.replayResult.checks) > 0 {
+		statusParts = append(statusParts, a.renderAssertionBadge(a.replayResult.checks))
 	}
 
 	// Show diff mode indicator
-	if a.diffRequestA != nil && a.focus != FocusDiff {
-		diffBadge := lipgloss.NewStyle().
-			Background(lipgloss.Color("#FBBF24")).
-			Foreground(lipgloss.Color("#000000")).
+	if a.diffIDA != "" && a.focus != FocusDiff {
+		text := "Diff: [A] selected, press 'd' on another request"
+		if a.diffMarkHidden {
+			text = "Diff: [A] is hidden by the filters, esc clears it"
+		}
+		diffBadge := a.theme.Badge(a.theme.ColorWarning, a.theme.ColorOnAccent).
 			Padding(0, 1).
-			Render("Diff: [A] selected, press 'd' on another request")
+			Render(text)
 		statusParts = append(statusParts, diffBadge)
 	}
 
@@ -2980,75 +6072,141 @@ func (a *App) renderFooter() string {
 	var help string
 	if a.focus == FocusFilter {
 		help = fmt.Sprintf("%s select  %s confirm  %s cancel",
-			HelpKeyStyle.Render("↑↓"),
-			HelpKeyStyle.Render("enter"),
-			HelpKeyStyle.Render("esc"))
+			a.theme.HelpKeyStyle.Render("↑↓"),
+			a.theme.HelpKeyStyle.Render("enter"),
+			a.theme.HelpKeyStyle.Render("esc"))
 	} else if a.focus == FocusReplayEdit {
-		if a.replayEditStep == ReplayEditStepBody {
-			help = fmt.Sprintf("%s save  %s cancel",
-				HelpKeyStyle.Render("tab"),
-				HelpKeyStyle.Render("esc"))
-		} else if a.replayEditStep == ReplayEditStepPath || a.replayEditStep == ReplayEditStepHeaderEdit {
+		if a.replayEditStep == ReplayEditStepPreview {
+			help = fmt.Sprintf("%s scroll  %s send  %s copy as cURL  %s back to editing",
+				a.theme.HelpKeyStyle.Render("j/k"),
+				a.theme.HelpKeyStyle.Render("enter"),
+				a.theme.HelpKeyStyle.Render("c"),
+				a.theme.HelpKeyStyle.Render("esc"))
+		} else if a.replayEditStep == ReplayEditStepBody {
+			help = fmt.Sprintf("%s save  %s format JSON  %s cancel",
+				a.theme.HelpKeyStyle.Render("tab"),
+				a.theme.HelpKeyStyle.Render("ctrl+f"),
+				a.theme.HelpKeyStyle.Render("esc"))
+		} else if a.replayEditStep == ReplayEditStepPath || a.replayEditStep == ReplayEditStepHeaderEdit ||
+			a.replayEditStep == ReplayEditStepParamEdit || a.replayEditStep == ReplayEditStepTemplateName ||
+			a.replayEditStep == ReplayEditStepTargetURL || a.replayEditStep == ReplayEditStepAssertionEdit {
 			help = fmt.Sprintf("%s move  %s confirm  %s cancel",
-				HelpKeyStyle.Render("←→"),
-				HelpKeyStyle.Render("enter"),
-				HelpKeyStyle.Render("esc"))
+				a.theme.HelpKeyStyle.Render("←→"),
+				a.theme.HelpKeyStyle.Render("enter"),
+				a.theme.HelpKeyStyle.Render("esc"))
 		} else {
 			help = fmt.Sprintf("%s select  %s confirm  %s back/cancel",
-				HelpKeyStyle.Render("↑↓"),
-				HelpKeyStyle.Render("enter"),
-				HelpKeyStyle.Render("esc"))
+				a.theme.HelpKeyStyle.Render("↑↓"),
+				a.theme.HelpKeyStyle.Render("enter"),
+				a.theme.HelpKeyStyle.Render("esc"))
 		}
 	} else if a.focus == FocusDiff {
+		layout := "side by side"
+		if a.splitDiff() {
+			layout = "unified"
+		}
+		ignored := "show ignored"
+		if a.diffShowIgnored {
+			ignored = "hide ignored"
+		}
+		help = fmt.Sprintf("%s  %s scroll  %s next/prev change  %s header  %s ignore it  %s %s  %s %s  %s close",
+			a.diffChangeLabel(),
+			a.theme.HelpKeyStyle.Render("j/k/mouse"),
+			a.theme.HelpKeyStyle.Render("n/N"),
+			a.theme.HelpKeyStyle.Render("tab"),
+			a.theme.HelpKeyStyle.Render("x"),
+			a.theme.HelpKeyStyle.Render("i"), ignored,
+			a.theme.HelpKeyStyle.Render("s"), layout,
+			a.theme.HelpKeyStyle.Render("esc"))
+	} else if a.focus == FocusReplayResult {
+		help = fmt.Sprintf("%s scroll  %s go to request  %s close",
+			a.theme.HelpKeyStyle.Render("j/k/mouse"),
+			a.theme.HelpKeyStyle.Render("enter"),
+			a.theme.HelpKeyStyle.Render("esc"))
+	} else if a.focus == FocusReplaySummary {
 		help = fmt.Sprintf("%s scroll  %s close",
-			HelpKeyStyle.Render("j/k/mouse"),
-			HelpKeyStyle.Render("esc"))
+			a.theme.HelpKeyStyle.Render("j/k/mouse"),
+			a.theme.HelpKeyStyle.Render("esc"))
+	} else if a.focus == FocusTemplates {
+		help = fmt.Sprintf("%s select  %s replay with edit  %s delete  %s close",
+			a.theme.HelpKeyStyle.Render("j/k"),
+			a.theme.HelpKeyStyle.Render("enter"),
+			a.theme.HelpKeyStyle.Render("x"),
+			a.theme.HelpKeyStyle.Render("esc"))
+	} else if a.focus == FocusStats {
+		help = fmt.Sprintf("%s compact  %s back",
+			a.theme.HelpKeyStyle.Render("c"),
+			a.theme.HelpKeyStyle.Render("esc"))
 	} else if a.focus == FocusHistory {
-		help = fmt.Sprintf("%s nav  %s load session  %s back",
-			HelpKeyStyle.Render("j/k"),
-			HelpKeyStyle.Render("enter"),
-			HelpKeyStyle.Render("esc"))
+		back := a.theme.HelpKeyStyle.Render("esc") + " back"
+		if a.offline && !a.viewingHistory {
+			back = a.helpKey(a.keys.Quit) + " quit"
+		}
+		help = fmt.Sprintf("%s nav  %s load session  %s search all  %s rename  %s",
+			a.theme.HelpKeyStyle.Render("j/k"),
+			a.theme.HelpKeyStyle.Render("enter"),
+			a.theme.HelpKeyStyle.Render("/"),
+			a.theme.HelpKeyStyle.Render("r"),
+			back)
+	} else if a.focus == FocusDetailPanel && a.searchQuery != "" {
+		// n steps through matches rather than opening a note
+		help = fmt.Sprintf("%s scroll  %s next/prev match  %s tabs  %s list  %s copy  %s  %s quit",
+			a.helpKeys(a.keys.Down, a.keys.Up),
+			a.helpKeys(a.keys.NextMatch, a.keys.PrevMatch),
+			a.helpKeys(a.keys.PrevTab, a.keys.NextTab),
+			a.helpKey(a.keys.Toggle),
+			a.helpKey(a.keys.Copy),
+			a.replayHelp(),
+			a.helpKey(a.keys.Quit))
 	} else if a.focus == FocusDetailPanel {
-		help = fmt.Sprintf("%s scroll  %s list  %s copy  %s replay  %s quit",
-			HelpKeyStyle.Render("j/k"),
-			HelpKeyStyle.Render("tab"),
-			HelpKeyStyle.Render("c"),
-			HelpKeyStyle.Render("r"),
-			HelpKeyStyle.Render("q"))
+		help = fmt.Sprintf("%s scroll  %s tabs  %s list  %s copy  %s  %s note  %s quit",
+			a.helpKeys(a.keys.Down, a.keys.Up),
+			a.helpKeys(a.keys.PrevTab, a.keys.NextTab),
+			a.helpKey(a.keys.Toggle),
+			a.helpKey(a.keys.Copy),
+			a.replayHelp(),
+			a.helpKey(a.keys.Note),
+			a.helpKey(a.keys.Quit))
 	} else {
-		if a.diffRequestA != nil {
+		if a.diffIDA != "" {
 			// Diff mode: show instruction to select second request
 			help = fmt.Sprintf("%s nav  %s select B for diff  %s cancel diff  %s quit",
-				HelpKeyStyle.Render("j/k"),
-				HelpKeyStyle.Render("d"),
-				HelpKeyStyle.Render("esc"),
-				HelpKeyStyle.Render("q"))
+				a.helpKeys(a.keys.Down, a.keys.Up),
+				a.helpKey(a.keys.Diff),
+				a.helpKey(a.keys.Escape),
+				a.helpKey(a.keys.Quit))
 		} else if a.viewingHistory {
-			help = fmt.Sprintf("%s nav  %s search  %s filter  %s live  %s copy  %s diff  %s quit",
-				HelpKeyStyle.Render("j/k"),
-				HelpKeyStyle.Render("/"),
-				HelpKeyStyle.Render("f"),
-				HelpKeyStyle.Render("h"),
-				HelpKeyStyle.Render("c"),
-				HelpKeyStyle.Render("d"),
-				HelpKeyStyle.Render("q"))
+			back := a.helpKey(a.keys.History) + " live  "
+			if a.viewingFile != "" {
+				back = ""
+			} else if a.offline {
+				back = a.helpKey(a.keys.History) + " sessions  "
+			}
+			help = fmt.Sprintf("%s nav  %s search  %s filter  %s%s copy  %s diff  %s quit",
+				a.helpKeys(a.keys.Down, a.keys.Up),
+				a.helpKey(a.keys.Search),
+				a.helpKey(a.keys.Filter),
+				back,
+				a.helpKey(a.keys.Copy),
+				a.helpKey(a.keys.Diff),
+				a.helpKey(a.keys.Quit))
 		} else {
 			help = fmt.Sprintf("%s nav  %s search  %s filter  %s replay  %s replay with edit  %s copy  %s diff  %s history  %s quit",
-				HelpKeyStyle.Render("j/k"),
-				HelpKeyStyle.Render("/"),
-				HelpKeyStyle.Render("f"),
-				HelpKeyStyle.Render("r"),
-				HelpKeyStyle.Render("R"),
-				HelpKeyStyle.Render("c"),
-				HelpKeyStyle.Render("d"),
-				HelpKeyStyle.Render("h"),
-				HelpKeyStyle.Render("q"))
+				a.helpKeys(a.keys.Down, a.keys.Up),
+				a.helpKey(a.keys.Search),
+				a.helpKey(a.keys.Filter),
+				a.helpKey(a.keys.Replay),
+				a.helpKey(a.keys.ReplayEdit),
+				a.helpKey(a.keys.Copy),
+				a.helpKey(a.keys.Diff),
+				a.helpKey(a.keys.History),
+				a.helpKey(a.keys.Quit))
 		}
 	}
 
 	// Add clear hint if filters or search active
 	if len(a.activeFilters) > 0 || a.searchQuery != "" {
-		help = fmt.Sprintf("%s clear  ", HelpKeyStyle.Render("x")) + help
+		help = fmt.Sprintf("%s clear  ", a.helpKey(a.keys.Clear)) + help
 	}
 
 	// Combine status and help
@@ -3060,153 +6218,250 @@ func (a *App) renderFooter() string {
 	}
 
 	// Add error message if present
+	prefixWidth := 1 // Left padding
 	if a.lastError != nil {
-		errMsg := ErrorStyle.Render(fmt.Sprintf("Error: %s  ", a.lastError.Error()))
+		errMsg := a.theme.ErrorStyle.Render(fmt.Sprintf("Error: %s  ", a.lastError.Error()))
 		footer = errMsg + footer
+		prefixWidth += lipgloss.Width(errMsg)
 	}
 
-	// Add status message (e.g., "Copied!") - show for 1 second
-	if a.statusMessage != "" && time.Since(a.statusMessageTime) < 1*time.Second {
-		statusMsg := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#10B981")).
-			Bold(true).
-			Render(a.statusMessage + "  ")
+	// Add status message (e.g., "Copied!") until it expires
+	if a.statusMessage != "" && time.Since(a.statusMessageTime) < a.statusMessageTTL {
+		statusStyle := lipgloss.NewStyle().
+			Foreground(a.theme.ColorSecondary).
+			Bold(true)
+		if a.statusIsError {
+			statusStyle = a.theme.ErrorStyle
+		}
+		statusMsg := statusStyle.Render(a.statusMessage + "  ")
 		footer = statusMsg + footer
+		prefixWidth += lipgloss.Width(statusMsg)
 	} else if a.statusMessage != "" {
 		// Clear expired message
 		a.statusMessage = ""
 	}
 
-	return HelpStyle.Width(a.width).Padding(0, 1).Render(footer)
+	for i := range a.filterBadgeSpans {
+		a.filterBadgeSpans[i][0] += prefixWidth
+		a.filterBadgeSpans[i][1] += prefixWidth
+	}
+
+	return a.renderFooterBar(footer)
 }
 
 // updateViewportSize updates the viewport dimensions
 func (a *App) updateViewportSize() {
-	contentHeight := a.height - 4 // header + footer
+	contentHeight := a.contentHeight()
 
 	// Calculate detail panel size for split view
 	var detailWidth, detailHeight int
-	if a.width >= 120 {
-		// Side by side: 30% list, 70% detail
-		listWidth := a.width * 30 / 100
-		if listWidth < 36 {
-			listWidth = 36
-		}
-		detailWidth = a.width - listWidth - 4 // border + padding
-		detailHeight = contentHeight - 2      // border
-	} else {
-		// Stacked: 40% list, 60% detail
-		listHeight := contentHeight * 40 / 100
-		if listHeight < 8 {
-			listHeight = 8
-		}
-		detailWidth = a.width - 4 // border + padding
-		detailHeight = contentHeight - listHeight - 2
+	switch {
+	case a.zoomed && a.zoomDetail:
+		detailWidth = a.width - 4 - 2
+		detailHeight = contentHeight - 2 - 2
+	case a.width >= 120:
+		// Text area of the panel box: its width, less border and padding,
+		// includes padding again
+		detailWidth = a.width - a.sideBySideListWidth() - 4 - 2
+		detailHeight = contentHeight - 2 - 2 // border, tab bar
+	case a.stackedListHeight(contentHeight) == contentHeight:
+		// The detail panel is shown alone when it has focus
+		detailWidth = a.width - 4 - 2
+		detailHeight = contentHeight - 2 - 2
+	default:
+		detailWidth = a.width - 4 - 2 // same as above
+		detailHeight = contentHeight - a.stackedListHeight(contentHeight) - 2 - 2
 	}
-	a.detailViewport = viewport.New(detailWidth, detailHeight)
-	a.detailViewport.Style = lipgloss.NewStyle()
+	detailWidth, detailHeight = max(detailWidth, 1), max(detailHeight, 1)
+	if !a.ready {
+		a.detailViewport = viewport.New(detailWidth, detailHeight)
+		a.detailViewport.Style = lipgloss.NewStyle()
+	}
+
+	// Keep the reader's place: the content rewraps to the new width, so
+	// restore the same relative position rather than the same line
+	var progress float64
+	if total := a.detailViewport.TotalLineCount(); total > 0 {
+		progress = float64(a.detailViewport.YOffset) / float64(total)
+	}
+	a.detailViewport.Width = detailWidth
+	a.detailViewport.Height = detailHeight
 
-	// Update content if we have requests
+	a.lastSelectedID = ""
 	a.updateDetailViewport()
+	a.detailViewport.SetYOffset(int(progress * float64(a.detailViewport.TotalLineCount())))
 }
 
 // updateDetailViewport updates the split-view detail viewport
 func (a *App) updateDetailViewport() {
 	if len(a.filteredReqs) == 0 || a.selected >= len(a.filteredReqs) {
-		a.detailViewport.SetContent("Select a request to view details")
+		a.detailShownID = ""
+		a.setDetailContent("Select a request to view details")
 		return
 	}
 
+	a.fillSelectedBodies()
 	req := a.filteredReqs[a.selected]
 
-	// Only update if selection changed
-	if req.ID != a.lastSelectedID {
-		a.lastSelectedID = req.ID
-		content := a.renderRequestDetail(req, a.detailViewport.Width, a.detailViewport.Height, false)
-		// Use lipgloss to wrap content to viewport width
+	// Only update if selection changed, or lastSelectedID was cleared to
+	// force a re-render
+	if req.ID == a.lastSelectedID {
+		return
+	}
+	a.lastSelectedID = req.ID
+	rerender := req.ID == a.detailShownID
+	a.detailShownID = req.ID
+	if !rerender {
+		// The hex toggle belongs to the request it was used on
+		a.hexToggled = false
+	}
+	if !rerender || a.searchQuery != a.matchQuery {
+		a.matchIndex = 0
+		a.matchQuery = a.searchQuery
+	}
+
+	a.matchSeq = 0
+	a.countMatches = true
+	content := a.renderRequestDetail(req, a.detailViewport.Width, a.detailViewport.Height, false)
+	a.countMatches = false
+	// Use lipgloss to wrap content to viewport width, unless lines are shown
+	// unwrapped and the viewport cuts them at the current X offset
+	if !a.unwrapped {
 		content = lipgloss.NewStyle().Width(a.detailViewport.Width).Render(content)
-		a.detailViewport.SetContent(content)
+	}
+	a.setDetailContent(content)
+	a.matchLines = findMatchLines(content, a.theme)
+	if a.matchIndex >= len(a.matchLines) {
+		a.matchIndex = 0
+	}
 
-		// If search is active, scroll to first match
-		if a.searchQuery != "" {
-			a.scrollToFirstMatch(req)
-		} else {
-			a.detailViewport.GotoTop()
-		}
+	// If search is active, scroll to the current match. A re-render of the same
+	// request (note saved, resize) keeps the scroll position.
+	if !rerender {
+		clear(a.tabOffsets)
+	}
+	if a.searchQuery != "" {
+		a.scrollToMatch()
+	} else if !rerender {
+		a.detailViewport.GotoTop()
 	}
 }
 
-// scrollToFirstMatch scrolls the detail viewport to the first occurrence of the search query
-func (a *App) scrollToFirstMatch(req ngrok.Request) {
-	query := strings.ToLower(a.searchQuery)
-
-	// Build a simplified version of the content to find line numbers
-	var lines []string
-	lines = append(lines, req.Request.Method+" "+req.Request.URI) // Line 0-1: title
-	lines = append(lines, "")
-	lines = append(lines, fmt.Sprintf("%d %s", req.StatusCode(), httpStatusText(req.StatusCode()))) // Status
-	lines = append(lines, fmt.Sprintf("%.2fms", req.DurationMs()))                                  // Duration
-	lines = append(lines, req.Start.Format("2006-01-02 15:04:05"))                                  // Time
-	lines = append(lines, "")
-	lines = append(lines, "Request Headers:")
-
-	// Request headers
-	for k, vals := range req.Request.Headers {
-		for _, v := range vals {
-			lines = append(lines, k+": "+v)
-		}
+// setDetailContent replaces the detail viewport content if it changed.
+// SetContent keeps the scroll position as far as the new content allows.
+func (a *App) setDetailContent(content string) {
+	if content == a.detailContent {
+		return
 	}
+	a.detailContent = content
+	a.detailViewport.SetContent(content)
+}
 
-	// Request body
-	reqBody := req.Request.DecodeBody()
-	if reqBody != "" {
-		lines = append(lines, "")
-		lines = append(lines, "Request Body:")
-		bodyLines := strings.Split(reqBody, "\n")
-		lines = append(lines, bodyLines...)
+// scrollToMatch scrolls the detail viewport to the current search match
+func (a *App) scrollToMatch() {
+	if len(a.matchLines) == 0 {
+		// No match on this tab, go to top
+		a.detailViewport.GotoTop()
+		return
 	}
+	// Scroll to the match's line (with some padding above)
+	a.detailViewport.SetYOffset(max(0, a.matchLines[a.matchIndex]-2))
+}
 
-	lines = append(lines, "")
-	lines = append(lines, "Response Headers:")
-
-	// Response headers
-	for k, vals := range req.Response.Headers {
-		for _, v := range vals {
-			lines = append(lines, k+": "+v)
-		}
+// findMatchLines returns the line of each highlighted search match in
+// rendered detail content, found by the escape sequences that start a match
+// highlight. Text that contains the query but isn't highlighted, like labels,
+// doesn't count.
+func findMatchLines(content string, theme *Theme) []int {
+	var starts []string
+	var end string
+	for _, style := range []lipgloss.Style{theme.SearchMatchStyle, theme.CurrentMatchStyle} {
+		if start, rest, ok := strings.Cut(style.Render("x"), "x"); ok && start != "" {
+			starts = append(starts, start)
+			end = rest
+		}
+	}
+	if len(starts) == 0 {
+		// Colors are off, so matches can't be told apart from other text
+		return nil
 	}
 
-	// Response body
-	respBody := req.Response.DecodeBody()
-	if respBody != "" {
-		lines = append(lines, "")
-		lines = append(lines, "Response Body:")
-		bodyLines := strings.Split(respBody, "\n")
-		lines = append(lines, bodyLines...)
-	}
+	var lines []int
+	continued := false // The previous line ended inside a match
+	for i, line := range strings.Split(content, "\n") {
+		var positions []int
+		for _, start := range starts {
+			for off := 0; ; {
+				idx := strings.Index(line[off:], start)
+				if idx == -1 {
+					break
+				}
+				positions = append(positions, off+idx)
+				off += idx + len(start)
+			}
+		}
+		sort.Ints(positions)
 
-	// Find first line with match
-	for i, line := range lines {
-		if strings.Contains(strings.ToLower(line), query) {
-			// Scroll to this line (with some padding above)
-			targetLine := max(0, i-2)
-			a.detailViewport.SetYOffset(targetLine)
-			return
+		for j, pos := range positions {
+			// Wrapping a match restarts its highlight on the next line;
+			// that is the same match, not another one
+			if j == 0 && pos == 0 && continued {
+				continue
+			}
+			lines = append(lines, i)
 		}
+		continued = len(positions) > 0 && !strings.Contains(line[positions[len(positions)-1]:], end)
 	}
+	return lines
+}
+
+// setStatus shows a transient message in the footer for ttl
+func (a *App) setStatus(msg string, ttl time.Duration) {
+	a.statusMessage = msg
+	a.statusMessageTime = time.Now()
+	a.statusMessageTTL = ttl
+	a.statusIsError = false
+}
 
-	// No match found in simplified content, go to top
-	a.detailViewport.GotoTop()
+// setErrorStatus shows a transient error in the footer for ttl
+func (a *App) setErrorStatus(err error, ttl time.Duration) {
+	a.setStatus("Error: "+err.Error(), ttl)
+	a.statusIsError = true
 }
 
 // Command helpers
 
+// tickInterval returns how long to wait before polling again, longer while
+// the terminal window is out of focus
+func (a *App) tickInterval() time.Duration {
+	if !a.windowFocus {
+		return a.idlePollInterval
+	}
+	return a.pollInterval
+}
+
 func tickCmd(d time.Duration) tea.Cmd {
 	return tea.Tick(d, func(t time.Time) tea.Msg {
 		return messages.TickMsg{Time: t}
 	})
 }
 
+// startSpinner starts the spinner ticking again if it has stopped
+func (a *App) startSpinner() tea.Cmd {
+	if a.spinning {
+		return nil
+	}
+	a.spinning = true
+	return a.spinner.Tick
+}
+
+// clockCmd fires on the next whole second, in step with the wall clock
+func clockCmd() tea.Cmd {
+	return tea.Every(time.Second, func(t time.Time) tea.Msg {
+		return messages.ClockMsg{Time: t}
+	})
+}
+
 func (a *App) fetchTunnels() tea.Cmd {
 	return func() tea.Msg {
 		tunnels, err := a.client.GetTunnels()
@@ -3216,11 +6471,38 @@ func (a *App) fetchTunnels() tea.Cmd {
 
 func (a *App) fetchRequests() tea.Cmd {
 	return func() tea.Msg {
-		requests, err := a.client.GetRequests(50)
+		requests, err := capture.Poll(a.client, a.pollLimit)
 		return messages.RequestsMsg{Requests: requests, Err: err}
 	}
 }
 
+// cleanupHistory applies the retention policy to stored history
+func (a *App) cleanupHistory() tea.Cmd {
+	if a.storage == nil {
+		return nil
+	}
+	store, policy := a.storage, a.retention
+	return func() tea.Msg {
+		removed, err := store.Cleanup(policy)
+		return messages.CleanupMsg{Removed: removed, Err: err}
+	}
+}
+
+// waitForSaveError waits for the next background write failure
+func (a *App) waitForSaveError() tea.Cmd {
+	if a.writer == nil {
+		return nil
+	}
+	errs := a.writer.Errors()
+	return func() tea.Msg {
+		err, ok := <-errs
+		if !ok {
+			return nil
+		}
+		return messages.SaveErrorMsg{Err: err}
+	}
+}
+
 func (a *App) replayRequest(requestID string) tea.Cmd {
 	return func() tea.Msg {
 		err := a.client.Replay(requestID)
@@ -3228,41 +6510,48 @@ func (a *App) replayRequest(requestID string) tea.Cmd {
 	}
 }
 
-// saveNewRequests saves any new requests to persistent storage
+// saveNewRequests queues any new requests for persistent storage
 func (a *App) saveNewRequests() {
-	if a.storage == nil || a.storage.CurrentSessionID() == "" {
-		return
-	}
-
-	for _, req := range a.requests {
-		// Skip if already saved
-		if a.savedReqIDs[req.ID] {
-			continue
-		}
-
-		// Convert to storage format and save
-		histReq := storage.HistoryRequest{
-			ID:         req.ID,
-			SessionID:  a.storage.CurrentSessionID(),
-			Method:     req.Request.Method,
-			Path:       req.Request.URI,
-			StatusCode: req.StatusCode(),
-			DurationMS: req.Duration / 1_000_000, // nanoseconds to milliseconds
-			Timestamp:  req.Start,
-			ReqHeaders: req.Request.Headers,
-			ReqBody:    req.Request.DecodeBody(),
-			ResHeaders: req.Response.Headers,
-			ResBody:    req.Response.DecodeBody(),
-		}
+	// Failures come back as SaveErrorMsg
+	a.recorder.Record(a.requests, func(histReq *storage.HistoryRequest) {
+		histReq.Notes = a.notes[histReq.ID]
+		histReq.ReplayOf = a.replayOf[histReq.ID]
+	})
+}
 
-		if err := a.storage.SaveRequest(histReq); err == nil {
-			a.savedReqIDs[req.ID] = true
-		}
-	}
+// fromHistoryRequest converts a stored request back into the form used for
+// display, filtering and replay
+func fromHistoryRequest(hr storage.HistoryRequest) ngrok.Request {
+	req := ngrok.Request{
+		ID:                 hr.ID,
+		RemoteAddr:         hr.RemoteAddr,
+		StoredResponseSize: hr.ResponseSize,
+		StoredRequestSize:  hr.ReqBodySize,
+		Start:              hr.Timestamp,
+		Duration:           hr.DurationMS * 1_000_000, // ms to ns
+		Request: ngrok.HTTPData{
+			Method:  hr.Method,
+			URI:     hr.Path,
+			Headers: hr.ReqHeaders,
+		},
+		Response: ngrok.HTTPData{
+			StatusCode: hr.StatusCode,
+			Headers:    hr.ResHeaders,
+		},
+	}
+	// Store body data for later retrieval
+	req.Request.Raw = hr.ReqBody
+	req.Response.Raw = hr.ResBody
+	return req
 }
 
-// CloseStorage closes the storage connection
+// CloseStorage saves the search and filters, flushes pending writes and
+// closes the storage connection
 func (a *App) CloseStorage() {
+	a.saveView()
+	if a.writer != nil {
+		a.writer.Close()
+	}
 	if a.storage != nil {
 		a.storage.Close()
 	}
@@ -0,0 +1,146 @@
+package tui
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sung01299/mole/internal/ngrok"
+)
+
+// rawMessage encodes an HTTP message as ngrok lists it
+func rawMessage(startLine, body string) string {
+	return base64.StdEncoding.EncodeToString([]byte(startLine + "\r\nContent-Type: application/json\r\n\r\n" + body))
+}
+
+// testRequests returns n captured webhook requests with JSON bodies
+func testRequests(n int) []ngrok.Request {
+	headers := map[string][]string{"Content-Type": {"application/json"}}
+	start := time.Now()
+	reqs := make([]ngrok.Request, n)
+	for i := range reqs {
+		body := fmt.Sprintf(`{"event":"payment.%d","amount":%d,"customer":{"id":"cus_%d","email":"user%d@example.com"}}`, i, i*100, i, i)
+		reqs[i] = ngrok.Request{
+			ID:       fmt.Sprintf("req_%d", i),
+			Start:    start.Add(-time.Duration(i) * time.Second),
+			Duration: int64(12 * time.Millisecond),
+			Request: ngrok.HTTPData{
+				Method:  "POST",
+				URI:     fmt.Sprintf("/hooks/%d", i),
+				Headers: headers,
+				Raw:     rawMessage("POST /hooks HTTP/1.1", body),
+			},
+			Response: ngrok.HTTPData{
+				StatusCode: 200,
+				Headers:    headers,
+				Raw:        rawMessage("HTTP/1.1 200 OK", `{"ok":true}`),
+			},
+		}
+	}
+	return reqs
+}
+
+// newTestApp returns an app listing reqs, with no ngrok or history behind it
+func newTestApp(reqs []ngrok.Request) *App {
+	a := NewApp(ngrok.NewClient("http://127.0.0.1:4040"), nil, DefaultOptions())
+	a.requests = reqs
+	return a
+}
+
+// Filtering again, as every poll does, uses the bodies decoded the first time
+func TestApplyFiltersDecodesBodiesOnce(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []Filter
+		search  string
+	}{
+		{"request body filter", []Filter{{Field: "req_body", Operator: "match", Value: "payment"}}, ""},
+		{"response body filter", []Filter{{Field: "res_body", Operator: "match", Value: "ok"}}, ""},
+		{"body search", nil, "body:payment"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(testRequests(500))
+			a.activeFilters = tt.filters
+			a.searchQuery = tt.search
+			a.compileSearchQuery()
+			a.applyFilters()
+			if len(a.filteredReqs) != 500 {
+				t.Fatalf("first pass kept %d requests, want 500", len(a.filteredReqs))
+			}
+
+			// Were the bodies decoded again, none of these would match
+			for i := range a.requests {
+				a.requests[i].Request.Raw = rawMessage("POST /hooks HTTP/1.1", `{}`)
+				a.requests[i].Response.Raw = rawMessage("HTTP/1.1 200 OK", `{}`)
+			}
+			a.applyFilters()
+			if len(a.filteredReqs) != 500 {
+				t.Errorf("second pass kept %d requests, want 500 from the decoded bodies kept", len(a.filteredReqs))
+			}
+		})
+	}
+}
+
+// A request still waiting for its response is decoded each time, as its
+// response may arrive by the next poll
+func TestDecodedBodiesPending(t *testing.T) {
+	req := testRequests(1)[0]
+	req.Response = ngrok.HTTPData{}
+	a := newTestApp([]ngrok.Request{req})
+	a.decodedBodies(req)
+	if _, ok := a.bodies[req.ID]; ok {
+		t.Error("bodies of a request without a response were kept")
+	}
+}
+
+// Polling slows to the idle interval while the terminal window is out of
+// focus and speeds up again once it's back
+func TestTickIntervalFocus(t *testing.T) {
+	opts := DefaultOptions()
+	opts.PollInterval = 300 * time.Millisecond
+	opts.IdlePollInterval = 5 * time.Second
+	a := NewApp(ngrok.NewClient("http://127.0.0.1:4040"), nil, opts)
+
+	steps := []struct {
+		msg  tea.Msg
+		want time.Duration
+	}{
+		{nil, opts.PollInterval},
+		{tea.BlurMsg{}, opts.IdlePollInterval},
+		{tea.FocusMsg{}, opts.PollInterval},
+	}
+	for _, s := range steps {
+		if s.msg != nil {
+			a.Update(s.msg)
+		}
+		if got := a.tickInterval(); got != s.want {
+			t.Errorf("after %T, tick interval = %s, want %s", s.msg, got, s.want)
+		}
+	}
+}
+
+// BenchmarkApplyFilters filters 500 requests on their bodies, as each poll
+// does, with the decoded bodies kept between polls and without
+func BenchmarkApplyFilters(b *testing.B) {
+	for _, cached := range []bool{true, false} {
+		name := "cached"
+		if !cached {
+			name = "decoded"
+		}
+		b.Run(name, func(b *testing.B) {
+			a := newTestApp(testRequests(500))
+			a.activeFilters = []Filter{{Field: "req_body", Operator: "match", Value: "payment"}}
+			a.applyFilters()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if !cached {
+					a.bodies = nil
+				}
+				a.applyFilters()
+			}
+		})
+	}
+}
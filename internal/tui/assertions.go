@@ -0,0 +1,384 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sung01299/mole/internal/ngrok"
+	"github.com/sung01299/mole/internal/tui/messages"
+	"github.com/sung01299/mole/internal/util"
+)
+
+// assertion is a check on the response to a replay, written as one of
+//
+//	status == 200, status != 500, status == 2xx
+//	body contains "ok":true, body matches /"id":\s*\d+/
+//	header Content-Type == application/json, header X-Id contains abc
+type assertion struct {
+	field  string // "status", "body" or "header"
+	header string // Header name, for a header check
+	op     string // "==", "!=", "contains" or "matches"
+	value  string
+	re     *regexp.Regexp // Compiled value of a matches check
+}
+
+// assertionResult is how one assertion fared against a response
+type assertionResult struct {
+	text string // The assertion as typed
+	ok   bool
+	got  string // What was found instead, when it failed
+	err  error  // Why the assertion couldn't be read
+}
+
+// parseAssertion reads an assertion as typed
+func parseAssertion(text string) (assertion, error) {
+	text = strings.TrimSpace(text)
+	field, rest, _ := strings.Cut(text, " ")
+	a := assertion{field: strings.ToLower(field)}
+	if a.field == "header" {
+		a.header, rest, _ = strings.Cut(strings.TrimSpace(rest), " ")
+		if a.header == "" {
+			return a, fmt.Errorf("expected a header name, as in header Content-Type == application/json")
+		}
+	}
+	op, value, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	a.op = strings.ToLower(op)
+	a.value = strings.TrimSpace(value)
+
+	ops := map[string][]string{
+		"status": {"==", "!="},
+		"body":   {"contains", "matches"},
+		"header": {"==", "!=", "contains", "matches"},
+	}
+	allowed, ok := ops[a.field]
+	if !ok {
+		return a, fmt.Errorf("expected status, body or header, as in status == 200")
+	}
+	if !slices.Contains(allowed, a.op) {
+		return a, fmt.Errorf("%s takes %s", a.field, strings.Join(allowed, ", "))
+	}
+	if a.value == "" {
+		return a, fmt.Errorf("expected a value after %s", a.op)
+	}
+
+	switch {
+	case a.field == "status":
+		low, high := 100, 599
+		code, isClass := strings.CutSuffix(strings.ToLower(a.value), "xx")
+		if isClass {
+			low, high = 1, 5
+		}
+		if n, err := strconv.Atoi(code); err != nil || n < low || n > high {
+			return a, fmt.Errorf("status must be a code like 200 or a class like 2xx")
+		}
+	case a.op == "matches":
+		pattern := a.value
+		if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+			pattern = pattern[1 : len(pattern)-1]
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return a, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		a.re = re
+	}
+	return a, nil
+}
+
+// check tests the assertion against a response, saying what was found
+// instead when it fails
+func (as assertion) check(resp ngrok.HTTPData, body string) (bool, string) {
+	var got string
+	switch as.field {
+	case "status":
+		got = strconv.Itoa(resp.StatusCode)
+		want := strings.ToLower(as.value)
+		match := got == want
+		if class := strings.TrimSuffix(want, "xx"); class != want {
+			match = strconv.Itoa(resp.StatusCode/100) == class
+		}
+		return match == (as.op == "=="), got
+	case "body":
+		got = "no match in the body"
+		if as.op == "contains" {
+			return strings.Contains(body, as.value), got
+		}
+		return as.re.MatchString(body), got
+	}
+
+	values := util.HeaderValues(resp.Headers, as.header)
+	if len(values) == 0 {
+		return as.op == "!=", "no " + as.header + " header"
+	}
+	got = strings.Join(values, ", ")
+	switch as.op {
+	case "==":
+		return got == as.value, got
+	case "!=":
+		return got != as.value, got
+	case "contains":
+		return strings.Contains(got, as.value), got
+	}
+	return as.re.MatchString(got), got
+}
+
+// checkAssertions runs each assertion against the response to a replay. A
+// replay that got no response fails them all.
+func checkAssertions(texts []string, req ngrok.Request) []assertionResult {
+	var results []assertionResult
+	body := req.Response.DecodeBody()
+	for _, text := range texts {
+		r := assertionResult{text: text}
+		as, err := parseAssertion(text)
+		switch {
+		case err != nil:
+			r.err = err
+		case req.Response.StatusCode == 0:
+			r.got = "no response"
+		default:
+			r.ok, r.got = as.check(req.Response, body)
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// firstFailure returns the first assertion that didn't pass, if any
+func firstFailure(results []assertionResult) *assertionResult {
+	for i := range results {
+		if !results[i].ok {
+			return &results[i]
+		}
+	}
+	return nil
+}
+
+// describe says why an assertion failed
+func (r assertionResult) describe() string {
+	if r.err != nil {
+		return fmt.Sprintf("%s (%v)", r.text, r.err)
+	}
+	return fmt.Sprintf("%s (got %s)", r.text, r.got)
+}
+
+// assertionSummary counts the assertions that passed
+func assertionSummary(results []assertionResult) string {
+	passed := 0
+	for _, r := range results {
+		if r.ok {
+			passed++
+		}
+	}
+	return fmt.Sprintf("%d/%d assertions passed", passed, len(results))
+}
+
+// withAssertions has the result of send checked against assertions
+func withAssertions(send tea.Cmd, assertions []string) tea.Cmd {
+	if len(assertions) == 0 {
+		return send
+	}
+	assertions = append([]string(nil), assertions...)
+	return func() tea.Msg {
+		msg := send()
+		if m, ok := msg.(messages.EditedReplayMsg); ok {
+			m.Assertions = assertions
+			return m
+		}
+		return msg
+	}
+}
+
+// handleReplayEditAssertions handles the assertion list, which works like
+// the header list. Leaving it keeps the assertions for the request being
+// edited.
+func (a *App) handleReplayEditAssertions(msg tea.KeyMsg) tea.Cmd {
+	// Assertion list: each assertion + [Add New] + [Done]
+	totalItems := len(a.replayEditAssertions) + 2
+
+	switch msg.Type {
+	case tea.KeyEscape:
+		a.leaveReplayEditAssertions()
+		return nil
+
+	case tea.KeyEnter:
+		if a.replayEditSelected < len(a.replayEditAssertions) {
+			a.replayAssertIdx = a.replayEditSelected
+			a.replayEditInput = a.replayEditAssertions[a.replayAssertIdx]
+		} else if a.replayEditSelected == len(a.replayEditAssertions) {
+			a.replayEditAssertions = append(a.replayEditAssertions, "")
+			a.replayAssertIdx = len(a.replayEditAssertions) - 1
+			a.replayEditInput = ""
+		} else {
+			a.leaveReplayEditAssertions()
+			return nil
+		}
+		a.replayEditCursor = len(a.replayEditInput)
+		a.replayEditErr = ""
+		a.replayEditStep = ReplayEditStepAssertionEdit
+		return nil
+
+	case tea.KeyBackspace, tea.KeyDelete:
+		if a.replayEditSelected < len(a.replayEditAssertions) {
+			a.replayEditAssertions = append(a.replayEditAssertions[:a.replayEditSelected], a.replayEditAssertions[a.replayEditSelected+1:]...)
+			if a.replayEditSelected >= len(a.replayEditAssertions) && a.replayEditSelected > 0 {
+				a.replayEditSelected--
+			}
+		}
+		return nil
+
+	case tea.KeyUp:
+		if a.replayEditSelected > 0 {
+			a.replayEditSelected--
+		}
+		return nil
+
+	case tea.KeyDown:
+		if a.replayEditSelected < totalItems-1 {
+			a.replayEditSelected++
+		}
+		return nil
+	}
+	return nil
+}
+
+// leaveReplayEditAssertions returns to the replay edit menu, remembering the
+// assertions for the request they were written for
+func (a *App) leaveReplayEditAssertions() {
+	if a.replayEditID != "" {
+		if len(a.replayEditAssertions) == 0 {
+			delete(a.assertions, a.replayEditID)
+		} else {
+			a.assertions[a.replayEditID] = append([]string(nil), a.replayEditAssertions...)
+		}
+	}
+	a.replayEditStep = ReplayEditStepMain
+	a.replayEditSelected = 5
+}
+
+// handleReplayEditAssertionEdit edits one assertion. Enter keeps it once it
+// parses; esc drops a new one left empty.
+func (a *App) handleReplayEditAssertionEdit(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEscape:
+		if a.replayEditAssertions[a.replayAssertIdx] == "" {
+			a.replayEditAssertions = append(a.replayEditAssertions[:a.replayAssertIdx], a.replayEditAssertions[a.replayAssertIdx+1:]...)
+		}
+		a.replayEditStep = ReplayEditStepAssertions
+		return nil
+
+	case tea.KeyEnter:
+		text := strings.TrimSpace(a.replayEditInput)
+		if _, err := parseAssertion(text); err != nil {
+			a.replayEditErr = err.Error()
+			return nil
+		}
+		a.replayEditAssertions[a.replayAssertIdx] = text
+		a.replayEditSelected = a.replayAssertIdx
+		a.replayEditStep = ReplayEditStepAssertions
+		return nil
+	}
+
+	a.replayEditInput, a.replayEditCursor, _ = editLine(a.replayEditInput, a.replayEditCursor, msg)
+	a.replayEditErr = ""
+	return nil
+}
+
+// renderReplayEditAssertions renders the assertion list, or the assertion
+// being edited with the forms it can take
+func (a *App) renderReplayEditAssertions(width, height int) string {
+	var lines []string
+
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(a.theme.ColorPrimary)
+	mutedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorMuted)
+	selectedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorPrimary).Bold(true)
+
+	if a.replayEditStep == ReplayEditStepAssertionEdit {
+		lines = append(lines, titleStyle.Render("Edit Assertion"))
+		lines = append(lines, "")
+		lines = append(lines, "> "+renderInputCursor(a.replayEditInput, a.replayEditCursor))
+		lines = append(lines, "")
+		if a.replayEditErr != "" {
+			lines = append(lines, a.theme.ErrorStyle.Render(a.replayEditErr), "")
+		}
+		for _, example := range []string{
+			"status == 200",
+			"status != 5xx",
+			`body contains "ok":true`,
+			`body matches /"id":\s*\d+/`,
+			"header Content-Type contains json",
+		} {
+			lines = append(lines, mutedStyle.Render("  "+example))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	lines = append(lines, titleStyle.Render("Edit Assertions"))
+	lines = append(lines, mutedStyle.Render("Checked against the response"))
+	lines = append(lines, "")
+
+	maxVisible := max(height-6, 3)
+	totalItems := len(a.replayEditAssertions) + 2
+	startIdx := 0
+	if a.replayEditSelected >= maxVisible {
+		startIdx = a.replayEditSelected - maxVisible + 1
+	}
+	endIdx := min(startIdx+maxVisible, totalItems)
+
+	for i := startIdx; i < endIdx; i++ {
+		var item string
+		switch {
+		case i < len(a.replayEditAssertions):
+			item = a.replayEditAssertions[i]
+			if len(item) > width-4 {
+				item = item[:width-7] + "..."
+			}
+		case i == len(a.replayEditAssertions):
+			item = "[Add New Assertion]"
+		default:
+			item = "[Done]"
+		}
+		if i == a.replayEditSelected {
+			lines = append(lines, selectedStyle.Render("▶ "+item))
+		} else {
+			lines = append(lines, "  "+item)
+		}
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, mutedStyle.Render("Enter: edit  Backspace: delete"))
+	return strings.Join(lines, "\n")
+}
+
+// renderAssertionResults lists how each assertion fared in the replay
+// result, failures in the error style with what was found instead
+func (a *App) renderAssertionResults(results []assertionResult) string {
+	var sb strings.Builder
+	sb.WriteString(a.theme.DetailLabelStyle.Render(assertionSummary(results)) + "\n")
+	okStyle := lipgloss.NewStyle().Foreground(a.theme.ColorSecondary)
+	for _, r := range results {
+		if r.ok {
+			sb.WriteString("  " + okStyle.Render("✓") + " " + r.text + "\n")
+		} else {
+			sb.WriteString("  " + a.theme.ErrorStyle.Render("✗ "+r.describe()) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// renderAssertionBadge renders the assertion outcome of the replay result
+// for the footer
+func (a *App) renderAssertionBadge(results []assertionResult) string {
+	color := a.theme.ColorSecondary
+	text := "✓ " + assertionSummary(results)
+	if firstFailure(results) != nil {
+		color = a.theme.ColorError
+		text = "✗ " + assertionSummary(results)
+	}
+	return a.theme.Badge(color, a.theme.ColorOnAccent).Padding(0, 1).Render(text)
+}
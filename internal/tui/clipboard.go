@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sung01299/mole/internal/tui/messages"
+)
+
+// clipboardCommands returns the clipboard programs to try on goos, in order
+func clipboardCommands(goos string) [][]string {
+	switch goos {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip.exe"}}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return [][]string{
+			{"wl-copy"},
+			{"xclip", "-selection", "clipboard"},
+			{"xsel", "--clipboard", "--input"},
+		}
+	}
+	return nil
+}
+
+// copyToClipboard copies text to the system clipboard. When no clipboard
+// program works, such as over SSH, the text is handed to the terminal with an
+// OSC 52 escape sequence instead.
+func copyToClipboard(text string) tea.Cmd {
+	return func() tea.Msg {
+		for _, args := range clipboardCommands(runtime.GOOS) {
+			if _, err := exec.LookPath(args[0]); err != nil {
+				continue
+			}
+			cmd := exec.Command(args[0], args[1:]...)
+			cmd.Stdin = strings.NewReader(text)
+			if err := cmd.Run(); err == nil {
+				return messages.CopyMsg{Success: true, Method: args[0]}
+			}
+		}
+
+		// Written to stderr so it doesn't interleave with the frames Bubbletea
+		// writes to stdout; both go to the same terminal
+		seq := osc52.New(text)
+		if os.Getenv("TMUX") != "" {
+			seq = seq.Tmux()
+		} else if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+			seq = seq.Screen()
+		}
+		if _, err := seq.WriteTo(os.Stderr); err != nil {
+			return messages.ErrorMsg{Err: fmt.Errorf("failed to copy: %w", err)}
+		}
+		return messages.CopyMsg{Success: true, Method: "OSC 52"}
+	}
+}
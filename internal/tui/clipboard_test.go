@@ -0,0 +1,35 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClipboardCommands(t *testing.T) {
+	unix := [][]string{
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	}
+	tests := []struct {
+		goos string
+		want [][]string
+	}{
+		{"darwin", [][]string{{"pbcopy"}}},
+		{"windows", [][]string{{"clip.exe"}}},
+		{"linux", unix},
+		{"freebsd", unix},
+		{"openbsd", unix},
+		{"netbsd", unix},
+		// Left to OSC 52
+		{"plan9", nil},
+		{"js", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			if got := clipboardCommands(tt.goos); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clipboardCommands(%q) = %v, want %v", tt.goos, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package tui
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultDiffIgnoreHeaders are the headers that differ between any two
+// requests, left out of diffs unless configured otherwise. A trailing *
+// matches any header starting with what comes before it.
+var DefaultDiffIgnoreHeaders = []string{
+	"date",
+	"etag",
+	"x-request-id",
+	"x-forwarded-for",
+	"ngrok-*",
+}
+
+// diffIgnored reports whether a header is on the diff ignore list
+func (a *App) diffIgnored(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range a.diffIgnoreHeaders {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// isDiffHeaderChange reports whether a row is a header that differs, which
+// the cursor can stop on
+func isDiffHeaderChange(row diffRow) bool {
+	return row.header != "" && row.kind != diffRowEqual && !row.ignored
+}
+
+// selectDiffHeader marks the header under the cursor, keeping the cursor on
+// a header that differs
+func (a *App) selectDiffHeader(rows []diffRow) {
+	if a.diffCursor < 0 {
+		return
+	}
+	n := 0
+	last := -1
+	for i := range rows {
+		if !isDiffHeaderChange(rows[i]) {
+			continue
+		}
+		if n == a.diffCursor {
+			rows[i].selected = true
+			return
+		}
+		last = i
+		n++
+	}
+	// The header it was on is gone, as after ignoring the last one
+	a.diffCursor = n - 1
+	if last >= 0 {
+		rows[last].selected = true
+	}
+}
+
+// moveDiffCursor moves the cursor to the next (delta 1) or previous (delta
+// -1) header that differs, wrapping around, and scrolls to it
+func (a *App) moveDiffCursor(delta int) {
+	var lines []int
+	for i, row := range a.diffRows {
+		if isDiffHeaderChange(row) {
+			lines = append(lines, a.diffRowLines[i])
+		}
+	}
+	if len(lines) == 0 {
+		a.setStatus("No headers differ", 2*time.Second)
+		return
+	}
+	if a.diffCursor < 0 && delta < 0 {
+		a.diffCursor = 0
+	}
+	a.diffCursor = (a.diffCursor + delta + len(lines)) % len(lines)
+
+	line := lines[a.diffCursor]
+	if line < a.diffViewport.YOffset || line >= a.diffViewport.YOffset+a.diffViewport.Height {
+		a.diffViewport.SetYOffset(max(0, line-a.diffViewport.Height/2))
+	}
+}
+
+// ignoreDiffHeader adds the header under the cursor to the ignore list for
+// this run
+func (a *App) ignoreDiffHeader() {
+	for _, row := range a.diffRows {
+		if row.selected {
+			a.diffIgnoreHeaders = append(a.diffIgnoreHeaders, strings.ToLower(row.header))
+			a.setStatus("Ignoring "+row.header+" in diffs until mole quits (diff_ignore_headers in the config keeps it)", 4*time.Second)
+			return
+		}
+	}
+	a.setStatus("Tab picks a header that differs to ignore", 2*time.Second)
+}
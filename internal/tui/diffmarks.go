@@ -0,0 +1,40 @@
+package tui
+
+import "time"
+
+// resolveDiffMarks looks the requests selected for a diff up again among the
+// current requests, so markers and the diff show their latest data. A marked
+// request that is gone, having expired or been cleared, is unmarked. An open
+// diff keeps what it last had instead, until it is closed.
+func (a *App) resolveDiffMarks() {
+	if a.diffIDA == "" {
+		return
+	}
+	reqA, ok := a.findRequest(a.diffIDA)
+	if ok {
+		a.diffRequestA = &reqA
+	}
+	if reqB, found := a.findRequest(a.diffIDB); found {
+		a.diffRequestB = &reqB
+	}
+	if !ok && a.focus != FocusDiff {
+		a.clearDiffMarks()
+		a.setStatus("Diff selection cleared: [A] is no longer listed", 3*time.Second)
+		return
+	}
+
+	a.diffMarkHidden = true
+	for _, req := range a.filteredReqs {
+		if req.ID == a.diffIDA {
+			a.diffMarkHidden = false
+			break
+		}
+	}
+}
+
+// clearDiffMarks drops the requests selected for a diff
+func (a *App) clearDiffMarks() {
+	a.diffIDA, a.diffIDB = "", ""
+	a.diffRequestA, a.diffRequestB = nil, nil
+	a.diffMarkHidden = false
+}
@@ -0,0 +1,302 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sung01299/mole/internal/util"
+)
+
+// splitDiffMinWidth is the narrowest terminal the diff view lays out side by
+// side
+const splitDiffMinWidth = 120
+
+// diffRowKind says how a row of the diff view compares A with B
+type diffRowKind int
+
+const (
+	diffRowText    diffRowKind = iota // Heading or note, across both columns
+	diffRowEqual                      // The same in A and B
+	diffRowChanged                    // left in A became right in B
+	diffRowRemoved                    // Only in A
+	diffRowAdded                      // Only in B
+	diffRowSkipped                    // Unchanged lines left out, described by left
+)
+
+// diffRow is one row of the diff view: a line of A beside the line of B it
+// lines up with
+type diffRow struct {
+	kind  diffRowKind
+	left  string
+	right string
+	block int // Rows of one run of body changes share a block, from 1
+
+	header   string // Name of the header the row compares, if it does
+	ignored  bool   // A header left out of the comparison, shown anyway
+	selected bool   // The header under the cursor
+}
+
+// textRows turns rendered text into rows across both columns
+func textRows(text string) []diffRow {
+	var rows []diffRow
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		rows = append(rows, diffRow{kind: diffRowText, left: line})
+	}
+	return rows
+}
+
+// pairDiffLines turns a line diff into rows, pairing the lines removed in a
+// run of changes with the lines added in it, in order
+func pairDiffLines(lines []util.DiffLine) []diffRow {
+	var rows []diffRow
+	block := 0
+	for i := 0; i < len(lines); {
+		if lines[i].Op == util.DiffEqual {
+			rows = append(rows, diffRow{kind: diffRowEqual, left: lines[i].Text, right: lines[i].Text})
+			i++
+			continue
+		}
+
+		var removed, added []string
+		for ; i < len(lines) && lines[i].Op != util.DiffEqual; i++ {
+			if lines[i].Op == util.DiffDelete {
+				removed = append(removed, lines[i].Text)
+			} else {
+				added = append(added, lines[i].Text)
+			}
+		}
+		block++
+		for j := 0; j < max(len(removed), len(added)); j++ {
+			row := diffRow{kind: diffRowChanged, block: block}
+			switch {
+			case j >= len(removed):
+				row.kind, row.right = diffRowAdded, added[j]
+			case j >= len(added):
+				row.kind, row.left = diffRowRemoved, removed[j]
+			default:
+				row.left, row.right = removed[j], added[j]
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// diffStyles are the styles for lines only in A, only in B, and in both.
+// An ignored header is dimmed whatever it did, and the header under the
+// cursor is shown reversed.
+func (a *App) diffStyles(row diffRow) (removed, added, unchanged lipgloss.Style) {
+	removed = lipgloss.NewStyle().Foreground(a.theme.ColorError)
+	added = lipgloss.NewStyle().Foreground(a.theme.ColorSecondary)
+	unchanged = lipgloss.NewStyle().Foreground(a.theme.ColorMuted)
+	if row.ignored {
+		removed, added = unchanged, unchanged
+	}
+	if row.selected {
+		removed, added = removed.Reverse(true), added.Reverse(true)
+	}
+	return removed, added, unchanged
+}
+
+// minWordDiffShare is how much of a changed line must be left as it was for
+// the words that changed to be picked out. Below it the lines have little in
+// common, and picking out nearly every word would only be noise.
+const minWordDiffShare = 0.5
+
+// renderChangedLines renders both sides of a changed row, picking out the
+// words that changed in reverse within each line
+func (a *App) renderChangedLines(row diffRow, removedStyle, addedStyle lipgloss.Style, prefix string) (left, right string) {
+	left = removedStyle.Render(prefix + "- " + row.left)
+	right = addedStyle.Render(prefix + "+ " + row.right)
+	if row.ignored {
+		return left, right
+	}
+	before, after := util.DiffWords(row.left, row.right)
+	kept := 0
+	for _, piece := range before {
+		if piece.Op == util.DiffEqual {
+			kept += len(piece.Text)
+		}
+	}
+	if float64(kept) < minWordDiffShare*float64(min(len(row.left), len(row.right))) {
+		return left, right
+	}
+
+	render := func(style lipgloss.Style, mark string, pieces []util.DiffLine) string {
+		// The header under the cursor is already reversed, so its changes
+		// are picked out the other way round
+		changed := style.Reverse(!row.selected)
+		var sb strings.Builder
+		sb.WriteString(style.Render(prefix + mark))
+		for _, piece := range pieces {
+			if piece.Op == util.DiffEqual {
+				sb.WriteString(style.Render(piece.Text))
+			} else {
+				sb.WriteString(changed.Render(piece.Text))
+			}
+		}
+		return sb.String()
+	}
+	return render(removedStyle, "- ", before), render(addedStyle, "+ ", after)
+}
+
+// renderUnifiedDiff lays the rows out one under the other, lines of A marked
+// - and lines of B marked +. A run of body changes lists all it removed
+// before all it added. It also returns the line each row starts on.
+func (a *App) renderUnifiedDiff(rows []diffRow, width int) (string, []int) {
+	wrap := lipgloss.NewStyle().Width(width)
+	var sb strings.Builder
+	lines := 0
+	write := func(line string) {
+		line = wrap.Render(line)
+		sb.WriteString(line + "\n")
+		lines += lipgloss.Height(line)
+	}
+
+	offsets := make([]int, len(rows))
+	for i := 0; i < len(rows); i++ {
+		row := rows[i]
+		offsets[i] = lines
+		removedStyle, addedStyle, unchangedStyle := a.diffStyles(row)
+		switch row.kind {
+		case diffRowText:
+			write(row.left)
+		case diffRowEqual, diffRowSkipped:
+			write(unchangedStyle.Render("    " + row.left))
+		default:
+			end := i + 1
+			if row.block != 0 {
+				for end < len(rows) && rows[end].block == row.block {
+					offsets[end] = lines
+					end++
+				}
+			}
+			var removed, added []string
+			for _, r := range rows[i:end] {
+				switch r.kind {
+				case diffRowRemoved:
+					removed = append(removed, removedStyle.Render("  - "+r.left))
+				case diffRowAdded:
+					added = append(added, addedStyle.Render("  + "+r.right))
+				default:
+					left, right := a.renderChangedLines(r, removedStyle, addedStyle, "  ")
+					removed = append(removed, left)
+					added = append(added, right)
+				}
+			}
+			for _, line := range append(removed, added...) {
+				write(line)
+			}
+			i = end - 1
+		}
+	}
+	return sb.String(), offsets
+}
+
+// renderSplitDiff lays the rows out in two columns, A on the left and B on
+// the right, wrapping long lines within their column so rows stay level. It
+// also returns the line each row starts on.
+func (a *App) renderSplitDiff(rows []diffRow, width int) (string, []int) {
+	colWidth := (width - 3) / 2
+	column := lipgloss.NewStyle().Width(colWidth)
+	full := lipgloss.NewStyle().Width(width)
+
+	var sb strings.Builder
+	lines := 0
+	write := func(block string) {
+		sb.WriteString(block + "\n")
+		lines += lipgloss.Height(block)
+	}
+
+	offsets := make([]int, len(rows))
+	for i, row := range rows {
+		offsets[i] = lines
+		removedStyle, addedStyle, unchangedStyle := a.diffStyles(row)
+		var left, right string
+		switch row.kind {
+		case diffRowText:
+			write(full.Render(row.left))
+			continue
+		case diffRowSkipped:
+			write(full.Render(unchangedStyle.Render("  " + row.left)))
+			continue
+		case diffRowEqual:
+			left = unchangedStyle.Render("  " + row.left)
+			right = unchangedStyle.Render("  " + row.right)
+		case diffRowChanged:
+			left, right = a.renderChangedLines(row, removedStyle, addedStyle, "")
+		case diffRowRemoved:
+			left = removedStyle.Render("- " + row.left)
+		case diffRowAdded:
+			right = addedStyle.Render("+ " + row.right)
+		}
+
+		left, right = column.Render(left), column.Render(right)
+		height := max(lipgloss.Height(left), lipgloss.Height(right))
+		divider := strings.TrimSuffix(strings.Repeat(" │ \n", height), "\n")
+		write(lipgloss.JoinHorizontal(lipgloss.Top, left, divider, right))
+	}
+	return sb.String(), offsets
+}
+
+// splitDiff reports whether the diff view is laid out side by side: when
+// the terminal is wide enough, unless unified was asked for
+func (a *App) splitDiff() bool {
+	return !a.diffUnified && a.width >= splitDiffMinWidth
+}
+
+// isDiffChange reports whether a row shows a difference that counts as a
+// change to jump to
+func isDiffChange(row diffRow) bool {
+	switch row.kind {
+	case diffRowChanged, diffRowRemoved, diffRowAdded:
+		return !row.ignored
+	}
+	return false
+}
+
+// diffChangeLines returns the line each change starts on. Changed rows that
+// follow one another make one change.
+func (a *App) diffChangeLines() []int {
+	var lines []int
+	for i, row := range a.diffRows {
+		if isDiffChange(row) && (i == 0 || !isDiffChange(a.diffRows[i-1])) {
+			lines = append(lines, a.diffRowLines[i])
+		}
+	}
+	return lines
+}
+
+// jumpDiffChange scrolls the diff to the next (delta 1) or previous (delta
+// -1) change, wrapping around
+func (a *App) jumpDiffChange(delta int) {
+	lines := a.diffChangeLines()
+	if len(lines) == 0 {
+		a.setStatus("No changes", 2*time.Second)
+		return
+	}
+	if a.diffChange < 0 && delta < 0 {
+		a.diffChange = 0
+	}
+	a.diffChange = (a.diffChange + delta + len(lines)) % len(lines)
+	// A little of what comes before shows above the change
+	a.diffViewport.SetYOffset(max(0, lines[a.diffChange]-2))
+}
+
+// diffChangeLabel describes the change jumped to, as in "change 3/9", or
+// how many there are before any is
+func (a *App) diffChangeLabel() string {
+	n := len(a.diffChangeLines())
+	switch {
+	case n == 0:
+		return "no changes"
+	case a.diffChange >= 0 && a.diffChange < n:
+		return fmt.Sprintf("change %d/%d", a.diffChange+1, n)
+	case n == 1:
+		return "1 change"
+	}
+	return fmt.Sprintf("%d changes", n)
+}
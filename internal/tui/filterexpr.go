@@ -0,0 +1,285 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// A filter expression is the typed form of a filter chain, as in
+//
+//	status >= 500 && path match "/api" || duration > 200ms
+//	path match "/api" && not (status == 2xx || duration < 50ms)
+//
+// It parses into the same []Filter the wizard builds, so either can edit
+// what the other made.
+
+// exprToken is a word, quoted string or operator in a filter expression
+type exprToken struct {
+	text   string // Unquoted, for strings
+	pos    int    // Byte offset in the expression
+	end    int    // Byte offset just past the token as written
+	quoted bool
+}
+
+// exprError is a filter expression mistake at a place in the expression
+type exprError struct {
+	pos, end int
+	msg      string
+}
+
+func (e *exprError) Error() string {
+	return fmt.Sprintf("col %d: %s", e.pos+1, e.msg)
+}
+
+// exprOperators are the symbol operators, longest first so ">=" isn't read
+// as ">"
+var exprOperators = []string{"&&", "||", "==", "!=", ">=", "<=", ">", "<", "(", ")"}
+
+// exprUnitValue splits a number with a unit, as in 200ms or 1.5kb
+var exprUnitValue = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)([a-zA-Z]+)$`)
+
+// lexFilterExpr splits a filter expression into tokens
+func lexFilterExpr(expr string) ([]exprToken, error) {
+	var toks []exprToken
+	i := 0
+	for i < len(expr) {
+		if expr[i] == ' ' || expr[i] == '\t' {
+			i++
+			continue
+		}
+
+		if expr[i] == '"' {
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(expr) && expr[j] != '"'; j++ {
+				// Only \" and \\ are escapes, so regexes keep their backslashes
+				if expr[j] == '\\' && j+1 < len(expr) && (expr[j+1] == '"' || expr[j+1] == '\\') {
+					j++
+				}
+				sb.WriteByte(expr[j])
+			}
+			if j == len(expr) {
+				return nil, &exprError{pos: i, end: len(expr), msg: "unterminated string"}
+			}
+			toks = append(toks, exprToken{text: sb.String(), pos: i, end: j + 1, quoted: true})
+			i = j + 1
+			continue
+		}
+
+		if op := exprOperatorAt(expr, i); op != "" {
+			toks = append(toks, exprToken{text: op, pos: i, end: i + len(op)})
+			i += len(op)
+			continue
+		}
+
+		j := i
+		for j < len(expr) && expr[j] != ' ' && expr[j] != '\t' && expr[j] != '"' && exprOperatorAt(expr, j) == "" {
+			j++
+		}
+		toks = append(toks, exprToken{text: expr[i:j], pos: i, end: j})
+		i = j
+	}
+	return toks, nil
+}
+
+// exprOperatorAt returns the symbol operator starting at expr[i], if any
+func exprOperatorAt(expr string, i int) string {
+	for _, op := range exprOperators {
+		if strings.HasPrefix(expr[i:], op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// parseFilterExpr parses a filter expression into a filter chain. Each
+// filter is a field, an operator and a value; && and || join them, applied
+// left to right as in the wizard. Parentheses group filters, and "not"
+// before a filter or group negates it.
+func (a *App) parseFilterExpr(expr string) ([]Filter, error) {
+	toks, err := lexFilterExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return nil, nil
+	}
+
+	var filters []Filter
+	next := 0
+	depth := 0 // Groups open so far
+	// peek reports whether the token n places ahead is text, ignoring case
+	peek := func(n int, text string) bool {
+		return next+n < len(toks) && !toks[next+n].quoted && strings.EqualFold(toks[next+n].text, text)
+	}
+	// want returns the next token, or an error at the end of the expression
+	want := func(what string) (exprToken, error) {
+		if next == len(toks) {
+			return exprToken{}, &exprError{pos: len(expr), end: len(expr), msg: "expected " + what}
+		}
+		tok := toks[next]
+		next++
+		return tok, nil
+	}
+
+	for {
+		var opens []bool
+		for {
+			if peek(0, "(") {
+				opens = append(opens, false)
+				next++
+			} else if peek(0, "not") && peek(1, "(") {
+				opens = append(opens, true)
+				next += 2
+			} else {
+				break
+			}
+		}
+		depth += len(opens)
+		not := peek(0, "not")
+		if not {
+			next++
+		}
+
+		fieldTok, err := want("a field")
+		if err != nil {
+			return nil, err
+		}
+		field, key := a.exprField(fieldTok.text)
+		if field == nil || fieldTok.quoted {
+			return nil, &exprError{pos: fieldTok.pos, end: fieldTok.end, msg: fmt.Sprintf("unknown field %q", fieldTok.text)}
+		}
+
+		opTok, err := want("an operator")
+		if err != nil {
+			return nil, err
+		}
+		op := strings.ToLower(opTok.text)
+		if opTok.quoted || !slices.Contains(field.Operators, op) {
+			return nil, &exprError{pos: opTok.pos, end: opTok.end,
+				msg: fmt.Sprintf("%s takes %s, not %q", key, strings.Join(field.Operators, " "), opTok.text)}
+		}
+
+		valueTok, err := want("a value")
+		if err != nil {
+			return nil, err
+		}
+		f := Filter{Field: key, Operator: op, Value: valueTok.text, Not: not, Open: joinGroupOpens(opens)}
+		if field.takesUnit(op) {
+			m := exprUnitValue.FindStringSubmatch(valueTok.text)
+			if m == nil || !slices.Contains(field.Units, strings.ToLower(m[2])) {
+				return nil, &exprError{pos: valueTok.pos, end: valueTok.end,
+					msg: fmt.Sprintf("expected a number with a unit (%s), as in 5%s", strings.Join(field.Units, ", "), field.Units[0])}
+			}
+			f.Value, f.Unit = m[1], strings.ToLower(m[2])
+		} else if err := validateFilterValue(field, op, f.Value); err != nil {
+			return nil, &exprError{pos: valueTok.pos, end: valueTok.end, msg: err.Error()}
+		}
+
+		for peek(0, ")") {
+			if depth == 0 {
+				return nil, &exprError{pos: toks[next].pos, end: toks[next].end, msg: "no group to close"}
+			}
+			f.Close++
+			depth--
+			next++
+		}
+
+		f = normalizeStatusFilter(f)
+
+		if next == len(toks) {
+			if depth > 0 {
+				return nil, &exprError{pos: len(expr), end: len(expr), msg: "expected )"}
+			}
+			filters = append(filters, f)
+			return filters, nil
+		}
+		joinTok := toks[next]
+		next++
+		if joinTok.quoted || (joinTok.text != "&&" && joinTok.text != "||") {
+			return nil, &exprError{pos: joinTok.pos, end: joinTok.end, msg: fmt.Sprintf("expected && or ||, not %q", joinTok.text)}
+		}
+		f.LogicalOperator = joinTok.text
+		filters = append(filters, f)
+	}
+}
+
+// exprField looks up a field by key or name, ignoring case. Any header can
+// be given as header.name, or req.name and res.name for the request or
+// response.
+func (a *App) exprField(name string) (*FilterField, string) {
+	lower := strings.ToLower(name)
+	for _, f := range filterFields {
+		if f.Key == customRequestHeader || f.Key == customResponseHeader {
+			continue
+		}
+		if f.Key == lower || strings.ToLower(f.Name) == lower {
+			return a.getFieldByKey(f.Key), f.Key
+		}
+	}
+	if header, ok := strings.CutPrefix(lower, "header."); ok && header != "" {
+		lower = "req." + header
+	}
+	for _, prefix := range []string{"req.", "res."} {
+		if header, ok := strings.CutPrefix(lower, prefix); ok && header != "" {
+			return a.getFieldByKey(lower), lower
+		}
+	}
+	return nil, ""
+}
+
+// formatFilterExpr writes a filter chain as an expression that
+// parseFilterExpr reads back
+func formatFilterExpr(filters []Filter) string {
+	var sb strings.Builder
+	for i, f := range filters {
+		value := f.Value
+		if f.Unit == "" && needsQuotes(value) {
+			value = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+		}
+		if f.Open != "" {
+			sb.WriteString(strings.ReplaceAll(groupOpenText(f.Open), "( ", "("))
+		}
+		if f.Not {
+			sb.WriteString("not ")
+		}
+		sb.WriteString(f.Field + " " + f.Operator + " " + value + f.Unit)
+		sb.WriteString(strings.Repeat(")", f.Close))
+		if i < len(filters)-1 {
+			join := f.LogicalOperator
+			if join == "" {
+				join = "&&"
+			}
+			sb.WriteString(" " + join + " ")
+		}
+	}
+	return sb.String()
+}
+
+// needsQuotes reports whether a filter value must be quoted to read back as
+// one word
+func needsQuotes(value string) bool {
+	if value == "" || strings.ContainsAny(value, " \t\"") {
+		return true
+	}
+	for i := range value {
+		if exprOperatorAt(value, i) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// renderExprError renders the expression with the part a mistake is at
+// underlined, or a marker at the end for a missing token
+func (a *App) renderExprError(e *exprError) string {
+	input := a.exprInput
+	pos, end := min(e.pos, len(input)), min(e.end, len(input))
+	mark := a.theme.ErrorStyle.Underline(true)
+	if pos == end {
+		return input + mark.Render("█")
+	}
+	return input[:pos] + mark.Render(input[pos:end]) + input[end:]
+}
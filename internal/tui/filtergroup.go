@@ -0,0 +1,144 @@
+package tui
+
+import "strings"
+
+// Filters chain left to right, so "A && B || C" means "(A && B) || C".
+// Groups change that: a filter can open groups before it and close groups
+// after it, and a chain inside a group is worked out on its own, as in
+// "A && (B || C)". Groups and single filters can be negated. The chain stays
+// a flat list, so the wizard, the manager and saved chains all work as
+// before; a chain without groups evaluates exactly as it always has.
+
+// groupOpens splits a filter's Open into the groups it opens, outermost
+// first, reporting for each whether it is negated
+func groupOpens(open string) []bool {
+	var nots []bool
+	for open != "" {
+		if rest, ok := strings.CutPrefix(open, "!("); ok {
+			nots = append(nots, true)
+			open = rest
+			continue
+		}
+		nots = append(nots, false)
+		open = strings.TrimPrefix(open, "(")
+	}
+	return nots
+}
+
+// joinGroupOpens is the Open for the given groups, outermost first
+func joinGroupOpens(nots []bool) string {
+	var sb strings.Builder
+	for _, not := range nots {
+		if not {
+			sb.WriteString("!(")
+		} else {
+			sb.WriteString("(")
+		}
+	}
+	return sb.String()
+}
+
+// groupOpenText renders the groups a filter opens, as in "not ( ("
+func groupOpenText(open string) string {
+	var parts []string
+	for _, not := range groupOpens(open) {
+		if not {
+			parts = append(parts, "not (")
+		} else {
+			parts = append(parts, "(")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// balanceGroups fixes up groups after a filter is removed or regrouped:
+// a close with no open group is dropped, and groups still open at the end
+// close after the last filter
+func balanceGroups(filters []Filter) {
+	depth := 0
+	for i := range filters {
+		depth += len(groupOpens(filters[i].Open))
+		filters[i].Close = min(filters[i].Close, depth)
+		depth -= filters[i].Close
+	}
+	if n := len(filters); n > 0 {
+		filters[n-1].Close += depth
+	}
+}
+
+// removeFromChain removes filters[i]. Groups around just that filter go
+// with it; other groups it opened or closed move to the filters beside it.
+func removeFromChain(filters []Filter, i int) []Filter {
+	removed := filters[i]
+	filters = append(filters[:i], filters[i+1:]...)
+
+	opens := groupOpens(removed.Open)
+	own := min(len(opens), removed.Close)
+	opens, closes := opens[:len(opens)-own], removed.Close-own
+	if i < len(filters) {
+		filters[i].Open = joinGroupOpens(opens) + filters[i].Open
+	}
+	if i > 0 {
+		filters[i-1].Close += closes
+	}
+	balanceGroups(filters)
+	return filters
+}
+
+// groupDepth returns how many groups are open just after filters[i] and its
+// own opens and closes
+func groupDepth(filters []Filter, i int) int {
+	depth := 0
+	for _, f := range filters[:i+1] {
+		depth += len(groupOpens(f.Open)) - f.Close
+	}
+	return depth
+}
+
+// chainGroup is a group being worked out while evaluating a chain
+type chainGroup struct {
+	result  bool
+	started bool   // Whether result holds anything yet
+	join    string // Joins result with what comes next
+	not     bool
+}
+
+// evalFilterChain evaluates a filter chain, with match telling whether
+// filters[i] holds, before any negation. Every filter is evaluated, in order,
+// as the chain always has.
+func evalFilterChain(filters []Filter, match func(i int) bool) bool {
+	stack := []chainGroup{{}}
+	add := func(v bool) {
+		g := &stack[len(stack)-1]
+		switch {
+		case !g.started:
+			g.result, g.started = v, true
+		case g.join == "||":
+			g.result = g.result || v
+		default:
+			// Default to AND
+			g.result = g.result && v
+		}
+	}
+	closeGroup := func() {
+		g := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		add(g.result != g.not)
+	}
+
+	for i, f := range filters {
+		for _, not := range groupOpens(f.Open) {
+			stack = append(stack, chainGroup{not: not})
+		}
+		add(match(i) != f.Not)
+		for i := 0; i < f.Close && len(stack) > 1; i++ {
+			closeGroup()
+		}
+		stack[len(stack)-1].join = f.LogicalOperator
+	}
+	// Groups left open close at the end
+	for len(stack) > 1 {
+		closeGroup()
+	}
+	return stack[0].result
+}
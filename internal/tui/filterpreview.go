@@ -0,0 +1,73 @@
+package tui
+
+import (
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sung01299/mole/internal/tui/messages"
+)
+
+// previewDelay is how long typing must pause before the filter wizard counts
+// what the value would match, so typing stays snappy with many requests
+const previewDelay = 150 * time.Millisecond
+
+// filterPreview is how many requests the filter being built would keep
+type filterPreview struct {
+	value string // Value the count is for
+	count int
+	total int // Requests not hidden, which the count is out of
+}
+
+// schedulePreview counts what the value being typed would match once typing
+// pauses. Only the last count scheduled is worked out.
+func (a *App) schedulePreview() tea.Cmd {
+	if a.focus != FocusFilter || a.filterStep != FilterStepValue {
+		a.preview = nil
+		return nil
+	}
+	if a.preview != nil && a.preview.value == a.filterInput {
+		return nil
+	}
+	a.previewSeq++
+	seq := a.previewSeq
+	return tea.Tick(previewDelay, func(time.Time) tea.Msg {
+		return messages.FilterPreviewMsg{Seq: seq}
+	})
+}
+
+// updatePreview counts the requests the active filters would keep with the
+// filter being built applied on top, in place of the filter being edited
+func (a *App) updatePreview() {
+	a.preview = nil
+	if a.focus != FocusFilter || a.filterStep != FilterStepValue || a.filterInput == "" {
+		return
+	}
+	field := a.getFieldByKey(a.pendingFilter.Field)
+	if validateFilterValue(field, a.pendingFilter.Operator, a.filterInput) != nil {
+		return
+	}
+
+	f := a.pendingFilter
+	f.Value = a.filterInput
+	f = normalizeStatusFilter(f)
+	chain := slices.Clone(a.activeFilters)
+	if a.editingFilter >= 0 && a.editingFilter < len(chain) {
+		chain[a.editingFilter] = f
+	} else {
+		chain = append(chain, f)
+	}
+
+	p := &filterPreview{value: a.filterInput}
+	for _, req := range a.requests {
+		if a.dismissed[req.ID] {
+			continue
+		}
+		p.total++
+		if a.matchesAllFilters(req, chain, nil) && (a.search.empty() || a.matchesSearch(req)) {
+			p.count++
+		}
+	}
+	a.preview = p
+}
@@ -0,0 +1,61 @@
+package tui
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// editLine applies a single-line editing key (typing, backspace, cursor
+// movement) to input and returns the updated input and cursor. It reports
+// false for keys it doesn't handle, such as enter and esc.
+func editLine(input string, cursor int, msg tea.KeyMsg) (string, int, bool) {
+	if cursor > len(input) {
+		cursor = len(input)
+	}
+
+	switch msg.Type {
+	case tea.KeyBackspace:
+		if cursor > 0 {
+			input = input[:cursor-1] + input[cursor:]
+			cursor--
+		}
+		return input, cursor, true
+
+	case tea.KeyDelete:
+		if cursor < len(input) {
+			input = input[:cursor] + input[cursor+1:]
+		}
+		return input, cursor, true
+
+	case tea.KeyLeft:
+		if cursor > 0 {
+			cursor--
+		}
+		return input, cursor, true
+
+	case tea.KeyRight:
+		if cursor < len(input) {
+			cursor++
+		}
+		return input, cursor, true
+
+	case tea.KeyHome, tea.KeyCtrlA:
+		return input, 0, true
+
+	case tea.KeyEnd, tea.KeyCtrlE:
+		return input, len(input), true
+
+	case tea.KeyRunes, tea.KeySpace:
+		char := string(msg.Runes)
+		input = input[:cursor] + char + input[cursor:]
+		cursor += len(char)
+		return input, cursor, true
+	}
+
+	return input, cursor, false
+}
+
+// renderInputCursor returns input with a block cursor drawn at cursor
+func renderInputCursor(input string, cursor int) string {
+	if cursor < len(input) {
+		return input[:cursor] + "█" + input[cursor:]
+	}
+	return input + "█"
+}
@@ -1,6 +1,13 @@
 package tui
 
-import "github.com/charmbracelet/bubbles/key"
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+)
 
 // KeyMap defines all keybindings for the application
 type KeyMap struct {
@@ -11,23 +18,60 @@ type KeyMap struct {
 	Bottom key.Binding
 
 	// Actions
-	Enter      key.Binding
-	Escape     key.Binding
-	Replay     key.Binding
-	ReplayEdit key.Binding
-	Diff       key.Binding
-	Toggle     key.Binding
-	Search     key.Binding
-	Filter     key.Binding
-	Copy       key.Binding
-	Clear      key.Binding
-	History    key.Binding
+	Enter        key.Binding
+	Escape       key.Binding
+	Replay       key.Binding
+	ReplayEdit   key.Binding
+	ReplayRepeat key.Binding
+	ReplayAll    key.Binding
+	ReplayLocal  key.Binding
+	Diff         key.Binding
+	Toggle       key.Binding
+	Search       key.Binding
+	Filter       key.Binding
+	FilterExpr   key.Binding
+	Copy         key.Binding
+	Open         key.Binding
+	CopyURL      key.Binding
+	CopyAs       key.Binding
+	BodyQuery    key.Binding
+	HexView      key.Binding
+	Wrap         key.Binding
+	ExportScript key.Binding
+	Export       key.Binding
+	Follow       key.Binding
+	Mark         key.Binding
+	MarkRange    key.Binding
+	Star         key.Binding
+	Dismiss      key.Binding
+	UnhideAll    key.Binding
+	Columns      key.Binding
+	Collapse     key.Binding
+	ShrinkList   key.Binding
+	GrowList     key.Binding
+	Zoom         key.Binding
+	QuickFilter  key.Binding
+	DetailTab    key.Binding
+	PrevTab      key.Binding
+	NextTab      key.Binding
+	Note         key.Binding
+	NextMatch    key.Binding
+	PrevMatch    key.Binding
+	Clear        key.Binding
+	History      key.Binding
+	Templates    key.Binding
+	ReplayLink   key.Binding
+	ReplayDiff   key.Binding
+	Stats        key.Binding
+	Traffic      key.Binding
 
 	// Scrolling (for detail view)
 	ScrollUp   key.Binding
 	ScrollDown key.Binding
 	PageUp     key.Binding
 	PageDown   key.Binding
+	Left       key.Binding
+	Right      key.Binding
 
 	// Application
 	Quit key.Binding
@@ -69,6 +113,18 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("R"),
 			key.WithHelp("R", "replay with edit"),
 		),
+		ReplayRepeat: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "repeat replay"),
+		),
+		ReplayAll: key.NewBinding(
+			key.WithKeys("A"),
+			key.WithHelp("A", "replay all listed"),
+		),
+		ReplayLocal: key.NewBinding(
+			key.WithKeys("L"),
+			key.WithHelp("L", "replay locally"),
+		),
 		Diff: key.NewBinding(
 			key.WithKeys("d"),
 			key.WithHelp("d", "diff"),
@@ -85,10 +141,118 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("f"),
 			key.WithHelp("f", "filter"),
 		),
+		FilterExpr: key.NewBinding(
+			key.WithKeys(":"),
+			key.WithHelp(":", "filter expression"),
+		),
 		Copy: key.NewBinding(
 			key.WithKeys("c"),
 			key.WithHelp("c", "copy curl"),
 		),
+		CopyURL: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "copy url"),
+		),
+		CopyAs: key.NewBinding(
+			key.WithKeys("C"),
+			key.WithHelp("C", "copy as..."),
+		),
+		BodyQuery: key.NewBinding(
+			key.WithKeys("."),
+			key.WithHelp(".", "query json body"),
+		),
+		HexView: key.NewBinding(
+			key.WithKeys("X"),
+			key.WithHelp("X", "hex/text body"),
+		),
+		Wrap: key.NewBinding(
+			key.WithKeys("W"),
+			key.WithHelp("W", "wrap lines"),
+		),
+		Open: key.NewBinding(
+			key.WithKeys("O"),
+			key.WithHelp("O", "open in browser"),
+		),
+		ExportScript: key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", "export curl script"),
+		),
+		Export: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "export json"),
+		),
+		Follow: key.NewBinding(
+			key.WithKeys("F"),
+			key.WithHelp("F", "follow newest"),
+		),
+		Mark: key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", "select"),
+		),
+		MarkRange: key.NewBinding(
+			key.WithKeys("V"),
+			key.WithHelp("V", "select range"),
+		),
+		Star: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "star"),
+		),
+		Dismiss: key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("D", "hide"),
+		),
+		UnhideAll: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "unhide all"),
+		),
+		Columns: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "duration/size columns"),
+		),
+		Collapse: key.NewBinding(
+			key.WithKeys("z"),
+			key.WithHelp("z", "collapse repeats"),
+		),
+		ShrinkList: key.NewBinding(
+			key.WithKeys("<"),
+			key.WithHelp("<", "shrink list"),
+		),
+		GrowList: key.NewBinding(
+			key.WithKeys(">"),
+			key.WithHelp(">", "grow list"),
+		),
+		Zoom: key.NewBinding(
+			key.WithKeys("Z"),
+			key.WithHelp("Z", "zoom panel"),
+		),
+		QuickFilter: key.NewBinding(
+			key.WithKeys("2", "4", "5"),
+			key.WithHelp("2/4/5", "only 2xx/4xx/5xx"),
+		),
+		DetailTab: key.NewBinding(
+			key.WithKeys("1", "2", "3", "4"),
+			key.WithHelp("1-4", "detail tab"),
+		),
+		PrevTab: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", "previous tab"),
+		),
+		NextTab: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", "next tab"),
+		),
+		Note: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "note"),
+		),
+		NextMatch: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "next match"),
+		),
+		PrevMatch: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "previous match"),
+		),
 		Clear: key.NewBinding(
 			key.WithKeys("x"),
 			key.WithHelp("x", "clear"),
@@ -97,6 +261,26 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("h"),
 			key.WithHelp("h", "history"),
 		),
+		Templates: key.NewBinding(
+			key.WithKeys("b"),
+			key.WithHelp("b", "templates"),
+		),
+		ReplayLink: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "replay original"),
+		),
+		ReplayDiff: key.NewBinding(
+			key.WithKeys("P"),
+			key.WithHelp("P", "diff replay"),
+		),
+		Stats: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "storage info"),
+		),
+		Traffic: key.NewBinding(
+			key.WithKeys("I"),
+			key.WithHelp("I", "traffic stats"),
+		),
 		ScrollUp: key.NewBinding(
 			key.WithKeys("ctrl+u"),
 			key.WithHelp("ctrl+u", "scroll up"),
@@ -113,6 +297,14 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("ctrl+f", "pgdown"),
 			key.WithHelp("ctrl+f", "page down"),
 		),
+		Left: key.NewBinding(
+			key.WithKeys("left", "h"),
+			key.WithHelp("←/h", "scroll left"),
+		),
+		Right: key.NewBinding(
+			key.WithKeys("right", "l"),
+			key.WithHelp("→/l", "scroll right"),
+		),
 		Quit: key.NewBinding(
 			key.WithKeys("q", "ctrl+c"),
 			key.WithHelp("q", "quit"),
@@ -124,6 +316,153 @@ func DefaultKeyMap() KeyMap {
 	}
 }
 
+// actions names the bindings for the config file's keys section
+func (k *KeyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"up":            &k.Up,
+		"down":          &k.Down,
+		"top":           &k.Top,
+		"bottom":        &k.Bottom,
+		"enter":         &k.Enter,
+		"escape":        &k.Escape,
+		"replay":        &k.Replay,
+		"replay_edit":   &k.ReplayEdit,
+		"replay_repeat": &k.ReplayRepeat,
+		"replay_all":    &k.ReplayAll,
+		"replay_local":  &k.ReplayLocal,
+		"diff":          &k.Diff,
+		"toggle":        &k.Toggle,
+		"search":        &k.Search,
+		"filter":        &k.Filter,
+		"filter_expr":   &k.FilterExpr,
+		"copy":          &k.Copy,
+		"open":          &k.Open,
+		"copy_url":      &k.CopyURL,
+		"copy_as":       &k.CopyAs,
+		"body_query":    &k.BodyQuery,
+		"hex_view":      &k.HexView,
+		"wrap":          &k.Wrap,
+		"export_script": &k.ExportScript,
+		"export":        &k.Export,
+		"follow":        &k.Follow,
+		"mark":          &k.Mark,
+		"mark_range":    &k.MarkRange,
+		"star":          &k.Star,
+		"dismiss":       &k.Dismiss,
+		"unhide_all":    &k.UnhideAll,
+		"columns":       &k.Columns,
+		"collapse":      &k.Collapse,
+		"shrink_list":   &k.ShrinkList,
+		"grow_list":     &k.GrowList,
+		"zoom":          &k.Zoom,
+		"prev_tab":      &k.PrevTab,
+		"next_tab":      &k.NextTab,
+		"note":          &k.Note,
+		"next_match":    &k.NextMatch,
+		"prev_match":    &k.PrevMatch,
+		"clear":         &k.Clear,
+		"history":       &k.History,
+		"templates":     &k.Templates,
+		"replay_link":   &k.ReplayLink,
+		"replay_diff":   &k.ReplayDiff,
+		"stats":         &k.Stats,
+		"traffic":       &k.Traffic,
+		"scroll_up":     &k.ScrollUp,
+		"scroll_down":   &k.ScrollDown,
+		"page_up":       &k.PageUp,
+		"page_down":     &k.PageDown,
+		"left":          &k.Left,
+		"right":         &k.Right,
+		"quit":          &k.Quit,
+		"help":          &k.Help,
+	}
+}
+
+// sharedKeys are actions that may share a key because they never apply at
+// the same time, like a note and the next search match in the detail panel
+var sharedKeys = map[[2]string]bool{
+	{"next_match", "note"}:         true,
+	{"history", "left"}:            true,
+	{"detail_tab", "quick_filter"}: true,
+}
+
+// NewKeyMap returns the default keybindings with actions rebound by name, as
+// in {"history": {"H"}}. Unknown actions are returned as warnings so an
+// older mole still starts with a newer config. Rebinding a key that another
+// action uses is an error listing every clash.
+func NewKeyMap(overrides map[string][]string) (*KeyMap, []string, error) {
+	km := DefaultKeyMap()
+	actions := km.actions()
+
+	var warnings []string
+	for _, name := range sortedKeys(overrides) {
+		keys := overrides[name]
+		b, ok := actions[name]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("unknown action %q in keys (available: %s)",
+				name, strings.Join(sortedKeys(actions), ", ")))
+			continue
+		}
+		if len(keys) == 0 {
+			return nil, nil, fmt.Errorf("no keys given for %q", name)
+		}
+		b.SetKeys(keys...)
+		b.SetHelp(strings.Join(keys, "/"), b.Help().Desc)
+	}
+
+	// The digit keys pick a tab or status class, so they can't be rebound
+	// but still take up their keys
+	actions["detail_tab"] = &km.DetailTab
+	actions["quick_filter"] = &km.QuickFilter
+
+	users := make(map[string][]string) // Key to the actions bound to it
+	for _, name := range sortedKeys(actions) {
+		for _, k := range actions[name].Keys() {
+			users[k] = append(users[k], name)
+		}
+	}
+	var clashes []string
+	for _, k := range sortedKeys(users) {
+		names := users[k]
+		for i := range names {
+			for _, other := range names[i+1:] {
+				if !sharedKeys[[2]string{names[i], other}] {
+					clashes = append(clashes, fmt.Sprintf("%q is bound to both %s and %s", k, names[i], other))
+				}
+			}
+		}
+	}
+	if len(clashes) > 0 {
+		return nil, nil, fmt.Errorf("key conflicts: %s", strings.Join(clashes, "; "))
+	}
+	return &km, warnings, nil
+}
+
+// sortedKeys returns a map's keys in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// shortKey returns the key a binding is best shown by in short help, its
+// first single-character key, as in "j" for down
+func shortKey(b key.Binding) string {
+	keys := b.Keys()
+	for _, k := range keys {
+		if utf8.RuneCountInString(k) == 1 {
+			return k
+		}
+	}
+	if len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
+
 // ShortHelp returns a short help string for the footer
 func (k KeyMap) ShortHelp() []key.Binding {
 	return []key.Binding{
@@ -0,0 +1,174 @@
+package tui
+
+import (
+	"slices"
+
+	"github.com/sung01299/mole/internal/debuglog"
+	"github.com/sung01299/mole/internal/ngrok"
+	"github.com/sung01299/mole/internal/storage"
+)
+
+// bodyCacheSize is how many requests' bodies are kept after being loaded
+// from history, so moving back and forth in the list doesn't query again
+const bodyCacheSize = 32
+
+// storedBodies is the bodies of a stored request, as GetRequestBodies
+// returns them
+type storedBodies struct {
+	id       string
+	request  string
+	response string
+}
+
+// lazyBodies tracks a history session listed without its bodies. Each
+// request's bodies are loaded when it is shown, and only the selected
+// request holds them in the list. None are kept in App.bodies, so at most
+// bodyCacheSize are held at once.
+type lazyBodies struct {
+	unloaded map[string]bool // Requests listed without their bodies
+	filledID string          // The request whose bodies were put in the list
+	recent   []storedBodies  // Most recently used last
+
+	// Every body of the session, loaded in one query while the list is
+	// searched or filtered on bodies and dropped once it has been
+	batch map[string]storage.Bodies
+}
+
+// unloadedBodies starts tracking requests listed without their bodies,
+// replacing whatever session was tracked before. nil stops tracking.
+func (a *App) unloadedBodies(histReqs []storage.HistoryRequest) {
+	a.lazy = lazyBodies{}
+	if histReqs == nil {
+		return
+	}
+	a.lazy.unloaded = make(map[string]bool, len(histReqs))
+	for _, hr := range histReqs {
+		a.lazy.unloaded[hr.ID] = true
+	}
+}
+
+// storedBodies returns the bodies of a request listed without them, from
+// the cache or else from storage
+func (a *App) storedBodies(id string) (storedBodies, bool) {
+	if i := slices.IndexFunc(a.lazy.recent, func(b storedBodies) bool { return b.id == id }); i >= 0 {
+		b := a.lazy.recent[i]
+		a.lazy.recent = append(slices.Delete(a.lazy.recent, i, i+1), b)
+		return b, true
+	}
+	if a.storage == nil {
+		return storedBodies{}, false
+	}
+	reqBody, resBody, err := a.storage.GetRequestBodies(id)
+	if err != nil {
+		debuglog.Printf("tui: loading bodies of %s: %v", id, err)
+		return storedBodies{}, false
+	}
+	b := storedBodies{id: id, request: reqBody, response: resBody}
+	if len(a.lazy.recent) >= bodyCacheSize {
+		a.lazy.recent = slices.Delete(a.lazy.recent, 0, 1)
+	}
+	a.lazy.recent = append(a.lazy.recent, b)
+	return b, true
+}
+
+// withBodies returns req with its bodies, loading them if it was listed
+// without
+func (a *App) withBodies(req ngrok.Request) ngrok.Request {
+	if !a.lazy.unloaded[req.ID] || req.Request.Raw != "" || req.Response.Raw != "" {
+		return req
+	}
+	if b, ok := a.lazy.batch[req.ID]; ok {
+		req.Request.Raw = b.Request
+		req.Response.Raw = b.Response
+		return req
+	}
+	if b, ok := a.storedBodies(req.ID); ok {
+		req.Request.Raw = b.request
+		req.Response.Raw = b.response
+	}
+	return req
+}
+
+// needsAllBodies reports whether the search or filters look at the bodies
+// of every listed request. Plain search terms are matched against text that
+// includes the bodies, whatever their scope.
+func (a *App) needsAllBodies() bool {
+	if !a.search.empty() {
+		return true
+	}
+	for _, f := range a.activeFilters {
+		if f.Field == "req_body" || f.Field == "res_body" {
+			return true
+		}
+	}
+	return false
+}
+
+// loadSessionBodies loads every body of a session listed without them, when
+// the list is about to be searched or filtered on them, rather than loading
+// them one query at a time. dropSessionBodies lets them go afterwards.
+func (a *App) loadSessionBodies() {
+	if !a.needsAllBodies() {
+		return
+	}
+	a.loadAllBodies()
+}
+
+// loadAllBodies loads every body of a session listed without them in one
+// query, for whatever is about to read them all
+func (a *App) loadAllBodies() {
+	if a.lazy.unloaded == nil || a.storage == nil {
+		return
+	}
+	bodies, err := a.storage.GetSessionBodies(a.viewingSessionID)
+	if err != nil {
+		debuglog.Printf("tui: loading bodies of session %s: %v", a.viewingSessionID, err)
+		return
+	}
+	a.lazy.batch = bodies
+}
+
+// dropSessionBodies lets go of the bodies loadAllBodies loaded
+func (a *App) dropSessionBodies() {
+	a.lazy.batch = nil
+}
+
+// fillSelectedBodies puts the bodies of the selected request in the list,
+// for everything that reads them from there, and takes them back out of
+// the request selected before
+func (a *App) fillSelectedBodies() {
+	if a.lazy.unloaded == nil {
+		return
+	}
+	id := a.filteredReqs[a.selected].ID
+	if id == a.lazy.filledID {
+		return
+	}
+	if prev := a.lazy.filledID; prev != "" {
+		a.setListedBodies(prev, func(req *ngrok.Request) {
+			req.Request.Raw, req.Response.Raw = "", ""
+		})
+		a.lazy.filledID = ""
+	}
+	if !a.lazy.unloaded[id] {
+		return
+	}
+	b, ok := a.storedBodies(id)
+	if !ok {
+		return
+	}
+	a.setListedBodies(id, func(req *ngrok.Request) {
+		req.Request.Raw, req.Response.Raw = b.request, b.response
+	})
+	a.lazy.filledID = id
+}
+
+// setListedBodies applies set to the request with the given ID in both the
+// full and the filtered list
+func (a *App) setListedBodies(id string, set func(*ngrok.Request)) {
+	for _, reqs := range [][]ngrok.Request{a.requests, a.filteredReqs} {
+		if i := slices.IndexFunc(reqs, func(r ngrok.Request) bool { return r.ID == id }); i >= 0 {
+			set(&reqs[i])
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sung01299/mole/internal/ngrok"
+	"github.com/sung01299/mole/internal/storage"
+	"github.com/sung01299/mole/internal/tui/messages"
+)
+
+// newHistoryApp returns an app viewing a stored session of n requests,
+// listed without their bodies. The first request's body was truncated when
+// it was stored.
+func newHistoryApp(t *testing.T, n int) *App {
+	t.Helper()
+	store, err := storage.New(storage.MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID, err := store.StartSession("https://example.ngrok.app", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	reqs := make([]storage.HistoryRequest, n)
+	for i := range reqs {
+		body := fmt.Sprintf(`{"event":"payment.%d"}`, i)
+		reqs[i] = storage.HistoryRequest{
+			ID:          fmt.Sprintf("req_%d", i),
+			Method:      "POST",
+			Path:        fmt.Sprintf("/hooks/%d", i),
+			StatusCode:  200,
+			Timestamp:   start.Add(time.Duration(i) * time.Second),
+			ReqHeaders:  map[string][]string{"Content-Type": {"application/json"}},
+			ReqBody:     body,
+			ResBody:     `{"ok":true}`,
+			ReqBodySize: len(body),
+		}
+	}
+	reqs[0].ReqBodySize = 4096
+	if err := store.SaveRequests(reqs); err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewApp(ngrok.NewClient("http://127.0.0.1:4040"), store, DefaultOptions())
+	t.Cleanup(a.CloseStorage)
+	a.loadHistoricalSession(sessionID)
+	if len(a.filteredReqs) != n {
+		t.Fatalf("listed %d requests, want %d", len(a.filteredReqs), n)
+	}
+	return a
+}
+
+// A session listed without its bodies exports them all, not only the
+// selected request's
+func TestExportCurlScriptLazySession(t *testing.T) {
+	a := newHistoryApp(t, 5)
+	a.updateDetailViewport()
+
+	t.Chdir(t.TempDir())
+	msg := a.exportCurlScript()().(messages.ExportMsg)
+	if msg.Err != nil {
+		t.Fatal(msg.Err)
+	}
+	data, err := os.ReadFile(msg.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := string(data)
+	for i := 0; i < 5; i++ {
+		if body := fmt.Sprintf(`payment.%d"`, i); !strings.Contains(script, body) {
+			t.Errorf("script is missing the body of req_%d:\n%s", i, script)
+		}
+	}
+	if !strings.Contains(script, "# Warning: request body was truncated") {
+		t.Errorf("script has no warning for the truncated body:\n%s", script)
+	}
+	if a.lazy.batch != nil {
+		t.Error("session bodies were kept after the export")
+	}
+}
+
+// Copying and querying a request loads its bodies, whether or not it has
+// been shown in the detail panel
+func TestLazyBodiesCopyAndQuery(t *testing.T) {
+	a := newHistoryApp(t, 3)
+	// The oldest is listed last, away from the selection
+	i := slices.IndexFunc(a.filteredReqs, func(r ngrok.Request) bool { return r.ID == "req_0" })
+	if i == a.selected {
+		t.Fatal("req_0 is selected")
+	}
+	req := a.filteredReqs[i]
+	if req.Request.Raw != "" {
+		t.Fatal("request was listed with its bodies")
+	}
+
+	if got := buildCurlCommand(a.withBodies(req), "https://example.ngrok.app"); !strings.Contains(got, "payment.0") {
+		t.Errorf("curl command is missing the body: %s", got)
+	}
+	a.detailTab = TabRequest
+	if got := a.queryBody(req); got != `{"event":"payment.0"}` {
+		t.Errorf("queryBody on the Request tab = %q", got)
+	}
+	a.detailTab = TabResponse
+	if got := a.queryBody(req); got != `{"ok":true}` {
+		t.Errorf("queryBody on the Response tab = %q", got)
+	}
+}
@@ -11,6 +11,11 @@ type TickMsg struct {
 	Time time.Time
 }
 
+// ClockMsg is sent every second so relative timestamps stay current
+type ClockMsg struct {
+	Time time.Time
+}
+
 // TunnelsMsg contains fetched tunnel data
 type TunnelsMsg struct {
 	Tunnels []ngrok.Tunnel
@@ -23,15 +28,40 @@ type RequestsMsg struct {
 	Err      error
 }
 
+// FilterPreviewMsg asks for the filter wizard's match count once typing has
+// paused
+type FilterPreviewMsg struct {
+	Seq int
+}
+
+// SearchHighlightMsg asks for the detail panel's search highlighting once
+// typing has paused
+type SearchHighlightMsg struct {
+	Seq int
+}
+
 // ReplayMsg indicates the result of a replay action
 type ReplayMsg struct {
 	RequestID string
 	Err       error
 }
 
-// WindowFocusMsg indicates whether the window has focus
-type WindowFocusMsg struct {
-	Focused bool
+// EditedReplayMsg carries the response to an edited replay. Request holds
+// what was sent and, unless Err says why not, the response.
+type EditedReplayMsg struct {
+	Request    ngrok.Request
+	Truncated  bool     // Body cut off at the size kept
+	Local      string   // Local address it was sent to, bypassing ngrok
+	Assertions []string // Checks to run against the response
+	Err        error
+}
+
+// RepeatReplayMsg reports one replay of a repeated run. Status is empty when
+// the replay went through ngrok, which doesn't say what came back.
+type RepeatReplayMsg struct {
+	Seq    int // Which run the replay belongs to
+	Status string
+	Err    error
 }
 
 // ErrorMsg represents an error to be displayed
@@ -42,4 +72,42 @@ type ErrorMsg struct {
 // CopyMsg indicates the result of a copy to clipboard action
 type CopyMsg struct {
 	Success bool
+	Method  string // How the text reached the clipboard, e.g. "pbcopy" or "OSC 52"
+}
+
+// OpenMsg reports the result of opening a request URL in the browser
+type OpenMsg struct {
+	URL string
+	Err error
+}
+
+// StarMsg reports the result of starring requests
+type StarMsg struct {
+	IDs   []string // Requests asked to be starred
+	Count int64
+	Err   error
+}
+
+// CleanupMsg reports the result of the startup history cleanup
+type CleanupMsg struct {
+	Removed int64
+	Err     error
+}
+
+// SaveErrorMsg reports a request that could not be written to history
+type SaveErrorMsg struct {
+	Err error
+}
+
+// CompactMsg reports the result of compacting the history database
+type CompactMsg struct {
+	Reclaimed int64
+	Err       error
+}
+
+// ExportMsg indicates that requests were exported to a file
+type ExportMsg struct {
+	Path  string
+	Count int
+	Err   error
 }
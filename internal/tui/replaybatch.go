@@ -0,0 +1,219 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sung01299/mole/internal/ngrok"
+	"github.com/sung01299/mole/internal/tui/messages"
+)
+
+// replayOutcome is how one request of a replayed list went
+type replayOutcome struct {
+	req    ngrok.Request
+	status string // Empty for replays through ngrok, which don't say
+	err    error
+}
+
+// replayableHeaders lists the headers to send again when replaying a
+// request, leaving out the ones the client and ngrok set themselves
+func replayableHeaders(headers map[string][]string) []HeaderEntry {
+	var entries []HeaderEntry
+	for k, vals := range headers {
+		lowerK := strings.ToLower(k)
+		if lowerK == "host" || lowerK == "content-length" ||
+			strings.HasPrefix(lowerK, "x-forwarded") {
+			continue
+		}
+		for _, v := range vals {
+			entries = append(entries, HeaderEntry{Key: k, Value: v})
+		}
+	}
+	return entries
+}
+
+// promptReplayAll asks to confirm replaying every listed request, and where
+// to send them
+func (a *App) promptReplayAll() {
+	if a.repeat != nil {
+		a.setStatus("A replay is already running (esc stops it)", 2*time.Second)
+		return
+	}
+	if len(a.filteredReqs) == 0 {
+		a.setStatus("Nothing to replay", 2*time.Second)
+		return
+	}
+
+	batch := make([]ngrok.Request, len(a.filteredReqs))
+	copy(batch, a.filteredReqs)
+	sort.SliceStable(batch, func(i, j int) bool { return batch[i].Start.Before(batch[j].Start) })
+
+	a.pendingRepeat = &replayRun{batch: batch}
+	a.repeatInput = ""
+	a.repeatCursor = 0
+	a.repeatErr = ""
+	a.prevFocus = a.focus
+	a.focus = FocusReplayAll
+}
+
+// handleReplayAllInput edits the target of a replay of every listed request.
+// Enter starts it: through ngrok when the target is empty, or straight to
+// the target's address.
+func (a *App) handleReplayAllInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEnter:
+		var target string
+		if strings.TrimSpace(a.repeatInput) != "" {
+			var err error
+			if target, err = parseTargetURL(a.repeatInput); err != nil {
+				a.repeatErr = err.Error()
+				return nil
+			}
+		} else if a.viewingHistory {
+			a.repeatErr = "ngrok no longer holds history requests, so give a target"
+			return nil
+		}
+
+		run := a.pendingRepeat
+		run.total = len(run.batch)
+		run.send = a.batchReplayStep(run.batch, target)
+		if target == "" {
+			batch := run.batch
+			run.expect = func(i int) pendingReplay {
+				return pendingReplay{parentID: batch[i].ID, method: batch[i].Request.Method, uri: batch[i].Request.URI}
+			}
+		}
+		a.repeatSeq++
+		run.seq = a.repeatSeq
+		a.repeat = run
+		a.pendingRepeat = nil
+		a.focus = a.prevFocus
+		return a.repeatStep(0)
+
+	case tea.KeyEscape:
+		a.pendingRepeat = nil
+		a.focus = a.prevFocus
+		return nil
+	}
+
+	a.repeatInput, a.repeatCursor, _ = editLine(a.repeatInput, a.repeatCursor, msg)
+	a.repeatErr = ""
+	return nil
+}
+
+// batchReplayStep replays the requests of a list in turn, through ngrok or,
+// given a target, sent there as captured
+func (a *App) batchReplayStep(batch []ngrok.Request, target string) func(int) tea.Msg {
+	if target == "" {
+		return func(i int) tea.Msg {
+			return messages.RepeatReplayMsg{Err: a.client.Replay(batch[i].ID)}
+		}
+	}
+	return func(i int) tea.Msg {
+		req := batch[i]
+		headers := make(map[string]string)
+		for _, h := range replayableHeaders(req.Request.Headers) {
+			headers[h.Key] = h.Value
+		}
+		send := sendReplay(req.Request.Method, target+req.Request.URI, req.Request.URI, a.replayBody(req), headers)
+		return repeatReplayMsg(send())
+	}
+}
+
+// showReplaySummary lists how each replay of a finished run went, or sums it
+// up in the footer if another mode has taken over input
+func (a *App) showReplaySummary(run *replayRun) {
+	a.replaySummary = run
+	if a.focus != FocusList && a.focus != FocusDetailPanel {
+		a.setStatus(run.summary(), 5*time.Second)
+		return
+	}
+	a.resultViewport.GotoTop()
+	a.prevFocus = a.focus
+	a.focus = FocusReplaySummary
+}
+
+// summary counts the replays of a run that went through
+func (r *replayRun) summary() string {
+	return fmt.Sprintf("Replayed %d requests: %d ok, %d failed", r.total, r.total-r.failed, r.failed)
+}
+
+// handleReplaySummaryInput handles keyboard input in the replay summary
+func (a *App) handleReplaySummaryInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "q":
+		a.replaySummary = nil
+		a.focus = a.prevFocus
+	case "up", "k":
+		a.resultViewport.LineUp(1)
+	case "down", "j":
+		a.resultViewport.LineDown(1)
+	case "g":
+		a.resultViewport.GotoTop()
+	case "G":
+		a.resultViewport.GotoBottom()
+	}
+	return nil
+}
+
+// renderReplaySummary renders how each request of a replayed list went,
+// oldest first
+func (a *App) renderReplaySummary(width, height int) string {
+	run := a.replaySummary
+	width = a.sizeResultViewport(width, height)
+
+	var sb strings.Builder
+	sb.WriteString(a.theme.DetailLabelStyle.Render(run.summary()))
+	sb.WriteString("\n\n")
+
+	okStyle := lipgloss.NewStyle().Foreground(a.theme.ColorSecondary)
+	muted := lipgloss.NewStyle().Foreground(a.theme.ColorMuted)
+	for _, o := range run.outcomes {
+		mark := okStyle.Render("✓")
+		if o.err != nil {
+			mark = a.theme.ErrorStyle.Render("✗")
+		}
+		method := lipgloss.NewStyle().Foreground(a.theme.MethodColor(o.req.Request.Method)).Render(fmt.Sprintf("%-7s", o.req.Request.Method))
+		line := fmt.Sprintf("%s %s %s", mark, method, o.req.Request.URI)
+		switch {
+		case o.err != nil:
+			line += "  " + a.theme.ErrorStyle.Render(o.err.Error())
+		case o.status != "":
+			code, _ := strconv.Atoi(strings.SplitN(o.status, " ", 2)[0])
+			line += "  " + lipgloss.NewStyle().Foreground(a.theme.StatusCodeColor(code)).Render(o.status)
+		default:
+			line += "  " + muted.Render("sent through ngrok")
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	a.resultViewport.SetContent(lipgloss.NewStyle().Width(width).Render(sb.String()))
+	return a.resultViewport.View()
+}
+
+// renderReplayAllPrompt renders the confirmation for replaying every listed
+// request in the footer
+func (a *App) renderReplayAllPrompt() string {
+	n := len(a.pendingRepeat.batch)
+	noun := "requests"
+	if n == 1 {
+		noun = "request"
+	}
+	prompt := lipgloss.NewStyle().Foreground(a.theme.ColorWarning).Bold(true).
+		Render(fmt.Sprintf("replay %d listed %s, oldest first? target:", n, noun))
+	hintText := "  (empty: through ngrok; enter: replay all, esc: cancel)"
+	if a.viewingHistory {
+		hintText = "  (a URL like http://localhost:8080; enter: replay all, esc: cancel)"
+	}
+	hint := lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Render(hintText)
+	if a.repeatErr != "" {
+		hint = a.theme.ErrorStyle.Render("  " + a.repeatErr)
+	}
+	return a.renderFooterBar(prompt + " " + renderInputCursor(a.repeatInput, a.repeatCursor) + hint)
+}
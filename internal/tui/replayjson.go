@@ -0,0 +1,77 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sung01299/mole/internal/util"
+)
+
+// replayEditIsJSON reports whether the edited request says its body is JSON
+func (a *App) replayEditIsJSON() bool {
+	for _, h := range a.replayEditHeaders {
+		if strings.EqualFold(h.Key, "Content-Type") {
+			mediaType, _, _ := strings.Cut(strings.ToLower(h.Value), ";")
+			mediaType = strings.TrimSpace(mediaType)
+			return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+		}
+	}
+	return false
+}
+
+// replayBodyJSONError returns where a JSON body being replayed is broken. A
+// body that isn't declared JSON, or is empty, is never broken.
+func (a *App) replayBodyJSONError(body string) *util.JSONError {
+	if !a.replayEditIsJSON() || strings.TrimSpace(body) == "" {
+		return nil
+	}
+	return util.CheckJSON(body)
+}
+
+// renderJSONStatus renders whether the body being edited is valid JSON, for
+// bodies declared as JSON
+func (a *App) renderJSONStatus(body string) string {
+	if !a.replayEditIsJSON() || strings.TrimSpace(body) == "" {
+		return ""
+	}
+	if err := util.CheckJSON(body); err != nil {
+		return a.theme.ErrorStyle.Render("JSON ✗ " + err.Error())
+	}
+	return lipgloss.NewStyle().Foreground(a.theme.ColorSecondary).Render("JSON ✓")
+}
+
+// formatReplayBody pretty-prints the JSON body being edited, keeping the
+// cursor at the end
+func (a *App) formatReplayBody() {
+	if err := util.CheckJSON(a.replayEditInput); err != nil {
+		a.setErrorStatus(err, 3*time.Second)
+		return
+	}
+	a.replayEditInput = util.PrettyJSON(a.replayEditInput)
+	a.replayEditCursor = len(a.replayEditInput)
+}
+
+// confirmJSONBody reports whether the edited request may be sent. A body
+// declared JSON that doesn't parse is warned about once; sending again goes
+// ahead anyway.
+func (a *App) confirmJSONBody() bool {
+	err := a.replayBodyJSONError(a.replayEditBody)
+	if err == nil || a.replayEditWarned {
+		return true
+	}
+	a.replayEditWarned = true
+	a.setErrorStatus(fmt.Errorf("body isn't valid JSON (%w), send again to send it anyway", err), 5*time.Second)
+	return false
+}
+
+// replayBodyLabel sums up the edited body for the replay edit menu
+func (a *App) replayBodyLabel() string {
+	label := fmt.Sprintf("(%d bytes)", len(a.replayEditBody))
+	if a.replayBodyJSONError(a.replayEditBody) != nil {
+		label = fmt.Sprintf("(%d bytes, invalid JSON)", len(a.replayEditBody))
+	}
+	return label
+}
@@ -0,0 +1,213 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sung01299/mole/internal/capture"
+	"github.com/sung01299/mole/internal/ngrok"
+)
+
+// pendingReplay is a replay sent through the tunnel that ngrok hasn't listed
+// yet. The first request with its method and URI to start after it was sent
+// is taken to be its capture.
+type pendingReplay struct {
+	parentID string // The request that was replayed
+	method   string
+	uri      string
+	sent     time.Time
+}
+
+// expectReplay notes that a replay of parentID is about to go out through the
+// tunnel, after delay, so its capture can be linked to it
+func (a *App) expectReplay(parentID, method, uri string, delay time.Duration) {
+	if parentID == "" {
+		return
+	}
+	a.pendingReplays = append(a.pendingReplays, pendingReplay{
+		parentID: parentID,
+		method:   method,
+		uri:      uri,
+		sent:     time.Now().Add(delay),
+	})
+}
+
+// linkReplays matches newly listed requests to the replays waiting for them,
+// oldest first. Replays that were never captured are dropped once
+// capture.ReplayCaptureWait has passed.
+func (a *App) linkReplays() {
+	if len(a.pendingReplays) == 0 {
+		return
+	}
+	var waiting []pendingReplay
+	linked := false
+	var diffWith *ngrok.Request
+	for _, p := range a.pendingReplays {
+		found, ok := capture.FindReplay(a.requests, p.method, p.uri, p.sent, func(id string) bool {
+			return id == p.parentID || a.replayOf[id] != ""
+		})
+		switch {
+		case ok:
+			a.replayOf[found.ID] = p.parentID
+			linked = true
+			if p.parentID == a.replayDiffPending {
+				diffWith = &found
+			}
+		case time.Since(p.sent) < capture.ReplayCaptureWait:
+			waiting = append(waiting, p)
+		}
+	}
+	a.pendingReplays = waiting
+	if linked {
+		// The selected request may be either end of the link
+		a.updateDetailViewport()
+	}
+	if diffWith != nil {
+		a.showPendingReplayDiff(*diffWith)
+	} else if a.replayDiffPending != "" && !a.awaitingReplay(a.replayDiffPending) {
+		a.replayDiffPending = ""
+		a.setStatus("The replay never showed up to diff", 3*time.Second)
+	}
+}
+
+// awaitingReplay reports whether a replay of id has been sent through the
+// tunnel but not listed yet
+func (a *App) awaitingReplay(id string) bool {
+	for _, p := range a.pendingReplays {
+		if p.parentID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// showPendingReplayDiff diffs a replay that was waited for against its
+// original. Should something else have been opened meanwhile, it is left
+// alone and P diffs the two later.
+func (a *App) showPendingReplayDiff(replay ngrok.Request) {
+	parent := a.replayDiffPending
+	a.replayDiffPending = ""
+	orig, ok := a.findRequest(parent)
+	if !ok {
+		return
+	}
+	if a.focus != FocusList && a.focus != FocusDetailPanel {
+		a.setStatus("Replay listed (P diffs it against the original)", 3*time.Second)
+		return
+	}
+	a.showDiff(orig, replay)
+}
+
+// replaysOf counts the listed replays of a request and returns the latest
+func (a *App) replaysOf(id string) (int, ngrok.Request) {
+	count := 0
+	var latest ngrok.Request
+	for _, req := range a.requests {
+		if a.replayOf[req.ID] != id {
+			continue
+		}
+		count++
+		if req.Start.After(latest.Start) {
+			latest = req
+		}
+	}
+	return count, latest
+}
+
+// findRequest returns a listed request by ID
+func (a *App) findRequest(id string) (ngrok.Request, bool) {
+	for _, req := range a.requests {
+		if req.ID == id {
+			return req, true
+		}
+	}
+	return ngrok.Request{}, false
+}
+
+// jumpToReplayLink selects the request the selected one replayed or, for an
+// original, its latest replay
+func (a *App) jumpToReplayLink() {
+	if len(a.filteredReqs) == 0 || a.selected >= len(a.filteredReqs) {
+		return
+	}
+	id := a.filteredReqs[a.selected].ID
+	target := a.replayOf[id]
+	if target == "" {
+		count, latest := a.replaysOf(id)
+		if count == 0 {
+			a.setStatus("Not a replay, and not replayed", 2*time.Second)
+			return
+		}
+		target = latest.ID
+	}
+	for i, req := range a.filteredReqs {
+		if req.ID == target {
+			a.selected = i
+			a.updateDetailViewport()
+			return
+		}
+	}
+	if _, ok := a.findRequest(target); ok {
+		a.setStatus("The linked request is hidden by the filters", 2*time.Second)
+	} else {
+		a.setStatus("The linked request is no longer listed", 2*time.Second)
+	}
+}
+
+// diffReplayLink opens the diff view on the selected request and the one it
+// replayed or, for an original, its latest replay. A replay sent but not
+// listed yet is waited for, and diffed as soon as it is.
+func (a *App) diffReplayLink() {
+	if len(a.filteredReqs) == 0 || a.selected >= len(a.filteredReqs) {
+		return
+	}
+	req := a.filteredReqs[a.selected]
+	if parent := a.replayOf[req.ID]; parent != "" {
+		orig, ok := a.findRequest(parent)
+		if !ok {
+			a.setStatus("The original request is no longer listed", 2*time.Second)
+			return
+		}
+		a.showDiff(orig, req)
+		return
+	}
+	if a.awaitingReplay(req.ID) {
+		a.replayDiffPending = req.ID
+		a.setStatus("Diffing the replay once ngrok lists it", 3*time.Second)
+		return
+	}
+	count, latest := a.replaysOf(req.ID)
+	if count == 0 {
+		a.setStatus("Not a replay, and not replayed", 2*time.Second)
+		return
+	}
+	a.showDiff(req, latest)
+}
+
+// renderReplayLineage renders where a request stands among replays: what it
+// replayed, and how often it has been replayed itself
+func (a *App) renderReplayLineage(req ngrok.Request) string {
+	style := lipgloss.NewStyle().Foreground(a.theme.ColorInfo)
+	var sb strings.Builder
+	if parent := a.replayOf[req.ID]; parent != "" {
+		if orig, ok := a.findRequest(parent); ok {
+			sb.WriteString(style.Render("↻ replay of "+orig.Start.Format("15:04:05")) + "\n")
+		} else {
+			sb.WriteString(style.Render("↻ replay of "+parent) + "\n")
+		}
+	}
+	if count, latest := a.replaysOf(req.ID); count > 0 {
+		times := "once"
+		if count > 1 {
+			times = fmt.Sprintf("%d times", count)
+		}
+		sb.WriteString(style.Render(fmt.Sprintf("↻ replayed %s, last at %s", times, latest.Start.Format("15:04:05"))) + "\n")
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return sb.String() + "\n"
+}
@@ -0,0 +1,189 @@
+package tui
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/sung01299/mole/internal/ngrok"
+	"github.com/sung01299/mole/internal/tui/messages"
+	"github.com/sung01299/mole/internal/util"
+)
+
+// Where replay with edit sends the request
+const (
+	replayTargetTunnel        = iota // The public tunnel URL, through ngrok
+	replayTargetLocal                // The tunnel's local address
+	replayTargetLocalKeepHost        // The local address, with the Host the request arrived with
+	replayTargetCustom               // A typed URL, as when no tunnel is running
+	replayTargetCount
+)
+
+// historyReplayHint explains why history can't be replayed through ngrok
+const historyReplayHint = "ngrok no longer holds history requests: R replays with edit, L locally"
+
+// replayBody returns the body to send when replaying req. History stores
+// bodies as plain text, decoded when they were captured, so they are sent
+// as they are.
+func (a *App) replayBody(req ngrok.Request) string {
+	if a.viewingHistory {
+		return a.withBodies(req).Request.Raw
+	}
+	return req.Request.DecodeBody()
+}
+
+// parseTargetURL checks a typed replay target, which must be an http or
+// https URL with a host, and drops any trailing slash
+func parseTargetURL(input string) (string, error) {
+	target := strings.TrimRight(strings.TrimSpace(input), "/")
+	if u, err := url.Parse(target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("target must be a URL like http://localhost:8080")
+	}
+	return target, nil
+}
+
+// tunnelFor returns the tunnel the request arrived on, falling back to the
+// first tunnel when it is unknown or has since closed
+func (a *App) tunnelFor(req ngrok.Request) *ngrok.Tunnel {
+	for i, t := range a.tunnels {
+		if req.TunnelName != "" && t.Name == req.TunnelName {
+			return &a.tunnels[i]
+		}
+	}
+	if len(a.tunnels) > 0 {
+		return &a.tunnels[0]
+	}
+	return nil
+}
+
+// localURL returns the address the request's tunnel forwards to, as a base
+// URL. ngrok reports it as given on its command line, so "3000" and
+// "localhost:3000" are filled out to http://localhost:3000.
+func (a *App) localURL(req ngrok.Request) string {
+	t := a.tunnelFor(req)
+	if t == nil || t.Config.Addr == "" {
+		return ""
+	}
+	addr := strings.TrimRight(t.Config.Addr, "/")
+	if !strings.Contains(addr, "://") {
+		if !strings.Contains(addr, ":") {
+			addr = "localhost:" + addr
+		}
+		addr = "http://" + addr
+	}
+	return addr
+}
+
+// originalHost returns the Host the request arrived with: its Host header,
+// or else the host of its tunnel's public URL
+func (a *App) originalHost(req ngrok.Request) string {
+	if hosts := util.HeaderValues(req.Request.Headers, "Host"); len(hosts) > 0 {
+		return hosts[0]
+	}
+	if u, err := url.Parse(a.tunnelURL(req)); err == nil {
+		return u.Host
+	}
+	return ""
+}
+
+// sentLocally notes on the result of send that the request went to addr
+// rather than through ngrok, which won't list it
+func sentLocally(send tea.Cmd, addr string) tea.Cmd {
+	return func() tea.Msg {
+		msg := send()
+		if m, ok := msg.(messages.EditedReplayMsg); ok {
+			m.Local = addr
+			return m
+		}
+		return msg
+	}
+}
+
+// replayLocally sends the request as captured straight to its tunnel's local
+// address, and shows what comes back
+func (a *App) replayLocally(req ngrok.Request) tea.Cmd {
+	addr := a.localURL(req)
+	if addr == "" {
+		a.lastError = fmt.Errorf("no local address known for this request's tunnel")
+		return nil
+	}
+	headers := make(map[string]string)
+	for _, h := range replayableHeaders(req.Request.Headers) {
+		headers[h.Key] = h.Value
+	}
+	send := sendReplay(req.Request.Method, addr+req.Request.URI, req.Request.URI, a.replayBody(req), headers)
+	return sentLocally(withAssertions(send, a.assertions[req.ID]), addr)
+}
+
+// cycleReplayTarget moves replay with edit on to the next target it can
+// send to. Reaching the typed target asks for its URL.
+func (a *App) cycleReplayTarget() {
+	for {
+		a.replayEditTarget = (a.replayEditTarget + 1) % replayTargetCount
+		switch a.replayEditTarget {
+		case replayTargetTunnel:
+			if len(a.tunnels) > 0 {
+				return
+			}
+		case replayTargetLocal, replayTargetLocalKeepHost:
+			if a.replayEditLocal != "" {
+				return
+			}
+		case replayTargetCustom:
+			a.promptTargetURL()
+			return
+		}
+	}
+}
+
+// promptTargetURL asks for the URL replay with edit sends to, offering the
+// one typed last
+func (a *App) promptTargetURL() {
+	a.replayEditStep = ReplayEditStepTargetURL
+	a.replayEditInput = a.replayEditURL
+	a.replayEditCursor = len(a.replayEditInput)
+	a.replayEditErr = ""
+}
+
+// handleReplayEditTargetURL edits the typed target. Enter keeps it once it
+// parses as a URL.
+func (a *App) handleReplayEditTargetURL(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEscape:
+		a.replayEditStep = ReplayEditStepMain
+		return nil
+
+	case tea.KeyEnter:
+		target, err := parseTargetURL(a.replayEditInput)
+		if err != nil {
+			a.replayEditErr = err.Error()
+			return nil
+		}
+		a.replayEditURL = target
+		a.replayEditTarget = replayTargetCustom
+		a.replayEditStep = ReplayEditStepMain
+		return nil
+	}
+
+	a.replayEditInput, a.replayEditCursor, _ = editLine(a.replayEditInput, a.replayEditCursor, msg)
+	a.replayEditErr = ""
+	return nil
+}
+
+// replayTargetLabel describes where replay with edit will send the request
+func (a *App) replayTargetLabel() string {
+	switch a.replayEditTarget {
+	case replayTargetLocal:
+		return a.replayEditLocal
+	case replayTargetLocalKeepHost:
+		return "local, Host " + a.replayEditHost
+	case replayTargetCustom:
+		if a.replayEditURL == "" {
+			return "(type a URL)"
+		}
+		return a.replayEditURL
+	}
+	return "tunnel"
+}
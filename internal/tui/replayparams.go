@@ -0,0 +1,234 @@
+package tui
+
+import (
+	"net/url"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// parseQueryParams splits the query of a request URI into its parameters,
+// decoded and in the order written. A parameter that doesn't decode is kept
+// as written.
+func parseQueryParams(uri string) []HeaderEntry {
+	_, query, ok := strings.Cut(uri, "?")
+	if !ok || query == "" {
+		return nil
+	}
+	var params []HeaderEntry
+	for _, pair := range strings.Split(query, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		params = append(params, HeaderEntry{Key: key, Value: value})
+	}
+	return params
+}
+
+// withQueryParams replaces the query of a request URI with params, encoded
+// and in order. Parameters without a name are left out.
+func withQueryParams(uri string, params []HeaderEntry) string {
+	path, _, _ := strings.Cut(uri, "?")
+	var pairs []string
+	for _, p := range params {
+		if p.Key != "" {
+			pairs = append(pairs, url.QueryEscape(p.Key)+"="+url.QueryEscape(p.Value))
+		}
+	}
+	if len(pairs) == 0 {
+		return path
+	}
+	return path + "?" + strings.Join(pairs, "&")
+}
+
+// handleReplayEditParams handles the query parameter list, which works like
+// the header list. Leaving it writes the parameters back into the path.
+func (a *App) handleReplayEditParams(msg tea.KeyMsg) tea.Cmd {
+	// Parameter list: each parameter + [Add New] + [Done]
+	totalItems := len(a.replayEditParams) + 2
+
+	switch msg.Type {
+	case tea.KeyEscape:
+		a.replayEditPath = withQueryParams(a.replayEditPath, a.replayEditParams)
+		a.replayEditStep = ReplayEditStepMain
+		a.replayEditSelected = 2
+		return nil
+
+	case tea.KeyEnter:
+		if a.replayEditSelected < len(a.replayEditParams) {
+			// Edit existing parameter
+			a.replayParamIdx = a.replayEditSelected
+			a.replayParamField = "key"
+			a.replayEditInput = a.replayEditParams[a.replayParamIdx].Key
+			a.replayEditCursor = len(a.replayEditInput)
+			a.replayEditStep = ReplayEditStepParamEdit
+		} else if a.replayEditSelected == len(a.replayEditParams) {
+			// Add new parameter
+			a.replayEditParams = append(a.replayEditParams, HeaderEntry{})
+			a.replayParamIdx = len(a.replayEditParams) - 1
+			a.replayParamField = "key"
+			a.replayEditInput = ""
+			a.replayEditCursor = 0
+			a.replayEditStep = ReplayEditStepParamEdit
+		} else {
+			// Done
+			a.replayEditPath = withQueryParams(a.replayEditPath, a.replayEditParams)
+			a.replayEditStep = ReplayEditStepMain
+			a.replayEditSelected = 2
+		}
+		return nil
+
+	case tea.KeyBackspace, tea.KeyDelete:
+		// Delete selected parameter
+		if a.replayEditSelected < len(a.replayEditParams) {
+			a.replayEditParams = append(a.replayEditParams[:a.replayEditSelected], a.replayEditParams[a.replayEditSelected+1:]...)
+			if a.replayEditSelected >= len(a.replayEditParams) && a.replayEditSelected > 0 {
+				a.replayEditSelected--
+			}
+		}
+		return nil
+
+	case tea.KeyUp:
+		if a.replayEditSelected > 0 {
+			a.replayEditSelected--
+		}
+		return nil
+
+	case tea.KeyDown:
+		if a.replayEditSelected < totalItems-1 {
+			a.replayEditSelected++
+		}
+		return nil
+	}
+	return nil
+}
+
+// handleReplayEditParamEdit edits a query parameter's name, then its value,
+// as typed rather than encoded
+func (a *App) handleReplayEditParamEdit(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEscape:
+		a.replayEditStep = ReplayEditStepParams
+		return nil
+
+	case tea.KeyEnter:
+		if a.replayParamField == "key" {
+			a.replayEditParams[a.replayParamIdx].Key = a.replayEditInput
+			a.replayParamField = "value"
+			a.replayEditInput = a.replayEditParams[a.replayParamIdx].Value
+			a.replayEditCursor = len(a.replayEditInput)
+		} else {
+			a.replayEditParams[a.replayParamIdx].Value = a.replayEditInput
+			a.replayEditStep = ReplayEditStepParams
+		}
+		return nil
+
+	case tea.KeyBackspace:
+		if len(a.replayEditInput) > 0 && a.replayEditCursor > 0 {
+			a.replayEditInput = a.replayEditInput[:a.replayEditCursor-1] + a.replayEditInput[a.replayEditCursor:]
+			a.replayEditCursor--
+		}
+		return nil
+
+	case tea.KeyLeft:
+		if a.replayEditCursor > 0 {
+			a.replayEditCursor--
+		}
+		return nil
+
+	case tea.KeyRight:
+		if a.replayEditCursor < len(a.replayEditInput) {
+			a.replayEditCursor++
+		}
+		return nil
+
+	case tea.KeyRunes:
+		char := string(msg.Runes)
+		a.replayEditInput = a.replayEditInput[:a.replayEditCursor] + char + a.replayEditInput[a.replayEditCursor:]
+		a.replayEditCursor += len(char)
+		return nil
+	}
+	return nil
+}
+
+// renderReplayEditParams renders the query parameter list, or the parameter
+// being edited, with the URI they make underneath
+func (a *App) renderReplayEditParams(width, height int) string {
+	var lines []string
+
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(a.theme.ColorPrimary)
+	mutedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorMuted)
+	selectedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorPrimary).Bold(true)
+
+	if a.replayEditStep == ReplayEditStepParamEdit {
+		fieldName := "Name"
+		if a.replayParamField == "value" {
+			fieldName = "Value"
+		}
+		lines = append(lines, titleStyle.Render("Edit Query Param "+fieldName))
+		lines = append(lines, "")
+		input := a.replayEditInput
+		if a.replayEditCursor < len(input) {
+			input = input[:a.replayEditCursor] + "█" + input[a.replayEditCursor:]
+		} else {
+			input = input + "█"
+		}
+		lines = append(lines, "> "+input)
+		return strings.Join(lines, "\n")
+	}
+
+	lines = append(lines, titleStyle.Render("Edit Query Params"))
+	lines = append(lines, mutedStyle.Render("Enter: edit  Backspace: delete"))
+	lines = append(lines, "")
+
+	maxVisible := height - 8
+	if maxVisible < 3 {
+		maxVisible = 3
+	}
+
+	totalItems := len(a.replayEditParams) + 2
+	startIdx := 0
+	if a.replayEditSelected >= maxVisible {
+		startIdx = a.replayEditSelected - maxVisible + 1
+	}
+	endIdx := min(startIdx+maxVisible, totalItems)
+
+	for i := startIdx; i < endIdx; i++ {
+		var item string
+		switch {
+		case i < len(a.replayEditParams):
+			p := a.replayEditParams[i]
+			item = p.Key + " = " + p.Value
+			if len(item) > width-4 {
+				item = item[:width-7] + "..."
+			}
+		case i == len(a.replayEditParams):
+			item = "[Add New Param]"
+		default:
+			item = "[Done]"
+		}
+		if i == a.replayEditSelected {
+			lines = append(lines, selectedStyle.Render("▶ "+item))
+		} else {
+			lines = append(lines, "  "+item)
+		}
+	}
+
+	uri := withQueryParams(a.replayEditPath, a.replayEditParams)
+	if len(uri) > width-2 {
+		uri = uri[:width-5] + "..."
+	}
+	lines = append(lines, "")
+	lines = append(lines, mutedStyle.Render(uri))
+	lines = append(lines, "")
+	lines = append(lines, mutedStyle.Render("↑↓: select  Enter: confirm  Esc: back"))
+	return strings.Join(lines, "\n")
+}
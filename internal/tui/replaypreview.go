@@ -0,0 +1,103 @@
+package tui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sung01299/mole/internal/util"
+)
+
+// previewEditedRequest shows the edited request exactly as it will go out,
+// to be confirmed before it is sent
+func (a *App) previewEditedRequest() {
+	baseURL, ok := a.replayEditBaseURL()
+	if !ok {
+		return
+	}
+	a.replayPreviewURL = baseURL + a.replayEditPath
+	a.replayEditStep = ReplayEditStepPreview
+	a.resultViewport.GotoTop()
+}
+
+// replayEditCurl returns the edited request as a cURL command
+func (a *App) replayEditCurl() string {
+	headers := make(map[string][]string)
+	host := ""
+	for k, v := range a.replayEditHeaderMap() {
+		if strings.EqualFold(k, "Host") {
+			host = v
+			continue
+		}
+		headers[k] = []string{v}
+	}
+	cmd := util.CurlCommand(a.replayEditMethod, headers, a.replayEditBody, util.ShellQuote(a.replayPreviewURL))
+	// CurlCommand leaves Host to curl, but a local target may keep the
+	// original one
+	if host != "" {
+		cmd = "curl -H " + util.ShellQuote("Host: "+host) + strings.TrimPrefix(cmd, "curl")
+	}
+	return cmd
+}
+
+// handleReplayEditPreview handles the preview: enter sends, c copies the
+// request as cURL without sending, esc goes back to editing
+func (a *App) handleReplayEditPreview(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		return a.sendEditedRequest(false)
+	case "esc":
+		a.replayEditStep = ReplayEditStepMain
+	case "c", "y":
+		return copyToClipboard(a.replayEditCurl())
+	case "up", "k":
+		a.resultViewport.LineUp(1)
+	case "down", "j":
+		a.resultViewport.LineDown(1)
+	}
+	return nil
+}
+
+// renderReplayPreview renders the edited request as it will be sent, and the
+// cURL command that sends it, in the detail panel
+func (a *App) renderReplayPreview(width, height int) string {
+	width = a.sizeResultViewport(width, height)
+
+	headers := make(map[string][]string)
+	for k, v := range a.replayEditHeaderMap() {
+		headers[k] = []string{v}
+	}
+	muted := lipgloss.NewStyle().Foreground(a.theme.ColorMuted)
+	method := lipgloss.NewStyle().Bold(true).Foreground(a.theme.MethodColor(a.replayEditMethod)).Render(a.replayEditMethod)
+
+	var sb strings.Builder
+	sb.WriteString(a.theme.DetailLabelStyle.Render("Preview"))
+	sb.WriteString(muted.Render("  nothing is sent until you press enter"))
+	sb.WriteString("\n\n")
+	sb.WriteString(method + " " + a.replayPreviewURL + "\n\n")
+	sb.WriteString(a.theme.DetailLabelStyle.Render("Request Headers:"))
+	sb.WriteString("\n")
+	sb.WriteString(a.renderHeaders(headers))
+	if a.replayEditBody != "" {
+		sb.WriteString("\n")
+		sb.WriteString(a.theme.DetailLabelStyle.Render("Request Body:"))
+		sb.WriteString("\n")
+		sb.WriteString(a.replayEditBody + "\n")
+	}
+	if len(a.replayEditAssertions) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(a.theme.DetailLabelStyle.Render("Assertions:"))
+		sb.WriteString("\n")
+		for _, text := range a.replayEditAssertions {
+			sb.WriteString("  " + text + "\n")
+		}
+	}
+	sb.WriteString("\n")
+	sb.WriteString(a.theme.DetailLabelStyle.Render("As cURL:"))
+	sb.WriteString("\n")
+	sb.WriteString(muted.Render(a.replayEditCurl()))
+
+	a.resultViewport.SetContent(lipgloss.NewStyle().Width(width).Render(sb.String()))
+	return a.resultViewport.View()
+}
@@ -0,0 +1,221 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sung01299/mole/internal/ngrok"
+	"github.com/sung01299/mole/internal/tui/messages"
+)
+
+// maxRepeats caps how many times one request is replayed in a run
+const maxRepeats = 1000
+
+// replayRun is a replay being repeated, or a list of requests being
+// replayed in turn. Each replay is sent once the last has come back and the
+// interval has passed, so a slow server is never sent more than one at a
+// time.
+type replayRun struct {
+	seq      int // Identifies the run, so replies to a stopped one are dropped
+	total    int
+	done     int
+	failed   int
+	interval time.Duration
+	last     string                    // Status of the last replay, when known
+	lastErr  error                     // Why the last replay failed
+	send     func(i int) tea.Msg       // Sends replay i, reporting a RepeatReplayMsg
+	expect   func(i int) pendingReplay // Replay i as ngrok will list it, when it goes through the tunnel
+	label    string                    // What is being replayed, for the prompt
+
+	// Replaying a list of requests
+	batch    []ngrok.Request
+	outcomes []replayOutcome // How each replay went, in order
+}
+
+// plainReplayStep replays a request through ngrok. ngrok doesn't say what the
+// replay got back, so there is no status to report.
+func (a *App) plainReplayStep(requestID string) func(int) tea.Msg {
+	return func(int) tea.Msg {
+		return messages.RepeatReplayMsg{Err: a.client.Replay(requestID)}
+	}
+}
+
+// editedReplayStep sends an edited request, reporting the status it got
+func editedReplayStep(send tea.Cmd) func(int) tea.Msg {
+	return func(int) tea.Msg {
+		return repeatReplayMsg(send())
+	}
+}
+
+// repeatReplayMsg reports a replay sent with sendReplay as one of a run
+func repeatReplayMsg(msg tea.Msg) messages.RepeatReplayMsg {
+	switch msg := msg.(type) {
+	case messages.EditedReplayMsg:
+		err := msg.Err
+		if failed := firstFailure(checkAssertions(msg.Assertions, msg.Request)); err == nil && failed != nil {
+			err = fmt.Errorf("assertion failed: %s", failed.describe())
+		}
+		return messages.RepeatReplayMsg{Status: msg.Request.Response.Status, Err: err}
+	case messages.ErrorMsg:
+		return messages.RepeatReplayMsg{Err: msg.Err}
+	}
+	return messages.RepeatReplayMsg{}
+}
+
+// promptRepeat asks how many times, and how far apart, to send a replay
+func (a *App) promptRepeat(label string, send func(int) tea.Msg) {
+	a.pendingRepeat = &replayRun{send: send, label: label}
+	a.repeatInput = a.lastRepeat
+	if a.repeatInput == "" {
+		a.repeatInput = "10 500ms"
+	}
+	a.repeatCursor = len(a.repeatInput)
+	a.repeatErr = ""
+	a.prevFocus = a.focus
+	a.focus = FocusRepeat
+}
+
+// parseRepeat reads a count and an optional interval, as in "10 500ms". A
+// bare number for the interval is in milliseconds.
+func parseRepeat(input string) (int, time.Duration, error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0, 0, fmt.Errorf("expected a count and an interval, as in 10 500ms")
+	}
+	count, err := strconv.Atoi(fields[0])
+	if err != nil || count < 1 || count > maxRepeats {
+		return 0, 0, fmt.Errorf("count must be a number from 1 to %d", maxRepeats)
+	}
+	if len(fields) == 1 {
+		return count, 0, nil
+	}
+	text := fields[1]
+	if _, err := strconv.Atoi(text); err == nil {
+		text += "ms"
+	}
+	interval, err := time.ParseDuration(text)
+	if err != nil || interval < 0 {
+		return 0, 0, fmt.Errorf("interval must be a duration, as in 500ms or 2s")
+	}
+	return count, interval, nil
+}
+
+// handleRepeatInput edits the repeat prompt. Enter starts the run.
+func (a *App) handleRepeatInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEnter:
+		count, interval, err := parseRepeat(a.repeatInput)
+		if err != nil {
+			a.repeatErr = err.Error()
+			return nil
+		}
+		a.lastRepeat = a.repeatInput
+		a.repeatSeq++
+		run := a.pendingRepeat
+		run.seq, run.total, run.interval = a.repeatSeq, count, interval
+		a.repeat = run
+		a.pendingRepeat = nil
+		a.focus = a.prevFocus
+		return a.repeatStep(0)
+
+	case tea.KeyEscape:
+		a.pendingRepeat = nil
+		a.focus = a.prevFocus
+		return nil
+	}
+
+	a.repeatInput, a.repeatCursor, _ = editLine(a.repeatInput, a.repeatCursor, msg)
+	a.repeatErr = ""
+	return nil
+}
+
+// repeatStep sends the run's next replay after delay
+func (a *App) repeatStep(delay time.Duration) tea.Cmd {
+	seq, send, i := a.repeat.seq, a.repeat.send, a.repeat.done
+	if expect := a.repeat.expect; expect != nil {
+		p := expect(i)
+		a.expectReplay(p.parentID, p.method, p.uri, delay)
+	}
+	step := func() tea.Msg {
+		msg := send(i).(messages.RepeatReplayMsg)
+		msg.Seq = seq
+		return msg
+	}
+	if delay == 0 {
+		return step
+	}
+	return tea.Tick(delay, func(time.Time) tea.Msg { return step() })
+}
+
+// updateRepeat counts a replay that came back and sends the next, or
+// reports how the run went once it is over
+func (a *App) updateRepeat(msg messages.RepeatReplayMsg) tea.Cmd {
+	run := a.repeat
+	if run == nil || msg.Seq != run.seq {
+		// The run was stopped
+		return nil
+	}
+	run.done++
+	run.last, run.lastErr = msg.Status, msg.Err
+	if msg.Err != nil {
+		run.failed++
+	}
+	if run.batch != nil {
+		run.outcomes = append(run.outcomes, replayOutcome{req: run.batch[run.done-1], status: msg.Status, err: msg.Err})
+	}
+
+	if run.done < run.total {
+		return tea.Batch(a.fetchRequests(), a.repeatStep(run.interval))
+	}
+
+	a.repeat = nil
+	if run.batch != nil {
+		a.showReplaySummary(run)
+		return a.fetchRequests()
+	}
+	if run.failed > 0 {
+		a.setErrorStatus(fmt.Errorf("%d of %d replays failed, the last with: %w", run.failed, run.total, run.lastErr), 5*time.Second)
+	} else {
+		a.setStatus(fmt.Sprintf("Replayed %d times", run.total), 5*time.Second)
+	}
+	return a.fetchRequests()
+}
+
+// stopRepeat cancels the run. A replay already sent still lands.
+func (a *App) stopRepeat() {
+	a.setStatus(fmt.Sprintf("Replay stopped at %d/%d", a.repeat.done, a.repeat.total), 3*time.Second)
+	a.repeat = nil
+}
+
+// renderRepeatBadge renders the run's progress for the footer
+func (a *App) renderRepeatBadge() string {
+	run := a.repeat
+	text := fmt.Sprintf("replay %d/%d", run.done, run.total)
+	switch {
+	case run.lastErr != nil:
+		text += ", last failed"
+	case run.last != "":
+		text += ", last status " + run.last
+	}
+	return a.theme.Badge(a.theme.ColorInfo, a.theme.ColorOnAccent).Padding(0, 1).Render(text)
+}
+
+// renderRepeatPrompt renders the repeat prompt in the footer
+func (a *App) renderRepeatPrompt() string {
+	prompt := lipgloss.NewStyle().Foreground(a.theme.ColorPrimary).Bold(true).
+		Render("repeat " + a.pendingRepeat.label + ":")
+	hintText := "  (count and interval; enter: start, esc: cancel)"
+	if a.repeatErr != "" {
+		hintText = "  " + a.repeatErr
+	}
+	hint := lipgloss.NewStyle().Foreground(a.theme.ColorMuted).Render(hintText)
+	if a.repeatErr != "" {
+		hint = a.theme.ErrorStyle.Render(hintText)
+	}
+	return a.renderFooterBar(prompt + " " + renderInputCursor(a.repeatInput, a.repeatCursor) + hint)
+}
@@ -0,0 +1,265 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sung01299/mole/internal/ngrok"
+	"github.com/sung01299/mole/internal/tui/messages"
+	"github.com/sung01299/mole/internal/util"
+)
+
+// replayBodyLimit is the most of an edited replay's response body kept to
+// show
+const replayBodyLimit = 1 << 20
+
+// replayResult is the response to an edited replay, shown as soon as it
+// comes back rather than once ngrok lists the request
+type replayResult struct {
+	req       ngrok.Request // What was sent and what came back, in the shape ngrok lists it
+	sent      time.Time
+	truncated bool   // Body cut off at replayBodyLimit
+	err       error  // Why no response came back
+	requestID string // The request ngrok captured for the replay, once listed
+	local     string // Local address it went to instead, which ngrok never sees
+	checks    []assertionResult
+}
+
+// sendReplay sends an edited request and reports what came back
+func sendReplay(method, url, path, body string, headers map[string]string) tea.Cmd {
+	return func() tea.Msg {
+		var reqBody io.Reader
+		if body != "" {
+			reqBody = strings.NewReader(body)
+		}
+
+		req, err := http.NewRequest(method, url, reqBody)
+		if err != nil {
+			return messages.ErrorMsg{Err: fmt.Errorf("failed to create request: %w", err)}
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		// Go sends the Host field, not a Host header
+		if host := req.Header.Get("Host"); host != "" {
+			req.Host = host
+			req.Header.Del("Host")
+		}
+
+		sent := time.Now()
+		result := ngrok.Request{
+			URI:   path,
+			Start: sent,
+			Request: ngrok.HTTPData{
+				Method:  method,
+				URI:     path,
+				Headers: req.Header,
+			},
+		}
+
+		client := &http.Client{Timeout: 30 * time.Second}
+		resp, err := client.Do(req)
+		if err != nil {
+			result.Duration = int64(time.Since(sent))
+			return messages.EditedReplayMsg{Request: result, Err: fmt.Errorf("request failed: %w", err)}
+		}
+		defer resp.Body.Close()
+
+		// One byte past the limit tells a body that fits from one that doesn't
+		data, err := io.ReadAll(io.LimitReader(resp.Body, replayBodyLimit+1))
+		result.Duration = int64(time.Since(sent))
+		truncated := len(data) > replayBodyLimit
+		if truncated {
+			data = data[:replayBodyLimit]
+		}
+
+		// Raw in the form ngrok keeps it, so the body is decoded the same way
+		var raw bytes.Buffer
+		fmt.Fprintf(&raw, "%s %s\r\n", resp.Proto, resp.Status)
+		resp.Header.Write(&raw)
+		raw.WriteString("\r\n")
+		raw.Write(data)
+
+		result.ResponseStatus = resp.Status
+		result.Response = ngrok.HTTPData{
+			Proto:      resp.Proto,
+			Headers:    resp.Header,
+			Raw:        base64.StdEncoding.EncodeToString(raw.Bytes()),
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		}
+		if truncated && resp.ContentLength > replayBodyLimit {
+			result.StoredResponseSize = int(resp.ContentLength)
+		}
+		if err != nil {
+			err = fmt.Errorf("reading response: %w", err)
+		}
+		return messages.EditedReplayMsg{Request: result, Truncated: truncated, Err: err}
+	}
+}
+
+// showReplayResult opens the response to an edited replay, unless another
+// mode has taken over input since it was sent
+func (a *App) showReplayResult(msg messages.EditedReplayMsg) {
+	a.replayResult = &replayResult{
+		req:       msg.Request,
+		sent:      msg.Request.Start,
+		truncated: msg.Truncated,
+		err:       msg.Err,
+		local:     msg.Local,
+		checks:    checkAssertions(msg.Assertions, msg.Request),
+	}
+	a.findReplayedRequest()
+
+	if a.focus != FocusList && a.focus != FocusDetailPanel {
+		failed := firstFailure(a.replayResult.checks)
+		switch {
+		case msg.Err != nil:
+			a.lastError = msg.Err
+		case failed != nil:
+			a.setErrorStatus(fmt.Errorf("replay returned %s, assertion failed: %s", msg.Request.Response.Status, failed.describe()), 5*time.Second)
+		case len(a.replayResult.checks) > 0:
+			a.setStatus("Replay returned "+msg.Request.Response.Status+", "+assertionSummary(a.replayResult.checks), 5*time.Second)
+		default:
+			a.setStatus("Replay returned "+msg.Request.Response.Status, 5*time.Second)
+		}
+		return
+	}
+	a.resultViewport.GotoTop()
+	a.prevFocus = a.focus
+	a.focus = FocusReplayResult
+}
+
+// findReplayedRequest looks for the request ngrok captured for the last
+// edited replay: the first with its method and path that started after it
+// was sent. The clocks are the same machine's, give or take ngrok's
+// rounding.
+func (a *App) findReplayedRequest() {
+	r := a.replayResult
+	if r == nil || r.requestID != "" || r.local != "" {
+		return
+	}
+	after := r.sent.Add(-time.Second)
+	var found *ngrok.Request
+	for i := range a.requests {
+		req := &a.requests[i]
+		if req.Request.Method != r.req.Request.Method || req.Request.URI != r.req.Request.URI || req.Start.Before(after) {
+			continue
+		}
+		if found == nil || req.Start.Before(found.Start) {
+			found = req
+		}
+	}
+	if found != nil {
+		r.requestID = found.ID
+	}
+}
+
+// isReplayedRequest reports whether a request is the capture of the last
+// edited replay
+func (a *App) isReplayedRequest(id string) bool {
+	return a.replayResult != nil && a.replayResult.requestID != "" && a.replayResult.requestID == id
+}
+
+// handleReplayResultInput handles keyboard input in the replay result view
+func (a *App) handleReplayResultInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "q":
+		a.focus = a.prevFocus
+	case "up", "k":
+		a.resultViewport.LineUp(1)
+	case "down", "j":
+		a.resultViewport.LineDown(1)
+	case "g":
+		a.resultViewport.GotoTop()
+	case "G":
+		a.resultViewport.GotoBottom()
+	case "enter":
+		// Go to the captured request, if ngrok has listed it and the
+		// filters keep it
+		id := a.replayResult.requestID
+		if a.replayResult.local != "" {
+			a.setStatus("Sent locally, so ngrok didn't capture it", 2*time.Second)
+			return nil
+		}
+		if id == "" {
+			a.setStatus("Replay not captured yet", 2*time.Second)
+			return nil
+		}
+		for i, req := range a.filteredReqs {
+			if req.ID == id {
+				a.selected = i
+				a.focus = a.prevFocus
+				a.updateDetailViewport()
+				return nil
+			}
+		}
+		a.setStatus("Replay hidden by filters", 2*time.Second)
+	}
+	return nil
+}
+
+// sizeResultViewport fits the result viewport to the text area of the
+// detail panel, which is its content width less the padding, and returns
+// that width
+func (a *App) sizeResultViewport(width, height int) int {
+	width -= 2
+	a.resultViewport.Width = width
+	a.resultViewport.Height = height
+	return width
+}
+
+// renderReplayResult renders the response to an edited replay
+func (a *App) renderReplayResult(width, height int) string {
+	r := a.replayResult
+	width = a.sizeResultViewport(width, height)
+
+	var sb strings.Builder
+	sb.WriteString(a.theme.DetailLabelStyle.Render("Replay result"))
+	muted := lipgloss.NewStyle().Foreground(a.theme.ColorMuted)
+	if r.local != "" {
+		sb.WriteString(muted.Render("  sent to " + r.local + ", bypassing ngrok"))
+	} else if r.requestID != "" {
+		sb.WriteString(muted.Render("  captured as " + r.requestID))
+	} else {
+		sb.WriteString(muted.Render("  waiting for ngrok to list it"))
+	}
+	sb.WriteString("\n\n")
+
+	if r.req.Response.StatusCode == 0 {
+		sb.WriteString(a.renderDetailTitle(r.req))
+		sb.WriteString(a.theme.ErrorStyle.Render(r.err.Error()) + "\n")
+		sb.WriteString(fmt.Sprintf("Duration: %.2fms\n", r.req.DurationMs()))
+		if len(r.checks) > 0 {
+			sb.WriteString("\n" + a.renderAssertionResults(r.checks))
+		}
+	} else {
+		tab := a.renderResponseTab(r.req)
+		// Duration goes under the status line
+		title := a.renderDetailTitle(r.req) + a.renderStatusLine(r.req)
+		sb.WriteString(title)
+		sb.WriteString(fmt.Sprintf("Duration: %.2fms\n", r.req.DurationMs()))
+		if len(r.checks) > 0 {
+			sb.WriteString("\n" + a.renderAssertionResults(r.checks))
+		}
+		sb.WriteString(strings.TrimPrefix(tab, title))
+		if r.truncated && r.req.StoredResponseSize == 0 {
+			sb.WriteString("\n" + muted.Italic(true).Render(
+				fmt.Sprintf("  (body truncated at %s)", util.FormatBytes(replayBodyLimit))))
+		}
+		if r.err != nil {
+			sb.WriteString("\n" + a.theme.ErrorStyle.Render(r.err.Error()))
+		}
+	}
+
+	a.resultViewport.SetContent(lipgloss.NewStyle().Width(width).Render(sb.String()))
+	return a.resultViewport.View()
+}
@@ -0,0 +1,29 @@
+package tui
+
+import "github.com/sung01299/mole/internal/ngrok"
+
+// RequestFilter matches requests against a filter expression, read the same
+// way as one typed with : in the TUI, for the commands that run without it
+type RequestFilter struct {
+	app     *App
+	filters []Filter
+}
+
+// NewRequestFilter parses a filter expression, as in
+// status >= 500 && path match "/api"
+func NewRequestFilter(expr string) (*RequestFilter, error) {
+	a := &App{}
+	filters, err := a.parseFilterExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &RequestFilter{app: a, filters: filters}, nil
+}
+
+// Match reports whether a request passes the filter. No request is starred,
+// as there is no history to look stars up in.
+func (f *RequestFilter) Match(req ngrok.Request) bool {
+	// Each request is matched once, so its decoded bodies needn't be kept
+	defer func() { f.app.bodies = nil }()
+	return f.app.matchesAllFilters(req, f.filters, nil)
+}
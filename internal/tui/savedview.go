@@ -0,0 +1,117 @@
+package tui
+
+import (
+	"encoding/json"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// savedView is the search and filter chain last used on a tunnel, kept in
+// the history database's settings so the next run can pick up where this
+// one left off
+type savedView struct {
+	Filters []Filter `json:"filters,omitempty"`
+	Search  string   `json:"search,omitempty"`
+}
+
+// savedViewKey is the setting a tunnel's view is saved under. Each tunnel
+// URL keeps its own, so different projects keep different defaults.
+func savedViewKey(tunnelURL string) string {
+	return "view:" + tunnelURL
+}
+
+// summary describes the view in one line, as the filter expression and the
+// search
+func (v *savedView) summary() string {
+	var parts []string
+	if len(v.Filters) > 0 {
+		parts = append(parts, formatFilterExpr(v.Filters))
+	}
+	if v.Search != "" {
+		parts = append(parts, "/"+v.Search)
+	}
+	return strings.Join(parts, "  ")
+}
+
+// saveView saves the current search and filters for the live tunnel. With
+// neither set, the saved view is removed. Nothing is saved while the restore
+// prompt is still waiting for an answer, so an unanswered prompt keeps the
+// old view.
+func (a *App) saveView() {
+	if a.storage == nil || len(a.tunnels) == 0 || a.pendingView != nil {
+		return
+	}
+
+	var value string
+	if len(a.activeFilters) > 0 || a.searchQuery != "" {
+		data, err := json.Marshal(savedView{Filters: a.activeFilters, Search: a.searchQuery})
+		if err != nil {
+			return
+		}
+		value = string(data)
+	}
+	a.storage.SetSetting(savedViewKey(a.tunnels[0].PublicURL), value)
+}
+
+// loadSavedView looks up the view last used on the tunnel and restores it,
+// or asks whether to. A saved view this version can't read back, like a
+// filter on a field that no longer exists, is ignored.
+func (a *App) loadSavedView(tunnelURL string) {
+	value, err := a.storage.GetSetting(savedViewKey(tunnelURL))
+	if err != nil || value == "" {
+		return
+	}
+	var v savedView
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		return
+	}
+	if len(v.Filters) > 0 {
+		// Read the chain back through the expression parser, which checks
+		// every field, operator and value
+		if v.Filters, err = a.parseFilterExpr(formatFilterExpr(v.Filters)); err != nil {
+			return
+		}
+	}
+	if _, err := compileSearch(v.Search); err != nil {
+		v.Search = ""
+	}
+	if len(v.Filters) == 0 && v.Search == "" {
+		return
+	}
+
+	// Don't replace filters made before the tunnel was known
+	if len(a.activeFilters) > 0 || a.searchQuery != "" {
+		return
+	}
+	if a.restoreView {
+		a.applyView(v)
+		return
+	}
+	if a.focus != FocusList {
+		return
+	}
+	a.pendingView = &v
+	a.prevFocus = a.focus
+	a.focus = FocusRestore
+}
+
+// handleRestoreInput answers the restore prompt: y restores the last view,
+// anything else starts without it
+func (a *App) handleRestoreInput(msg tea.KeyMsg) tea.Cmd {
+	a.focus = a.prevFocus
+	v := a.pendingView
+	a.pendingView = nil
+	if msg.String() == "y" && v != nil {
+		a.applyView(*v)
+	}
+	return nil
+}
+
+// applyView makes a saved view the current search and filters
+func (a *App) applyView(v savedView) {
+	a.activeFilters = v.Filters
+	a.searchQuery = v.Search
+	a.compileSearchQuery()
+	a.performSearch()
+}
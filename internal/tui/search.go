@@ -0,0 +1,275 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+
+	"github.com/sung01299/mole/internal/ngrok"
+)
+
+// regexSearchPrefix starts a search pattern that is a regular expression
+const regexSearchPrefix = "r/"
+
+// searchScope is the part of a request a search term is limited to
+type searchScope string
+
+const (
+	scopeAny    searchScope = "" // Everything searched by default
+	scopePath   searchScope = "path"
+	scopeBody   searchScope = "body"
+	scopeHeader searchScope = "header"
+	scopeStatus searchScope = "status"
+)
+
+// searchScopes are the prefixes that limit a search term, as in path:checkout
+var searchScopes = map[string]searchScope{
+	"path":   scopePath,
+	"body":   scopeBody,
+	"header": scopeHeader,
+	"status": scopeStatus,
+}
+
+// searchTerm is one part of a search query
+type searchTerm struct {
+	scope   searchScope
+	pattern searchPattern
+}
+
+// searchTerms is a compiled search query. A request must match every term.
+type searchTerms []searchTerm
+
+// compileSearch compiles a search query. A query with scoped terms, such as
+// "path:checkout status:500 error", is split on spaces into terms that must
+// all match. Any other query is a single term matched anywhere, as typed.
+func compileSearch(query string) (searchTerms, error) {
+	fields := strings.Fields(query)
+	scoped := false
+	for _, f := range fields {
+		if _, ok := parseScope(f); ok {
+			scoped = true
+			break
+		}
+	}
+	if !scoped {
+		fields = []string{query}
+	}
+
+	var terms searchTerms
+	for _, f := range fields {
+		scope, expr := scopeAny, f
+		if s, ok := parseScope(f); ok {
+			scope = s
+			_, expr, _ = strings.Cut(f, ":")
+		}
+		pattern, err := compilePattern(expr)
+		if err != nil {
+			return nil, err
+		}
+		if !pattern.empty() {
+			terms = append(terms, searchTerm{scope: scope, pattern: pattern})
+		}
+	}
+	return terms, nil
+}
+
+// parseScope returns the scope a term's prefix names
+func parseScope(term string) (searchScope, bool) {
+	prefix, _, ok := strings.Cut(term, ":")
+	if !ok {
+		return scopeAny, false
+	}
+	scope, ok := searchScopes[strings.ToLower(prefix)]
+	return scope, ok
+}
+
+// empty reports whether there is nothing to search for, so every request
+// passes the search
+func (t searchTerms) empty() bool {
+	return len(t) == 0
+}
+
+// find returns the start and end of each match in text, which belongs to
+// scope, merged across terms. Text outside every scope, like the method,
+// passes scopeAny and only gets unscoped matches.
+func (t searchTerms) find(text string, scope searchScope) [][2]int {
+	var spans [][2]int
+	for _, term := range t {
+		if term.scope == scopeAny || term.scope == scope {
+			spans = append(spans, term.pattern.find(text)...)
+		}
+	}
+	if len(spans) < 2 {
+		return spans
+	}
+
+	// Overlapping matches of different terms become one
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	merged := spans[:1]
+	for _, span := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if span[0] <= last[1] {
+			last[1] = max(last[1], span[1])
+		} else {
+			merged = append(merged, span)
+		}
+	}
+	return merged
+}
+
+// searchPattern is what a search term looks for: a substring, or a regular
+// expression when it starts with r/. Both ignore case.
+type searchPattern struct {
+	text string         // Lowercased substring
+	re   *regexp.Regexp // Set for regex patterns
+}
+
+// compilePattern compiles a search pattern. Regex patterns use Go's regexp
+// syntax, as in r/order_[0-9]{6}.
+func compilePattern(query string) (searchPattern, error) {
+	expr, isRegex := strings.CutPrefix(query, regexSearchPrefix)
+	if !isRegex {
+		return searchPattern{text: strings.ToLower(query)}, nil
+	}
+	if expr == "" {
+		return searchPattern{}, nil
+	}
+	re, err := compileRegex(expr)
+	if err != nil {
+		return searchPattern{}, err
+	}
+	return searchPattern{re: re}, nil
+}
+
+// compileRegex compiles a case-insensitive pattern, with a short error
+// message that fits in the footer
+func compileRegex(expr string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("(?i)" + expr)
+	if err != nil {
+		var syntaxErr *syntax.Error
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("invalid regex: %s", syntaxErr.Code)
+		}
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	return re, nil
+}
+
+// empty reports whether the pattern matches nothing in particular, so every
+// request passes the search
+func (p searchPattern) empty() bool {
+	return p.text == "" && p.re == nil
+}
+
+// matches reports whether s contains a match
+func (p searchPattern) matches(s string) bool {
+	if p.re != nil {
+		return p.re.MatchString(s)
+	}
+	return strings.Contains(strings.ToLower(s), p.text)
+}
+
+// find returns the start and end of each match in s, skipping empty ones
+func (p searchPattern) find(s string) [][2]int {
+	var spans [][2]int
+	if p.re != nil {
+		for _, loc := range p.re.FindAllStringIndex(s, -1) {
+			if loc[1] > loc[0] {
+				spans = append(spans, [2]int{loc[0], loc[1]})
+			}
+		}
+		return spans
+	}
+	if p.text == "" {
+		return nil
+	}
+
+	lower := strings.ToLower(s)
+	if len(lower) != len(s) {
+		// Lowercasing changed byte lengths, so positions in lower don't
+		// line up with s
+		return nil
+	}
+	for start := 0; ; {
+		idx := strings.Index(lower[start:], p.text)
+		if idx == -1 {
+			return spans
+		}
+		start += idx
+		spans = append(spans, [2]int{start, start + len(p.text)})
+		start += len(p.text)
+	}
+}
+
+// searchText is the text of a request a plain search term is looked for in,
+// lowercased once rather than on every keystroke and poll. Regex terms are
+// matched against the fields as they are.
+type searchText struct {
+	path    string
+	headers string // One "Name: value" line per header value
+	reqBody string
+	resBody string
+}
+
+// newSearchText lowercases the searchable text of a request with the given
+// decoded bodies
+func newSearchText(req ngrok.Request, reqBody, resBody string) *searchText {
+	var headers strings.Builder
+	for _, h := range []map[string][]string{req.Request.Headers, req.Response.Headers} {
+		for k, vals := range h {
+			for _, v := range vals {
+				headers.WriteString(k + ": " + v + "\n")
+			}
+		}
+	}
+	return &searchText{
+		path:    strings.ToLower(req.Request.URI),
+		headers: strings.ToLower(headers.String()),
+		reqBody: strings.ToLower(reqBody),
+		resBody: strings.ToLower(resBody),
+	}
+}
+
+// slashRegex returns the pattern of a filter value written as /pattern/
+func slashRegex(value string) (string, bool) {
+	if len(value) >= 2 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") {
+		return value[1 : len(value)-1], true
+	}
+	return "", false
+}
+
+// parsePrefix parses the value of an "in" filter: a CIDR range such as
+// 52.0.0.0/8, or a single address
+func parsePrefix(value string) (netip.Prefix, error) {
+	value = strings.TrimSpace(value)
+	if prefix, err := netip.ParsePrefix(value); err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid CIDR range: %q", value)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// addrInPrefix reports whether a remote address, with or without a port, is
+// in the range an "in" filter gives. Anything that doesn't parse is outside
+// it.
+func addrInPrefix(remoteAddr, value string) bool {
+	prefix, err := parsePrefix(value)
+	if err != nil {
+		return false
+	}
+	var addr netip.Addr
+	if addrPort, err := netip.ParseAddrPort(remoteAddr); err == nil {
+		addr = addrPort.Addr()
+	} else if addr, err = netip.ParseAddr(remoteAddr); err != nil {
+		return false
+	}
+	// IPv4 clients of a dual-stack listener show up as ::ffff:1.2.3.4
+	return prefix.Contains(addr.Unmap())
+}
@@ -0,0 +1,58 @@
+package tui
+
+import "testing"
+
+func TestSearchScopes(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"payment.42", 1},
+		{"PAYMENT.42", 1},  // Plain text ignores case
+		{"body:user7", 11}, // user7, and user70 to user79
+		{"path:/hooks/99", 1},
+		{"path:payment", 0}, // Only in the body
+		{"header:application/json", 100},
+		{"status:200", 100},
+		{"body:payment r/amount\":4200\\b", 1},
+		{"nowhere", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			a := newTestApp(testRequests(100))
+			a.searchQuery = tt.query
+			a.compileSearchQuery()
+			if a.searchErr != "" {
+				t.Fatalf("compiling %q: %s", tt.query, a.searchErr)
+			}
+			a.applyFilters()
+			if len(a.filteredReqs) != tt.want {
+				t.Errorf("%q matched %d requests, want %d", tt.query, len(a.filteredReqs), tt.want)
+			}
+		})
+	}
+}
+
+// BenchmarkSearch searches 1000 requests for text in their bodies, the
+// first time and again with the lowercased text kept from the first
+func BenchmarkSearch(b *testing.B) {
+	for _, warm := range []bool{false, true} {
+		name := "cold"
+		if warm {
+			name = "warm"
+		}
+		b.Run(name, func(b *testing.B) {
+			a := newTestApp(testRequests(1000))
+			a.searchQuery = "user999@example"
+			a.compileSearchQuery()
+			a.applyFilters()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if !warm {
+					a.bodies = nil
+				}
+				a.applyFilters()
+			}
+		})
+	}
+}
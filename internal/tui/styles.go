@@ -1,133 +1,388 @@
 package tui
 
-import "github.com/charmbracelet/lipgloss"
-
-// Color palette
-var (
-	ColorPrimary   = lipgloss.Color("#7C3AED") // Purple
-	ColorSecondary = lipgloss.Color("#10B981") // Green
-	ColorWarning   = lipgloss.Color("#F59E0B") // Amber
-	ColorError     = lipgloss.Color("#EF4444") // Red
-	ColorMuted     = lipgloss.Color("#6B7280") // Gray
-	ColorBorder    = lipgloss.Color("#374151") // Dark Gray
-	ColorHighlight = lipgloss.Color("#1F2937") // Darker Gray for backgrounds
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
 )
 
-// Status code colors
-func StatusCodeColor(code int) lipgloss.Color {
-	switch {
-	case code >= 200 && code < 300:
-		return ColorSecondary // Green for success
-	case code >= 300 && code < 400:
-		return lipgloss.Color("#3B82F6") // Blue for redirects
-	case code >= 400 && code < 500:
-		return ColorWarning // Amber for client errors
-	case code >= 500:
-		return ColorError // Red for server errors
-	default:
-		return ColorMuted
+// DefaultThemeName is the theme used unless the config or MOLE_THEME picks
+// another one
+const DefaultThemeName = "dark"
+
+// Theme holds the UI colors and the styles built from them. Render functions
+// read it from App, so the whole UI follows the chosen theme.
+type Theme struct {
+	Name string
+
+	// Color palette
+	ColorPrimary      lipgloss.TerminalColor // Accents and the focused panel
+	ColorSecondary    lipgloss.TerminalColor // Success, key hints
+	ColorWarning      lipgloss.TerminalColor // Client errors, slow requests
+	ColorError        lipgloss.TerminalColor // Server errors, very slow requests
+	ColorInfo         lipgloss.TerminalColor // Redirects, GET requests
+	ColorMuted        lipgloss.TerminalColor // Labels and secondary text
+	ColorBorder       lipgloss.TerminalColor // Unfocused panel borders
+	ColorHighlight    lipgloss.TerminalColor // Background of the selected row
+	ColorText         lipgloss.TerminalColor // Values
+	ColorSubtle       lipgloss.TerminalColor // List rows
+	ColorMatch        lipgloss.TerminalColor // Background of search matches
+	ColorCurrentMatch lipgloss.TerminalColor // Background of the current detail panel match
+	ColorOnPrimary    lipgloss.TerminalColor // Text on the primary color
+	ColorOnAccent     lipgloss.TerminalColor // Text on warning and match colors
+
+	// SyntaxStyle is the chroma style bodies are highlighted with
+	SyntaxStyle string
+
+	// Styles, built from the palette
+	BaseStyle         lipgloss.Style
+	HeaderStyle       lipgloss.Style
+	TunnelURLStyle    lipgloss.Style
+	TunnelLocalStyle  lipgloss.Style
+	ListTitleStyle    lipgloss.Style
+	SelectedItemStyle lipgloss.Style
+	NormalItemStyle   lipgloss.Style
+	MethodStyle       lipgloss.Style
+	StatusStyle       lipgloss.Style
+	PathStyle         lipgloss.Style
+	DurationStyle     lipgloss.Style
+	DetailTitleStyle  lipgloss.Style
+	DetailLabelStyle  lipgloss.Style
+	DetailValueStyle  lipgloss.Style
+	NoteStyle         lipgloss.Style
+	BorderStyle       lipgloss.Style
+	ActiveBorderStyle lipgloss.Style
+	HelpStyle         lipgloss.Style
+	HelpKeyStyle      lipgloss.Style
+	SearchMatchStyle  lipgloss.Style
+	CurrentMatchStyle lipgloss.Style
+	ErrorStyle        lipgloss.Style
+	SpinnerStyle      lipgloss.Style
+}
+
+// builtinThemes holds the palette of each built-in theme, by name
+var builtinThemes = map[string]Theme{
+	// Tuned for dark terminals
+	"dark": {
+		ColorPrimary:      lipgloss.Color("#7C3AED"), // Purple
+		ColorSecondary:    lipgloss.Color("#10B981"), // Green
+		ColorWarning:      lipgloss.Color("#F59E0B"), // Amber
+		ColorError:        lipgloss.Color("#EF4444"), // Red
+		ColorInfo:         lipgloss.Color("#3B82F6"), // Blue
+		ColorMuted:        lipgloss.Color("#6B7280"), // Gray
+		ColorBorder:       lipgloss.Color("#374151"), // Dark Gray
+		ColorHighlight:    lipgloss.Color("#1F2937"), // Darker Gray for backgrounds
+		ColorText:         lipgloss.Color("#FFFFFF"),
+		ColorSubtle:       lipgloss.Color("#D1D5DB"),
+		ColorMatch:        lipgloss.Color("#FBBF24"),
+		ColorCurrentMatch: lipgloss.Color("#F97316"),
+		ColorOnPrimary:    lipgloss.Color("#FFFFFF"),
+		ColorOnAccent:     lipgloss.Color("#000000"),
+		SyntaxStyle:       "monokai",
+	},
+	// Darker shades that stay readable on a white background
+	"light": {
+		ColorPrimary:      lipgloss.Color("#6D28D9"),
+		ColorSecondary:    lipgloss.Color("#047857"),
+		ColorWarning:      lipgloss.Color("#B45309"),
+		ColorError:        lipgloss.Color("#B91C1C"),
+		ColorInfo:         lipgloss.Color("#1D4ED8"),
+		ColorMuted:        lipgloss.Color("#4B5563"),
+		ColorBorder:       lipgloss.Color("#9CA3AF"),
+		ColorHighlight:    lipgloss.Color("#E5E7EB"),
+		ColorText:         lipgloss.Color("#111827"),
+		ColorSubtle:       lipgloss.Color("#374151"),
+		ColorMatch:        lipgloss.Color("#FDE68A"),
+		ColorCurrentMatch: lipgloss.Color("#FDBA74"),
+		ColorOnPrimary:    lipgloss.Color("#FFFFFF"),
+		ColorOnAccent:     lipgloss.Color("#000000"),
+		SyntaxStyle:       "github",
+	},
+	// Bright, saturated colors and white text on a dark background
+	"high-contrast": {
+		ColorPrimary:      lipgloss.Color("#D787FF"),
+		ColorSecondary:    lipgloss.Color("#00FF87"),
+		ColorWarning:      lipgloss.Color("#FFD700"),
+		ColorError:        lipgloss.Color("#FF5F5F"),
+		ColorInfo:         lipgloss.Color("#5FD7FF"),
+		ColorMuted:        lipgloss.Color("#D0D0D0"),
+		ColorBorder:       lipgloss.Color("#FFFFFF"),
+		ColorHighlight:    lipgloss.Color("#444444"),
+		ColorText:         lipgloss.Color("#FFFFFF"),
+		ColorSubtle:       lipgloss.Color("#FFFFFF"),
+		ColorMatch:        lipgloss.Color("#FFFF00"),
+		ColorCurrentMatch: lipgloss.Color("#FF8700"),
+		ColorOnPrimary:    lipgloss.Color("#000000"),
+		ColorOnAccent:     lipgloss.Color("#000000"),
+		SyntaxStyle:       "native",
+	},
+	// The terminal's own colors, with bold and reversed text for emphasis
+	"monochrome": {
+		ColorPrimary:      lipgloss.NoColor{},
+		ColorSecondary:    lipgloss.NoColor{},
+		ColorWarning:      lipgloss.NoColor{},
+		ColorError:        lipgloss.NoColor{},
+		ColorInfo:         lipgloss.NoColor{},
+		ColorMuted:        lipgloss.NoColor{},
+		ColorBorder:       lipgloss.NoColor{},
+		ColorHighlight:    lipgloss.NoColor{},
+		ColorText:         lipgloss.NoColor{},
+		ColorSubtle:       lipgloss.NoColor{},
+		ColorMatch:        lipgloss.NoColor{},
+		ColorCurrentMatch: lipgloss.NoColor{},
+		ColorOnPrimary:    lipgloss.NoColor{},
+		ColorOnAccent:     lipgloss.NoColor{},
+		SyntaxStyle:       "bw",
+	},
+}
+
+// ThemeNames returns the names of the built-in themes
+func ThemeNames() []string {
+	names := make([]string, 0, len(builtinThemes))
+	for name := range builtinThemes {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
 }
 
-// HTTP method colors
-func MethodColor(method string) lipgloss.Color {
-	switch method {
-	case "GET":
-		return lipgloss.Color("#3B82F6") // Blue
-	case "POST":
-		return ColorSecondary // Green
-	case "PUT", "PATCH":
-		return ColorWarning // Amber
-	case "DELETE":
-		return ColorError // Red
-	default:
-		return ColorMuted
+// NewTheme returns a built-in theme with some of its colors replaced.
+// Overrides are keyed by color name, as in the config file's colors section
+// (primary, muted, ...), with values like "#4B5563", an ANSI color number,
+// or "none" for the terminal's default.
+func NewTheme(name string, overrides map[string]string) (*Theme, error) {
+	if name == "" {
+		name = DefaultThemeName
+	}
+	t, ok := builtinThemes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+	t.Name = name
+
+	colors := t.colors()
+	for key, value := range overrides {
+		color, ok := colors[key]
+		if !ok {
+			names := make([]string, 0, len(colors))
+			for n := range colors {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown color %q (available: %s)", key, strings.Join(names, ", "))
+		}
+		parsed, err := parseColor(value)
+		if err != nil {
+			return nil, fmt.Errorf("color %s: %w", key, err)
+		}
+		*color = parsed
+	}
+
+	t.buildStyles()
+	return &t, nil
+}
+
+// colors maps the names used for overrides to the palette
+func (t *Theme) colors() map[string]*lipgloss.TerminalColor {
+	return map[string]*lipgloss.TerminalColor{
+		"primary":       &t.ColorPrimary,
+		"secondary":     &t.ColorSecondary,
+		"warning":       &t.ColorWarning,
+		"error":         &t.ColorError,
+		"info":          &t.ColorInfo,
+		"muted":         &t.ColorMuted,
+		"border":        &t.ColorBorder,
+		"highlight":     &t.ColorHighlight,
+		"text":          &t.ColorText,
+		"subtle":        &t.ColorSubtle,
+		"match":         &t.ColorMatch,
+		"current_match": &t.ColorCurrentMatch,
+		"on_primary":    &t.ColorOnPrimary,
+		"on_accent":     &t.ColorOnAccent,
 	}
 }
 
-// Styles
-var (
+// parseColor parses a hex color, an ANSI color number, or "none"
+func parseColor(value string) (lipgloss.TerminalColor, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case strings.EqualFold(value, "none"):
+		return lipgloss.NoColor{}, nil
+	case strings.HasPrefix(value, "#"):
+		hex := value[1:]
+		if _, err := strconv.ParseUint(hex, 16, 32); err != nil || (len(hex) != 3 && len(hex) != 6) {
+			return nil, fmt.Errorf("invalid hex color %q", value)
+		}
+		return lipgloss.Color(value), nil
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 && n <= 255 {
+		return lipgloss.Color(value), nil
+	}
+	return nil, fmt.Errorf("invalid color %q (use #RRGGBB, 0-255 or none)", value)
+}
+
+// buildStyles builds the styles from the palette
+func (t *Theme) buildStyles() {
 	// Base styles
-	BaseStyle = lipgloss.NewStyle()
+	t.BaseStyle = lipgloss.NewStyle()
 
 	// Header styles
-	HeaderStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#FFFFFF")).
-			Background(ColorPrimary).
-			Padding(0, 1)
+	t.HeaderStyle = t.Badge(t.ColorPrimary, t.ColorOnPrimary).
+		Bold(true).
+		Padding(0, 1)
 
-	TunnelURLStyle = lipgloss.NewStyle().
-			Foreground(ColorSecondary).
-			Bold(true)
+	t.TunnelURLStyle = lipgloss.NewStyle().
+		Foreground(t.ColorSecondary).
+		Bold(true)
 
-	TunnelLocalStyle = lipgloss.NewStyle().
-				Foreground(ColorMuted)
+	t.TunnelLocalStyle = lipgloss.NewStyle().
+		Foreground(t.ColorMuted)
 
 	// List styles
-	ListTitleStyle = lipgloss.NewStyle().
-			Foreground(ColorPrimary).
-			Bold(true)
+	t.ListTitleStyle = lipgloss.NewStyle().
+		Foreground(t.ColorPrimary).
+		Bold(true)
 
-	SelectedItemStyle = lipgloss.NewStyle().
-				Background(ColorHighlight).
-				Foreground(lipgloss.Color("#FFFFFF"))
+	t.SelectedItemStyle = t.Badge(t.ColorHighlight, t.ColorText)
 
-	NormalItemStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#D1D5DB"))
+	t.NormalItemStyle = lipgloss.NewStyle().
+		Foreground(t.ColorSubtle)
 
 	// Method badge style
-	MethodStyle = lipgloss.NewStyle().
-			Bold(true)
+	t.MethodStyle = lipgloss.NewStyle().
+		Bold(true)
 
 	// Status code style
-	StatusStyle = lipgloss.NewStyle().
-			Bold(true)
+	t.StatusStyle = lipgloss.NewStyle().
+		Bold(true)
 
 	// Path style
-	PathStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#D1D5DB"))
+	t.PathStyle = lipgloss.NewStyle().
+		Foreground(t.ColorSubtle)
 
 	// Duration style
-	DurationStyle = lipgloss.NewStyle().
-			Foreground(ColorMuted)
+	t.DurationStyle = lipgloss.NewStyle().
+		Foreground(t.ColorMuted)
 
 	// Detail panel styles
-	DetailTitleStyle = lipgloss.NewStyle().
-				Foreground(ColorPrimary).
-				Bold(true)
+	t.DetailTitleStyle = lipgloss.NewStyle().
+		Foreground(t.ColorPrimary).
+		Bold(true)
+
+	t.DetailLabelStyle = lipgloss.NewStyle().
+		Foreground(t.ColorMuted)
 
-	DetailLabelStyle = lipgloss.NewStyle().
-				Foreground(ColorMuted)
+	t.DetailValueStyle = lipgloss.NewStyle().
+		Foreground(t.ColorText)
 
-	DetailValueStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#FFFFFF"))
+	// Note style (user annotations on requests)
+	t.NoteStyle = lipgloss.NewStyle().
+		Foreground(t.ColorWarning).
+		Italic(true)
 
 	// Border styles with consistent padding
-	BorderStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(ColorBorder).
-			Padding(0, 1)
+	t.BorderStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.ColorBorder).
+		Padding(0, 1)
 
-	ActiveBorderStyle = lipgloss.NewStyle().
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(ColorPrimary).
-				Padding(0, 1)
+	activeBorder := lipgloss.RoundedBorder()
+	if isNoColor(t.ColorPrimary) {
+		// Without colors, the focused panel stands out by its heavier border
+		activeBorder = lipgloss.ThickBorder()
+	}
+	t.ActiveBorderStyle = lipgloss.NewStyle().
+		Border(activeBorder).
+		BorderForeground(t.ColorPrimary).
+		Padding(0, 1)
 
 	// Help/Footer styles
-	HelpStyle = lipgloss.NewStyle().
-			Foreground(ColorMuted)
+	t.HelpStyle = lipgloss.NewStyle().
+		Foreground(t.ColorMuted)
+
+	t.HelpKeyStyle = lipgloss.NewStyle().
+		Foreground(t.ColorSecondary).
+		Bold(true)
 
-	HelpKeyStyle = lipgloss.NewStyle().
-			Foreground(ColorSecondary).
-			Bold(true)
+	// Search match highlights; the current match in the detail panel
+	// stands out from the rest
+	t.SearchMatchStyle = t.Badge(t.ColorMatch, t.ColorOnAccent)
+	t.CurrentMatchStyle = t.Badge(t.ColorCurrentMatch, t.ColorOnAccent).
+		Bold(true)
+	if isNoColor(t.ColorMatch) {
+		// Reversed like any badge, so underline matches to tell them apart
+		t.SearchMatchStyle = t.SearchMatchStyle.Underline(true)
+		t.CurrentMatchStyle = t.CurrentMatchStyle.Underline(true)
+	}
 
 	// Error style
-	ErrorStyle = lipgloss.NewStyle().
-			Foreground(ColorError).
-			Bold(true)
+	t.ErrorStyle = lipgloss.NewStyle().
+		Foreground(t.ColorError).
+		Bold(true)
 
 	// Spinner style
-	SpinnerStyle = lipgloss.NewStyle().
-			Foreground(ColorPrimary)
-)
+	t.SpinnerStyle = lipgloss.NewStyle().
+		Foreground(t.ColorPrimary)
+}
+
+// Badge returns a style for fg text on a bg background. Without a
+// background color, as in the monochrome theme, the text is reversed.
+func (t *Theme) Badge(bg, fg lipgloss.TerminalColor) lipgloss.Style {
+	if isNoColor(bg) {
+		return lipgloss.NewStyle().Reverse(true)
+	}
+	return lipgloss.NewStyle().Background(bg).Foreground(fg)
+}
+
+// isNoColor reports whether c leaves the terminal's default color
+func isNoColor(c lipgloss.TerminalColor) bool {
+	_, ok := c.(lipgloss.NoColor)
+	return ok
+}
+
+// Status code colors
+func (t *Theme) StatusCodeColor(code int) lipgloss.TerminalColor {
+	switch {
+	case code >= 200 && code < 300:
+		return t.ColorSecondary // Green for success
+	case code >= 300 && code < 400:
+		return t.ColorInfo // Blue for redirects
+	case code >= 400 && code < 500:
+		return t.ColorWarning // Amber for client errors
+	case code >= 500:
+		return t.ColorError // Red for server errors
+	default:
+		return t.ColorMuted
+	}
+}
+
+// HTTP method colors
+func (t *Theme) MethodColor(method string) lipgloss.TerminalColor {
+	switch method {
+	case "GET":
+		return t.ColorInfo // Blue
+	case "POST":
+		return t.ColorSecondary // Green
+	case "PUT", "PATCH":
+		return t.ColorWarning // Amber
+	case "DELETE":
+		return t.ColorError // Red
+	default:
+		return t.ColorMuted
+	}
+}
+
+// Duration colors, in milliseconds
+func (t *Theme) DurationColor(ms float64) lipgloss.TerminalColor {
+	switch {
+	case ms > 2000:
+		return t.ColorError // Red for very slow
+	case ms > 500:
+		return t.ColorWarning // Amber for slow
+	default:
+		return t.ColorMuted
+	}
+}
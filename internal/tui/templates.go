@@ -0,0 +1,168 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sung01299/mole/internal/ngrok"
+	"github.com/sung01299/mole/internal/storage"
+)
+
+// promptTemplateName asks for the name to save the edited request under,
+// offering the name of the template it was loaded from
+func (a *App) promptTemplateName() {
+	if a.storage == nil {
+		a.setStatus("Templates need history storage", 2*time.Second)
+		return
+	}
+	a.replayEditStep = ReplayEditStepTemplateName
+	a.replayEditInput = a.replayEditTemplate
+	a.replayEditCursor = len(a.replayEditInput)
+}
+
+// handleReplayEditTemplateName edits the template name. Enter saves the
+// request as it stands, replacing a template of the same name.
+func (a *App) handleReplayEditTemplateName(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEscape:
+		a.replayEditStep = ReplayEditStepMain
+		return nil
+
+	case tea.KeyEnter:
+		name := strings.TrimSpace(a.replayEditInput)
+		if name == "" {
+			return nil
+		}
+		headers := make(map[string][]string)
+		for _, h := range a.replayEditHeaders {
+			if h.Key != "" {
+				headers[h.Key] = append(headers[h.Key], h.Value)
+			}
+		}
+		err := a.storage.SaveTemplate(storage.Template{
+			Name:       name,
+			Method:     a.replayEditMethod,
+			Path:       a.replayEditPath,
+			Headers:    headers,
+			Body:       a.replayEditBody,
+			Assertions: a.replayEditAssertions,
+		})
+		if err != nil {
+			a.setErrorStatus(err, 3*time.Second)
+			return nil
+		}
+		a.replayEditTemplate = name
+		a.replayEditStep = ReplayEditStepMain
+		a.setStatus("Saved template "+name, 2*time.Second)
+		return nil
+	}
+
+	a.replayEditInput, a.replayEditCursor, _ = editLine(a.replayEditInput, a.replayEditCursor, msg)
+	return nil
+}
+
+// openTemplates lists the saved templates to pick one to replay
+func (a *App) openTemplates() {
+	if a.storage == nil {
+		a.setStatus("Templates need history storage", 2*time.Second)
+		return
+	}
+	templates, err := a.storage.ListTemplates()
+	if err != nil {
+		a.setErrorStatus(err, 3*time.Second)
+		return
+	}
+	if len(templates) == 0 {
+		a.setStatus("No templates yet (save one from replay with edit)", 3*time.Second)
+		return
+	}
+	a.templates = templates
+	a.templateSelected = 0
+	a.prevFocus = a.focus
+	a.focus = FocusTemplates
+}
+
+// handleTemplatesInput handles keyboard input in the template browser
+func (a *App) handleTemplatesInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "q":
+		a.templates = nil
+		a.focus = a.prevFocus
+	case "up", "k":
+		if a.templateSelected > 0 {
+			a.templateSelected--
+		}
+	case "down", "j":
+		if a.templateSelected < len(a.templates)-1 {
+			a.templateSelected++
+		}
+	case "enter":
+		a.loadTemplate(a.templates[a.templateSelected])
+		a.templates = nil
+	case "x", "delete", "backspace":
+		t := a.templates[a.templateSelected]
+		if err := a.storage.DeleteTemplate(t.Name); err != nil {
+			a.setErrorStatus(err, 3*time.Second)
+			return nil
+		}
+		a.templates = append(a.templates[:a.templateSelected], a.templates[a.templateSelected+1:]...)
+		a.setStatus("Deleted template "+t.Name, 2*time.Second)
+		if len(a.templates) == 0 {
+			a.focus = a.prevFocus
+		} else if a.templateSelected >= len(a.templates) {
+			a.templateSelected--
+		}
+	}
+	return nil
+}
+
+// loadTemplate opens replay with edit on a saved template, to be sent to the
+// current tunnel
+func (a *App) loadTemplate(t storage.Template) {
+	a.initReplayEdit(ngrok.Request{})
+	a.replayEditMethod = t.Method
+	a.replayEditPath = t.Path
+	a.replayEditHeaders = replayableHeaders(t.Headers)
+	a.replayEditBody = t.Body
+	a.replayEditAssertions = t.Assertions
+	a.replayEditTemplate = t.Name
+	a.focus = FocusReplayEdit
+}
+
+// renderTemplates renders the template browser in the request list panel
+func (a *App) renderTemplates(width, height int) string {
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(a.theme.ColorPrimary)
+	mutedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorMuted)
+	selectedStyle := lipgloss.NewStyle().Foreground(a.theme.ColorPrimary).Bold(true)
+
+	lines := []string{titleStyle.Render("Templates"), mutedStyle.Render("Enter: load  x: delete"), ""}
+
+	maxVisible := max(height-4, 3)
+	startIdx := 0
+	if a.templateSelected >= maxVisible {
+		startIdx = a.templateSelected - maxVisible + 1
+	}
+	endIdx := min(startIdx+maxVisible, len(a.templates))
+
+	for i := startIdx; i < endIdx; i++ {
+		t := a.templates[i]
+		item := t.Name
+		request := fmt.Sprintf("%s %s", t.Method, t.Path)
+		if room := width - 4 - len(item); room > 5 {
+			if len(request) > room {
+				request = request[:room-3] + "..."
+			}
+			item += "  " + mutedStyle.Render(request)
+		}
+		if i == a.templateSelected {
+			lines = append(lines, selectedStyle.Render("▶ "+t.Name)+strings.TrimPrefix(item, t.Name))
+		} else {
+			lines = append(lines, "  "+item)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,137 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/sung01299/mole/internal/ngrok"
+)
+
+// trafficStats summarizes the listed requests for the stats bar. It is
+// worked out when the list changes, on each poll or filter change, so
+// rendering only has to format it.
+type trafficStats struct {
+	total   int
+	classes [6]int      // Requests per status class, as in classes[5] for 5xx
+	avgMs   float64     // Average duration of answered requests
+	p95Ms   float64     // 95th percentile duration of answered requests
+	timed   int         // Answered requests, which the durations cover
+	starts  []time.Time // Start of each request, newest first
+}
+
+// computeTrafficStats summarizes reqs
+func computeTrafficStats(reqs []ngrok.Request) trafficStats {
+	s := trafficStats{total: len(reqs), starts: make([]time.Time, 0, len(reqs))}
+	var durations []float64
+	var sum float64
+	for _, req := range reqs {
+		s.starts = append(s.starts, req.Start)
+		code := req.Response.StatusCode
+		if code == 0 {
+			// Still waiting for a response
+			continue
+		}
+		if class := code / 100; class < len(s.classes) {
+			s.classes[class]++
+		}
+		ms := req.DurationMs()
+		durations = append(durations, ms)
+		sum += ms
+	}
+	sort.Slice(s.starts, func(i, j int) bool { return s.starts[i].After(s.starts[j]) })
+
+	if s.timed = len(durations); s.timed > 0 {
+		sort.Float64s(durations)
+		s.avgMs = sum / float64(s.timed)
+		// Nearest rank
+		s.p95Ms = durations[int(math.Ceil(0.95*float64(s.timed)))-1]
+	}
+	return s
+}
+
+// since counts the requests started after t
+func (s trafficStats) since(t time.Time) int {
+	return sort.Search(len(s.starts), func(i int) bool { return !s.starts[i].After(t) })
+}
+
+// renderTrafficBar renders the stats bar for the listed requests
+func (a *App) renderTrafficBar() string {
+	s := a.traffic
+	label := func(text string) string { return a.theme.HelpStyle.Render(text) }
+
+	noun := "requests"
+	if s.total == 1 {
+		noun = "request"
+	}
+	parts := []string{fmt.Sprintf("%d %s", s.total, label(noun))}
+	if a.isFiltered() {
+		parts[0] += label(" (filtered)")
+	}
+	for _, class := range []int{2, 4, 5} {
+		count := lipgloss.NewStyle().Foreground(a.theme.StatusCodeColor(class * 100))
+		parts = append(parts, label(fmt.Sprintf("%dxx ", class))+count.Render(fmt.Sprint(s.classes[class])))
+	}
+	if s.timed > 0 {
+		p95 := lipgloss.NewStyle().Foreground(a.theme.DurationColor(s.p95Ms))
+		parts = append(parts,
+			label("avg ")+formatListDuration(s.avgMs),
+			label("p95 ")+p95.Render(formatListDuration(s.p95Ms)))
+	}
+	parts = append(parts, fmt.Sprintf("%d%s", s.since(time.Now().Add(-time.Minute)), label("/min")))
+	return " " + strings.Join(parts, "  ")
+}
+
+const (
+	sparklineWindow   = 5 * time.Minute // Span of time the header sparkline covers
+	sparklineMaxWidth = 60              // Widest the sparkline gets, in columns
+	sparklineMinWidth = 10              // Narrower than this, it isn't shown
+	sparklineMinTerm  = 80              // Terminal width below which it isn't shown
+)
+
+// sparkBlocks are the sparkline's bar heights, lowest first
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// renderSparkline renders the slowest duration of each slice of the last few
+// minutes as a bar, colored by the worst status in that slice, in width
+// columns. Slices without requests are blank.
+func (a *App) renderSparkline(reqs []ngrok.Request, now time.Time, width int) string {
+	slowest := make([]float64, width)
+	worst := make([]int, width)
+	seen := make([]bool, width)
+	start := now.Add(-sparklineWindow)
+	var top float64
+	for _, req := range reqs {
+		if req.Start.Before(start) || req.Response.StatusCode == 0 {
+			continue
+		}
+		i := min(int(req.Start.Sub(start)*time.Duration(width)/sparklineWindow), width-1)
+		seen[i] = true
+		if ms := req.DurationMs(); ms > slowest[i] {
+			slowest[i] = ms
+		}
+		if slowest[i] > top {
+			top = slowest[i]
+		}
+		worst[i] = max(worst[i], req.Response.StatusCode)
+	}
+
+	var sb strings.Builder
+	for i := range width {
+		if !seen[i] {
+			sb.WriteByte(' ')
+			continue
+		}
+		level := 0
+		if top > 0 {
+			level = int(slowest[i] / top * float64(len(sparkBlocks)-1))
+		}
+		style := lipgloss.NewStyle().Foreground(a.theme.StatusCodeColor(worst[i]))
+		sb.WriteString(style.Render(string(sparkBlocks[level])))
+	}
+	return sb.String()
+}
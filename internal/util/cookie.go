@@ -0,0 +1,50 @@
+package util
+
+import "strings"
+
+// HeaderValues returns every value of a header, whatever the case of its name
+func HeaderValues(headers map[string][]string, name string) []string {
+	var values []string
+	for k, vals := range headers {
+		if strings.EqualFold(k, name) {
+			values = append(values, vals...)
+		}
+	}
+	return values
+}
+
+// FormatCookies lists the cookies sent in a request's Cookie headers, one
+// name=value line each
+func FormatCookies(headers map[string][]string) []string {
+	var lines []string
+	for _, header := range HeaderValues(headers, "Cookie") {
+		for _, cookie := range strings.Split(header, ";") {
+			if cookie = strings.TrimSpace(cookie); cookie != "" {
+				lines = append(lines, cookie)
+			}
+		}
+	}
+	return lines
+}
+
+// FormatSetCookies lists the cookies a response sets, each header on its own:
+// a name=value line followed by its attributes, like "Path: /" or
+// "HttpOnly", on indented lines
+func FormatSetCookies(headers map[string][]string) []string {
+	var lines []string
+	for _, header := range HeaderValues(headers, "Set-Cookie") {
+		parts := strings.Split(header, ";")
+		lines = append(lines, strings.TrimSpace(parts[0]))
+		for _, attr := range parts[1:] {
+			attr = strings.TrimSpace(attr)
+			if attr == "" {
+				continue
+			}
+			if name, value, ok := strings.Cut(attr, "="); ok {
+				attr = strings.TrimSpace(name) + ": " + strings.TrimSpace(value)
+			}
+			lines = append(lines, "  "+attr)
+		}
+	}
+	return lines
+}
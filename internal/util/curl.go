@@ -0,0 +1,68 @@
+package util
+
+import (
+	"sort"
+	"strings"
+)
+
+// ShellQuote quotes a string for safe use as a single POSIX shell word
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	// Close the quote, emit an escaped quote, and reopen it
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// CurlCommand builds a cURL command line. target is appended verbatim as the
+// final argument, so callers must quote it themselves (see ShellQuote).
+func CurlCommand(method string, headers map[string][]string, body string, target string) string {
+	var parts []string
+	parts = append(parts, "curl")
+
+	// Method. curl sends a body as a POST unless told otherwise.
+	if method == "" {
+		method = "GET"
+	}
+	if method != "GET" || body != "" {
+		parts = append(parts, "-X", method)
+	}
+
+	// Headers, sorted so the output is stable
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		// Skip headers that curl handles automatically or are ngrok-specific
+		if SkipReplayHeader(key) || strings.EqualFold(key, "user-agent") {
+			continue
+		}
+		for _, v := range headers[key] {
+			parts = append(parts, "-H", ShellQuote(key+": "+v))
+		}
+	}
+
+	// Body
+	if body != "" {
+		parts = append(parts, "--data-raw", ShellQuote(body))
+	}
+
+	parts = append(parts, target)
+
+	return strings.Join(parts, " ")
+}
+
+// SkipReplayHeader reports whether a header is connection-specific and should
+// not be copied when re-sending a request. Content-Encoding is dropped too,
+// since bodies are re-sent decompressed.
+func SkipReplayHeader(key string) bool {
+	lowerKey := strings.ToLower(key)
+	return lowerKey == "host" ||
+		lowerKey == "content-length" ||
+		lowerKey == "content-encoding" ||
+		lowerKey == "accept-encoding" ||
+		strings.HasPrefix(lowerKey, "x-forwarded")
+}
@@ -0,0 +1,195 @@
+package util
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// DiffOp says what a diff does with a line
+type DiffOp int
+
+const (
+	DiffEqual  DiffOp = iota // In both texts
+	DiffDelete               // Only in the first text
+	DiffInsert               // Only in the second text
+)
+
+// DiffLine is one line of a line diff
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// maxDiffEdits bounds the work DiffLines does on texts with nothing in
+// common. Past it, what is left is shown as removed and then added.
+const maxDiffEdits = 2000
+
+// DiffLines returns a shortest line diff turning a into b, using Myers'
+// algorithm. Where lines are both removed and added, the removals come
+// first.
+func DiffLines(a, b []string) []DiffLine {
+	// Lines shared at either end need no search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines []DiffLine
+	for _, line := range a[:prefix] {
+		lines = append(lines, DiffLine{DiffEqual, line})
+	}
+	lines = append(lines, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{DiffEqual, line})
+	}
+	return lines
+}
+
+// myersDiff finds the shortest edit script from a to b. v holds, for each
+// diagonal k = x-y, the furthest x reached with d edits; a copy is kept for
+// every d to walk back along once b is reached.
+func myersDiff(a, b []string) []DiffLine {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replaceLines(a, b)
+	}
+
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	for d := 0; d <= n+m && d <= maxDiffEdits; d++ {
+		// Step d only reads diagonals -d..d of the step before
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Down: insert b[y]
+			} else {
+				x = v[offset+k-1] + 1 // Right: delete a[x]
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace)
+			}
+		}
+	}
+	return replaceLines(a, b)
+}
+
+// backtrackDiff walks back from the end of both texts through the saved
+// steps, collecting the edit script in reverse
+func backtrackDiff(a, b []string, trace [][]int) []DiffLine {
+	var reversed []DiffLine
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v[k-1+d] < v[k+1+d]) {
+			prevK = k + 1
+		}
+		prevX := v[prevK+d]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, DiffLine{DiffEqual, a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			reversed = append(reversed, DiffLine{DiffInsert, b[prevY]})
+		} else {
+			reversed = append(reversed, DiffLine{DiffDelete, a[prevX]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, DiffLine{DiffEqual, a[x-1]})
+		x--
+		y--
+	}
+
+	lines := make([]DiffLine, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines
+}
+
+// replaceLines is the diff that removes all of a and adds all of b
+func replaceLines(a, b []string) []DiffLine {
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	for _, line := range a {
+		lines = append(lines, DiffLine{DiffDelete, line})
+	}
+	for _, line := range b {
+		lines = append(lines, DiffLine{DiffInsert, line})
+	}
+	return lines
+}
+
+// DiffWords diffs two versions of a line word by word, where a word is a
+// run of letters and digits, a run of spaces, or any other single
+// character. It returns the line in pieces twice: a as kept or removed, and
+// b as kept or added.
+func DiffWords(a, b string) (before, after []DiffLine) {
+	for _, piece := range DiffLines(splitWords(a), splitWords(b)) {
+		if piece.Op != DiffInsert {
+			before = appendPiece(before, piece)
+		}
+		if piece.Op != DiffDelete {
+			after = appendPiece(after, piece)
+		}
+	}
+	return before, after
+}
+
+// appendPiece adds a piece to a line, joining it to the last one when they
+// match, so the line is in as few pieces as it can be
+func appendPiece(pieces []DiffLine, piece DiffLine) []DiffLine {
+	if n := len(pieces); n > 0 && pieces[n-1].Op == piece.Op {
+		pieces[n-1].Text += piece.Text
+		return pieces
+	}
+	return append(pieces, piece)
+}
+
+// splitWords splits a line into the words DiffWords compares
+func splitWords(s string) []string {
+	var words []string
+	class := func(r rune) int {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			return 1
+		case unicode.IsSpace(r):
+			return 2
+		}
+		return 0
+	}
+	start := 0
+	for i, r := range s {
+		if i > start {
+			prev, _ := utf8.DecodeLastRuneInString(s[:i])
+			if class(r) == 0 || class(r) != class(prev) {
+				words = append(words, s[start:i])
+				start = i
+			}
+		}
+	}
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+	return words
+}
@@ -0,0 +1,81 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// FetchSnippet builds a JavaScript fetch() call for a request. JSON bodies are
+// passed through JSON.stringify so they stay readable; anything else becomes a
+// template literal.
+func FetchSnippet(method string, headers map[string][]string, body string, url string) string {
+	var sb strings.Builder
+	sb.WriteString("fetch(" + jsString(url) + ", {\n")
+
+	if method == "" {
+		method = "GET"
+	}
+	sb.WriteString("  method: " + jsString(method) + ",\n")
+
+	// Headers, sorted so the output is stable
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		// Same headers curl leaves out; the browser sets these itself
+		if SkipReplayHeader(k) || strings.EqualFold(k, "user-agent") {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if len(keys) > 0 {
+		sb.WriteString("  headers: {\n")
+		for i, k := range keys {
+			sb.WriteString("    " + jsString(k) + ": " + jsString(strings.Join(headers[k], ", ")))
+			if i < len(keys)-1 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("  },\n")
+	}
+
+	if body != "" {
+		if IsJSON(body) {
+			// JSON is a valid JavaScript expression as it is
+			sb.WriteString("  body: JSON.stringify(" + indentContinuation(PrettyJSON(body), "  ") + "),\n")
+		} else {
+			sb.WriteString("  body: " + jsTemplateLiteral(body) + ",\n")
+		}
+	}
+
+	sb.WriteString("});")
+	return sb.String()
+}
+
+// jsString quotes s as a JavaScript string literal
+func jsString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return `""`
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// jsTemplateLiteral quotes s as a JavaScript template literal, escaping
+// backslashes, backticks and substitutions
+func jsTemplateLiteral(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "`", "\\`")
+	s = strings.ReplaceAll(s, "${", "\\${")
+	return "`" + s + "`"
+}
+
+// indentContinuation indents every line of text but the first
+func indentContinuation(text, prefix string) string {
+	return strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
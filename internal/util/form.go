@@ -0,0 +1,134 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// multipartPreviewBytes is how much of a text part is shown
+const multipartPreviewBytes = 1024
+
+// DecodeFormBody renders an application/x-www-form-urlencoded body as one
+// decoded "key: value" line per field, and a multipart/form-data body as its
+// parts. It reports false for other content types, or bodies that don't parse.
+func DecodeFormBody(body string, contentType string) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		return decodeURLEncoded(body), true
+	case "multipart/form-data":
+		if params["boundary"] == "" {
+			return "", false
+		}
+		return decodeMultipart(body, params["boundary"])
+	}
+	return "", false
+}
+
+// decodeURLEncoded decodes form fields in the order they were sent
+func decodeURLEncoded(body string) string {
+	return strings.Join(decodeFields(strings.TrimSpace(body)), "\n")
+}
+
+// QueryParams decodes the query string of a request URI into one
+// "key: value" line per parameter, in the order they were sent. A repeated
+// key gets a line for each value.
+func QueryParams(uri string) []string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil
+	}
+	return decodeFields(u.RawQuery)
+}
+
+// decodeFields decodes "&"-separated key=value pairs into "key: value" lines
+func decodeFields(encoded string) []string {
+	var lines []string
+	for _, field := range strings.Split(encoded, "&") {
+		if field == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(field, "=")
+		lines = append(lines, unescapeFormValue(key)+": "+unescapeFormValue(value))
+	}
+	return lines
+}
+
+// unescapeFormValue decodes a form key or value, keeping it as sent if it is
+// not validly escaped
+func unescapeFormValue(s string) string {
+	if decoded, err := url.QueryUnescape(s); err == nil {
+		return decoded
+	}
+	return s
+}
+
+// decodeMultipart lists each part with its headers and a preview of its
+// content. Binary parts are noted by size only.
+func decodeMultipart(body string, boundary string) (string, bool) {
+	r := multipart.NewReader(strings.NewReader(body), boundary)
+	var parts []string
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if len(parts) == 0 {
+				return "", false
+			}
+			// Usually a body cut short when it was stored
+			parts = append(parts, "(rest of the body could not be read)")
+			break
+		}
+
+		var sb strings.Builder
+		sb.WriteString("--- name: " + fmt.Sprintf("%q", part.FormName()))
+		if name := part.FileName(); name != "" {
+			sb.WriteString(", filename: " + fmt.Sprintf("%q", name))
+		}
+		sb.WriteString("\n")
+
+		// Other part headers, sorted so the output is stable
+		keys := make([]string, 0, len(part.Header))
+		for k := range part.Header {
+			if k != "Content-Disposition" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sb.WriteString(k + ": " + strings.Join(part.Header[k], ", ") + "\n")
+		}
+
+		content, err := io.ReadAll(part)
+		switch {
+		case err != nil:
+			sb.WriteString("(could not be read)")
+		case !isText(content):
+			sb.WriteString(fmt.Sprintf("(binary, %s)", FormatBytes(int64(len(content)))))
+		case len(content) > multipartPreviewBytes:
+			preview := strings.ToValidUTF8(string(content[:multipartPreviewBytes]), "")
+			sb.WriteString(preview + fmt.Sprintf("\n... (%s in total)", FormatBytes(int64(len(content)))))
+		default:
+			sb.WriteString(string(content))
+		}
+		parts = append(parts, sb.String())
+	}
+	return strings.Join(parts, "\n\n"), true
+}
+
+// isText reports whether content looks like text rather than binary data
+func isText(content []byte) bool {
+	return utf8.Valid(content) && !bytes.ContainsRune(content, 0)
+}
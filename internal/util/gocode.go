@@ -0,0 +1,97 @@
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// GoSnippet builds a standalone Go program that sends a request with
+// net/http. status is the response the request originally got, noted as the
+// expected result.
+func GoSnippet(method string, headers map[string][]string, body string, url string, status int) string {
+	if method == "" {
+		method = "GET"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("package main\n\nimport (\n\t\"fmt\"\n\t\"io\"\n\t\"net/http\"\n")
+	if body != "" {
+		sb.WriteString("\t\"strings\"\n")
+	}
+	sb.WriteString(")\n\nfunc main() {\n")
+
+	if body != "" {
+		sb.WriteString("\tbody := strings.NewReader(" + goStringLiteral(body) + ")\n")
+		sb.WriteString(fmt.Sprintf("\treq, err := http.NewRequest(%s, %s, body)\n", strconv.Quote(method), strconv.Quote(url)))
+	} else {
+		sb.WriteString(fmt.Sprintf("\treq, err := http.NewRequest(%s, %s, nil)\n", strconv.Quote(method), strconv.Quote(url)))
+	}
+	sb.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+
+	// Headers, sorted so the output is stable
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		// Same headers curl leaves out; net/http sets these itself
+		if SkipReplayHeader(k) || strings.EqualFold(k, "user-agent") {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for i, v := range headers[k] {
+			call := "Add"
+			if i == 0 {
+				call = "Set"
+			}
+			sb.WriteString(fmt.Sprintf("\treq.Header.%s(%s, %s)\n", call, strconv.Quote(k), strconv.Quote(v)))
+		}
+	}
+
+	sb.WriteString("\n\tclient := &http.Client{}\n")
+	sb.WriteString("\tresp, err := client.Do(req)\n")
+	sb.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	sb.WriteString("\tdefer resp.Body.Close()\n\n")
+	if status > 0 {
+		expected := strings.TrimSpace(fmt.Sprintf("%d %s", status, http.StatusText(status)))
+		sb.WriteString("\t// Expected status: " + expected + "\n")
+	}
+	sb.WriteString("\trespBody, err := io.ReadAll(resp.Body)\n")
+	sb.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n")
+	sb.WriteString("\tfmt.Println(resp.Status)\n")
+	sb.WriteString("\tfmt.Println(string(respBody))\n")
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// goStringLiteral quotes s as a Go string literal, preferring a raw string so
+// bodies stay readable
+func goStringLiteral(s string) string {
+	if canBeRawString(s) {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+// canBeRawString reports whether s survives a raw string literal unchanged.
+// Raw strings cannot hold backticks, the compiler drops carriage returns from
+// them, and source files may not contain NULs, BOMs or invalid UTF-8.
+func canBeRawString(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r == '`', r == '\uFEFF':
+			return false
+		case r < ' ' && r != '\n' && r != '\t':
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/quick"
+)
+
+// graphQLOperation is one operation in a GraphQL request body
+type graphQLOperation struct {
+	Query         *string         `json:"query"`
+	OperationName string          `json:"operationName"`
+	Variables     json.RawMessage `json:"variables"`
+}
+
+// IsGraphQLPath reports whether a request path looks like a GraphQL endpoint
+func IsGraphQLPath(path string) bool {
+	if i := strings.IndexByte(path, '?'); i != -1 {
+		path = path[:i]
+	}
+	return strings.Contains(strings.ToLower(path), "graphql")
+}
+
+// FormatGraphQL renders a GraphQL request body, a single operation or a
+// batched array of them, with each query shown as written and its variables
+// as JSON underneath. It reports false if the body isn't a GraphQL request.
+// Off a GraphQL path, a "query" key only counts if it holds a selection set,
+// so search APIs taking {"query": "shoes"} are left alone.
+func FormatGraphQL(body string, path string) (string, bool) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", false
+	}
+
+	var ops []graphQLOperation
+	if body[0] == '[' {
+		if err := json.Unmarshal([]byte(body), &ops); err != nil || len(ops) == 0 {
+			return "", false
+		}
+	} else {
+		var op graphQLOperation
+		if err := json.Unmarshal([]byte(body), &op); err != nil {
+			return "", false
+		}
+		ops = []graphQLOperation{op}
+	}
+
+	onPath := IsGraphQLPath(path)
+	for _, op := range ops {
+		if op.Query == nil || (!onPath && !strings.Contains(*op.Query, "{")) {
+			return "", false
+		}
+	}
+
+	var parts []string
+	for i, op := range ops {
+		var sb strings.Builder
+		if len(ops) > 1 {
+			sb.WriteString(fmt.Sprintf("Operation %d of %d\n", i+1, len(ops)))
+		}
+		if op.OperationName != "" {
+			sb.WriteString("Operation name: " + op.OperationName + "\n")
+		}
+		sb.WriteString(HighlightGraphQL(strings.TrimSpace(*op.Query)))
+
+		if vars := bytes.TrimSpace(op.Variables); len(vars) > 0 && string(vars) != "null" {
+			sb.WriteString("\n\nVariables:\n")
+			sb.WriteString(HighlightJSON(PrettyJSON(string(vars))))
+		}
+		parts = append(parts, sb.String())
+	}
+	return strings.Join(parts, "\n\n"), true
+}
+
+// HighlightGraphQL applies syntax highlighting to a GraphQL document
+// Returns the original if highlighting fails
+func HighlightGraphQL(data string) string {
+	if data == "" {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	if err := quick.Highlight(&buf, data, "graphql", "terminal256", SyntaxStyle); err != nil {
+		return data
+	}
+	return buf.String()
+}
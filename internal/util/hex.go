@@ -0,0 +1,79 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultHexDumpBytes is how much of a binary body the hex dump shows by default
+const DefaultHexDumpBytes = 4096
+
+// IsBinary reports whether a body is binary data rather than text: invalid
+// UTF-8, or control characters other than whitespace. Such bodies would
+// corrupt the terminal if printed as they are.
+func IsBinary(body string) bool {
+	if !utf8.ValidString(body) {
+		return true
+	}
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case c == '\t', c == '\n', c == '\r', c == '\f':
+		case c < ' ', c == 0x7f:
+			return true
+		}
+	}
+	return false
+}
+
+// HexDump renders data like hexdump -C: an offset, 16 bytes in hex and the
+// printable characters. Only the first limit bytes are shown (0 = no limit).
+func HexDump(data string, limit int) string {
+	shown := data
+	if limit > 0 && len(shown) > limit {
+		shown = shown[:limit]
+	}
+
+	var sb strings.Builder
+	for off := 0; off < len(shown); off += 16 {
+		line := shown[off:min(off+16, len(shown))]
+		sb.WriteString(fmt.Sprintf("%08x  ", off))
+		for i := 0; i < 16; i++ {
+			if i < len(line) {
+				sb.WriteString(fmt.Sprintf("%02x ", line[i]))
+			} else {
+				sb.WriteString("   ")
+			}
+			if i == 7 {
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString(" |")
+		for i := 0; i < len(line); i++ {
+			if c := line[i]; c >= ' ' && c < 0x7f {
+				sb.WriteByte(c)
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		sb.WriteString("|\n")
+	}
+	if len(shown) < len(data) {
+		sb.WriteString(fmt.Sprintf("... %s more", FormatBytes(int64(len(data)-len(shown)))))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// PrintableText replaces control characters and invalid UTF-8 with '.' so
+// binary data can be shown as text without corrupting the terminal
+func PrintableText(data string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t', r == '\n':
+			return r
+		case r == utf8.RuneError, r < ' ', r == 0x7f:
+			return '.'
+		}
+		return r
+	}, data)
+}
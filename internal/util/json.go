@@ -3,7 +3,10 @@ package util
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/alecthomas/chroma/v2/quick"
 )
@@ -32,6 +35,45 @@ func IsJSON(data string) bool {
 	return (data[0] == '{' || data[0] == '[') && json.Valid([]byte(data))
 }
 
+// JSONError is a mistake in a JSON document, at a 1-based line and column
+type JSONError struct {
+	Line, Col int
+	Msg       string
+}
+
+func (e *JSONError) Error() string {
+	return fmt.Sprintf("line %d col %d: %s", e.Line, e.Col, e.Msg)
+}
+
+// CheckJSON reports where a JSON document stops being valid, or nil if it
+// is valid
+func CheckJSON(data string) *JSONError {
+	var v any
+	err := json.Unmarshal([]byte(data), &v)
+	if err == nil {
+		return nil
+	}
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return &JSONError{Line: 1, Col: 1, Msg: err.Error()}
+	}
+
+	// Offset counts the bytes read, up to and including the one at fault
+	at := max(int(syntaxErr.Offset)-1, 0)
+	if syntaxErr.Offset == int64(len(data)) && strings.HasPrefix(syntaxErr.Error(), "unexpected end") {
+		at = len(data)
+	}
+	at = min(at, len(data))
+	before := data[:at]
+	line := strings.Count(before, "\n") + 1
+	col := utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:]) + 1
+	return &JSONError{Line: line, Col: col, Msg: syntaxErr.Error()}
+}
+
+// SyntaxStyle is the chroma style bodies are highlighted with. The TUI sets
+// it from its theme at startup.
+var SyntaxStyle = "monokai"
+
 // HighlightJSON applies syntax highlighting to JSON
 // Returns the highlighted string (with ANSI codes) or the original if highlighting fails
 func HighlightJSON(data string) string {
@@ -40,7 +82,7 @@ func HighlightJSON(data string) string {
 	}
 
 	var buf bytes.Buffer
-	err := quick.Highlight(&buf, data, "json", "terminal256", "monokai")
+	err := quick.Highlight(&buf, data, "json", "terminal256", SyntaxStyle)
 	if err != nil {
 		return data
 	}
@@ -48,7 +90,8 @@ func HighlightJSON(data string) string {
 }
 
 // FormatBody formats request/response body
-// If it's JSON, it will be pretty-printed and highlighted
+// If it's JSON, XML or HTML, it will be pretty-printed and highlighted, and
+// form bodies are decoded
 func FormatBody(body string, contentType string) string {
 	if body == "" {
 		return "(empty)"
@@ -62,6 +105,24 @@ func FormatBody(body string, contentType string) string {
 		return HighlightJSON(pretty)
 	}
 
+	// Form submissions, one field per line
+	if decoded, ok := DecodeFormBody(body, contentType); ok {
+		return decoded
+	}
+
+	// XML and HTML, by content type or a leading '<'
+	ct := strings.ToLower(contentType)
+	switch {
+	case strings.Contains(ct, "text/html"), !strings.Contains(ct, "xml") && IsMarkup(body) && looksLikeHTML(body):
+		return HighlightMarkup(PrettyHTML(body), "html")
+	case strings.Contains(ct, "xml"), IsMarkup(body):
+		if !wellFormedXML(body) {
+			// Malformed, so show it as it came
+			return body
+		}
+		return HighlightMarkup(indentMarkup(tokenizeMarkup(body, false), false), "xml")
+	}
+
 	return body
 }
 
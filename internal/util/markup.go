@@ -0,0 +1,271 @@
+package util
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/quick"
+)
+
+// markupToken is a piece of an XML or HTML document as it was written
+type markupToken struct {
+	kind markupKind
+	text string // The token exactly as written
+	name string // Tag name, for start and end tags
+}
+
+type markupKind int
+
+const (
+	markupText markupKind = iota
+	markupStart
+	markupEnd
+	markupSelfClosing // <br/>, and HTML void elements like <br>
+	markupOther       // Comments, CDATA sections, declarations and processing instructions
+)
+
+// htmlVoidElements never have an end tag
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true,
+	"img": true, "input": true, "link": true, "meta": true, "source": true,
+	"track": true, "wbr": true,
+}
+
+// htmlRawElements hold text that is shown as written rather than reindented
+var htmlRawElements = map[string]bool{
+	"script": true, "style": true, "pre": true, "textarea": true,
+}
+
+// IsMarkup reports whether a body looks like XML or HTML
+func IsMarkup(data string) bool {
+	return strings.HasPrefix(strings.TrimSpace(data), "<")
+}
+
+// looksLikeHTML reports whether a markup body is an HTML page
+func looksLikeHTML(data string) bool {
+	start := strings.ToLower(strings.TrimSpace(data))
+	return strings.HasPrefix(start, "<!doctype html") || strings.HasPrefix(start, "<html")
+}
+
+// PrettyXML indents an XML document. Namespace prefixes, CDATA sections and
+// comments are kept as written. Malformed XML is returned unchanged.
+func PrettyXML(data string) string {
+	if !wellFormedXML(data) {
+		return data
+	}
+	return indentMarkup(tokenizeMarkup(data, false), false)
+}
+
+// PrettyHTML indents an HTML document. HTML is rarely well-formed XML, so
+// void elements like <br> are recognised and mismatched tags are tolerated.
+func PrettyHTML(data string) string {
+	return indentMarkup(tokenizeMarkup(data, true), true)
+}
+
+// HighlightMarkup applies syntax highlighting with the "xml" or "html" lexer.
+// Returns the original if highlighting fails.
+func HighlightMarkup(data string, lexer string) string {
+	if data == "" {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	if err := quick.Highlight(&buf, data, lexer, "terminal256", SyntaxStyle); err != nil {
+		return data
+	}
+	return buf.String()
+}
+
+// wellFormedXML reports whether data parses as a single XML document
+func wellFormedXML(data string) bool {
+	dec := xml.NewDecoder(strings.NewReader(data))
+	dec.Strict = true
+	sawElement := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return sawElement
+		}
+		if err != nil {
+			return false
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			sawElement = true
+		}
+	}
+}
+
+// tokenizeMarkup splits markup into tags and text without interpreting it, so
+// the document can be reindented exactly as written
+func tokenizeMarkup(data string, html bool) []markupToken {
+	var toks []markupToken
+	rawUntil := "" // End tag that closes an HTML raw text element
+
+	for len(data) > 0 {
+		if rawUntil != "" {
+			end := strings.Index(strings.ToLower(data), rawUntil)
+			if end == -1 {
+				end = len(data)
+			}
+			if end > 0 {
+				toks = append(toks, markupToken{kind: markupText, text: data[:end]})
+			}
+			data = data[end:]
+			rawUntil = ""
+			continue
+		}
+
+		if data[0] != '<' {
+			end := strings.IndexByte(data, '<')
+			if end == -1 {
+				end = len(data)
+			}
+			toks = append(toks, markupToken{kind: markupText, text: data[:end]})
+			data = data[end:]
+			continue
+		}
+
+		var n int
+		kind := markupOther
+		switch {
+		case strings.HasPrefix(data, "<!--"):
+			n = tokenEnd(data, "-->")
+		case strings.HasPrefix(data, "<![CDATA["):
+			n = tokenEnd(data, "]]>")
+		case strings.HasPrefix(data, "<?"):
+			n = tokenEnd(data, "?>")
+		case strings.HasPrefix(data, "<!"):
+			n = tokenEnd(data, ">")
+		default:
+			n = tagEnd(data)
+			switch {
+			case strings.HasPrefix(data, "</"):
+				kind = markupEnd
+			case strings.HasSuffix(data[:n], "/>"):
+				kind = markupSelfClosing
+			default:
+				kind = markupStart
+			}
+		}
+
+		tok := markupToken{kind: kind, text: data[:n]}
+		if kind != markupOther {
+			tok.name = tagName(tok.text)
+			if html {
+				tok.name = strings.ToLower(tok.name)
+				if kind == markupStart && htmlVoidElements[tok.name] {
+					tok.kind = markupSelfClosing
+				}
+				if tok.kind == markupStart && htmlRawElements[tok.name] {
+					rawUntil = "</" + tok.name
+				}
+			}
+		}
+		toks = append(toks, tok)
+		data = data[n:]
+	}
+	return toks
+}
+
+// tokenEnd returns the length of the token at the start of data that ends
+// with suffix, or all of data if it is never closed
+func tokenEnd(data, suffix string) int {
+	if end := strings.Index(data, suffix); end != -1 {
+		return end + len(suffix)
+	}
+	return len(data)
+}
+
+// tagEnd returns the length of the tag at the start of data, skipping over
+// quoted attribute values that may contain '>'
+func tagEnd(data string) int {
+	var quote byte
+	for i := 1; i < len(data); i++ {
+		switch c := data[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+// tagName returns the name of a start or end tag, including any namespace
+// prefix
+func tagName(tag string) string {
+	name := strings.TrimLeft(tag, "</")
+	if end := strings.IndexAny(name, " \t\r\n/>"); end != -1 {
+		name = name[:end]
+	}
+	return name
+}
+
+// isInlineContent reports whether a token may share a line with the tags
+// around it: text, or a CDATA section
+func isInlineContent(tok markupToken) bool {
+	return tok.kind == markupText || strings.HasPrefix(tok.text, "<![CDATA[")
+}
+
+// indentMarkup puts each tag on its own line, indented by depth. An element
+// holding only text stays on one line, as in <id>42</id>.
+func indentMarkup(toks []markupToken, html bool) string {
+	var lines []string
+	depth := 0
+	indent := func() string { return strings.Repeat("  ", depth) }
+
+	for i := 0; i < len(toks); i++ {
+		tok := toks[i]
+		switch tok.kind {
+		case markupStart:
+			// <a>text</a> and <a></a> on one line
+			if i+1 < len(toks) && toks[i+1].kind == markupEnd && toks[i+1].name == tok.name {
+				lines = append(lines, indent()+tok.text+toks[i+1].text)
+				i++
+				continue
+			}
+			if i+2 < len(toks) && isInlineContent(toks[i+1]) && toks[i+2].kind == markupEnd &&
+				toks[i+2].name == tok.name && !strings.Contains(strings.TrimSpace(toks[i+1].text), "\n") {
+				text := toks[i+1].text
+				if !(html && htmlRawElements[tok.name]) {
+					text = strings.TrimSpace(text)
+				}
+				lines = append(lines, indent()+tok.text+text+toks[i+2].text)
+				i += 2
+				continue
+			}
+			lines = append(lines, indent()+tok.text)
+			depth++
+
+		case markupEnd:
+			depth = max(depth-1, 0)
+			lines = append(lines, indent()+tok.text)
+
+		case markupText:
+			text := strings.TrimSpace(tok.text)
+			if text == "" {
+				continue
+			}
+			if html && i > 0 && toks[i-1].kind == markupStart && htmlRawElements[toks[i-1].name] {
+				// Script, style and pre content keeps its own layout
+				lines = append(lines, strings.Trim(tok.text, "\r\n"))
+				continue
+			}
+			for _, line := range strings.Split(text, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					lines = append(lines, indent()+line)
+				}
+			}
+
+		default:
+			lines = append(lines, indent()+tok.text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,91 @@
+package util
+
+import "testing"
+
+func TestPrettyXML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "nested elements",
+			in:   `<a><b>1</b><c><d/></c></a>`,
+			want: "<a>\n  <b>1</b>\n  <c>\n    <d/>\n  </c>\n</a>",
+		},
+		{
+			name: "namespaces",
+			in:   `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><m:Ping xmlns:m="urn:x">hi</m:Ping></soap:Body></soap:Envelope>`,
+			want: "<soap:Envelope xmlns:soap=\"http://schemas.xmlsoap.org/soap/envelope/\">\n  <soap:Body>\n    <m:Ping xmlns:m=\"urn:x\">hi</m:Ping>\n  </soap:Body>\n</soap:Envelope>",
+		},
+		{
+			name: "CDATA kept as written",
+			in:   `<a><![CDATA[<b>not a tag</b>]]></a>`,
+			want: `<a><![CDATA[<b>not a tag</b>]]></a>`,
+		},
+		{
+			name: "CDATA among elements",
+			in:   `<a><b><![CDATA[x < y]]></b><c/></a>`,
+			want: "<a>\n  <b><![CDATA[x < y]]></b>\n  <c/>\n</a>",
+		},
+		{
+			name: "declaration and comment",
+			in:   `<?xml version="1.0"?><!-- c --><a><b/><c>t</c></a>`,
+			want: "<?xml version=\"1.0\"?>\n<!-- c -->\n<a>\n  <b/>\n  <c>t</c>\n</a>",
+		},
+		{
+			name: "mismatched tags left as they are",
+			in:   `<a><b></a>`,
+			want: `<a><b></a>`,
+		},
+		{
+			name: "unclosed element left as it is",
+			in:   `<a>`,
+			want: `<a>`,
+		},
+		{
+			name: "not XML",
+			in:   `plain text`,
+			want: `plain text`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PrettyXML(tt.in); got != tt.want {
+				t.Errorf("PrettyXML(%q) =\n%s\nwant\n%s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrettyHTML(t *testing.T) {
+	in := `<html><body><p>Hi<br>there</p><img src="x"></body></html>`
+	want := "<html>\n  <body>\n    <p>\n      Hi\n      <br>\n      there\n    </p>\n    <img src=\"x\">\n  </body>\n</html>"
+	if got := PrettyHTML(in); got != want {
+		t.Errorf("PrettyHTML(%q) =\n%s\nwant\n%s", in, got, want)
+	}
+}
+
+func TestFormatBodyMarkup(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		raw         bool // Shown as it came rather than formatted
+	}{
+		{"xml by content type", `<a><b/></a>`, "application/xml", false},
+		{"xml with charset", `<a><b/></a>`, "text/xml; charset=utf-8", false},
+		{"sniffed xml", `<a><b/></a>`, "", false},
+		{"html by content type", `<p>hi</p>`, "text/html", false},
+		{"malformed xml", `<a><b></a>`, "application/xml", true},
+		{"sniffed malformed xml", `<a><b></a>`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatBody(tt.body, tt.contentType)
+			if (got == tt.body) != tt.raw {
+				t.Errorf("FormatBody(%q, %q) = %q, want raw: %v", tt.body, tt.contentType, got, tt.raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// querySegment is one step of a parsed query: an object key or an array index
+type querySegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseQuery parses a jq-style path such as .data.object.id, .items[0].status
+// or ."odd key"[-1]. A lone "." selects the whole document.
+func parseQuery(expr string) ([]querySegment, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, ".") && !strings.HasPrefix(expr, "[") {
+		return nil, fmt.Errorf("query must start with '.'")
+	}
+
+	var segs []querySegment
+	i := 0
+	for i < len(expr) {
+		switch expr[i] {
+		case '.':
+			i++
+			if i == len(expr) {
+				if len(segs) > 0 {
+					return nil, fmt.Errorf("missing key after '.' at position %d", i)
+				}
+				return segs, nil
+			}
+			switch {
+			case expr[i] == '"':
+				key, n, err := parseQuotedKey(expr[i:])
+				if err != nil {
+					return nil, fmt.Errorf("%w at position %d", err, i+1)
+				}
+				segs = append(segs, querySegment{key: key})
+				i += n
+			case expr[i] == '[':
+				// .[0] is the same as [0]
+			case isKeyChar(expr[i]):
+				start := i
+				for i < len(expr) && isKeyChar(expr[i]) {
+					i++
+				}
+				segs = append(segs, querySegment{key: expr[start:i]})
+			default:
+				return nil, fmt.Errorf("unexpected %q at position %d", expr[i], i+1)
+			}
+
+		case '[':
+			end := strings.IndexByte(expr[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("missing ']' for '[' at position %d", i+1)
+			}
+			inner := strings.TrimSpace(expr[i+1 : i+end])
+			if strings.HasPrefix(inner, `"`) {
+				key, n, err := parseQuotedKey(inner)
+				if err != nil || n != len(inner) {
+					return nil, fmt.Errorf("invalid key %s at position %d", inner, i+2)
+				}
+				segs = append(segs, querySegment{key: key})
+			} else {
+				idx, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q at position %d", inner, i+2)
+				}
+				segs = append(segs, querySegment{index: idx, isIndex: true})
+			}
+			i += end + 1
+
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d", expr[i], i+1)
+		}
+	}
+	return segs, nil
+}
+
+// parseQuotedKey reads a JSON string literal at the start of s and returns it
+// with the number of bytes it took up
+func parseQuotedKey(s string) (string, int, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			var key string
+			if err := json.Unmarshal([]byte(s[:i+1]), &key); err != nil {
+				return "", 0, fmt.Errorf("invalid quoted key")
+			}
+			return key, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quoted key")
+}
+
+// isKeyChar reports whether c can appear in an unquoted key
+func isKeyChar(c byte) bool {
+	return c == '_' || c == '-' || c == '$' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// QueryJSON evaluates a jq-style path against a JSON document and returns the
+// selected value as indented JSON. Like jq, a missing key yields null.
+func QueryJSON(data string, expr string) (string, error) {
+	segs, err := parseQuery(expr)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(data) == "" {
+		return "", fmt.Errorf("body is empty")
+	}
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber() // Keep large IDs exact
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("body is not JSON")
+	}
+
+	for _, seg := range segs {
+		switch cur := v.(type) {
+		case nil:
+			// Indexing null gives null, as in jq
+		case map[string]any:
+			if seg.isIndex {
+				return "", fmt.Errorf("cannot index object with number %d", seg.index)
+			}
+			v = cur[seg.key]
+		case []any:
+			if !seg.isIndex {
+				return "", fmt.Errorf("cannot index array with %q", seg.key)
+			}
+			idx := seg.index
+			if idx < 0 {
+				idx += len(cur)
+			}
+			if idx < 0 || idx >= len(cur) {
+				v = nil
+			} else {
+				v = cur[idx]
+			}
+		default:
+			return "", fmt.Errorf("cannot index %s", jsonTypeName(cur))
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// jsonTypeName names the JSON type of a decoded scalar value
+func jsonTypeName(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "value"
+}
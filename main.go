@@ -1,49 +1,421 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/sung01299/mole/internal/capture"
+	"github.com/sung01299/mole/internal/config"
+	"github.com/sung01299/mole/internal/debuglog"
 	"github.com/sung01299/mole/internal/ngrok"
+	"github.com/sung01299/mole/internal/storage"
 	"github.com/sung01299/mole/internal/tui"
 )
 
-const version = "0.1.0"
-
 func main() {
-	// Check for version flag
-	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-v") {
-		fmt.Printf("mole %s\n", version)
+	showVersion := flag.Bool("version", false, "print the version and build details, and exit")
+	flag.BoolVar(showVersion, "v", false, "shorthand for --version")
+	versionJSON := flag.Bool("json", false, "with --version, print them as JSON")
+	apiFlag := flag.String("api", "", "URL of ngrok's local API (env NGROK_API_URL, default "+ngrok.DefaultBaseURL+")")
+	poll := flag.Duration("poll", tui.DefaultPollInterval, "how often to poll ngrok for requests, at least "+tui.MinPollInterval.String())
+	idlePoll := flag.Duration("idle-poll", tui.DefaultIdlePollInterval, "how often to poll while the terminal window is out of focus")
+	limit := flag.Int("limit", capture.DefaultPollLimit, "how many of its latest requests ngrok is asked for each poll")
+	dbFlag := flag.String("db", "", "path to the history database, or :memory: to keep nothing (env MOLE_DB_PATH)")
+	noStore := flag.Bool("no-store", false, "don't persist request history (same as --db :memory:)")
+	themeFlag := flag.String("theme", "", "color theme: "+strings.Join(tui.ThemeNames(), ", ")+" (env MOLE_THEME)")
+	sessionName := flag.String("session-name", "", "label for this session in the history view")
+	noRedact := flag.Bool("no-redact", false, "store sensitive header values (Authorization, Cookie, ...) in history as-is")
+	historyOnly := flag.Bool("history", false, "browse the sessions in history without ngrok, which isn't polled")
+	debug := flag.Bool("debug", false, "write a debug log to ~/.mole/mole.log (env MOLE_DEBUG=1)")
+	flag.Usage = printUsage
+	flag.Parse()
+
+	build := currentBuild()
+	if *showVersion {
+		if err := printVersion(os.Stdout, build, *versionJSON); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}
+	if *versionJSON {
+		fmt.Fprintln(os.Stderr, "Error: --json only goes with --version (commands take their own --json)")
+		os.Exit(2)
+	}
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+	if env, _ := strconv.ParseBool(os.Getenv("MOLE_DEBUG")); env || *debug {
+		if err := openDebugLog(build); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: debug log disabled: %v\n", err)
+		}
+		defer debuglog.Close()
+	}
+
+	configPath, err := resolveConfigPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// mole config works on the config file itself, so a broken one mustn't
+	// stop it
+	if flag.Arg(0) == "config" {
+		os.Exit(runCommand("config", flag.Args()[1:], &commandEnv{configPath: configPath}))
+	}
+
+	// Settings come from the defaults, then the config file, then the
+	// environment, then flags
+	cfg, warnings, err := config.Load(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: config: %v\n", err)
+		os.Exit(1)
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: config: %s\n", w)
+	}
+
+	opts := tui.DefaultOptions()
+	if err := applyConfig(&opts, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: config: %v\n", err)
+		os.Exit(1)
+	}
+	opts.SessionName = *sessionName
+	opts.Version = build.Version
+	if env := os.Getenv("MOLE_REDACT_HEADERS"); env != "" {
+		opts.RedactHeaders = strings.Split(env, ",")
+	}
+	if *noRedact {
+		opts.RedactHeaders = nil
+	}
+	if err := optionsFromEnv(&opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if setFlags["poll"] {
+		opts.PollInterval = *poll
+	}
+	if setFlags["idle-poll"] {
+		opts.IdlePollInterval = *idlePoll
+	}
+	if setFlags["limit"] {
+		opts.PollLimit = *limit
+	}
+	if err := checkPolling(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	// Polling never speeds up when the window loses focus
+	opts.IdlePollInterval = max(opts.IdlePollInterval, opts.PollInterval)
 
-	// Initialize ngrok client
-	baseURL := os.Getenv("NGROK_API_URL")
+	dbPath, err := resolveDBPath(*dbFlag, *noStore, cfg.DBPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Initialize ngrok client: --api, then NGROK_API_URL, then the config
+	// file, then the default
+	baseURL := *apiFlag
+	if baseURL == "" {
+		baseURL = os.Getenv("NGROK_API_URL")
+	}
+	if baseURL == "" {
+		baseURL = cfg.APIURL
+	}
 	if baseURL == "" {
 		baseURL = ngrok.DefaultBaseURL
 	}
 
 	client := ngrok.NewClient(baseURL)
 
-	// Check if ngrok is running
-	if !client.IsAvailable() {
-		fmt.Println("⚠️  Cannot connect to ngrok local API at", baseURL)
-		fmt.Println()
-		fmt.Println("Make sure ngrok is running:")
-		fmt.Println("  $ ngrok http 8080")
-		fmt.Println()
-		fmt.Println("Or set NGROK_API_URL environment variable to a custom URL.")
+	// Subcommands run in place of the TUI
+	if flag.NArg() > 0 {
+		env := &commandEnv{configPath: configPath, dbPath: dbPath, opts: opts, client: client, baseURL: baseURL,
+			cfg: cfg, themeFlag: *themeFlag}
+		os.Exit(runCommand(flag.Arg(0), flag.Args()[1:], env))
+	}
+
+	if err := loadLook(&opts, cfg, *themeFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Create and run TUI
-	app := tui.NewApp(client)
-	p := tea.NewProgram(app, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	var store *storage.Storage
+	if *historyOnly {
+		// Browsing history without ngrok needs the history, where live
+		// capture can do without it
+		if store, err = openHistory("--history", dbPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Offline = true
+	} else {
+		if !checkNgrok(client, baseURL) {
+			os.Exit(1)
+		}
+
+		// Open history storage (non-fatal if it fails; the TUI shows history as off)
+		if store, err = storage.New(dbPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: history disabled: %v\n", err)
+			store = nil
+		}
+	}
 
-	if _, err := p.Run(); err != nil {
+	// Create and run TUI
+	if err := runTUI(tui.NewApp(client, store, opts)); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// runTUI runs the app until it quits
+func runTUI(app *tui.App) error {
+	p := tea.NewProgram(app, tea.WithAltScreen(), tea.WithMouseCellMotion(), tea.WithReportFocus())
+
+	// Quit through the program on signals (including a closed terminal) so
+	// the session is still ended below
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		<-sigs
+		p.Quit()
+	}()
+
+	_, err := p.Run()
+	// Flush queued history and end the session before exiting
+	app.CloseStorage()
+	return err
+}
+
+// printUsage is the --help text: the flags the TUI takes, then the commands
+// run in its place
+func printUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage: mole [flags]")
+	fmt.Fprintln(out, "       mole [flags] <command> [args]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Flags:")
+	flag.PrintDefaults()
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	printCommandUsages(out)
+}
+
+// checkPolling validates the polling options, wherever they were set
+func checkPolling(opts tui.Options) error {
+	if opts.PollInterval < tui.MinPollInterval {
+		return fmt.Errorf("the poll interval (--poll or poll_interval) must be at least %s, got %s", tui.MinPollInterval, opts.PollInterval)
+	}
+	if opts.IdlePollInterval < tui.MinPollInterval {
+		return fmt.Errorf("the idle poll interval (--idle-poll or idle_poll_interval) must be at least %s, got %s", tui.MinPollInterval, opts.IdlePollInterval)
+	}
+	if opts.PollLimit < 1 {
+		return fmt.Errorf("the poll limit (--limit or poll_limit) must be at least 1, got %d", opts.PollLimit)
+	}
+	return nil
+}
+
+// resolveDBPath picks the history database path: --no-store, then --db,
+// then MOLE_DB_PATH, then the config file, then the default location
+func resolveDBPath(dbFlag string, noStore bool, configured string) (string, error) {
+	if noStore {
+		return storage.MemoryPath, nil
+	}
+	if dbFlag != "" {
+		return dbFlag, nil
+	}
+	if env := os.Getenv("MOLE_DB_PATH"); env != "" {
+		return env, nil
+	}
+	if configured != "" {
+		return configured, nil
+	}
+	return storage.DefaultDBPath()
+}
+
+// loadLook sets the theme and keys, for what draws the TUI: the theme from
+// --theme, then MOLE_THEME, then the config file. Commands that only print
+// don't load them, so a mistake in either doesn't stop them.
+func loadLook(opts *tui.Options, cfg *config.Config, themeFlag string) error {
+	themeName := cfg.Theme
+	if env := os.Getenv("MOLE_THEME"); env != "" {
+		themeName = env
+	}
+	if themeFlag != "" {
+		themeName = themeFlag
+	}
+	theme, err := tui.NewTheme(themeName, cfg.Colors)
+	if err != nil {
+		return fmt.Errorf("theme: %w", err)
+	}
+	keyOverrides := make(map[string][]string, len(cfg.Keys))
+	for action, keys := range cfg.Keys {
+		keyOverrides[action] = keys
+	}
+	keys, warnings, err := tui.NewKeyMap(keyOverrides)
+	if err != nil {
+		return fmt.Errorf("keys: %w", err)
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: keys: %s\n", w)
+	}
+	opts.Theme, opts.Keys = theme, keys
+	return nil
+}
+
+// openDebugLog starts the debug log, noting what mole was started with
+func openDebugLog(build buildInfo) error {
+	path, err := debuglog.DefaultPath()
+	if err != nil {
+		return err
+	}
+	if err := debuglog.Open(path); err != nil {
+		return err
+	}
+	debuglog.Printf("mole %s (%s, %s) started: %q", build.Version, build.Commit, build.GoVersion, os.Args[1:])
+	return nil
+}
+
+// resolveConfigPath picks the config file: MOLE_CONFIG, then
+// ~/.mole/config.yaml
+func resolveConfigPath() (string, error) {
+	if env := os.Getenv("MOLE_CONFIG"); env != "" {
+		return env, nil
+	}
+	return config.DefaultPath()
+}
+
+// applyConfig overrides options with the settings in the config file. The
+// theme and keys are built from it separately.
+func applyConfig(opts *tui.Options, cfg *config.Config) error {
+	if cfg.PollInterval != 0 {
+		opts.PollInterval = cfg.PollInterval
+	}
+	if cfg.IdlePollInterval != 0 {
+		opts.IdlePollInterval = cfg.IdlePollInterval
+	}
+	if cfg.PollLimit != 0 {
+		opts.PollLimit = cfg.PollLimit
+	}
+	maxBodyKB := opts.MaxBodyBytes / 1024
+	ints := []struct {
+		key        string
+		configured *int
+		value      *int
+	}{
+		{"retention.keep_days", cfg.Retention.KeepDays, &opts.Retention.KeepDays},
+		{"retention.keep_count", cfg.Retention.KeepCount, &opts.Retention.KeepCount},
+		{"retention.max_db_mb", cfg.Retention.MaxDBMB, &opts.Retention.MaxSizeMB},
+		{"max_body_kb", cfg.MaxBodyKB, &maxBodyKB},
+		{"hex_dump_bytes", cfg.HexDumpBytes, &opts.HexDumpBytes},
+	}
+	for _, v := range ints {
+		if v.configured == nil {
+			continue
+		}
+		if *v.configured < 0 {
+			return fmt.Errorf("%s must be a non-negative number, got %d", v.key, *v.configured)
+		}
+		*v.value = *v.configured
+	}
+	opts.MaxBodyBytes = maxBodyKB * 1024
+	if cfg.RedactHeaders != nil {
+		opts.RedactHeaders = cfg.RedactHeaders
+	}
+	opts.RestoreFilters = cfg.RestoreFilters
+	if cfg.DiffIgnoreHeaders != nil {
+		opts.DiffIgnore = cfg.DiffIgnoreHeaders
+	}
+	return nil
+}
+
+// optionsFromEnv overrides options from MOLE_KEEP_DAYS, MOLE_KEEP_COUNT,
+// MOLE_MAX_DB_MB, MOLE_MAX_BODY_KB and MOLE_HEX_DUMP_BYTES
+func optionsFromEnv(opts *tui.Options) error {
+	maxBodyKB := opts.MaxBodyBytes / 1024
+	vars := []struct {
+		name  string
+		value *int
+	}{
+		{"MOLE_KEEP_DAYS", &opts.Retention.KeepDays},
+		{"MOLE_KEEP_COUNT", &opts.Retention.KeepCount},
+		{"MOLE_MAX_DB_MB", &opts.Retention.MaxSizeMB},
+		{"MOLE_MAX_BODY_KB", &maxBodyKB},
+		{"MOLE_HEX_DUMP_BYTES", &opts.HexDumpBytes},
+	}
+	for _, v := range vars {
+		raw := os.Getenv(v.name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%s must be a non-negative number, got %q", v.name, raw)
+		}
+		*v.value = n
+	}
+	opts.MaxBodyBytes = maxBodyKB * 1024
+	return nil
+}
+
+// checkNgrok reports whether the ngrok API answers, explaining how to start
+// ngrok if it doesn't
+func checkNgrok(client *ngrok.Client, baseURL string) bool {
+	if client.IsAvailable() {
+		return true
+	}
+	fmt.Println("⚠️  Cannot connect to ngrok local API at", baseURL)
+	fmt.Println()
+	fmt.Println("Make sure ngrok is running:")
+	fmt.Println("  $ ngrok http 8080")
+	fmt.Println()
+	fmt.Println("Or point mole at another URL with --api or the NGROK_API_URL environment variable.")
+	fmt.Println()
+	fmt.Println("To browse the sessions in history without ngrok:")
+	fmt.Println("  $ mole --history")
+	return false
+}
+
+// runImport imports exported session files into the history database
+func runImport(env *commandEnv, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: mole import <file.json> [file.json...]")
+		return 2
+	}
+
+	store, err := openHistory("import", env.dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	for _, path := range args {
+		result, err := store.ImportSessionJSON(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", path, err)
+			return 1
+		}
+		fmt.Printf("Imported %d requests into session %s", result.Imported, result.SessionID)
+		if result.Skipped > 0 {
+			fmt.Printf(" (%d already present)", result.Skipped)
+		}
+		switch {
+		case result.Templates == 1:
+			fmt.Print(", plus 1 template")
+		case result.Templates > 1:
+			fmt.Printf(", plus %d templates", result.Templates)
+		}
+		fmt.Println()
+	}
+
+	return 0
+}
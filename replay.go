@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sung01299/mole/internal/capture"
+	"github.com/sung01299/mole/internal/ngrok"
+)
+
+// runReplay replays a captured request through ngrok, waiting for each
+// replay to be listed and printing what it got back
+func runReplay(env *commandEnv, args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	target := fs.String("target", "", "address to send the replay to instead of the tunnel's, as in localhost:3000")
+	count := fs.Int("count", 1, "how many times to replay it")
+	interval := fs.Duration("interval", 0, "time to wait between replays, as in 500ms")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: mole replay [--target <addr>] [--count <n>] [--interval <duration>] <request-id>")
+		fs.PrintDefaults()
+	}
+	// Flags may come after the ID too
+	var id string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		if id != "" {
+			fs.Usage()
+			return 2
+		}
+		id, args = fs.Arg(0), fs.Args()[1:]
+	}
+	if id == "" {
+		fs.Usage()
+		return 2
+	}
+	if *count < 1 || *interval < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --count must be at least 1 and --interval can't be negative")
+		return 2
+	}
+
+	listed, err := capture.Poll(env.client, env.opts.PollLimit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	orig, err := resolveRequestID(listed, id, env.opts.PollLimit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Anything listed already, and each replay once captured, can't be the
+	// capture of a later replay
+	taken := make(map[string]bool, len(listed))
+	for _, req := range listed {
+		taken[req.ID] = true
+	}
+
+	fmt.Printf("Replaying %s %s (%s)\n", orig.Request.Method, orig.Request.URI, orig.ID)
+	failed := 0
+	for i := 1; i <= *count; i++ {
+		prefix := ""
+		if *count > 1 {
+			prefix = fmt.Sprintf("[%d/%d] ", i, *count)
+		}
+		if i > 1 {
+			time.Sleep(*interval)
+		}
+
+		sent := time.Now()
+		if err := env.client.ReplayTo(orig.ID, *target); err != nil {
+			fmt.Fprintf(os.Stderr, "%sError: %v\n", prefix, err)
+			failed++
+			continue
+		}
+		replay, err := awaitReplay(env, orig, sent, taken)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%sError: %v\n", prefix, err)
+			failed++
+			continue
+		}
+		taken[replay.ID] = true
+
+		status := replay.Response.Status
+		if status == "" {
+			status = fmt.Sprint(replay.StatusCode())
+		}
+		fmt.Printf("%s%s in %.0fms (%s)\n", prefix, status, replay.DurationMs(), replay.ID)
+	}
+
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// resolveRequestID finds the listed request an ID, or the start of one,
+// refers to. ngrok's IDs all begin alike, as in airt_2Mv9ad1c2, so the start
+// may also leave out the part up to the underscore. limit is how many
+// requests were asked of ngrok.
+func resolveRequestID(listed []ngrok.Request, id string, limit int) (ngrok.Request, error) {
+	var matches []ngrok.Request
+	for _, req := range listed {
+		if req.ID == id {
+			return req, nil
+		}
+		_, rest, _ := strings.Cut(req.ID, "_")
+		if strings.HasPrefix(req.ID, id) || strings.HasPrefix(rest, id) {
+			matches = append(matches, req)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return ngrok.Request{}, fmt.Errorf("no captured request with ID %q (ngrok lists the last %d)", id, limit)
+	case 1:
+		return matches[0], nil
+	}
+	var ids []string
+	for _, req := range matches[:min(len(matches), 5)] {
+		ids = append(ids, req.ID)
+	}
+	if len(matches) > 5 {
+		ids = append(ids, "...")
+	}
+	return ngrok.Request{}, fmt.Errorf("ID %q is ambiguous, it could be %s", id, strings.Join(ids, ", "))
+}
+
+// awaitReplay polls ngrok until it lists the replay of orig sent at sent
+func awaitReplay(env *commandEnv, orig ngrok.Request, sent time.Time, taken map[string]bool) (ngrok.Request, error) {
+	deadline := sent.Add(capture.ReplayCaptureWait)
+	for time.Now().Before(deadline) {
+		time.Sleep(env.opts.PollInterval)
+		listed, err := capture.Poll(env.client, env.opts.PollLimit)
+		if err != nil {
+			continue
+		}
+		replay, ok := capture.FindReplay(listed, orig.Request.Method, orig.Request.URI, sent, func(id string) bool {
+			return taken[id]
+		})
+		if ok {
+			return replay, nil
+		}
+	}
+	return ngrok.Request{}, fmt.Errorf("replay sent, but ngrok didn't list it within %s", capture.ReplayCaptureWait)
+}
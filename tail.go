@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sung01299/mole/internal/capture"
+	"github.com/sung01299/mole/internal/ngrok"
+	"github.com/sung01299/mole/internal/storage"
+	"github.com/sung01299/mole/internal/tui"
+)
+
+// runTail prints requests as ngrok captures them, one per line, saving them
+// to history as the TUI does, until interrupted
+func runTail(env *commandEnv, args []string) int {
+	fs := flag.NewFlagSet("tail", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print each request as a JSON object on one line, headers and bodies included")
+	filterExpr := fs.String("filter", "", `only print requests matching a filter expression, as in 'status >= 500 && path match "/api"'`)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: mole tail [--json] [--filter <expr>]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var filter *tui.RequestFilter
+	if *filterExpr != "" {
+		var err error
+		if filter, err = tui.NewRequestFilter(*filterExpr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --filter: %v\n", err)
+			return 2
+		}
+	}
+
+	// History is optional here as in the TUI
+	client, opts := env.client, env.opts
+	store, err := storage.New(env.dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: history disabled: %v\n", err)
+		store = nil
+	}
+	var writer *storage.Writer
+	var saveErrs <-chan error
+	if store != nil {
+		writer = storage.NewWriter(store)
+		saveErrs = writer.Errors()
+	}
+	recorder := capture.NewRecorder(store, writer, opts.RedactHeaders, opts.MaxBodyBytes)
+	enc := json.NewEncoder(os.Stdout)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+
+	var lastErr string
+	for {
+		// The session starts once ngrok has a tunnel to name it after
+		if store != nil && store.CurrentSessionID() == "" {
+			if tunnels, err := client.GetTunnels(); err == nil && len(tunnels) > 0 {
+				store.StartSession(tunnels[0].PublicURL, opts.SessionName)
+			}
+		}
+
+		reqs, err := capture.Poll(client, opts.PollLimit)
+		switch {
+		case err != nil:
+			// Reported once, not on every poll until ngrok is back
+			if err.Error() != lastErr {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				lastErr = err.Error()
+			}
+		default:
+			lastErr = ""
+			fresh := recorder.Record(reqs, nil)
+			// ngrok lists the newest first
+			for i := len(fresh) - 1; i >= 0; i-- {
+				req := fresh[i]
+				if filter != nil && !filter.Match(req) {
+					continue
+				}
+				if *asJSON {
+					enc.Encode(storage.ToExportRequest(recorder.HistoryRequest(req)))
+				} else {
+					fmt.Println(tailLine(req))
+				}
+			}
+		}
+
+		select {
+		case <-sigs:
+			// Flush queued history and end the session before exiting
+			if store != nil {
+				writer.Close()
+				store.Close()
+			}
+			return 0
+		case err := <-saveErrs:
+			fmt.Fprintf(os.Stderr, "Warning: history: %v\n", err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// tailLine is the line mole tail prints for a request: when it started, its
+// method and path, and the status and time of its response
+func tailLine(req ngrok.Request) string {
+	status := "---"
+	if code := req.StatusCode(); code != 0 {
+		status = fmt.Sprint(code)
+	}
+	return fmt.Sprintf("%s %-7s %s %s %.0fms",
+		req.Start.Local().Format("2006-01-02 15:04:05.000"), req.Request.Method, req.Request.URI, status, req.DurationMs())
+}
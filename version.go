@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"text/tabwriter"
+)
+
+// Set at build time, as the Makefile does with
+// -ldflags "-X main.version=... -X main.commit=... -X main.buildTime=...".
+// Whatever is left unset is read from the build info Go embeds.
+var (
+	version   string
+	commit    string
+	buildTime string
+)
+
+// buildInfo describes the running binary, for mole --version
+type buildInfo struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit,omitempty"`
+	Modified   bool   `json:"modified,omitempty"` // Built from a tree with uncommitted changes
+	CommitTime string `json:"commit_time,omitempty"`
+	BuildTime  string `json:"build_time,omitempty"`
+	GoVersion  string `json:"go_version"`
+	Platform   string `json:"platform"`
+}
+
+// currentBuild gathers the build info from the ldflags, falling back on what
+// go build and go install embed: the commit and its time for a build in a
+// checkout, or the module version for go install module@version. A checkout
+// is given a pseudo-version, which says no more than the commit does.
+func currentBuild() buildInfo {
+	info := buildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		inCheckout := false
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				inCheckout = true
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				info.CommitTime = s.Value
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+		if info.Version == "" && !inCheckout && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if len(info.Commit) > 12 {
+		info.Commit = info.Commit[:12]
+	}
+	return info
+}
+
+// printVersion writes the build info for mole --version, as JSON for tools
+// or as a block of lines for people
+func printVersion(w io.Writer, info buildInfo, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+
+	fmt.Fprintf(w, "mole %s\n", info.Version)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if info.Commit != "" {
+		c := info.Commit
+		if info.Modified {
+			c += " (modified)"
+		}
+		fmt.Fprintf(tw, "  Commit\t%s\n", c)
+	}
+	if info.CommitTime != "" {
+		fmt.Fprintf(tw, "  Committed\t%s\n", info.CommitTime)
+	}
+	if info.BuildTime != "" {
+		fmt.Fprintf(tw, "  Built\t%s\n", info.BuildTime)
+	}
+	fmt.Fprintf(tw, "  Go\t%s\n", info.GoVersion)
+	fmt.Fprintf(tw, "  Platform\t%s\n", info.Platform)
+	return tw.Flush()
+}
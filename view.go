@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sung01299/mole/internal/storage"
+	"github.com/sung01299/mole/internal/tui"
+)
+
+// runView opens an exported session, or an HTTP Archive, in the TUI without
+// ngrok or the history database
+func runView(env *commandEnv, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: mole view <file.json|file.har>")
+		return 2
+	}
+
+	export, err := storage.ReadExportFile(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(export.Requests) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: %s has no requests\n", args[0])
+		return 1
+	}
+
+	opts := env.opts
+	opts.Offline = true
+	opts.ViewName = filepath.Base(args[0])
+	if export.Label != "" {
+		opts.ViewName += " · " + export.Label
+	}
+	// Newest first, as history lists them; archives are oldest first
+	opts.ViewRequests = export.HistoryRequests()
+	sort.SliceStable(opts.ViewRequests, func(i, j int) bool {
+		return opts.ViewRequests[i].Timestamp.After(opts.ViewRequests[j].Timestamp)
+	})
+	if err := runTUI(tui.NewApp(env.client, nil, opts)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sung01299/mole/internal/capture"
+	"github.com/sung01299/mole/internal/tui"
+)
+
+// Exit statuses of mole watch. 2 is a usage error, as for every command.
+const (
+	watchExitMatched = 1 // A request matching the filter came in
+	watchExitNgrok   = 3 // ngrok couldn't be reached, at the start or since
+)
+
+// watchPollFailures is how many polls in a row may fail before mole watch
+// gives ngrok up for gone
+const watchPollFailures = 3
+
+// runWatch polls ngrok for a while and fails if any request that came in
+// meanwhile matches a filter, for smoke tests in CI. Nothing is saved to
+// history.
+func runWatch(env *commandEnv, args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	filterExpr := fs.String("filter", "", `filter expression requests are checked against, as in 'status >= 500' (default: every request matches)`)
+	duration := fs.Duration("for", 0, "how long to watch, as in 60s")
+	quiet := fs.Bool("quiet", false, "don't print the matching requests, only the summary")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: mole watch --for <duration> [--filter <expr>] [--quiet]")
+		fs.PrintDefaults()
+		fmt.Fprintln(os.Stderr, "\nExits 0 if no request matched, 1 if any did, and 3 if ngrok couldn't be reached.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 || *duration <= 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var filter *tui.RequestFilter
+	if *filterExpr != "" {
+		var err error
+		if filter, err = tui.NewRequestFilter(*filterExpr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --filter: %v\n", err)
+			return 2
+		}
+	}
+
+	// Only requests that come in from now on count
+	client, opts := env.client, env.opts
+	listed, err := capture.Poll(client, opts.PollLimit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: can't reach ngrok at %s: %v\n", env.baseURL, err)
+		return watchExitNgrok
+	}
+	seen := capture.NewRecorder(nil, nil, nil, 0)
+	seen.Record(listed, nil)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+	deadline := time.After(*duration)
+	start := time.Now()
+
+	matched, failures := 0, 0
+	for done := false; !done; {
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			done = true
+		case <-sigs:
+			// Stopped early: report what was seen so far
+			done = true
+		}
+
+		reqs, err := capture.Poll(client, opts.PollLimit)
+		if err != nil {
+			failures++
+			if failures >= watchPollFailures {
+				fmt.Fprintf(os.Stderr, "Error: lost ngrok after %s: %v\n", time.Since(start).Round(time.Second), err)
+				return watchExitNgrok
+			}
+			continue
+		}
+		failures = 0
+
+		fresh := seen.Record(reqs, nil)
+		// ngrok lists the newest first
+		for i := len(fresh) - 1; i >= 0; i-- {
+			if filter != nil && !filter.Match(fresh[i]) {
+				continue
+			}
+			matched++
+			if !*quiet {
+				fmt.Println(tailLine(fresh[i]))
+			}
+		}
+	}
+
+	elapsed := time.Since(start).Round(time.Second)
+	if matched == 0 {
+		fmt.Fprintf(os.Stderr, "No matching requests in %s\n", elapsed)
+		return 0
+	}
+	noun := "requests"
+	if matched == 1 {
+		noun = "request"
+	}
+	fmt.Fprintf(os.Stderr, "%d matching %s in %s\n", matched, noun, elapsed)
+	return watchExitMatched
+}